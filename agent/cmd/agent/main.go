@@ -11,9 +11,15 @@ import (
 
 	"github.com/yourusername/agent-platform/agent/internal/client"
 	"github.com/yourusername/agent-platform/agent/internal/config"
+	"github.com/yourusername/agent-platform/agent/internal/executor"
+	"github.com/yourusername/agent-platform/agent/internal/version"
+	"github.com/yourusername/agent-platform/pkg/tasksign"
 )
 
 func main() {
+	// 以任务执行辅助进程身份启动时设置资源限制后直接 exec 脚本，不会返回
+	executor.Init()
+
 	configPath := flag.String("config", "agent/config.yaml", "配置文件路径")
 	flag.Parse()
 
@@ -23,8 +29,25 @@ func main() {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
+	// 加载本地脚本策略
+	var policy *config.Policy
+	if cfg.Agent.PolicyFile != "" {
+		if policy, err = config.LoadPolicy(cfg.Agent.PolicyFile); err != nil {
+			log.Fatalf("Failed to load policy: %v", err)
+		}
+	}
+
 	// 创建客户端
-	c := client.NewClient(cfg.Server.Address, cfg.Server.TLS, cfg.Agent.ID)
+	c := client.NewClient(cfg, policy)
+	if cfg.Agent.TaskPublicKeyFile != "" {
+		pub, err := tasksign.LoadPublicKey(cfg.Agent.TaskPublicKeyFile)
+		if err != nil {
+			log.Fatalf("Failed to load task public key: %v", err)
+		}
+		c.SetTaskKey(pub)
+	} else {
+		log.Println("agent.task_public_key_file not configured, task signatures are not verified")
+	}
 
 	// 连接到服务器
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -35,13 +58,14 @@ func main() {
 	}
 	defer c.Close()
 
-	log.Printf("Agent %s started", cfg.Agent.ID)
+	log.Printf("Agent %s started, version %s", cfg.Agent.ID, version.Version)
 
-	// 运行客户端
+	// 运行客户端，断线后自动重连
+	runCtx, stop := context.WithCancel(context.Background())
+	done := make(chan struct{})
 	go func() {
-		if err := c.Run(context.Background()); err != nil {
-			log.Printf("Client error: %v", err)
-		}
+		defer close(done)
+		c.RunWithReconnect(runCtx)
 	}()
 
 	// 等待退出信号
@@ -49,5 +73,7 @@ func main() {
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
 	<-sigCh
 
+	stop()
+	<-done
 	log.Println("Agent shutting down")
 }
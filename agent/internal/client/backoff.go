@@ -0,0 +1,33 @@
+package client
+
+import (
+	"math/rand"
+	"time"
+)
+
+// backoff 带随机抖动的指数退避：第 n 次等待时间在 [d/2, d) 之间，d = min*2^n，不超过 max
+type backoff struct {
+	min     time.Duration
+	max     time.Duration
+	attempt int
+}
+
+func newBackoff(min, max time.Duration) *backoff {
+	return &backoff{min: min, max: max}
+}
+
+func (b *backoff) Next() time.Duration {
+	d := b.min << b.attempt
+	if d <= 0 || d > b.max {
+		d = b.max
+	} else {
+		b.attempt++
+	}
+
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(d-half)))
+}
+
+func (b *backoff) Reset() {
+	b.attempt = 0
+}
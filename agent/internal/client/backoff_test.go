@@ -0,0 +1,23 @@
+package client
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	b := newBackoff(time.Second, 10*time.Second)
+
+	expected := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second, 10 * time.Second}
+	for i, max := range expected {
+		d := b.Next()
+		if d < max/2 || d >= max {
+			t.Errorf("attempt %d: expected delay in [%s, %s), got %s", i, max/2, max, d)
+		}
+	}
+
+	b.Reset()
+	if d := b.Next(); d >= time.Second {
+		t.Errorf("expected delay below 1s after reset, got %s", d)
+	}
+}
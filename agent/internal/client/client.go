@@ -2,41 +2,108 @@ package client
 
 import (
 	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
 
 	pb "github.com/yourusername/agent-platform/proto"
+	"github.com/yourusername/agent-platform/agent/internal/config"
 	"github.com/yourusername/agent-platform/agent/internal/executor"
+	"github.com/yourusername/agent-platform/agent/internal/inventory"
+	"github.com/yourusername/agent-platform/agent/internal/outbox"
 	"github.com/yourusername/agent-platform/agent/internal/plugin"
+	"github.com/yourusername/agent-platform/pkg/tlsutil"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
 )
 
+const (
+	// defaultCollectInterval 未配置 collect_interval 时的指标上报间隔
+	defaultCollectInterval = 30 * time.Second
+	// defaultHeartbeatInterval 未配置 heartbeat_interval 时的心跳间隔
+	defaultHeartbeatInterval = 30 * time.Second
+	// defaultDataDir 未配置 data_dir 时的数据目录
+	defaultDataDir = "/var/lib/agent"
+
+	// 重连退避参数
+	minReconnectDelay = time.Second
+	maxReconnectDelay = time.Minute
+	// stableConnection 连接保持超过该时长后重置退避
+	stableConnection = time.Minute
+)
+
+var errNotConnected = errors.New("not connected to platform")
+
 type Client struct {
-	serverAddr    string
-	useTLS        bool
-	agentID       string
-	conn          *grpc.ClientConn
-	executor      *executor.Executor
-	pluginManager *plugin.Manager
+	serverAddr        string
+	serverConfig      config.ServerConfig
+	agentID           string
+	collectInterval   time.Duration
+	heartbeatInterval time.Duration
+	credentialFile    string // 登记后平台签发的凭据
+	enrollmentToken   string
+	conn              *grpc.ClientConn
+	sendMu            sync.Mutex
+	stream            pb.AgentService_ConnectClient // 当前连接，断开期间为 nil
+	outbox            *outbox.Outbox
+	executor          *executor.Executor
+	pluginManager     *plugin.Manager
+	policy            *config.Policy    // 本地脚本策略，nil 表示不限制
+	taskKey           ed25519.PublicKey // 平台任务签名公钥，nil 表示不校验签名
+	replay            *replayGuard
 }
 
-func NewClient(serverAddr string, useTLS bool, agentID string) *Client {
-	return &Client{
-		serverAddr:    serverAddr,
-		useTLS:        useTLS,
-		agentID:       agentID,
-		executor:      executor.NewExecutor(),
-		pluginManager: plugin.NewManager("/var/lib/agent/plugins"),
+// NewClient 创建客户端，policy 为 nil 时不做本地脚本策略检查
+func NewClient(cfg *config.Config, policy *config.Policy) *Client {
+	collectInterval := time.Duration(cfg.Agent.CollectInterval) * time.Second
+	if collectInterval <= 0 {
+		collectInterval = defaultCollectInterval
+	}
+	heartbeatInterval := time.Duration(cfg.Agent.HeartbeatInterval) * time.Second
+	if heartbeatInterval <= 0 {
+		heartbeatInterval = defaultHeartbeatInterval
+	}
+	dataDir := cfg.Agent.DataDir
+	if dataDir == "" {
+		dataDir = defaultDataDir
+	}
+
+	taskExecutor := executor.NewExecutor()
+	taskExecutor.SetCgroupDir(cfg.Agent.TaskCgroupDir)
+
+	c := &Client{
+		serverAddr:        cfg.Server.Address,
+		serverConfig:      cfg.Server,
+		agentID:           cfg.Agent.ID,
+		collectInterval:   collectInterval,
+		heartbeatInterval: heartbeatInterval,
+		credentialFile:    filepath.Join(dataDir, "credential"),
+		enrollmentToken:   cfg.Agent.EnrollmentToken,
+		outbox:            outbox.New(filepath.Join(dataDir, "outbox")),
+		executor:          taskExecutor,
+		pluginManager:     plugin.NewManager(dataDir),
+		policy:            policy,
+		replay:            newReplayGuard(filepath.Join(dataDir, "received_tasks")),
 	}
+	c.pluginManager.OnStatus(c.sendPluginStatus)
+	return c
 }
 
 func (c *Client) Connect(ctx context.Context) error {
-	var opts []grpc.DialOption
-	if !c.useTLS {
-		opts = append(opts, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	creds, err := c.transportCredentials()
+	if err != nil {
+		return err
 	}
+	opts := []grpc.DialOption{grpc.WithTransportCredentials(creds)}
 
 	conn, err := grpc.DialContext(ctx, c.serverAddr, opts...)
 	if err != nil {
@@ -47,25 +114,87 @@ func (c *Client) Connect(ctx context.Context) error {
 	return nil
 }
 
+// RunWithReconnect 保持与平台的连接直到 ctx 结束：连接断开后按带随机抖动的指数退避重连并重新注册。
+// 断开期间产生的任务结果和指标缓存在 outbox 中，重连后按产生顺序回放
+func (c *Client) RunWithReconnect(ctx context.Context) {
+	// 按采集间隔批量上报插件指标，退出时上报剩余数据
+	metrics := c.pluginManager.Metrics()
+	metrics.Start(c.collectInterval, c.sendMetrics)
+	defer metrics.Stop()
+
+	b := newBackoff(minReconnectDelay, maxReconnectDelay)
+	for {
+		start := time.Now()
+		err := c.Run(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err == nil {
+			err = errors.New("stream closed by server")
+		}
+		if time.Since(start) >= stableConnection {
+			b.Reset()
+		}
+
+		delay := b.Next()
+		log.Printf("Disconnected from platform: %v, reconnecting in %s", err, delay)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// transportCredentials 根据配置返回连接凭据，启用 TLS 时证书文件更新后自动重新加载
+func (c *Client) transportCredentials() (credentials.TransportCredentials, error) {
+	if !c.serverConfig.TLS {
+		return insecure.NewCredentials(), nil
+	}
+
+	reloader, err := tlsutil.NewReloader(c.serverConfig.CertFile, c.serverConfig.KeyFile, c.serverConfig.CAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS certificates: %w", err)
+	}
+	return credentials.NewTLS(tlsutil.ClientConfig(reloader, c.serverConfig.ServerName)), nil
+}
+
+// Run 建立一次流连接并处理服务器消息，直到连接断开
 func (c *Client) Run(ctx context.Context) error {
+	credential, err := c.credential(ctx)
+	if err != nil {
+		return err
+	}
+
 	client := pb.NewAgentServiceClient(c.conn)
 	stream, err := client.Connect(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to create stream: %w", err)
 	}
 
-	// 发送注册消息
+	// 发送注册消息，附带主机资产信息与凭据
+	register := inventory.Collect().ToRegister(c.agentID)
+	register.Credential = credential
 	if err := stream.Send(&pb.AgentMessage{
 		Message: &pb.AgentMessage_Register{
-			Register: &pb.AgentRegister{
-				AgentId: c.agentID,
-			},
+			Register: register,
 		},
 	}); err != nil {
 		return fmt.Errorf("failed to register: %w", err)
 	}
 
-	// 接收服务器消息
+	defer c.deactivate(stream)
+	defer c.pluginManager.AbortTransfers()
+	return c.receive(ctx, stream)
+}
+
+// receive 处理服务器消息，直到连接断开。注册成功后才回放断开期间缓存的消息并开始发送心跳，
+// 注册被拒绝时缓存的消息保留在 outbox 中
+func (c *Client) receive(ctx context.Context, stream pb.AgentService_ConnectClient) error {
+	heartbeatCtx, stopHeartbeat := context.WithCancel(ctx)
+	defer stopHeartbeat()
+
+	registered := false
 	for {
 		msg, err := stream.Recv()
 		if err == io.EOF {
@@ -77,83 +206,397 @@ func (c *Client) Run(ctx context.Context) error {
 
 		switch m := msg.Message.(type) {
 		case *pb.ServerMessage_TaskRequest:
-			go c.handleTask(ctx, stream, m.TaskRequest)
+			go c.handleTask(ctx, m.TaskRequest)
+		case *pb.ServerMessage_CancelTask:
+			c.handleCancelTask(m.CancelTask)
 		case *pb.ServerMessage_RegisterResponse:
+			if !m.RegisterResponse.Success {
+				return fmt.Errorf("registration rejected: %s", m.RegisterResponse.Error)
+			}
+			// 平台也以 RegisterResponse 确认任务结果，只处理第一次
+			if registered {
+				continue
+			}
+			registered = true
 			log.Printf("Registered successfully")
+			if err := c.activate(stream); err != nil {
+				return fmt.Errorf("failed to replay buffered messages: %w", err)
+			}
+			// 定期发送心跳，平台据此判断 Agent 是否在线
+			go c.heartbeatLoop(heartbeatCtx)
 		case *pb.ServerMessage_HeartbeatAck:
-			log.Printf("Heartbeat acknowledged")
 		case *pb.ServerMessage_InstallPlugin:
-			go c.handleInstallPlugin(ctx, stream, m.InstallPlugin)
+			// 安装包分块紧随安装请求到达，需在接收循环中按顺序处理
+			if m.InstallPlugin.TransferId != "" {
+				c.beginPluginTransfer(m.InstallPlugin)
+			} else {
+				go c.handleInstallPlugin(ctx, m.InstallPlugin)
+			}
+		case *pb.ServerMessage_PluginChunk:
+			c.handlePluginChunk(m.PluginChunk)
 		case *pb.ServerMessage_UninstallPlugin:
-			go c.handleUninstallPlugin(ctx, stream, m.UninstallPlugin)
+			go c.handleUninstallPlugin(ctx, m.UninstallPlugin)
 		case *pb.ServerMessage_ListPlugins:
-			go c.handleListPlugins(ctx, stream, m.ListPlugins)
+			go c.handleListPlugins(ctx, m.ListPlugins)
 		}
 	}
 }
 
-func (c *Client) handleTask(ctx context.Context, stream pb.AgentService_ConnectClient, task *pb.TaskRequest) {
+// activate 先回放断开期间缓存的消息，全部发送后才将 stream 设为当前连接，
+// 保证缓存的消息先于新消息到达
+func (c *Client) activate(stream pb.AgentService_ConnectClient) error {
+	replayed := 0
+	for {
+		if err := c.outbox.Drain(func(msg *pb.AgentMessage) error {
+			if err := stream.Send(msg); err != nil {
+				return err
+			}
+			replayed++
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		c.sendMu.Lock()
+		if c.outbox.Len() == 0 {
+			c.stream = stream
+			c.sendMu.Unlock()
+			break
+		}
+		c.sendMu.Unlock()
+	}
+
+	if replayed > 0 {
+		log.Printf("Replayed %d buffered messages", replayed)
+	}
+	return nil
+}
+
+func (c *Client) deactivate(stream pb.AgentService_ConnectClient) {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+	if c.stream == stream {
+		c.stream = nil
+	}
+}
+
+func (c *Client) handleTask(ctx context.Context, task *pb.TaskRequest) {
 	log.Printf("Received task: %s", task.TaskId)
 
+	// 先校验签名，未通过校验的任务内容不可信
+	if err := c.verifyTask(task); err != nil {
+		c.rejectTask(task.TaskId, "rejected by agent: "+err.Error())
+		return
+	}
+
+	taskResult := &pb.TaskResult{
+		TaskId: task.TaskId,
+	}
+
 	// 将 TaskType 枚举转换为字符串
 	var scriptType string
 	switch task.Type {
 	case pb.TaskType_TASK_TYPE_SHELL:
 		scriptType = "shell"
+	case pb.TaskType_TASK_TYPE_BASH:
+		scriptType = "bash"
 	case pb.TaskType_TASK_TYPE_PYTHON:
 		scriptType = "python"
+	case pb.TaskType_TASK_TYPE_PERL:
+		scriptType = "perl"
+	case pb.TaskType_TASK_TYPE_POWERSHELL:
+		scriptType = "powershell"
+	case pb.TaskType_TASK_TYPE_FILE:
+		scriptType = "file"
 	default:
 		log.Printf("Unknown task type: %v", task.Type)
+		taskResult.ExitCode = -1
+		taskResult.Status = pb.TaskStatus_TASK_STATUS_FAILED
+		taskResult.Error = fmt.Sprintf("unknown task type: %v", task.Type)
+		c.sendTaskResult(taskResult)
 		return
 	}
 
-	result, err := c.executor.Execute(ctx, scriptType, task.Script, int(task.Timeout))
+	// file 类型执行前校验文件内容
+	script := task.Script
+	if scriptType == "file" {
+		sum := sha256.Sum256(task.File)
+		if task.FileSha256 == "" || !strings.EqualFold(task.FileSha256, hex.EncodeToString(sum[:])) {
+			c.rejectTask(task.TaskId, "rejected by agent: file checksum mismatch")
+			return
+		}
+		script = string(task.File)
+	}
 
-	taskResult := &pb.TaskResult{
-		TaskId: task.TaskId,
+	// 本地策略独立于平台策略执行，拒绝的任务不会开始执行
+	timeout, user := int(task.Timeout), task.RunAsUser
+	if c.policy != nil {
+		if err := c.policy.Check(&config.PolicyTask{
+			Type:    scriptType,
+			Script:  script,
+			Args:    task.Args,
+			Env:     task.Env,
+			Timeout: timeout,
+			User:    user,
+		}); err != nil {
+			c.rejectTask(task.TaskId, "rejected by agent policy: "+err.Error())
+			return
+		}
+		timeout = c.policy.Timeout(timeout)
+		user = c.policy.User(user)
 	}
 
+	// 通知平台任务已开始执行
+	if err := c.send(&pb.AgentMessage{
+		Message: &pb.AgentMessage_TaskAck{
+			TaskAck: &pb.TaskAck{
+				TaskId:    task.TaskId,
+				StartedAt: timestampNow(),
+			},
+		},
+	}); err != nil {
+		log.Printf("Failed to send task ack: %v", err)
+	}
+
+	// 执行过程中实时上报输出
+	var seq int64
+	onOutput := func(data []byte, isStderr bool) {
+		seq++
+		if err := c.send(&pb.AgentMessage{
+			Message: &pb.AgentMessage_TaskLog{
+				TaskLog: &pb.TaskLog{
+					TaskId:    task.TaskId,
+					Output:    string(data),
+					IsStderr:  isStderr,
+					Timestamp: timestampNow(),
+					Seq:       seq,
+				},
+			},
+		}); err != nil && !errors.Is(err, errNotConnected) {
+			log.Printf("Failed to send task log: %v", err)
+		}
+	}
+
+	opts := &executor.RunOptions{
+		User:    user,
+		Group:   task.RunAsGroup,
+		WorkDir: task.WorkDir,
+		Umask:   task.Umask,
+		Env:     task.Env,
+		Limits: executor.Limits{
+			CPUSeconds:   task.GetLimits().GetCpuSeconds(),
+			AddressSpace: task.GetLimits().GetAddressSpace(),
+			OpenFiles:    task.GetLimits().GetOpenFiles(),
+			Processes:    task.GetLimits().GetProcesses(),
+		},
+		Args:     task.Args,
+		FileName: task.FileName,
+	}
+	result, err := c.executor.ExecuteStream(ctx, task.TaskId, scriptType, script, timeout, opts, onOutput)
+
 	if err != nil {
 		taskResult.ExitCode = -1
 		taskResult.Stderr = err.Error()
+		taskResult.Error = err.Error()
+		// 超时、取消和触发资源限制时保留已产生的部分输出
+		if result != nil {
+			taskResult.Stdout = result.Stdout
+			taskResult.Stderr = result.Stderr
+			taskResult.LimitExceeded = result.LimitExceeded
+		}
+		switch {
+		case errors.Is(err, executor.ErrTimeout):
+			taskResult.Status = pb.TaskStatus_TASK_STATUS_TIMEOUT
+		case errors.Is(err, executor.ErrCancelled):
+			taskResult.Status = pb.TaskStatus_TASK_STATUS_CANCELLED
+		default:
+			taskResult.Status = pb.TaskStatus_TASK_STATUS_FAILED
+		}
 	} else {
 		taskResult.ExitCode = int32(result.ExitCode)
 		taskResult.Stdout = result.Stdout
 		taskResult.Stderr = result.Stderr
+		taskResult.Status = pb.TaskStatus_TASK_STATUS_COMPLETED
 	}
 
-	if err := stream.Send(&pb.AgentMessage{
+	c.sendTaskResult(taskResult)
+}
+
+func (c *Client) handleCancelTask(req *pb.CancelTaskRequest) {
+	if c.executor.Cancel(req.TaskId) {
+		log.Printf("Cancelling task: %s", req.TaskId)
+		return
+	}
+	log.Printf("Cancel requested for unknown task: %s", req.TaskId)
+}
+
+func (c *Client) sendTaskResult(taskResult *pb.TaskResult) {
+	taskResult.CompletedAt = timestampNow()
+
+	c.sendDurable(&pb.AgentMessage{
 		Message: &pb.AgentMessage_TaskResult{
 			TaskResult: taskResult,
 		},
-	}); err != nil {
-		log.Printf("Failed to send task result: %v", err)
+	})
+}
+
+func (c *Client) heartbeatLoop(ctx context.Context) {
+	ticker := time.NewTicker(c.heartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := c.send(&pb.AgentMessage{
+				Message: &pb.AgentMessage_Heartbeat{
+					Heartbeat: &pb.Heartbeat{
+						AgentId:   c.agentID,
+						Timestamp: timestampNow(),
+					},
+				},
+			}); err != nil {
+				log.Printf("Failed to send heartbeat: %v", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (c *Client) sendMetrics(points []*pb.MetricPoint) {
+	c.sendDurable(&pb.AgentMessage{
+		Message: &pb.AgentMessage_MetricBatch{
+			MetricBatch: &pb.MetricBatch{
+				AgentId: c.agentID,
+				Metrics: points,
+			},
+		},
+	})
+}
+
+// sendPluginStatus 上报插件状态变化，插件崩溃等事件在断开期间也不能丢失
+func (c *Client) sendPluginStatus(status *pb.PluginStatus) {
+	c.sendDurable(&pb.AgentMessage{
+		Message: &pb.AgentMessage_PluginStatus{
+			PluginStatus: status,
+		},
+	})
+}
+
+// send 在当前连接上发送消息，未连接时返回 errNotConnected。
+// gRPC 流不允许多个 goroutine 并发 Send，由 sendMu 串行化
+func (c *Client) send(msg *pb.AgentMessage) error {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+
+	if c.stream == nil {
+		return errNotConnected
+	}
+	return c.stream.Send(msg)
+}
+
+// sendDurable 发送不能丢失的消息（任务结果、指标），未连接或发送失败时缓存到 outbox，重连后回放
+func (c *Client) sendDurable(msg *pb.AgentMessage) {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+
+	if c.stream != nil {
+		err := c.stream.Send(msg)
+		if err == nil {
+			return
+		}
+		log.Printf("Failed to send message, buffering it: %v", err)
+	}
+	c.outbox.Push(msg)
+}
+
+func timestampNow() *pb.Timestamp {
+	now := time.Now()
+	return &pb.Timestamp{
+		Seconds: now.Unix(),
+		Nanos:   int32(now.Nanosecond()),
 	}
 }
 
-func (c *Client) handleInstallPlugin(ctx context.Context, stream pb.AgentService_ConnectClient, req *pb.InstallPluginRequest) {
+// handleInstallPlugin 加载并启动插件目录中已有的插件
+func (c *Client) handleInstallPlugin(ctx context.Context, req *pb.InstallPluginRequest) {
 	log.Printf("Installing plugin: %s", req.PluginName)
 
+	if err := c.verifyPlugin(req); err != nil {
+		c.sendInstallPluginResponse(req.PluginName, req.Version, err)
+		return
+	}
 	err := c.pluginManager.Load(req.PluginName)
 	if err == nil {
 		err = c.pluginManager.Start(req.PluginName)
+		if err == nil {
+			err = c.pluginManager.Configure(req.PluginName, req.Config)
+		}
+		if err != nil {
+			c.pluginManager.Unload(req.PluginName)
+		}
+	}
+	c.sendInstallPluginResponse(req.PluginName, req.Version, err)
+}
+
+// beginPluginTransfer 开始接收插件安装包
+func (c *Client) beginPluginTransfer(req *pb.InstallPluginRequest) {
+	log.Printf("Receiving plugin package: %s@%s (%d bytes)", req.PluginName, req.Version, req.Size)
+
+	// 未通过验签时不创建接收，之后到达的分块按未知接收忽略
+	if err := c.verifyPlugin(req); err != nil {
+		c.sendInstallPluginResponse(req.PluginName, req.Version, err)
+		return
+	}
+	if err := c.pluginManager.BeginTransfer(req); err != nil {
+		c.sendInstallPluginResponse(req.PluginName, req.Version, err)
 	}
+}
 
+// handlePluginChunk 写入安装包分块，接收完成并校验通过后解包安装
+func (c *Client) handlePluginChunk(chunk *pb.PluginPackageChunk) {
+	req, pkgPath, err := c.pluginManager.WriteChunk(chunk)
+	if errors.Is(err, plugin.ErrUnknownTransfer) {
+		// 接收已失败并上报过，忽略剩余分块
+		return
+	}
+	if err != nil {
+		c.sendInstallPluginResponse(req.PluginName, req.Version, err)
+		return
+	}
+	if pkgPath == "" {
+		return
+	}
+
+	go func() {
+		manifest, err := c.pluginManager.InstallPackage(req.PluginName, pkgPath, req.Config)
+		version := req.Version
+		if err == nil {
+			version = manifest.Version
+			log.Printf("Installed plugin %s@%s", req.PluginName, version)
+		}
+		c.sendInstallPluginResponse(req.PluginName, version, err)
+	}()
+}
+
+func (c *Client) sendInstallPluginResponse(name, version string, err error) {
 	response := &pb.InstallPluginResponse{
-		Success: err == nil,
+		Success:    err == nil,
+		PluginName: name,
+		Version:    version,
 	}
 	if err != nil {
 		response.Error = err.Error()
 	}
 
-	stream.Send(&pb.AgentMessage{
+	c.send(&pb.AgentMessage{
 		Message: &pb.AgentMessage_InstallPluginResponse{
 			InstallPluginResponse: response,
 		},
 	})
 }
 
-func (c *Client) handleUninstallPlugin(ctx context.Context, stream pb.AgentService_ConnectClient, req *pb.UninstallPluginRequest) {
+func (c *Client) handleUninstallPlugin(ctx context.Context, req *pb.UninstallPluginRequest) {
 	log.Printf("Uninstalling plugin: %s", req.PluginName)
 
 	err := c.pluginManager.Unload(req.PluginName)
@@ -165,19 +608,19 @@ func (c *Client) handleUninstallPlugin(ctx context.Context, stream pb.AgentServi
 		response.Error = err.Error()
 	}
 
-	stream.Send(&pb.AgentMessage{
+	c.send(&pb.AgentMessage{
 		Message: &pb.AgentMessage_UninstallPluginResponse{
 			UninstallPluginResponse: response,
 		},
 	})
 }
 
-func (c *Client) handleListPlugins(ctx context.Context, stream pb.AgentService_ConnectClient, req *pb.ListPluginsRequest) {
+func (c *Client) handleListPlugins(ctx context.Context, req *pb.ListPluginsRequest) {
 	log.Printf("Listing plugins")
 
 	plugins := c.pluginManager.List()
 
-	stream.Send(&pb.AgentMessage{
+	c.send(&pb.AgentMessage{
 		Message: &pb.AgentMessage_ListPluginsResponse{
 			ListPluginsResponse: &pb.ListPluginsResponse{
 				Plugins: plugins,
@@ -186,7 +629,12 @@ func (c *Client) handleListPlugins(ctx context.Context, stream pb.AgentService_C
 	})
 }
 
+// Close 停止所有插件、关闭连接，并将尚未发送的消息（包括插件的停止状态）写入磁盘
 func (c *Client) Close() error {
+	c.pluginManager.StopAll()
+	if err := c.outbox.Close(); err != nil {
+		log.Printf("Failed to persist buffered messages: %v", err)
+	}
 	if c.conn != nil {
 		return c.conn.Close()
 	}
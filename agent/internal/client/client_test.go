@@ -1,11 +1,30 @@
 package client
 
 import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
+
+	pb "github.com/yourusername/agent-platform/proto"
+	"github.com/yourusername/agent-platform/agent/internal/config"
+	"github.com/yourusername/agent-platform/pkg/tasksign"
 )
 
 func TestNewClient(t *testing.T) {
-	client := NewClient("localhost:9090", false, "test-agent-id")
+	cfg := &config.Config{
+		Server: config.ServerConfig{Address: "localhost:9090"},
+		Agent:  config.AgentConfig{ID: "test-agent-id", DataDir: t.TempDir()},
+	}
+	client := NewClient(cfg, nil)
 	if client == nil {
 		t.Fatal("NewClient returned nil")
 	}
@@ -13,4 +32,308 @@ func TestNewClient(t *testing.T) {
 	if client.serverAddr != "localhost:9090" {
 		t.Errorf("expected serverAddr localhost:9090, got %s", client.serverAddr)
 	}
+
+	if client.collectInterval != 30*time.Second {
+		t.Errorf("expected default collectInterval 30s, got %s", client.collectInterval)
+	}
+}
+
+type fakeStream struct {
+	pb.AgentService_ConnectClient
+	sent []*pb.AgentMessage
+	recv []*pb.ServerMessage // 依次由 Recv 返回，之后返回 io.EOF
+	err  error
+}
+
+func (s *fakeStream) Send(msg *pb.AgentMessage) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.sent = append(s.sent, msg)
+	return nil
+}
+
+func (s *fakeStream) Recv() (*pb.ServerMessage, error) {
+	if len(s.recv) == 0 {
+		return nil, io.EOF
+	}
+	msg := s.recv[0]
+	s.recv = s.recv[1:]
+	return msg, nil
+}
+
+func TestBufferAndReplayWhileDisconnected(t *testing.T) {
+	cfg := &config.Config{
+		Agent: config.AgentConfig{ID: "test-agent-id", DataDir: t.TempDir()},
+	}
+	client := NewClient(cfg, nil)
+
+	// 未连接时任务结果和指标进入缓存，心跳等消息直接丢弃
+	client.sendTaskResult(&pb.TaskResult{TaskId: "task-1"})
+	client.sendMetrics([]*pb.MetricPoint{{Name: "cpu_usage", Value: 1}})
+	if err := client.send(&pb.AgentMessage{}); !errors.Is(err, errNotConnected) {
+		t.Errorf("expected errNotConnected, got %v", err)
+	}
+	if client.outbox.Len() != 2 {
+		t.Fatalf("expected 2 buffered messages, got %d", client.outbox.Len())
+	}
+
+	// 回放失败时保持未连接状态
+	broken := &fakeStream{err: errors.New("broken pipe")}
+	if err := client.activate(broken); err == nil {
+		t.Fatal("expected activate to fail")
+	}
+	if client.stream != nil {
+		t.Fatal("expected client to stay disconnected")
+	}
+
+	stream := &fakeStream{}
+	if err := client.activate(stream); err != nil {
+		t.Fatalf("activate failed: %v", err)
+	}
+	if len(stream.sent) != 2 || stream.sent[0].GetTaskResult().TaskId != "task-1" || stream.sent[1].GetMetricBatch() == nil {
+		t.Fatalf("unexpected replayed messages: %v", stream.sent)
+	}
+
+	// 重连后直接发送
+	client.sendTaskResult(&pb.TaskResult{TaskId: "task-2"})
+	if len(stream.sent) != 3 || client.outbox.Len() != 0 {
+		t.Errorf("expected message sent directly, got %d sent, %d buffered", len(stream.sent), client.outbox.Len())
+	}
+
+	client.deactivate(stream)
+	if client.stream != nil {
+		t.Error("expected client to be disconnected")
+	}
+}
+
+func TestReplayAfterRegistration(t *testing.T) {
+	cfg := &config.Config{
+		Agent: config.AgentConfig{ID: "test-agent-id", DataDir: t.TempDir()},
+	}
+	client := NewClient(cfg, nil)
+	client.sendTaskResult(&pb.TaskResult{TaskId: "task-1"})
+
+	registerResponse := func(success bool) *pb.ServerMessage {
+		return &pb.ServerMessage{Message: &pb.ServerMessage_RegisterResponse{RegisterResponse: &pb.Response{Success: success, Error: "invalid credential"}}}
+	}
+
+	// 注册被拒绝时缓存的结果不发送
+	rejected := &fakeStream{recv: []*pb.ServerMessage{registerResponse(false)}}
+	if err := client.receive(context.Background(), rejected); err == nil || !strings.Contains(err.Error(), "registration rejected") {
+		t.Fatalf("expected registration to be rejected, got %v", err)
+	}
+	if len(rejected.sent) != 0 || client.outbox.Len() != 1 {
+		t.Fatalf("expected result to stay buffered, got %d sent, %d buffered", len(rejected.sent), client.outbox.Len())
+	}
+
+	accepted := &fakeStream{recv: []*pb.ServerMessage{registerResponse(true)}}
+	if err := client.receive(context.Background(), accepted); err != nil {
+		t.Fatal(err)
+	}
+	if len(accepted.sent) != 1 || accepted.sent[0].GetTaskResult().TaskId != "task-1" || client.outbox.Len() != 0 {
+		t.Errorf("expected result to be replayed after registration, got %v", accepted.sent)
+	}
+	client.deactivate(accepted)
+}
+
+func TestCredentialFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credential")
+
+	credential, err := loadCredential(path)
+	if err != nil || credential != "" {
+		t.Fatalf("expected empty credential, got %q, %v", credential, err)
+	}
+
+	if err := saveCredential(path, "ac_secret"); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("expected mode 0600, got %v", info.Mode().Perm())
+	}
+
+	credential, err = loadCredential(path)
+	if err != nil || credential != "ac_secret" {
+		t.Errorf("expected saved credential, got %q, %v", credential, err)
+	}
+}
+
+func TestHandleTaskRejectedByPolicy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	if err := os.WriteFile(path, []byte("deny_patterns: ['rm\\s+-rf']\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	policy, err := config.LoadPolicy(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{
+		Agent: config.AgentConfig{ID: "test-agent-id", DataDir: t.TempDir()},
+	}
+	client := NewClient(cfg, policy)
+	stream := &fakeStream{}
+	if err := client.activate(stream); err != nil {
+		t.Fatalf("activate failed: %v", err)
+	}
+
+	client.handleTask(context.Background(), &pb.TaskRequest{
+		TaskId: "task-1",
+		Type:   pb.TaskType_TASK_TYPE_SHELL,
+		Script: "rm -rf /",
+	})
+
+	// 被拒绝的任务不发送开始确认，直接上报结果
+	if len(stream.sent) != 1 {
+		t.Fatalf("expected only a task result, got %v", stream.sent)
+	}
+	result := stream.sent[0].GetTaskResult()
+	if result == nil || result.Status != pb.TaskStatus_TASK_STATUS_REJECTED || result.Error == "" {
+		t.Fatalf("unexpected task result: %v", result)
+	}
+}
+
+func TestHandleTaskFileChecksum(t *testing.T) {
+	cfg := &config.Config{
+		Agent: config.AgentConfig{ID: "test-agent-id", DataDir: t.TempDir()},
+	}
+	client := NewClient(cfg, nil)
+	stream := &fakeStream{}
+	if err := client.activate(stream); err != nil {
+		t.Fatalf("activate failed: %v", err)
+	}
+
+	file := []byte("#!/bin/sh\necho \"hello $1\"\n")
+	sum := sha256.Sum256(file)
+	client.handleTask(context.Background(), &pb.TaskRequest{
+		TaskId:     "task-1",
+		Type:       pb.TaskType_TASK_TYPE_FILE,
+		File:       file,
+		FileSha256: "0000",
+	})
+	client.handleTask(context.Background(), &pb.TaskRequest{
+		TaskId:     "task-2",
+		Type:       pb.TaskType_TASK_TYPE_FILE,
+		File:       file,
+		FileName:   "hello.sh",
+		FileSha256: hex.EncodeToString(sum[:]),
+		Args:       []string{"world"},
+		Timeout:    10,
+	})
+
+	// 校验和不匹配的任务直接被拒绝
+	rejected := stream.sent[0].GetTaskResult()
+	if rejected == nil || rejected.TaskId != "task-1" || rejected.Status != pb.TaskStatus_TASK_STATUS_REJECTED {
+		t.Fatalf("expected task-1 to be rejected, got %v", stream.sent[0])
+	}
+	result := stream.sent[len(stream.sent)-1].GetTaskResult()
+	if result == nil || result.Status != pb.TaskStatus_TASK_STATUS_COMPLETED || result.Stdout != "hello world\n" {
+		t.Fatalf("unexpected task result: %v", result)
+	}
+}
+
+func TestHandleTaskVerifiesSignature(t *testing.T) {
+	pub, key, _ := ed25519.GenerateKey(rand.Reader)
+	dataDir := t.TempDir()
+	cfg := &config.Config{
+		Agent: config.AgentConfig{ID: "test-agent-id", DataDir: dataDir},
+	}
+	client := NewClient(cfg, nil)
+	client.SetTaskKey(pub)
+	stream := &fakeStream{}
+	if err := client.activate(stream); err != nil {
+		t.Fatalf("activate failed: %v", err)
+	}
+
+	lastResult := func() *pb.TaskResult {
+		return stream.sent[len(stream.sent)-1].GetTaskResult()
+	}
+	signed := func(id string, expiresAt time.Time) *pb.TaskRequest {
+		req := &pb.TaskRequest{TaskId: id, Type: pb.TaskType_TASK_TYPE_SHELL, Script: "echo ok", Timeout: 10}
+		tasksign.Sign(key, "test-agent-id", req, expiresAt)
+		return req
+	}
+
+	client.handleTask(context.Background(), &pb.TaskRequest{TaskId: "unsigned", Type: pb.TaskType_TASK_TYPE_SHELL, Script: "echo ok"})
+	if r := lastResult(); r.Status != pb.TaskStatus_TASK_STATUS_REJECTED || !strings.Contains(r.Error, "not signed") {
+		t.Fatalf("expected unsigned task to be rejected, got %v", r)
+	}
+
+	client.handleTask(context.Background(), signed("expired", time.Now().Add(-time.Minute)))
+	if r := lastResult(); r.Status != pb.TaskStatus_TASK_STATUS_REJECTED || !strings.Contains(r.Error, "expired") {
+		t.Fatalf("expected expired task to be rejected, got %v", r)
+	}
+
+	// 发给其他 Agent 的任务不能在本 Agent 上执行
+	other := &pb.TaskRequest{TaskId: "other-agent", Type: pb.TaskType_TASK_TYPE_SHELL, Script: "echo ok", Timeout: 10}
+	tasksign.Sign(key, "other-agent-id", other, time.Now().Add(time.Minute))
+	client.handleTask(context.Background(), other)
+	if r := lastResult(); r.Status != pb.TaskStatus_TASK_STATUS_REJECTED || !strings.Contains(r.Error, "invalid task signature") {
+		t.Fatalf("expected task signed for another agent to be rejected, got %v", r)
+	}
+
+	valid := signed("task-1", time.Now().Add(time.Minute))
+	client.handleTask(context.Background(), valid)
+	if r := lastResult(); r.Status != pb.TaskStatus_TASK_STATUS_COMPLETED || r.ExitCode != 0 {
+		t.Fatalf("expected signed task to run, got %v", r)
+	}
+
+	// 重启后仍然拒绝重放
+	restarted := NewClient(cfg, nil)
+	restarted.SetTaskKey(pub)
+	stream = &fakeStream{}
+	if err := restarted.activate(stream); err != nil {
+		t.Fatalf("activate failed: %v", err)
+	}
+	restarted.handleTask(context.Background(), valid)
+	if r := lastResult(); r.Status != pb.TaskStatus_TASK_STATUS_REJECTED || !strings.Contains(r.Error, "already been received") {
+		t.Fatalf("expected replayed task to be rejected, got %v", r)
+	}
+}
+
+func TestPluginInstallRequiresSignature(t *testing.T) {
+	pub, key, _ := ed25519.GenerateKey(rand.Reader)
+	cfg := &config.Config{
+		Agent: config.AgentConfig{ID: "test-agent-id", DataDir: t.TempDir()},
+	}
+	client := NewClient(cfg, nil)
+	client.SetTaskKey(pub)
+	stream := &fakeStream{}
+	if err := client.activate(stream); err != nil {
+		t.Fatalf("activate failed: %v", err)
+	}
+
+	pkg := []byte("not a real package")
+	sum := sha256.Sum256(pkg)
+	req := &pb.InstallPluginRequest{AgentId: "test-agent-id", PluginName: "demo", TransferId: "t1", Sha256: hex.EncodeToString(sum[:]), Size: int64(len(pkg))}
+
+	// 未签名的安装请求不开始接收，分块被忽略
+	client.beginPluginTransfer(req)
+	client.handlePluginChunk(&pb.PluginPackageChunk{TransferId: "t1", Data: pkg, Last: true})
+	if len(stream.sent) != 1 {
+		t.Fatalf("expected a single install response, got %v", stream.sent)
+	}
+	if r := stream.sent[0].GetInstallPluginResponse(); r.Success || !strings.Contains(r.Error, "not signed") {
+		t.Fatalf("expected unsigned install to be rejected, got %v", r)
+	}
+
+	// 签名给其他 Agent 的安装请求同样被拒绝
+	other := &pb.InstallPluginRequest{AgentId: "other-agent-id", PluginName: "demo"}
+	tasksign.SignPlugin(key, other, time.Now().Add(time.Minute))
+	client.handleInstallPlugin(context.Background(), other)
+	if r := stream.sent[len(stream.sent)-1].GetInstallPluginResponse(); r.Success || !strings.Contains(r.Error, "invalid") {
+		t.Fatalf("expected install signed for another agent to be rejected, got %v", r)
+	}
+
+	// 签名有效时开始接收
+	tasksign.SignPlugin(key, req, time.Now().Add(time.Minute))
+	client.beginPluginTransfer(req)
+	if n := len(stream.sent); n != 2 {
+		t.Fatalf("expected signed transfer to start without a response, got %d messages", n)
+	}
+	client.pluginManager.AbortTransfers()
 }
@@ -0,0 +1,62 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	pb "github.com/yourusername/agent-platform/proto"
+)
+
+// loadCredential 读取登记时平台签发的凭据，文件不存在时返回空字符串
+func loadCredential(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read credential: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// saveCredential 以仅属主可读的权限原子写入凭据
+func saveCredential(path, credential string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(credential), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// credential 返回注册时携带的凭据。尚未登记且配置了引导令牌时先向平台登记，
+// 换取的凭据保存在数据目录中，之后不再需要引导令牌
+func (c *Client) credential(ctx context.Context) (string, error) {
+	credential, err := loadCredential(c.credentialFile)
+	if err != nil || credential != "" {
+		return credential, err
+	}
+	if c.enrollmentToken == "" {
+		return "", nil
+	}
+
+	resp, err := pb.NewAgentServiceClient(c.conn).Enroll(ctx, &pb.EnrollRequest{
+		AgentId:        c.agentID,
+		BootstrapToken: c.enrollmentToken,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to enroll: %w", err)
+	}
+	if err := saveCredential(c.credentialFile, resp.Credential); err != nil {
+		return "", fmt.Errorf("failed to save credential: %w", err)
+	}
+	log.Printf("Enrolled with platform, credential saved to %s", c.credentialFile)
+	return resp.Credential, nil
+}
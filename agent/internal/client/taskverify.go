@@ -0,0 +1,125 @@
+package client
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	pb "github.com/yourusername/agent-platform/proto"
+	"github.com/yourusername/agent-platform/pkg/tasksign"
+)
+
+var errReplayedTask = errors.New("task id has already been received")
+
+// replayGuard 记录签名尚未过期的已接收任务 ID，拒绝重放。
+// 记录保存在数据目录中，Agent 重启后仍然有效
+type replayGuard struct {
+	path string
+	mu   sync.Mutex
+	seen map[string]int64 // 任务 ID -> 签名过期时间（Unix 秒）
+}
+
+func newReplayGuard(path string) *replayGuard {
+	g := &replayGuard{path: path, seen: make(map[string]int64)}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Printf("Failed to read received task ids: %v", err)
+		}
+		return g
+	}
+	if err := json.Unmarshal(data, &g.seen); err != nil {
+		log.Printf("Failed to parse received task ids: %v", err)
+		g.seen = make(map[string]int64)
+	}
+	return g
+}
+
+// check 记录任务 ID，已接收过时返回 errReplayedTask。过期的记录会被清理，
+// 签名过期后的重放由过期时间校验拒绝
+func (g *replayGuard) check(taskID string, expiresAt int64, now time.Time) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for id, exp := range g.seen {
+		if exp < now.Unix() {
+			delete(g.seen, id)
+		}
+	}
+	if _, ok := g.seen[taskID]; ok {
+		return errReplayedTask
+	}
+	g.seen[taskID] = expiresAt
+
+	if err := g.save(); err != nil {
+		log.Printf("Failed to save received task ids: %v", err)
+	}
+	return nil
+}
+
+func (g *replayGuard) save() error {
+	data, err := json.Marshal(g.seen)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(g.path), 0700); err != nil {
+		return err
+	}
+	tmp := g.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, g.path)
+}
+
+// SetTaskKey 固定平台的任务签名公钥。设置后拒绝执行未签名、签名无效、已过期或重放的任务，
+// 并拒绝未签名或签名无效的插件安装请求
+func (c *Client) SetTaskKey(pub ed25519.PublicKey) {
+	c.taskKey = pub
+}
+
+// verifyTask 校验任务签名并检查重放，未配置公钥时不校验
+func (c *Client) verifyTask(task *pb.TaskRequest) error {
+	if c.taskKey == nil {
+		return nil
+	}
+	now := time.Now()
+	if err := tasksign.Verify(c.taskKey, c.agentID, task, now); err != nil {
+		return err
+	}
+	if err := c.replay.check(task.TaskId, task.ExpiresAt, now); err != nil {
+		return err
+	}
+	return nil
+}
+
+// verifyPlugin 校验插件安装请求的签名，未配置公钥时不校验。
+// 签名覆盖安装包的 SHA-256 与大小，在接收安装包之前校验，解包前再校验安装包摘要
+func (c *Client) verifyPlugin(req *pb.InstallPluginRequest) error {
+	if c.taskKey == nil {
+		return nil
+	}
+	if err := tasksign.VerifyPlugin(c.taskKey, c.agentID, req, time.Now()); err != nil {
+		return fmt.Errorf("rejected by agent: %w", err)
+	}
+	return nil
+}
+
+// rejectTask 上报任务被 Agent 拒绝执行
+func (c *Client) rejectTask(taskID, reason string) {
+	log.Printf("Task %s rejected: %s", taskID, reason)
+	c.sendTaskResult(&pb.TaskResult{
+		TaskId:      taskID,
+		ExitCode:    -1,
+		Status:      pb.TaskStatus_TASK_STATUS_REJECTED,
+		Stderr:      reason,
+		Error:       reason,
+		CompletedAt: timestampNow(),
+	})
+}
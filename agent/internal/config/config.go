@@ -15,11 +15,29 @@ type Config struct {
 type ServerConfig struct {
 	Address string `yaml:"address"`
 	TLS     bool   `yaml:"tls"`
+	// 以下为 TLS 配置：ca_file 为空时使用系统根证书校验平台证书；
+	// cert_file/key_file 为 Agent 的客户端证书，其 CN 或 SAN 需与 agent.id 一致。证书文件更新后自动重新加载
+	CAFile     string `yaml:"ca_file"`
+	CertFile   string `yaml:"cert_file"`
+	KeyFile    string `yaml:"key_file"`
+	ServerName string `yaml:"server_name"` // 校验平台证书时使用的主机名，默认取 address 中的主机名
 }
 
 type AgentConfig struct {
-	ID              string `yaml:"id"`
-	CollectInterval int    `yaml:"collect_interval"`
+	ID                string `yaml:"id"`
+	CollectInterval   int    `yaml:"collect_interval"`
+	HeartbeatInterval int    `yaml:"heartbeat_interval"`
+	DataDir           string `yaml:"data_dir"` // 插件、离线缓存等数据目录，默认 /var/lib/agent
+	// EnrollmentToken 首次启动时用于登记的一次性引导令牌，登记后凭据保存在 data_dir/credential。
+	// 凭据被吊销后需删除该文件并配置新的引导令牌
+	EnrollmentToken string `yaml:"enrollment_token"`
+	// PolicyFile 本地脚本策略文件，为空时不做本地检查
+	PolicyFile string `yaml:"policy_file"`
+	// TaskPublicKeyFile 平台任务签名公钥（PKIX PEM），配置后拒绝执行未签名、已过期或重放的任务
+	TaskPublicKeyFile string `yaml:"task_public_key_file"`
+	// TaskCgroupDir 委派给 Agent 的 cgroup v2 目录（systemd 单元设置 Delegate=yes），
+	// 配置后任务的进程数限制使用 cgroup 的 pids.max，否则使用 RLIMIT_NPROC
+	TaskCgroupDir string `yaml:"task_cgroup_dir"`
 }
 
 type LogConfig struct {
@@ -0,0 +1,118 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Policy Agent 本地脚本策略，与平台策略相互独立，平台被攻破时仍然生效
+type Policy struct {
+	DenyPatterns      []string `yaml:"deny_patterns"`        // 脚本匹配任一正则时拒绝
+	AllowedTypes      []string `yaml:"allowed_types"`        // 允许的脚本类型，为空时不限制；file 类型必须显式列出
+	MaxTimeout        int      `yaml:"max_timeout"`          // 允许的最大超时时间（秒），0 表示不限制
+	DefaultRunAsUser  string   `yaml:"default_run_as_user"`  // 任务未指定运行用户时使用的用户，为空时以 Agent 自身身份运行
+	AllowedRunAsUsers []string `yaml:"allowed_run_as_users"` // 允许的运行用户，为空时不限制；设置后任务必须以其中之一运行
+
+	deny []*regexp.Regexp
+}
+
+// LoadPolicy 加载本地策略文件并编译禁止规则
+func LoadPolicy(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var p Policy
+	if err := yaml.Unmarshal(data, &p); err != nil {
+		return nil, err
+	}
+	for _, pattern := range p.DenyPatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid deny pattern %q: %w", pattern, err)
+		}
+		p.deny = append(p.deny, re)
+	}
+	return &p, nil
+}
+
+// PolicyTask 策略检查的任务内容
+type PolicyTask struct {
+	Type    string
+	Script  string // file 类型为文件内容
+	Args    []string
+	Env     map[string]string
+	Timeout int
+	User    string // 任务指定的运行用户
+}
+
+// Check 检查任务是否被策略允许，不允许时返回拒绝原因。
+// 禁止规则同时检查脚本、参数（以空格连接）与环境变量（NAME=value）
+func (p *Policy) Check(task *PolicyTask) error {
+	content := []string{task.Script, strings.Join(task.Args, " ")}
+	for name, value := range task.Env {
+		content = append(content, name+"="+value)
+	}
+	for _, re := range p.deny {
+		for _, c := range content {
+			if re.MatchString(c) {
+				return fmt.Errorf("script matches deny pattern %q", re.String())
+			}
+		}
+	}
+	if p.MaxTimeout > 0 && p.Timeout(task.Timeout) > p.MaxTimeout {
+		return fmt.Errorf("timeout %ds exceeds maximum %ds", task.Timeout, p.MaxTimeout)
+	}
+	if err := p.checkUser(p.User(task.User)); err != nil {
+		return err
+	}
+	if len(p.AllowedTypes) == 0 && task.Type != "file" {
+		return nil
+	}
+	// file 类型可以执行任意程序，需在 allowed_types 中显式允许
+	for _, t := range p.AllowedTypes {
+		if t == task.Type {
+			return nil
+		}
+	}
+	return fmt.Errorf("script type %s is not allowed", task.Type)
+}
+
+// Timeout 返回任务实际使用的超时时间。设置了 max_timeout 时，未指定超时（0 表示不限制）的任务
+// 以 max_timeout 为限，避免平台通过不设超时绕过限制
+func (p *Policy) Timeout(timeout int) int {
+	if p.MaxTimeout > 0 && timeout <= 0 {
+		return p.MaxTimeout
+	}
+	return timeout
+}
+
+// User 返回任务实际使用的运行用户，任务未指定时使用 default_run_as_user
+func (p *Policy) User(user string) string {
+	if user == "" {
+		return p.DefaultRunAsUser
+	}
+	return user
+}
+
+// checkUser 检查运行用户是否在 allowed_run_as_users 中。
+// 以 Agent 自身身份运行（用户为空）同样需要显式允许，避免平台不指定用户而以 root 执行
+func (p *Policy) checkUser(user string) error {
+	if len(p.AllowedRunAsUsers) == 0 {
+		return nil
+	}
+	for _, u := range p.AllowedRunAsUsers {
+		if u == user {
+			return nil
+		}
+	}
+	if user == "" {
+		return fmt.Errorf("running as the agent user is not allowed, set default_run_as_user")
+	}
+	return fmt.Errorf("run as user %s is not allowed", user)
+}
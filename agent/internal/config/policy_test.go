@@ -0,0 +1,139 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPolicyCheck(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	content := `
+deny_patterns:
+  - 'rm\s+-rf\s+/'
+  - 'mkfs'
+allowed_types: [shell]
+max_timeout: 600
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	policy, err := LoadPolicy(path)
+	if err != nil {
+		t.Fatalf("LoadPolicy failed: %v", err)
+	}
+
+	tests := []struct {
+		taskType string
+		script   string
+		timeout  int
+		allowed  bool
+	}{
+		{"shell", "uptime", 30, true},
+		{"shell", "rm -rf / --no-preserve-root", 30, false},
+		{"shell", "mkfs.ext4 /dev/sda", 30, false},
+		{"python", "print(1)", 30, false},
+		{"shell", "uptime", 3600, false},
+		{"shell", "uptime", 0, true},
+	}
+	for _, tt := range tests {
+		err := policy.Check(&PolicyTask{Type: tt.taskType, Script: tt.script, Timeout: tt.timeout})
+		if tt.allowed && err != nil {
+			t.Errorf("expected %q to be allowed, got %v", tt.script, err)
+		}
+		if !tt.allowed && err == nil {
+			t.Errorf("expected %q (%s, %ds) to be rejected", tt.script, tt.taskType, tt.timeout)
+		}
+	}
+}
+
+func TestPolicyCheckArgsEnvFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	if err := os.WriteFile(path, []byte("deny_patterns: ['rm\\s+-rf\\s+/']\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	policy, err := LoadPolicy(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		task    *PolicyTask
+		allowed bool
+	}{
+		{"args", &PolicyTask{Type: "shell", Script: `"$@"`, Args: []string{"rm", "-rf", "/"}}, false},
+		{"env", &PolicyTask{Type: "shell", Script: `sh -c "$CMD"`, Env: map[string]string{"CMD": "rm -rf /"}}, false},
+		{"file without opt-in", &PolicyTask{Type: "file", Script: "#!/bin/sh\nuptime\n"}, false},
+		{"shell", &PolicyTask{Type: "shell", Script: "uptime", Args: []string{"-p"}}, true},
+	}
+	for _, tt := range tests {
+		if err := policy.Check(tt.task); (err == nil) != tt.allowed {
+			t.Errorf("%s: expected allowed=%v, got %v", tt.name, tt.allowed, err)
+		}
+	}
+
+	policy.AllowedTypes = []string{"shell", "file"}
+	if err := policy.Check(&PolicyTask{Type: "file", Script: "#!/bin/sh\nuptime\n"}); err != nil {
+		t.Errorf("expected file to be allowed when listed, got %v", err)
+	}
+	if err := policy.Check(&PolicyTask{Type: "file", Script: "#!/bin/sh\nrm -rf /\n"}); err == nil {
+		t.Error("expected file content to be checked")
+	}
+}
+
+func TestPolicyTimeout(t *testing.T) {
+	policy := &Policy{MaxTimeout: 600}
+	for timeout, expected := range map[int]int{0: 600, -1: 600, 30: 30, 3600: 3600} {
+		if got := policy.Timeout(timeout); got != expected {
+			t.Errorf("Timeout(%d) = %d, expected %d", timeout, got, expected)
+		}
+	}
+
+	// 未设置 max_timeout 时保持不限制
+	if got := (&Policy{}).Timeout(0); got != 0 {
+		t.Errorf("expected no timeout without max_timeout, got %d", got)
+	}
+}
+
+func TestPolicyRunAsUser(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	if err := os.WriteFile(path, []byte("default_run_as_user: nobody\nallowed_run_as_users: [nobody, deploy]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	policy, err := LoadPolicy(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := policy.User(""); got != "nobody" {
+		t.Errorf("expected default user nobody, got %q", got)
+	}
+	if got := policy.User("deploy"); got != "deploy" {
+		t.Errorf("expected requested user deploy, got %q", got)
+	}
+	for user, allowed := range map[string]bool{"": true, "deploy": true, "root": false, "mallory": false} {
+		err := policy.Check(&PolicyTask{Type: "shell", Script: "id", User: user})
+		if (err == nil) != allowed {
+			t.Errorf("user %q: expected allowed=%v, got %v", user, allowed, err)
+		}
+	}
+
+	// 未设置默认用户时，以 Agent 自身身份运行同样需要显式允许
+	policy.DefaultRunAsUser = ""
+	if err := policy.Check(&PolicyTask{Type: "shell", Script: "id"}); err == nil {
+		t.Error("expected running as the agent user to be rejected")
+	}
+}
+
+func TestLoadPolicyInvalidPattern(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	if err := os.WriteFile(path, []byte("deny_patterns: ['(']\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadPolicy(path); err == nil {
+		t.Error("expected error for invalid deny pattern")
+	}
+}
@@ -0,0 +1,88 @@
+package executor
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// taskCgroup 为单个任务创建的 cgroup v2 子目录，进程在 clone 时直接加入，不存在竞争窗口。
+// cgroupDir 需要委派给 Agent（systemd 单元设置 Delegate=yes）且 Agent 进程不在该目录中
+type taskCgroup struct {
+	dir string
+	fd  *os.File
+}
+
+func newTaskCgroup(parent, name string, processes uint64) (*taskCgroup, error) {
+	// 启用 pids 控制器，已启用时写入同样成功
+	if err := os.WriteFile(filepath.Join(parent, "cgroup.subtree_control"), []byte("+pids"), 0); err != nil {
+		return nil, fmt.Errorf("failed to enable pids controller in %s: %w", parent, err)
+	}
+
+	dir := filepath.Join(parent, "task-"+name)
+	if err := os.Mkdir(dir, 0755); err != nil && !os.IsExist(err) {
+		return nil, fmt.Errorf("failed to create cgroup: %w", err)
+	}
+	cg := &taskCgroup{dir: dir}
+	if err := os.WriteFile(filepath.Join(dir, "pids.max"), []byte(strconv.FormatUint(processes, 10)), 0); err != nil {
+		cg.remove()
+		return nil, fmt.Errorf("failed to set pids.max: %w", err)
+	}
+
+	fd, err := os.Open(dir)
+	if err != nil {
+		cg.remove()
+		return nil, err
+	}
+	cg.fd = fd
+	return cg, nil
+}
+
+func (c *taskCgroup) attach(cmd *exec.Cmd) {
+	cmd.SysProcAttr.UseCgroupFD = true
+	cmd.SysProcAttr.CgroupFD = int(c.fd.Fd())
+}
+
+// limitHit 判断进程数是否曾达到上限（pids.events 中的 max 计数）
+func (c *taskCgroup) limitHit() bool {
+	f, err := os.Open(filepath.Join(c.dir, "pids.events"))
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == "max" {
+			n, _ := strconv.Atoi(fields[1])
+			return n > 0
+		}
+	}
+	return false
+}
+
+// remove 删除 cgroup 目录，脚本派生的后台进程仍在运行时先将其全部终止
+func (c *taskCgroup) remove() {
+	if c.fd != nil {
+		c.fd.Close()
+	}
+	if err := os.Remove(c.dir); err == nil || os.IsNotExist(err) {
+		return
+	}
+
+	os.WriteFile(filepath.Join(c.dir, "cgroup.kill"), []byte("1"), 0)
+	for i := 0; i < 10; i++ {
+		time.Sleep(100 * time.Millisecond)
+		if err := os.Remove(c.dir); err == nil {
+			return
+		}
+	}
+	log.Printf("Failed to remove cgroup %s", c.dir)
+}
@@ -0,0 +1,21 @@
+//go:build unix && !linux
+
+package executor
+
+import (
+	"errors"
+	"os/exec"
+)
+
+// taskCgroup cgroup v2 仅在 Linux 上可用
+type taskCgroup struct{}
+
+func newTaskCgroup(parent, name string, processes uint64) (*taskCgroup, error) {
+	return nil, errors.New("cgroup v2 is only supported on linux")
+}
+
+func (c *taskCgroup) attach(cmd *exec.Cmd) {}
+
+func (c *taskCgroup) limitHit() bool { return false }
+
+func (c *taskCgroup) remove() {}
@@ -0,0 +1,82 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// ErrInterpreterNotFound Agent 上未安装脚本类型所需的解释器
+var ErrInterpreterNotFound = errors.New("interpreter not found")
+
+// defaultFileName file 类型未指定文件名时使用的文件名
+const defaultFileName = "artifact"
+
+// taskCommand 按脚本类型构造命令。powershell 与 file 类型先将内容写入临时目录，
+// 返回的 tmpDir 需在执行结束后删除
+func taskCommand(ctx context.Context, scriptType, script string, opts *RunOptions) (cmd *exec.Cmd, tmpDir string, err error) {
+	var args []string
+	fileName := defaultFileName
+	if opts != nil {
+		args = opts.Args
+		if opts.FileName != "" {
+			fileName = opts.FileName
+		}
+	}
+
+	switch scriptType {
+	case "shell":
+		// sh -c 之后的第一个参数为 $0
+		return exec.CommandContext(ctx, "sh", append([]string{"-c", script, "sh"}, args...)...), "", nil
+	case "bash":
+		return exec.CommandContext(ctx, "bash", append([]string{"-c", script, "bash"}, args...)...), "", nil
+	case "python":
+		return exec.CommandContext(ctx, "python3", append([]string{"-c", script}, args...)...), "", nil
+	case "perl":
+		return exec.CommandContext(ctx, "perl", append([]string{"-e", script}, args...)...), "", nil
+	case "powershell":
+		pwsh, err := exec.LookPath("pwsh")
+		if err != nil {
+			return nil, "", fmt.Errorf("%w: pwsh", ErrInterpreterNotFound)
+		}
+		// 通过 -Command 执行时无法传递参数，写入脚本文件后以 -File 执行
+		tmpDir, path, err := writeTaskFile("script.ps1", script, 0o600)
+		if err != nil {
+			return nil, "", err
+		}
+		return exec.CommandContext(ctx, pwsh, append([]string{"-NoProfile", "-NonInteractive", "-File", path}, args...)...), tmpDir, nil
+	case "file":
+		if fileName != filepath.Base(fileName) || fileName == "." || fileName == ".." {
+			return nil, "", fmt.Errorf("invalid file name: %s", fileName)
+		}
+		tmpDir, path, err := writeTaskFile(fileName, script, 0o700)
+		if err != nil {
+			return nil, "", err
+		}
+		return exec.CommandContext(ctx, path, args...), tmpDir, nil
+	default:
+		return nil, "", fmt.Errorf("unsupported script type: %s", scriptType)
+	}
+}
+
+// writeTaskFile 在新建的临时目录中写入文件，返回目录与文件路径
+func writeTaskFile(name, content string, perm os.FileMode) (string, string, error) {
+	dir, err := os.MkdirTemp("", "agent-task-")
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), perm); err != nil {
+		os.RemoveAll(dir)
+		return "", "", fmt.Errorf("failed to write task file: %w", err)
+	}
+	// 不受 umask 影响
+	if err := os.Chmod(path, perm); err != nil {
+		os.RemoveAll(dir)
+		return "", "", fmt.Errorf("failed to chmod task file: %w", err)
+	}
+	return dir, path, nil
+}
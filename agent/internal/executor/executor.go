@@ -3,20 +3,40 @@ package executor
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"os"
 	"os/exec"
+	"strconv"
+	"sync"
 	"time"
 )
 
+var (
+	ErrTimeout        = errors.New("execution timed out")
+	ErrCancelled      = errors.New("execution cancelled")
+	ErrAlreadyRunning = errors.New("task already running")
+)
+
+// waitDelay 进程被终止后等待输出管道关闭的最长时间
+const waitDelay = 5 * time.Second
+
 type ExecutionResult struct {
-	ExitCode int
-	Stdout   string
-	Stderr   string
+	ExitCode      int
+	Stdout        string
+	Stderr        string
+	LimitExceeded string // 因资源限制被终止时为触发的限制名称
 }
 
+// OutputFunc 在脚本产生输出时被调用，用于实时上报日志
+type OutputFunc func(data []byte, isStderr bool)
+
 type Executor struct {
 	maxConcurrent int
 	semaphore     chan struct{}
+	mu            sync.Mutex
+	running       map[string]context.CancelCauseFunc
+	cgroupDir     string
 }
 
 func NewExecutor() *Executor {
@@ -24,16 +44,40 @@ func NewExecutor() *Executor {
 	return &Executor{
 		maxConcurrent: maxConcurrent,
 		semaphore:     make(chan struct{}, maxConcurrent),
+		running:       make(map[string]context.CancelCauseFunc),
 	}
 }
 
+// SetCgroupDir 设置委派给 Agent 的 cgroup v2 目录，设置后进程数限制通过 cgroup 实现
+func (e *Executor) SetCgroupDir(dir string) {
+	e.cgroupDir = dir
+}
+
 func (e *Executor) Execute(ctx context.Context, scriptType, script string, timeoutSeconds int) (*ExecutionResult, error) {
+	return e.ExecuteStream(ctx, "", scriptType, script, timeoutSeconds, nil, nil)
+}
+
+// ExecuteStream 执行脚本，并在运行过程中将输出块通过 onOutput 回调实时传出。
+// taskID 非空时任务可通过 Cancel 取消；超时或取消时返回已产生的部分输出及对应错误。
+// opts 为 nil 时以 Agent 自身的用户、目录与资源限制运行，因资源限制被终止时返回 ErrLimitExceeded。
+// scriptType 为 shell、bash、python、perl、powershell 或 file，file 类型的 script 为可执行文件内容
+func (e *Executor) ExecuteStream(ctx context.Context, taskID, scriptType, script string, timeoutSeconds int, opts *RunOptions, onOutput OutputFunc) (*ExecutionResult, error) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	if taskID != "" {
+		if err := e.track(taskID, cancel); err != nil {
+			return nil, err
+		}
+		defer e.untrack(taskID)
+	}
+
 	// 获取信号量，限制并发执行数
 	select {
 	case e.semaphore <- struct{}{}:
 		defer func() { <-e.semaphore }()
 	case <-ctx.Done():
-		return nil, ctx.Err()
+		return nil, contextError(ctx, timeoutSeconds)
 	}
 
 	// 创建超时上下文
@@ -43,27 +87,63 @@ func (e *Executor) Execute(ctx context.Context, scriptType, script string, timeo
 		defer cancel()
 	}
 
-	var cmd *exec.Cmd
-	switch scriptType {
-	case "shell":
-		cmd = exec.CommandContext(ctx, "sh", "-c", script)
-	case "python":
-		cmd = exec.CommandContext(ctx, "python3", "-c", script)
-	default:
-		return nil, fmt.Errorf("unsupported script type: %s", scriptType)
+	cmd, tmpDir, err := taskCommand(ctx, scriptType, script, opts)
+	if err != nil {
+		return nil, err
+	}
+	if tmpDir != "" {
+		defer os.RemoveAll(tmpDir)
+	}
+
+	// 在独立进程组中运行，取消或超时时终止整个进程组
+	setProcessGroup(cmd)
+	cmd.WaitDelay = waitDelay
+
+	name := taskID
+	if name == "" {
+		name = strconv.FormatInt(time.Now().UnixNano(), 10)
+	}
+	if opts != nil && len(opts.Env) > 0 {
+		cmd.Env = cmd.Environ()
+		for k, v := range opts.Env {
+			cmd.Env = append(cmd.Env, k+"="+v)
+		}
+	}
+	sb, err := prepare(cmd, opts, e.cgroupDir, name)
+	if err != nil {
+		return nil, err
+	}
+	defer sb.cleanup()
+	if tmpDir != "" {
+		// 以其他用户身份运行时，临时文件需归该用户所有
+		if err := chownToCredential(cmd, tmpDir); err != nil {
+			return nil, err
+		}
 	}
 
 	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+	var outputMu sync.Mutex
+	cmd.Stdout = &outputWriter{mu: &outputMu, buf: &stdout, onOutput: onOutput}
+	cmd.Stderr = &outputWriter{mu: &outputMu, buf: &stderr, onOutput: onOutput, isStderr: true}
 
-	err := cmd.Run()
+	err = cmd.Run()
 
 	result := &ExecutionResult{
 		Stdout: stdout.String(),
 		Stderr: stderr.String(),
 	}
 
+	if ctx.Err() != nil {
+		result.ExitCode = -1
+		return result, contextError(ctx, timeoutSeconds)
+	}
+
+	if limit := sb.limitExceeded(cmd.ProcessState); limit != "" {
+		result.ExitCode = cmd.ProcessState.ExitCode()
+		result.LimitExceeded = limit
+		return result, fmt.Errorf("%w: %s", ErrLimitExceeded, limit)
+	}
+
 	if err != nil {
 		if exitErr, ok := err.(*exec.ExitError); ok {
 			exitCode := exitErr.ExitCode()
@@ -80,3 +160,65 @@ func (e *Executor) Execute(ctx context.Context, scriptType, script string, timeo
 	result.ExitCode = 0
 	return result, nil
 }
+
+// Cancel 取消正在执行或排队等待执行的任务
+func (e *Executor) Cancel(taskID string) bool {
+	e.mu.Lock()
+	cancel, exists := e.running[taskID]
+	e.mu.Unlock()
+
+	if !exists {
+		return false
+	}
+	cancel(ErrCancelled)
+	return true
+}
+
+func (e *Executor) track(taskID string, cancel context.CancelCauseFunc) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if _, exists := e.running[taskID]; exists {
+		return fmt.Errorf("%w: %s", ErrAlreadyRunning, taskID)
+	}
+	e.running[taskID] = cancel
+	return nil
+}
+
+func (e *Executor) untrack(taskID string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	delete(e.running, taskID)
+}
+
+// contextError 将上下文结束原因转换为超时或取消错误
+func contextError(ctx context.Context, timeoutSeconds int) error {
+	if errors.Is(context.Cause(ctx), ErrCancelled) {
+		return ErrCancelled
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("%w after %ds", ErrTimeout, timeoutSeconds)
+	}
+	return ctx.Err()
+}
+
+// outputWriter 将输出写入缓冲区，同时回调给调用方。
+// stdout 与 stderr 共用一把锁，保证回调按产生顺序串行执行
+type outputWriter struct {
+	mu       *sync.Mutex
+	buf      *bytes.Buffer
+	onOutput OutputFunc
+	isStderr bool
+}
+
+func (w *outputWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.onOutput != nil {
+		chunk := make([]byte, len(p))
+		copy(chunk, p)
+		w.onOutput(chunk, w.isStderr)
+	}
+	return w.buf.Write(p)
+}
@@ -2,6 +2,7 @@ package executor
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 )
@@ -26,6 +27,44 @@ func TestExecuteShellScript(t *testing.T) {
 	}
 }
 
+func TestExecuteStream(t *testing.T) {
+	executor := NewExecutor()
+
+	var stdout, stderr string
+	result, err := executor.ExecuteStream(context.Background(), "", "shell", "echo out; echo err >&2", 10, nil,
+		func(data []byte, isStderr bool) {
+			if isStderr {
+				stderr += string(data)
+			} else {
+				stdout += string(data)
+			}
+		})
+	if err != nil {
+		t.Fatalf("ExecuteStream failed: %v", err)
+	}
+
+	if stdout != "out\n" || stderr != "err\n" {
+		t.Errorf("unexpected streamed output: stdout=%q stderr=%q", stdout, stderr)
+	}
+	if result.Stdout != "out\n" {
+		t.Errorf("expected buffered stdout 'out\\n', got %q", result.Stdout)
+	}
+}
+
+func TestExecuteWithEnv(t *testing.T) {
+	executor := NewExecutor()
+
+	opts := &RunOptions{Env: map[string]string{"GREETING": "hello", "TARGET": "world"}}
+	result, err := executor.ExecuteStream(context.Background(), "", "shell", `echo "$GREETING $TARGET"`, 10, opts, nil)
+	if err != nil {
+		t.Fatalf("ExecuteStream failed: %v", err)
+	}
+
+	if result.Stdout != "hello world\n" {
+		t.Errorf("expected stdout 'hello world\\n', got %q", result.Stdout)
+	}
+}
+
 func TestExecuteWithTimeout(t *testing.T) {
 	executor := NewExecutor()
 
@@ -36,4 +75,36 @@ func TestExecuteWithTimeout(t *testing.T) {
 	if err == nil {
 		t.Fatal("expected timeout error")
 	}
+	if !errors.Is(err, ErrTimeout) {
+		t.Errorf("expected ErrTimeout, got %v", err)
+	}
+}
+
+func TestCancel(t *testing.T) {
+	executor := NewExecutor()
+
+	go func() {
+		time.Sleep(500 * time.Millisecond)
+		if !executor.Cancel("task-1") {
+			t.Error("expected running task to be cancelled")
+		}
+	}()
+
+	start := time.Now()
+	result, err := executor.ExecuteStream(context.Background(), "task-1", "shell", "echo started; sleep 10 & wait", 30, nil, nil)
+	if !errors.Is(err, ErrCancelled) {
+		t.Fatalf("expected ErrCancelled, got %v", err)
+	}
+
+	// 整个进程组被终止，子进程不会拖住执行
+	if time.Since(start) > 5*time.Second {
+		t.Errorf("cancel took too long: %v", time.Since(start))
+	}
+	if result == nil || result.Stdout != "started\n" {
+		t.Errorf("expected partial output, got %+v", result)
+	}
+
+	if executor.Cancel("task-1") {
+		t.Error("expected finished task to be untracked")
+	}
 }
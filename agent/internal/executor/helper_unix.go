@@ -0,0 +1,101 @@
+//go:build unix
+
+package executor
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// helperEnv 以执行辅助进程身份启动时携带的参数。
+// Go 无法在 fork 与 exec 之间调用 setrlimit/umask，因此由 Agent 自身的可执行文件
+// 以目标用户身份启动，设置好资源限制与 umask 后再 exec 目标程序
+const helperEnv = "AGENT_EXEC_HELPER"
+
+type helperSpec struct {
+	Umask  int    `json:"umask"` // -1 表示不修改
+	Limits Limits `json:"limits"`
+}
+
+// Init 在 main 与 TestMain 开头调用。以执行辅助进程身份启动时设置资源限制后 exec 目标程序，不会返回
+func Init() {
+	raw, ok := os.LookupEnv(helperEnv)
+	if !ok {
+		return
+	}
+
+	if err := runHelper(raw); err != nil {
+		fmt.Fprintf(os.Stderr, "agent exec helper: %v\n", err)
+		os.Exit(127)
+	}
+}
+
+func runHelper(raw string) error {
+	var spec helperSpec
+	if err := json.Unmarshal([]byte(raw), &spec); err != nil {
+		return err
+	}
+	if len(os.Args) < 2 {
+		return fmt.Errorf("missing command")
+	}
+
+	if spec.Umask >= 0 {
+		syscall.Umask(spec.Umask)
+	}
+	if err := applyLimits(spec.Limits); err != nil {
+		return err
+	}
+
+	env := make([]string, 0, len(os.Environ()))
+	for _, kv := range os.Environ() {
+		if !strings.HasPrefix(kv, helperEnv+"=") {
+			env = append(env, kv)
+		}
+	}
+	return syscall.Exec(os.Args[1], os.Args[1:], env)
+}
+
+func applyLimits(l Limits) error {
+	if l.CPUSeconds > 0 {
+		// 软限制触发 SIGXCPU，留出 1 秒后由硬限制 SIGKILL 兜底
+		if err := setLimit(unix.RLIMIT_CPU, l.CPUSeconds, l.CPUSeconds+1); err != nil {
+			return fmt.Errorf("failed to limit cpu time: %w", err)
+		}
+	}
+	if l.AddressSpace > 0 {
+		if err := setLimit(unix.RLIMIT_AS, l.AddressSpace, l.AddressSpace); err != nil {
+			return fmt.Errorf("failed to limit address space: %w", err)
+		}
+	}
+	if l.OpenFiles > 0 {
+		if err := setLimit(unix.RLIMIT_NOFILE, l.OpenFiles, l.OpenFiles); err != nil {
+			return fmt.Errorf("failed to limit open files: %w", err)
+		}
+	}
+	if l.Processes > 0 {
+		if err := setLimit(unix.RLIMIT_NPROC, l.Processes, l.Processes); err != nil {
+			return fmt.Errorf("failed to limit processes: %w", err)
+		}
+	}
+	return nil
+}
+
+// setLimit 设置资源限制，不超过当前的硬限制
+func setLimit(resource int, soft, hard uint64) error {
+	var cur unix.Rlimit
+	if err := unix.Getrlimit(resource, &cur); err != nil {
+		return err
+	}
+	if cur.Max != unix.RLIM_INFINITY && hard > cur.Max {
+		hard = cur.Max
+	}
+	if soft > hard {
+		soft = hard
+	}
+	return unix.Setrlimit(resource, &unix.Rlimit{Cur: soft, Max: hard})
+}
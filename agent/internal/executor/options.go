@@ -0,0 +1,55 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// ErrLimitExceeded 脚本因触发资源限制被终止
+var ErrLimitExceeded = errors.New("resource limit exceeded")
+
+// 资源限制名称，用于上报触发的限制
+const (
+	LimitCPUSeconds   = "cpu_seconds"
+	LimitAddressSpace = "address_space"
+	LimitOpenFiles    = "open_files"
+	LimitProcesses    = "processes"
+)
+
+// RunOptions 脚本的运行身份、工作目录与资源限制，零值表示继承 Agent 的设置
+type RunOptions struct {
+	User    string // 用户名或 UID
+	Group   string // 用户组名或 GID，为空时使用用户的主组
+	WorkDir string
+	Umask   string            // 八进制，如 "022"
+	Env     map[string]string // 追加到 Agent 环境变量之后的任务环境变量
+	Limits  Limits
+
+	Args     []string // 脚本或可执行文件的参数
+	FileName string   // file 类型写入临时目录时使用的文件名，为空时为 artifact
+}
+
+// Limits 资源限制，0 表示不限制
+type Limits struct {
+	CPUSeconds   uint64 // CPU 时间（秒），超过后进程收到 SIGXCPU
+	AddressSpace uint64 // 虚拟内存（字节）
+	OpenFiles    uint64 // 打开文件数
+	Processes    uint64 // 进程数，配置了 cgroup 目录时使用 cgroup v2 的 pids.max
+}
+
+func (l Limits) isZero() bool {
+	return l == Limits{}
+}
+
+// parseUmask 解析八进制 umask，为空时返回 -1
+func parseUmask(umask string) (int, error) {
+	if umask == "" {
+		return -1, nil
+	}
+	v, err := strconv.ParseUint(umask, 8, 32)
+	if err != nil || v > 0o777 {
+		return 0, fmt.Errorf("invalid umask: %s", umask)
+	}
+	return int(v), nil
+}
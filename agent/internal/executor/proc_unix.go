@@ -0,0 +1,216 @@
+//go:build unix
+
+package executor
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		// 向整个进程组发送 SIGKILL，避免 sh 派生的子进程残留
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
+
+// sandbox 按 RunOptions 设置进程的运行身份与资源限制，并在结束后判断是否因资源限制被终止
+type sandbox struct {
+	limits Limits
+	cgroup *taskCgroup // 进程数由 cgroup 限制时非 nil
+}
+
+// prepare 在启动前设置运行身份、工作目录、umask 与资源限制。需在 setProcessGroup 之后调用
+func prepare(cmd *exec.Cmd, opts *RunOptions, cgroupDir, name string) (*sandbox, error) {
+	sb := &sandbox{}
+	if opts == nil {
+		return sb, nil
+	}
+	sb.limits = opts.Limits
+	cmd.Dir = opts.WorkDir
+
+	umask, err := parseUmask(opts.Umask)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.User != "" || opts.Group != "" {
+		cred, home, err := lookupCredential(opts.User, opts.Group)
+		if err != nil {
+			return nil, err
+		}
+		cmd.SysProcAttr.Credential = cred
+		if opts.User != "" {
+			cmd.Env = append(cmd.Environ(), "HOME="+home, "USER="+opts.User, "LOGNAME="+opts.User)
+		}
+	}
+
+	rlimits := opts.Limits
+	if opts.Limits.Processes > 0 && cgroupDir != "" {
+		cg, err := newTaskCgroup(cgroupDir, name, opts.Limits.Processes)
+		if err != nil {
+			return nil, err
+		}
+		cg.attach(cmd)
+		sb.cgroup = cg
+		rlimits.Processes = 0
+	}
+
+	if umask >= 0 || !rlimits.isZero() {
+		if err := wrapHelper(cmd, helperSpec{Umask: umask, Limits: rlimits}); err != nil {
+			sb.cleanup()
+			return nil, err
+		}
+	}
+	return sb, nil
+}
+
+// wrapHelper 改为通过执行辅助进程启动 cmd，由其设置 umask 与资源限制
+func wrapHelper(cmd *exec.Cmd, spec helperSpec) error {
+	if cmd.Err != nil {
+		return cmd.Err
+	}
+	self, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate agent executable: %w", err)
+	}
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return err
+	}
+
+	cmd.Env = append(cmd.Environ(), helperEnv+"="+string(data))
+	cmd.Args = append([]string{self, cmd.Path}, cmd.Args[1:]...)
+	cmd.Path = self
+	return nil
+}
+
+// limitExceeded 根据退出状态判断进程是否因资源限制被终止，返回触发的限制名称
+func (s *sandbox) limitExceeded(state *os.ProcessState) string {
+	if state == nil {
+		return ""
+	}
+	if s.cgroup != nil && s.cgroup.limitHit() {
+		return LimitProcesses
+	}
+
+	status, ok := state.Sys().(syscall.WaitStatus)
+	if !ok {
+		return ""
+	}
+	if s.limits.CPUSeconds > 0 {
+		cpu := state.UserTime() + state.SystemTime()
+		// sh 的子进程被 SIGXCPU 终止时，sh 以 128+信号值退出
+		if (status.Signaled() && status.Signal() == syscall.SIGXCPU) ||
+			(status.Exited() && status.ExitStatus() == 128+int(syscall.SIGXCPU)) ||
+			(status.Signaled() && cpu >= time.Duration(s.limits.CPUSeconds)*time.Second) {
+			return LimitCPUSeconds
+		}
+	}
+	if s.limits.AddressSpace > 0 && status.Signaled() {
+		// 内存分配失败通常表现为段错误或 abort
+		switch status.Signal() {
+		case syscall.SIGSEGV, syscall.SIGBUS, syscall.SIGABRT:
+			return LimitAddressSpace
+		}
+	}
+	return ""
+}
+
+func (s *sandbox) cleanup() {
+	if s.cgroup != nil {
+		s.cgroup.remove()
+	}
+}
+
+// lookupCredential 解析运行用户与用户组，返回凭据与用户主目录
+func lookupCredential(userName, groupName string) (*syscall.Credential, string, error) {
+	cred := &syscall.Credential{
+		Uid: uint32(os.Getuid()),
+		Gid: uint32(os.Getgid()),
+	}
+	var home string
+
+	if userName != "" {
+		u, err := lookupUser(userName)
+		if err != nil {
+			return nil, "", err
+		}
+		uid, _ := strconv.ParseUint(u.Uid, 10, 32)
+		gid, _ := strconv.ParseUint(u.Gid, 10, 32)
+		cred.Uid, cred.Gid = uint32(uid), uint32(gid)
+		home = u.HomeDir
+
+		groupIDs, err := u.GroupIds()
+		if err == nil {
+			for _, id := range groupIDs {
+				if g, err := strconv.ParseUint(id, 10, 32); err == nil {
+					cred.Groups = append(cred.Groups, uint32(g))
+				}
+			}
+		}
+	}
+
+	if groupName != "" {
+		g, err := lookupGroup(groupName)
+		if err != nil {
+			return nil, "", err
+		}
+		gid, _ := strconv.ParseUint(g.Gid, 10, 32)
+		cred.Gid = uint32(gid)
+	}
+	if cred.Groups == nil {
+		// 不继承 Agent 的附加用户组
+		cred.Groups = []uint32{cred.Gid}
+	}
+	return cred, home, nil
+}
+
+func lookupUser(name string) (*user.User, error) {
+	if _, err := strconv.ParseUint(name, 10, 32); err == nil {
+		if u, err := user.LookupId(name); err == nil {
+			return u, nil
+		}
+	}
+	u, err := user.Lookup(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown user %s: %w", name, err)
+	}
+	return u, nil
+}
+
+func lookupGroup(name string) (*user.Group, error) {
+	if _, err := strconv.ParseUint(name, 10, 32); err == nil {
+		if g, err := user.LookupGroupId(name); err == nil {
+			return g, nil
+		}
+	}
+	g, err := user.LookupGroup(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown group %s: %w", name, err)
+	}
+	return g, nil
+}
+
+// chownToCredential 将 dir 及其中的文件交给 cmd 的运行身份所有，未指定运行身份时不做修改
+func chownToCredential(cmd *exec.Cmd, dir string) error {
+	if cmd.SysProcAttr == nil || cmd.SysProcAttr.Credential == nil {
+		return nil
+	}
+	uid, gid := int(cmd.SysProcAttr.Credential.Uid), int(cmd.SysProcAttr.Credential.Gid)
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		return os.Lchown(path, uid, gid)
+	})
+}
@@ -0,0 +1,35 @@
+//go:build windows
+
+package executor
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+)
+
+// Windows 下没有进程组信号，沿用 CommandContext 默认的终止行为
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// Init 仅 Unix 需要执行辅助进程，Windows 下为空操作
+func Init() {}
+
+// sandbox Windows 下只支持设置工作目录
+type sandbox struct{}
+
+func prepare(cmd *exec.Cmd, opts *RunOptions, cgroupDir, name string) (*sandbox, error) {
+	if opts == nil {
+		return &sandbox{}, nil
+	}
+	if opts.User != "" || opts.Group != "" || opts.Umask != "" || !opts.Limits.isZero() {
+		return nil, errors.New("run-as user, umask and resource limits are not supported on windows")
+	}
+	cmd.Dir = opts.WorkDir
+	return &sandbox{}, nil
+}
+
+func (s *sandbox) limitExceeded(state *os.ProcessState) string { return "" }
+
+func (s *sandbox) cleanup() {}
+
+func chownToCredential(cmd *exec.Cmd, dir string) error { return nil }
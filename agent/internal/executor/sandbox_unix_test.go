@@ -0,0 +1,151 @@
+//go:build unix
+
+package executor
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	// 资源限制通过重新执行测试二进制实现
+	Init()
+	os.Exit(m.Run())
+}
+
+func TestExecuteWithRunOptions(t *testing.T) {
+	executor := NewExecutor()
+	dir := t.TempDir()
+
+	result, err := executor.ExecuteStream(context.Background(), "", "shell", "umask; pwd; ulimit -n", 10,
+		&RunOptions{WorkDir: dir, Umask: "027", Limits: Limits{OpenFiles: 64}}, nil)
+	if err != nil {
+		t.Fatalf("ExecuteStream failed: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(result.Stdout), "\n")
+	if len(lines) != 3 || lines[0] != "0027" || lines[1] != dir || lines[2] != "64" {
+		t.Errorf("unexpected output: %q", result.Stdout)
+	}
+}
+
+func TestExecuteCPULimit(t *testing.T) {
+	executor := NewExecutor()
+
+	result, err := executor.ExecuteStream(context.Background(), "task-cpu", "shell", "while :; do :; done", 10,
+		&RunOptions{Limits: Limits{CPUSeconds: 1}}, nil)
+	if !errors.Is(err, ErrLimitExceeded) {
+		t.Fatalf("expected ErrLimitExceeded, got %v", err)
+	}
+	if result.LimitExceeded != LimitCPUSeconds {
+		t.Errorf("expected cpu_seconds, got %q", result.LimitExceeded)
+	}
+}
+
+func TestExecuteAsUser(t *testing.T) {
+	if os.Getuid() != 0 || runtime.GOOS != "linux" {
+		t.Skip("requires root on linux")
+	}
+	executor := NewExecutor()
+
+	result, err := executor.ExecuteStream(context.Background(), "", "shell", "id -un; echo $HOME", 10,
+		&RunOptions{User: "nobody", WorkDir: "/"}, nil)
+	if err != nil {
+		t.Fatalf("ExecuteStream failed: %v", err)
+	}
+	if !strings.HasPrefix(result.Stdout, "nobody\n") {
+		t.Errorf("expected to run as nobody, got %q", result.Stdout)
+	}
+
+	// 临时文件归运行用户所有
+	result, err = executor.ExecuteStream(context.Background(), "", "file", "#!/bin/sh\nid -un\n", 10,
+		&RunOptions{User: "nobody", WorkDir: "/"}, nil)
+	if err != nil {
+		t.Fatalf("ExecuteStream failed: %v", err)
+	}
+	if result.Stdout != "nobody\n" {
+		t.Errorf("expected file to run as nobody, got %q (stderr %q)", result.Stdout, result.Stderr)
+	}
+}
+
+func TestExecuteInvalidOptions(t *testing.T) {
+	executor := NewExecutor()
+
+	if _, err := executor.Execute(context.Background(), "shell", "true", 10); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if _, err := executor.ExecuteStream(context.Background(), "", "shell", "true", 10, &RunOptions{Umask: "999"}, nil); err == nil {
+		t.Error("expected error for invalid umask")
+	}
+	if _, err := executor.ExecuteStream(context.Background(), "", "shell", "true", 10, &RunOptions{User: "no-such-user-xyz"}, nil); err == nil {
+		t.Error("expected error for unknown user")
+	}
+}
+
+func TestExecuteInterpreters(t *testing.T) {
+	executor := NewExecutor()
+
+	tests := []struct {
+		scriptType  string
+		interpreter string
+		script      string
+	}{
+		{"shell", "sh", `echo "$1-$2"`},
+		{"bash", "bash", `echo "${1}-${2}"`},
+		{"python", "python3", `import sys; print("-".join(sys.argv[1:]))`},
+		{"perl", "perl", `print join("-", @ARGV), "\n"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.scriptType, func(t *testing.T) {
+			if _, err := exec.LookPath(tt.interpreter); err != nil {
+				t.Skipf("%s not installed", tt.interpreter)
+			}
+			result, err := executor.ExecuteStream(context.Background(), "", tt.scriptType, tt.script, 10,
+				&RunOptions{Args: []string{"a b", "c"}}, nil)
+			if err != nil {
+				t.Fatalf("ExecuteStream failed: %v", err)
+			}
+			if result.Stdout != "a b-c\n" {
+				t.Errorf("unexpected output: %q", result.Stdout)
+			}
+		})
+	}
+
+	if _, err := exec.LookPath("pwsh"); err != nil {
+		_, err := executor.Execute(context.Background(), "powershell", "Write-Output hi", 10)
+		if !errors.Is(err, ErrInterpreterNotFound) {
+			t.Errorf("expected ErrInterpreterNotFound, got %v", err)
+		}
+	}
+}
+
+func TestExecuteFile(t *testing.T) {
+	executor := NewExecutor()
+
+	artifact := "#!/bin/sh\necho \"$0\"\necho \"args: $*\"\n"
+	result, err := executor.ExecuteStream(context.Background(), "", "file", artifact, 10,
+		&RunOptions{Args: []string{"--check", "x"}, FileName: "check.sh"}, nil)
+	if err != nil {
+		t.Fatalf("ExecuteStream failed: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(result.Stdout), "\n")
+	if len(lines) != 2 || filepath.Base(lines[0]) != "check.sh" || lines[1] != "args: --check x" {
+		t.Fatalf("unexpected output: %q", result.Stdout)
+	}
+	// 执行结束后删除临时目录
+	if _, err := os.Stat(filepath.Dir(lines[0])); !os.IsNotExist(err) {
+		t.Errorf("expected temp dir to be removed, got %v", err)
+	}
+
+	if _, err := executor.ExecuteStream(context.Background(), "", "file", artifact, 10,
+		&RunOptions{FileName: "../escape.sh"}, nil); err == nil {
+		t.Error("expected invalid file name to be rejected")
+	}
+}
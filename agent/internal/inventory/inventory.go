@@ -0,0 +1,150 @@
+package inventory
+
+import (
+	"bufio"
+	"net"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	pb "github.com/yourusername/agent-platform/proto"
+	"github.com/yourusername/agent-platform/agent/internal/version"
+)
+
+// Inventory 主机资产信息，随注册消息上报给平台
+type Inventory struct {
+	Hostname      string
+	IP            string
+	OS            string
+	OSVersion     string
+	Arch          string
+	Version       string
+	KernelVersion string
+	CPUCount      int
+	MemoryTotal   uint64 // 字节
+	BootTime      time.Time
+}
+
+// Collect 采集本机资产信息，读取失败的字段保持为空
+func Collect() *Inventory {
+	inv := &Inventory{
+		OS:       runtime.GOOS,
+		Arch:     runtime.GOARCH,
+		Version:  version.Version,
+		CPUCount: runtime.NumCPU(),
+	}
+
+	inv.Hostname, _ = os.Hostname()
+	inv.IP = primaryIP()
+	inv.OSVersion = readOSRelease("/etc/os-release")
+	inv.KernelVersion = readFirstLine("/proc/sys/kernel/osrelease")
+	inv.MemoryTotal = readMemTotal("/proc/meminfo")
+	inv.BootTime = readBootTime("/proc/stat")
+	return inv
+}
+
+// ToRegister 转换为注册消息
+func (inv *Inventory) ToRegister(agentID string) *pb.AgentRegister {
+	register := &pb.AgentRegister{
+		AgentId:       agentID,
+		Hostname:      inv.Hostname,
+		Ip:            inv.IP,
+		Os:            inv.OS,
+		OsVersion:     inv.OSVersion,
+		Arch:          inv.Arch,
+		Version:       inv.Version,
+		KernelVersion: inv.KernelVersion,
+		CpuCount:      int32(inv.CPUCount),
+		MemoryTotal:   inv.MemoryTotal,
+	}
+	if !inv.BootTime.IsZero() {
+		register.BootTime = &pb.Timestamp{Seconds: inv.BootTime.Unix()}
+	}
+	return register
+}
+
+// primaryIP 返回第一个非回环的 IPv4 地址
+func primaryIP() string {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return ""
+	}
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok || ipNet.IP.IsLoopback() || ipNet.IP.IsLinkLocalUnicast() {
+			continue
+		}
+		if ip := ipNet.IP.To4(); ip != nil {
+			return ip.String()
+		}
+	}
+	return ""
+}
+
+func readFirstLine(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	line, _, _ := strings.Cut(string(data), "\n")
+	return strings.TrimSpace(line)
+}
+
+// readOSRelease 读取发行版名称，如 "Ubuntu 22.04.3 LTS"
+func readOSRelease(path string) string {
+	for _, line := range readLines(path) {
+		if value, ok := strings.CutPrefix(line, "PRETTY_NAME="); ok {
+			return strings.Trim(value, `"'`)
+		}
+	}
+	return ""
+}
+
+// readMemTotal 读取 /proc/meminfo 中的 MemTotal 并转换为字节
+func readMemTotal(path string) uint64 {
+	for _, line := range readLines(path) {
+		if value, ok := strings.CutPrefix(line, "MemTotal:"); ok {
+			fields := strings.Fields(value)
+			if len(fields) == 0 {
+				return 0
+			}
+			kb, err := strconv.ParseUint(fields[0], 10, 64)
+			if err != nil {
+				return 0
+			}
+			return kb * 1024
+		}
+	}
+	return 0
+}
+
+// readBootTime 读取 /proc/stat 中的 btime（开机时间的 Unix 秒数）
+func readBootTime(path string) time.Time {
+	for _, line := range readLines(path) {
+		if value, ok := strings.CutPrefix(line, "btime "); ok {
+			sec, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+			if err != nil {
+				return time.Time{}
+			}
+			return time.Unix(sec, 0)
+		}
+	}
+	return time.Time{}
+}
+
+func readLines(path string) []string {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines
+}
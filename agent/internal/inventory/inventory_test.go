@@ -0,0 +1,48 @@
+package inventory
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestParseProcFiles(t *testing.T) {
+	meminfo := writeFile(t, "meminfo", "MemTotal:       16318004 kB\nMemFree:         1234 kB\n")
+	if got := readMemTotal(meminfo); got != 16318004*1024 {
+		t.Errorf("expected MemTotal %d, got %d", 16318004*1024, got)
+	}
+
+	stat := writeFile(t, "stat", "cpu  1 2 3 4\nbtime 1700000000\nprocesses 42\n")
+	if got := readBootTime(stat); got.Unix() != 1700000000 {
+		t.Errorf("expected boot time 1700000000, got %d", got.Unix())
+	}
+
+	osRelease := writeFile(t, "os-release", "NAME=\"Ubuntu\"\nPRETTY_NAME=\"Ubuntu 22.04.3 LTS\"\n")
+	if got := readOSRelease(osRelease); got != "Ubuntu 22.04.3 LTS" {
+		t.Errorf("expected Ubuntu 22.04.3 LTS, got %q", got)
+	}
+
+	if got := readMemTotal(filepath.Join(t.TempDir(), "missing")); got != 0 {
+		t.Errorf("expected 0 for missing file, got %d", got)
+	}
+}
+
+func TestCollect(t *testing.T) {
+	inv := Collect()
+	if inv.CPUCount <= 0 {
+		t.Errorf("expected positive CPU count, got %d", inv.CPUCount)
+	}
+
+	register := inv.ToRegister("agent-1")
+	if register.AgentId != "agent-1" || register.Arch != inv.Arch || register.Version == "" {
+		t.Errorf("unexpected register message: %v", register)
+	}
+}
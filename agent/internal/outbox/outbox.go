@@ -0,0 +1,205 @@
+package outbox
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	pb "github.com/yourusername/agent-platform/proto"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// DefaultMemoryLimit 内存中最多缓存的消息数，超出部分写入磁盘
+	DefaultMemoryLimit = 256
+	// DefaultDiskLimit 磁盘上最多缓存的消息数，超出时丢弃最旧的消息
+	DefaultDiskLimit = 10000
+
+	fileSuffix = ".msg"
+)
+
+// Outbox 缓存与平台断开期间产生的消息（任务结果、指标等），重连后按产生顺序回放。
+// 较新的消息保存在内存中，内存满时最旧的消息转存到磁盘，
+// 因此磁盘上的消息总是早于内存中的消息
+type Outbox struct {
+	mu          sync.Mutex
+	dir         string
+	memory      []*pb.AgentMessage
+	files       []uint64 // 磁盘上消息的序号，按产生顺序排列
+	nextSeq     uint64
+	memoryLimit int
+	diskLimit   int
+}
+
+// New 创建 Outbox，并加载上次运行遗留在 dir 中的消息
+func New(dir string) *Outbox {
+	o := &Outbox{
+		dir:         dir,
+		memoryLimit: DefaultMemoryLimit,
+		diskLimit:   DefaultDiskLimit,
+	}
+	o.load()
+	return o
+}
+
+func (o *Outbox) load() {
+	entries, err := os.ReadDir(o.dir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Failed to read outbox dir %s: %v", o.dir, err)
+		}
+		return
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, fileSuffix) {
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(name, fileSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		o.files = append(o.files, seq)
+	}
+	sort.Slice(o.files, func(i, j int) bool { return o.files[i] < o.files[j] })
+	if len(o.files) > 0 {
+		o.nextSeq = o.files[len(o.files)-1] + 1
+		log.Printf("Loaded %d buffered messages from %s", len(o.files), o.dir)
+	}
+}
+
+// Push 缓存一条消息
+func (o *Outbox) Push(msg *pb.AgentMessage) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.memory = append(o.memory, msg)
+	for len(o.memory) > o.memoryLimit {
+		o.spill(o.memory[0])
+		o.memory = o.memory[1:]
+	}
+}
+
+// Len 返回缓存的消息总数
+func (o *Outbox) Len() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.files) + len(o.memory)
+}
+
+// Drain 按产生顺序逐条回放缓存的消息，send 成功后才删除该消息；
+// send 返回错误时停止回放，剩余消息保留到下一次
+func (o *Outbox) Drain(send func(msg *pb.AgentMessage) error) error {
+	for {
+		msg, fromDisk, seq, ok := o.peek()
+		if !ok {
+			return nil
+		}
+		if err := send(msg); err != nil {
+			return err
+		}
+		o.remove(fromDisk, seq)
+	}
+}
+
+// peek 返回最旧的一条消息；磁盘上损坏的消息会被丢弃
+func (o *Outbox) peek() (*pb.AgentMessage, bool, uint64, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for len(o.files) > 0 {
+		seq := o.files[0]
+		data, err := os.ReadFile(o.path(seq))
+		if err == nil {
+			msg := &pb.AgentMessage{}
+			if err = proto.Unmarshal(data, msg); err == nil {
+				return msg, true, seq, true
+			}
+		}
+		log.Printf("Dropping unreadable buffered message %d: %v", seq, err)
+		os.Remove(o.path(seq))
+		o.files = o.files[1:]
+	}
+
+	if len(o.memory) > 0 {
+		return o.memory[0], false, 0, true
+	}
+	return nil, false, 0, false
+}
+
+func (o *Outbox) remove(fromDisk bool, seq uint64) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if fromDisk {
+		if len(o.files) > 0 && o.files[0] == seq {
+			os.Remove(o.path(seq))
+			o.files = o.files[1:]
+		}
+		return
+	}
+	if len(o.memory) > 0 {
+		o.memory = o.memory[1:]
+	}
+}
+
+// Close 将内存中的消息全部写入磁盘，以便进程重启后继续回放
+func (o *Outbox) Close() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for _, msg := range o.memory {
+		o.spill(msg)
+	}
+	o.memory = nil
+	return nil
+}
+
+// spill 将消息写入磁盘，超过磁盘上限时丢弃最旧的消息。调用方需持有锁
+func (o *Outbox) spill(msg *pb.AgentMessage) {
+	if err := o.writeFile(msg); err != nil {
+		log.Printf("Failed to buffer message to disk, dropping it: %v", err)
+		return
+	}
+
+	for len(o.files) > o.diskLimit {
+		log.Printf("Outbox full, dropping oldest buffered message %d", o.files[0])
+		os.Remove(o.path(o.files[0]))
+		o.files = o.files[1:]
+	}
+}
+
+func (o *Outbox) writeFile(msg *pb.AgentMessage) error {
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+	if err := os.MkdirAll(o.dir, 0700); err != nil {
+		return fmt.Errorf("failed to create outbox dir: %w", err)
+	}
+
+	seq := o.nextSeq
+	// 先写临时文件再重命名，避免进程中断留下不完整的消息
+	tmp := o.path(seq) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, o.path(seq)); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	o.nextSeq++
+	o.files = append(o.files, seq)
+	return nil
+}
+
+func (o *Outbox) path(seq uint64) string {
+	return filepath.Join(o.dir, fmt.Sprintf("%020d%s", seq, fileSuffix))
+}
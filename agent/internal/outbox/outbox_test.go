@@ -0,0 +1,91 @@
+package outbox
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	pb "github.com/yourusername/agent-platform/proto"
+)
+
+func resultMessage(taskID string) *pb.AgentMessage {
+	return &pb.AgentMessage{
+		Message: &pb.AgentMessage_TaskResult{
+			TaskResult: &pb.TaskResult{TaskId: taskID},
+		},
+	}
+}
+
+func drainTaskIDs(t *testing.T, o *Outbox) []string {
+	var ids []string
+	err := o.Drain(func(msg *pb.AgentMessage) error {
+		ids = append(ids, msg.GetTaskResult().TaskId)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Drain failed: %v", err)
+	}
+	return ids
+}
+
+func TestOutboxSpillAndReplayInOrder(t *testing.T) {
+	dir := t.TempDir()
+	o := New(dir)
+	o.memoryLimit = 2
+
+	for i := 0; i < 5; i++ {
+		o.Push(resultMessage(fmt.Sprintf("task-%d", i)))
+	}
+	if len(o.files) != 3 || len(o.memory) != 2 {
+		t.Fatalf("expected 3 messages on disk and 2 in memory, got %d and %d", len(o.files), len(o.memory))
+	}
+
+	// 发送失败时保留消息
+	sendErr := errors.New("stream closed")
+	if err := o.Drain(func(msg *pb.AgentMessage) error { return sendErr }); err != sendErr {
+		t.Fatalf("expected send error, got %v", err)
+	}
+	if o.Len() != 5 {
+		t.Fatalf("expected 5 buffered messages, got %d", o.Len())
+	}
+
+	ids := drainTaskIDs(t, o)
+	expected := []string{"task-0", "task-1", "task-2", "task-3", "task-4"}
+	if fmt.Sprint(ids) != fmt.Sprint(expected) {
+		t.Errorf("expected %v, got %v", expected, ids)
+	}
+	if o.Len() != 0 {
+		t.Errorf("expected empty outbox, got %d", o.Len())
+	}
+}
+
+func TestOutboxPersistsAcrossRestart(t *testing.T) {
+	dir := t.TempDir()
+	o := New(dir)
+	o.Push(resultMessage("task-1"))
+	o.Push(resultMessage("task-2"))
+	o.Close()
+
+	restarted := New(dir)
+	restarted.Push(resultMessage("task-3"))
+
+	ids := drainTaskIDs(t, restarted)
+	if fmt.Sprint(ids) != "[task-1 task-2 task-3]" {
+		t.Errorf("unexpected replay order: %v", ids)
+	}
+}
+
+func TestOutboxDiskLimit(t *testing.T) {
+	o := New(t.TempDir())
+	o.memoryLimit = 0
+	o.diskLimit = 2
+
+	for i := 0; i < 4; i++ {
+		o.Push(resultMessage(fmt.Sprintf("task-%d", i)))
+	}
+
+	ids := drainTaskIDs(t, o)
+	if fmt.Sprint(ids) != "[task-2 task-3]" {
+		t.Errorf("expected oldest messages dropped, got %v", ids)
+	}
+}
@@ -0,0 +1,234 @@
+package plugin
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"log"
+	"os"
+	"path/filepath"
+
+	pb "github.com/yourusername/agent-platform/proto"
+	"github.com/yourusername/agent-platform/pkg/pluginpkg"
+)
+
+// stagingDir 接收与解包安装包的临时目录，插件名称不能以 . 开头，不会与插件目录冲突
+const stagingDir = ".staging"
+
+var ErrUnknownTransfer = errors.New("unknown plugin package transfer")
+
+// transfer 正在接收的插件安装包
+type transfer struct {
+	req     *pb.InstallPluginRequest
+	file    *os.File
+	hash    hash.Hash
+	written int64
+}
+
+// BeginTransfer 开始接收平台分块发送的插件安装包
+func (m *Manager) BeginTransfer(req *pb.InstallPluginRequest) error {
+	if req.TransferId == "" || filepath.Base(req.TransferId) != req.TransferId {
+		return fmt.Errorf("invalid transfer id %q", req.TransferId)
+	}
+	dir := filepath.Join(m.pluginsDir(), stagingDir)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	f, err := os.OpenFile(filepath.Join(dir, req.TransferId+".tar.gz"), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create package file: %w", err)
+	}
+
+	m.transferMu.Lock()
+	m.transfers[req.TransferId] = &transfer{req: req, file: f, hash: sha256.New()}
+	m.transferMu.Unlock()
+	return nil
+}
+
+// WriteChunk 按顺序写入安装包分块。写入最后一块后校验大小与 SHA-256，
+// 返回安装请求与安装包路径；校验失败或分块乱序时放弃本次接收
+func (m *Manager) WriteChunk(chunk *pb.PluginPackageChunk) (*pb.InstallPluginRequest, string, error) {
+	m.transferMu.Lock()
+	t, exists := m.transfers[chunk.TransferId]
+	m.transferMu.Unlock()
+	if !exists {
+		return nil, "", ErrUnknownTransfer
+	}
+
+	err := t.write(chunk)
+	if err == nil && !chunk.Last {
+		return t.req, "", nil
+	}
+
+	m.transferMu.Lock()
+	delete(m.transfers, chunk.TransferId)
+	m.transferMu.Unlock()
+	t.file.Close()
+	if err == nil {
+		err = t.verify()
+	}
+	if err != nil {
+		os.Remove(t.file.Name())
+		return t.req, "", err
+	}
+	return t.req, t.file.Name(), nil
+}
+
+func (t *transfer) write(chunk *pb.PluginPackageChunk) error {
+	if chunk.Offset != t.written {
+		return fmt.Errorf("unexpected chunk offset %d, expected %d", chunk.Offset, t.written)
+	}
+	if t.written+int64(len(chunk.Data)) > t.req.Size {
+		return fmt.Errorf("plugin package exceeds declared size %d", t.req.Size)
+	}
+	if _, err := t.file.Write(chunk.Data); err != nil {
+		return fmt.Errorf("failed to write package file: %w", err)
+	}
+	t.hash.Write(chunk.Data)
+	t.written += int64(len(chunk.Data))
+	return nil
+}
+
+func (t *transfer) verify() error {
+	if t.written != t.req.Size {
+		return fmt.Errorf("plugin package size mismatch: got %d, expected %d", t.written, t.req.Size)
+	}
+	if sum := hex.EncodeToString(t.hash.Sum(nil)); sum != t.req.Sha256 {
+		return fmt.Errorf("plugin package checksum mismatch: got %s, expected %s", sum, t.req.Sha256)
+	}
+	return nil
+}
+
+// AbortTransfers 放弃所有未完成的接收，连接断开时调用
+func (m *Manager) AbortTransfers() {
+	m.transferMu.Lock()
+	defer m.transferMu.Unlock()
+
+	for id, t := range m.transfers {
+		t.file.Close()
+		os.Remove(t.file.Name())
+		delete(m.transfers, id)
+	}
+}
+
+// InstallPackage 将已校验的安装包解包并替换插件目录，然后加载、启动插件并发送配置。
+// 清单、兼容性与配置检查在停止旧版本之前完成，目录以重命名方式替换，新版本无法启动时恢复旧版本
+func (m *Manager) InstallPackage(name, pkgPath string, config map[string]string) (*pluginpkg.Manifest, error) {
+	m.installMu.Lock()
+	defer m.installMu.Unlock()
+	defer os.Remove(pkgPath)
+
+	staged, err := os.MkdirTemp(filepath.Join(m.pluginsDir(), stagingDir), name+"-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	defer os.RemoveAll(staged)
+
+	manifest, err := extractPackage(pkgPath, staged)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkManifest(name, manifest); err != nil {
+		return nil, err
+	}
+	entrypoint := filepath.Join(staged, filepath.FromSlash(manifest.Entrypoint))
+	if info, err := os.Stat(entrypoint); err != nil || !info.Mode().IsRegular() {
+		return nil, fmt.Errorf("package does not contain entrypoint %s", manifest.Entrypoint)
+	}
+	if _, err := manifest.ValidateConfig(config); err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(staged, 0755); err != nil {
+		return nil, err
+	}
+
+	// 停止旧版本后替换目录
+	m.mu.RLock()
+	previous := m.plugins[name]
+	m.mu.RUnlock()
+	if previous != nil {
+		if err := m.Unload(name); err != nil {
+			return nil, err
+		}
+	}
+
+	target := filepath.Join(m.pluginsDir(), name)
+	backup := target + ".old"
+	os.RemoveAll(backup)
+	hasBackup := false
+	if _, err := os.Stat(target); err == nil {
+		if err := os.Rename(target, backup); err != nil {
+			return nil, m.restore(name, previous, fmt.Errorf("failed to move old version: %w", err))
+		}
+		hasBackup = true
+	}
+	if err := os.Rename(staged, target); err != nil {
+		if hasBackup {
+			os.Rename(backup, target)
+		}
+		return nil, m.restore(name, previous, fmt.Errorf("failed to install plugin: %w", err))
+	}
+
+	err = m.Load(name)
+	if err == nil {
+		err = m.Start(name)
+	}
+	if err == nil {
+		err = m.Configure(name, config)
+	}
+	if err != nil {
+		m.Unload(name)
+		os.RemoveAll(target)
+		if hasBackup {
+			os.Rename(backup, target)
+		}
+		return nil, m.restore(name, previous, err)
+	}
+
+	os.RemoveAll(backup)
+	return manifest, nil
+}
+
+// restore 安装失败后重新启动替换前已加载的插件并恢复其配置，返回安装错误
+func (m *Manager) restore(name string, previous *Plugin, installErr error) error {
+	if previous == nil {
+		return installErr
+	}
+	if err := m.Load(name); err != nil {
+		log.Printf("Failed to reload plugin %s after failed install: %v", name, err)
+		return installErr
+	}
+	if err := m.Start(name); err != nil {
+		log.Printf("Failed to restart plugin %s after failed install: %v", name, err)
+		return installErr
+	}
+
+	previous.mu.RLock()
+	config := previous.config
+	previous.mu.RUnlock()
+	if len(config) > 0 {
+		m.mu.RLock()
+		plugin := m.plugins[name]
+		m.mu.RUnlock()
+		if err := plugin.SendConfig(config); err != nil {
+			log.Printf("Failed to restore config of plugin %s: %v", name, err)
+		}
+	}
+	return installErr
+}
+
+func extractPackage(pkgPath, dir string) (*pluginpkg.Manifest, error) {
+	f, err := os.Open(pkgPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	manifest, err := pluginpkg.Extract(f, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract plugin package: %w", err)
+	}
+	return manifest, nil
+}
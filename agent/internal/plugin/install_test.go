@@ -0,0 +1,134 @@
+package plugin
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	pb "github.com/yourusername/agent-platform/proto"
+)
+
+// buildPackage 生成包含 plugin.yaml 与同名可执行脚本的安装包
+func buildPackage(t *testing.T, name, version string) []byte {
+	t.Helper()
+	files := map[string]string{
+		"plugin.yaml": "name: " + name + "\nversion: " + version + "\n",
+		name:          "#!/bin/sh\n# " + version + "\nwhile read line; do :; done\n",
+	}
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for file, body := range files {
+		tw.WriteHeader(&tar.Header{Name: file, Mode: 0755, Size: int64(len(body)), Typeflag: tar.TypeReg})
+		tw.Write([]byte(body))
+	}
+	tw.Close()
+	gz.Close()
+	return buf.Bytes()
+}
+
+// transferPackage 分两块发送安装包，返回接收完成后的安装包路径
+func transferPackage(t *testing.T, m *Manager, id, name string, pkg []byte, checksum string) (string, error) {
+	t.Helper()
+	if checksum == "" {
+		sum := sha256.Sum256(pkg)
+		checksum = hex.EncodeToString(sum[:])
+	}
+	req := &pb.InstallPluginRequest{PluginName: name, TransferId: id, Sha256: checksum, Size: int64(len(pkg))}
+	if err := m.BeginTransfer(req); err != nil {
+		t.Fatal(err)
+	}
+
+	half := len(pkg) / 2
+	if _, path, err := m.WriteChunk(&pb.PluginPackageChunk{TransferId: id, Data: pkg[:half]}); err != nil || path != "" {
+		t.Fatalf("unexpected first chunk result: %q, %v", path, err)
+	}
+	_, path, err := m.WriteChunk(&pb.PluginPackageChunk{TransferId: id, Offset: int64(half), Data: pkg[half:], Last: true})
+	return path, err
+}
+
+func TestInstallPackage(t *testing.T) {
+	dataDir := t.TempDir()
+	m := NewManager(dataDir)
+	defer m.Unload("demo")
+
+	path, err := transferPackage(t, m, "t1", "demo", buildPackage(t, "demo", "1.0.0"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.InstallPackage("demo", path, nil); err != nil {
+		t.Fatal(err)
+	}
+	if info := m.List(); len(info) != 1 || info[0].Version != "1.0.0" || !info[0].Enabled {
+		t.Fatalf("unexpected plugins: %v", info)
+	}
+
+	// 升级时替换整个插件目录
+	path, err = transferPackage(t, m, "t2", "demo", buildPackage(t, "demo", "2.0.0"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.InstallPackage("demo", path, nil); err != nil {
+		t.Fatal(err)
+	}
+	if info := m.List(); len(info) != 1 || info[0].Version != "2.0.0" || !info[0].Enabled {
+		t.Fatalf("unexpected plugins after upgrade: %v", info)
+	}
+	script, _ := os.ReadFile(filepath.Join(dataDir, "plugins", "demo", "demo"))
+	if !strings.Contains(string(script), "2.0.0") {
+		t.Errorf("expected new version on disk, got %q", script)
+	}
+	if _, err := os.Stat(filepath.Join(dataDir, "plugins", "demo.old")); !os.IsNotExist(err) {
+		t.Error("expected old version to be removed")
+	}
+
+	// 安装包与插件名称不符时保留并继续运行原版本
+	path, err = transferPackage(t, m, "t3", "demo", buildPackage(t, "other", "3.0.0"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.InstallPackage("demo", path, nil); err == nil {
+		t.Fatal("expected install to fail")
+	}
+	if info := m.List(); len(info) != 1 || info[0].Version != "2.0.0" || !info[0].Enabled {
+		t.Fatalf("expected old version to keep running, got %v", info)
+	}
+	script, _ = os.ReadFile(filepath.Join(dataDir, "plugins", "demo", "demo"))
+	if !strings.Contains(string(script), "2.0.0") {
+		t.Errorf("expected installed version to be kept, got %q", script)
+	}
+}
+
+func TestTransferRejectsBadPackage(t *testing.T) {
+	m := NewManager(t.TempDir())
+	pkg := buildPackage(t, "demo", "1.0.0")
+
+	if _, err := transferPackage(t, m, "t1", "demo", pkg, strings.Repeat("0", 64)); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("expected checksum mismatch, got %v", err)
+	}
+
+	// 分块乱序时放弃接收，之后的分块被忽略
+	sum := sha256.Sum256(pkg)
+	req := &pb.InstallPluginRequest{PluginName: "demo", TransferId: "t2", Sha256: hex.EncodeToString(sum[:]), Size: int64(len(pkg))}
+	if err := m.BeginTransfer(req); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := m.WriteChunk(&pb.PluginPackageChunk{TransferId: "t2", Offset: 10, Data: pkg}); err == nil {
+		t.Fatal("expected out-of-order chunk to fail")
+	}
+	if _, _, err := m.WriteChunk(&pb.PluginPackageChunk{TransferId: "t2", Data: pkg, Last: true}); err != ErrUnknownTransfer {
+		t.Fatalf("expected ErrUnknownTransfer, got %v", err)
+	}
+
+	entries, _ := os.ReadDir(filepath.Join(m.pluginsDir(), stagingDir))
+	if len(entries) != 0 {
+		t.Errorf("expected staging directory to be empty, got %d entries", len(entries))
+	}
+}
@@ -1,26 +1,88 @@
 package plugin
 
 import (
+	"errors"
 	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
 	"sync"
 
 	pb "github.com/yourusername/agent-platform/proto"
+	"github.com/yourusername/agent-platform/agent/internal/version"
+	"github.com/yourusername/agent-platform/pkg/pluginpkg"
+	"github.com/yourusername/agent-platform/pkg/pluginproto"
 )
 
+// Capabilities Agent 向插件提供的能力，插件清单与握手中声明的 capabilities 必须都在其中
+var Capabilities = []string{"config", "metrics", "logs", "events", "health"}
+
+// Subscriber 处理插件发送的某一类消息
+type Subscriber func(p *Plugin, msg *pluginproto.Message)
+
+// Manager 管理插件进程，插件安装在 <dataDir>/plugins/<name>/ 目录下
 type Manager struct {
 	mu      sync.RWMutex
 	plugins map[string]*Plugin
 	dataDir string
+	metrics *MetricCollector
+	// onStatus 接收插件状态变化
+	onStatus StatusHandler
+
+	subMu       sync.RWMutex
+	subscribers map[string][]Subscriber // 按消息类型订阅插件消息
+
+	transferMu sync.Mutex
+	transfers  map[string]*transfer
+	installMu  sync.Mutex // 串行执行安装包的解包与替换
 }
 
 func NewManager(dataDir string) *Manager {
-	return &Manager{
-		plugins: make(map[string]*Plugin),
-		dataDir: dataDir,
+	m := &Manager{
+		plugins:     make(map[string]*Plugin),
+		dataDir:     dataDir,
+		metrics:     NewMetricCollector(),
+		transfers:   make(map[string]*transfer),
+		subscribers: make(map[string][]Subscriber),
 	}
+	m.Subscribe(pluginproto.TypeMetric, m.handleMetric)
+	m.Subscribe(pluginproto.TypeLog, handleLog)
+	m.Subscribe(pluginproto.TypeEvent, handleEvent)
+	m.Subscribe(pluginproto.TypeHealth, m.handleHealth)
+	m.Subscribe(pluginproto.TypeError, m.handleError)
+	return m
+}
+
+// Subscribe 订阅所有插件发送的某一类消息，订阅者在插件的读取 goroutine 中依次调用，不应阻塞
+func (m *Manager) Subscribe(msgType string, fn Subscriber) {
+	m.subMu.Lock()
+	defer m.subMu.Unlock()
+	m.subscribers[msgType] = append(m.subscribers[msgType], fn)
 }
 
+func (m *Manager) pluginsDir() string {
+	return filepath.Join(m.dataDir, "plugins")
+}
+
+// Metrics 返回汇总插件指标的收集器
+func (m *Manager) Metrics() *MetricCollector {
+	return m.metrics
+}
+
+// Load 加载插件，插件目录中有 plugin.yaml 时解析清单并检查与当前 Agent 的兼容性
 func (m *Manager) Load(name string) error {
+	manifest, err := m.readManifest(name)
+	if err != nil {
+		return err
+	}
+	if manifest != nil {
+		if err := checkManifest(name, manifest); err != nil {
+			return err
+		}
+	}
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -29,10 +91,70 @@ func (m *Manager) Load(name string) error {
 	}
 
 	plugin := NewPlugin(name, m.dataDir)
+	if manifest != nil {
+		plugin.setManifest(manifest)
+	}
+	plugin.onMessage = m.handleMessage
+	plugin.onExit = m.handleExit
 	m.plugins[name] = plugin
 	return nil
 }
 
+// readManifest 读取插件目录中的 plugin.yaml，文件不存在时返回 nil
+func (m *Manager) readManifest(name string) (*pluginpkg.Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(m.pluginsDir(), name, pluginpkg.ManifestFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plugin manifest: %w", err)
+	}
+	return pluginpkg.ParseManifest(data)
+}
+
+// checkManifest 检查插件清单与插件名称、当前平台、Agent 版本与能力是否匹配
+func checkManifest(name string, manifest *pluginpkg.Manifest) error {
+	if manifest.Name != name {
+		return fmt.Errorf("manifest declares plugin %s, expected %s", manifest.Name, name)
+	}
+	if err := manifest.Compatible(runtime.GOOS, runtime.GOARCH, version.Version); err != nil {
+		return err
+	}
+	if manifest.Protocol > pluginproto.Version {
+		return fmt.Errorf("%w: requires protocol version %d, agent supports %d", pluginpkg.ErrIncompatible, manifest.Protocol, pluginproto.Version)
+	}
+	if missing := manifest.MissingCapabilities(Capabilities); len(missing) > 0 {
+		return fmt.Errorf("%w: unsupported capabilities %s", pluginpkg.ErrIncompatible, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// Configure 按插件清单中的配置 Schema 校验并转换配置后发送给插件，配置为空时不发送
+func (m *Manager) Configure(name string, config map[string]string) error {
+	m.mu.RLock()
+	plugin, exists := m.plugins[name]
+	m.mu.RUnlock()
+
+	if !exists {
+		return fmt.Errorf("plugin %s not loaded", name)
+	}
+
+	typed := make(map[string]interface{}, len(config))
+	for k, v := range config {
+		typed[k] = v
+	}
+	if manifest := plugin.Manifest(); manifest != nil {
+		var err error
+		if typed, err = manifest.ValidateConfig(config); err != nil {
+			return err
+		}
+	}
+	if len(typed) == 0 {
+		return nil
+	}
+	return plugin.SendConfig(typed)
+}
+
 func (m *Manager) Start(name string) error {
 	m.mu.RLock()
 	plugin, exists := m.plugins[name]
@@ -42,7 +164,31 @@ func (m *Manager) Start(name string) error {
 		return fmt.Errorf("plugin %s not loaded", name)
 	}
 
-	return plugin.Start()
+	previous := plugin.State()
+	if err := plugin.Start(); err != nil {
+		return err
+	}
+	// 声明了协议版本的插件需在启动后完成握手
+	if manifest := plugin.Manifest(); manifest != nil && manifest.Protocol > 0 {
+		if err := plugin.waitHandshake(); err != nil {
+			m.stop(plugin)
+			return err
+		}
+	}
+	m.report(plugin, &pb.PluginStatus{PreviousState: previous})
+	return nil
+}
+
+// CheckHealth 请求插件报告健康状态，插件需支持协议版本 1 以上
+func (m *Manager) CheckHealth(name string) (*pluginproto.Health, error) {
+	m.mu.RLock()
+	plugin, exists := m.plugins[name]
+	m.mu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("plugin %s not loaded", name)
+	}
+	return plugin.CheckHealth()
 }
 
 func (m *Manager) Stop(name string) error {
@@ -54,26 +200,53 @@ func (m *Manager) Stop(name string) error {
 		return fmt.Errorf("plugin %s not loaded", name)
 	}
 
-	return plugin.Stop()
+	return m.stop(plugin)
 }
 
+// Unload 停止并卸载插件。插件先从 Manager 中移除，停止期间不会处理其退出
 func (m *Manager) Unload(name string) error {
 	m.mu.Lock()
-	defer m.mu.Unlock()
-
 	plugin, exists := m.plugins[name]
 	if !exists {
+		m.mu.Unlock()
 		return fmt.Errorf("plugin %s not loaded", name)
 	}
+	delete(m.plugins, name)
+	m.mu.Unlock()
 
-	if err := plugin.Stop(); err != nil {
+	if err := m.stop(plugin); err != nil {
+		m.mu.Lock()
+		if _, exists := m.plugins[name]; !exists {
+			m.plugins[name] = plugin
+		}
+		m.mu.Unlock()
 		return fmt.Errorf("failed to stop plugin: %w", err)
 	}
-
-	delete(m.plugins, name)
 	return nil
 }
 
+// StopAll 并行停止所有插件，Agent 退出时调用，使插件有机会保存状态
+func (m *Manager) StopAll() {
+	m.mu.RLock()
+	plugins := make([]*Plugin, 0, len(m.plugins))
+	for _, plugin := range m.plugins {
+		plugins = append(plugins, plugin)
+	}
+	m.mu.RUnlock()
+
+	var wg sync.WaitGroup
+	for _, plugin := range plugins {
+		wg.Add(1)
+		go func(p *Plugin) {
+			defer wg.Done()
+			if err := m.stop(p); err != nil {
+				log.Printf("Failed to stop plugin %s: %v", p.Name(), err)
+			}
+		}(plugin)
+	}
+	wg.Wait()
+}
+
 func (m *Manager) List() []*pb.PluginInfo {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
@@ -84,3 +257,92 @@ func (m *Manager) List() []*pb.PluginInfo {
 	}
 	return infos
 }
+
+// handleMessage 将插件发送的消息分发给订阅者
+func (m *Manager) handleMessage(p *Plugin, msg *pluginproto.Message) {
+	m.subMu.RLock()
+	subscribers := m.subscribers[msg.Type]
+	m.subMu.RUnlock()
+
+	if len(subscribers) == 0 {
+		log.Printf("Plugin %s: unknown message type %s", p.Name(), msg.Type)
+		return
+	}
+	for _, fn := range subscribers {
+		fn(p, msg)
+	}
+}
+
+func (m *Manager) handleMetric(p *Plugin, msg *pluginproto.Message) {
+	var metric pluginproto.Metric
+	if err := msg.Decode(&metric); err != nil {
+		log.Printf("Plugin %s: %v", p.Name(), err)
+		return
+	}
+	point, err := parseMetric(p.Name(), &metric)
+	if err != nil {
+		log.Printf("Plugin %s: %v", p.Name(), err)
+		return
+	}
+	// 清单声明了指标名称时丢弃未声明的指标
+	if manifest := p.Manifest(); manifest != nil && len(manifest.Metrics) > 0 && !containsString(manifest.Metrics, point.Name) {
+		log.Printf("Plugin %s: dropping undeclared metric %s", p.Name(), point.Name)
+		return
+	}
+	m.metrics.Add(point)
+}
+
+// handleLog 将插件日志写入 Agent 日志
+func handleLog(p *Plugin, msg *pluginproto.Message) {
+	var entry pluginproto.Log
+	if err := msg.Decode(&entry); err != nil {
+		log.Printf("Plugin %s: %v", p.Name(), err)
+		return
+	}
+	if len(entry.Fields) > 0 {
+		log.Printf("Plugin %s [%s]: %s %v", p.Name(), entry.Level, entry.Message, entry.Fields)
+		return
+	}
+	log.Printf("Plugin %s [%s]: %s", p.Name(), entry.Level, entry.Message)
+}
+
+func handleEvent(p *Plugin, msg *pluginproto.Message) {
+	var event pluginproto.Event
+	if err := msg.Decode(&event); err != nil {
+		log.Printf("Plugin %s: %v", p.Name(), err)
+		return
+	}
+	log.Printf("Plugin %s event %s: %s %v", p.Name(), event.Name, event.Message, event.Fields)
+}
+
+// handleHealth 记录插件主动上报的健康状态，状态变化时写入日志
+func (m *Manager) handleHealth(p *Plugin, msg *pluginproto.Message) {
+	var health pluginproto.Health
+	if err := msg.Decode(&health); err != nil {
+		log.Printf("Plugin %s: %v", p.Name(), err)
+		return
+	}
+	if previous := p.setHealth(&health); previous != health.Status {
+		log.Printf("Plugin %s health: %q -> %q %s", p.Name(), previous, health.Status, health.Message)
+	}
+}
+
+// handleError 将插件报告的错误随状态上报平台
+func (m *Manager) handleError(p *Plugin, msg *pluginproto.Message) {
+	var e pluginproto.Error
+	if err := msg.Decode(&e); err != nil {
+		log.Printf("Plugin %s: %v", p.Name(), err)
+		return
+	}
+	log.Printf("Plugin %s error: %v", p.Name(), &e)
+	m.report(p, &pb.PluginStatus{PreviousState: p.State(), Error: e.Error()})
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -1,7 +1,16 @@
 package plugin
 
 import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
+
+	pb "github.com/yourusername/agent-platform/proto"
+	"github.com/yourusername/agent-platform/pkg/pluginpkg"
+	"github.com/yourusername/agent-platform/pkg/pluginproto"
 )
 
 func TestPluginManager(t *testing.T) {
@@ -33,3 +42,142 @@ func TestPluginManager(t *testing.T) {
 		}
 	})
 }
+
+func TestHandleMetricMessage(t *testing.T) {
+	manager := NewManager("/tmp/test-plugins")
+	p := NewPlugin("cpu", "/tmp/test-plugins")
+
+	manager.handleMessage(p, newMessage(t, pluginproto.TypeMetric, map[string]interface{}{
+		"name":      "cpu_usage",
+		"value":     42.5,
+		"timestamp": 1700000000,
+		"labels":    map[string]string{"core": "all"},
+	}))
+	// 缺少数值的指标被丢弃
+	manager.handleMessage(p, newMessage(t, pluginproto.TypeMetric, map[string]interface{}{"name": "broken"}))
+
+	var flushed []*pb.MetricPoint
+	manager.Metrics().Start(time.Hour, func(points []*pb.MetricPoint) {
+		flushed = append(flushed, points...)
+	})
+	manager.Metrics().Stop()
+
+	if len(flushed) != 1 {
+		t.Fatalf("expected 1 metric, got %d", len(flushed))
+	}
+	point := flushed[0]
+	if point.Name != "cpu_usage" || point.Value != 42.5 {
+		t.Errorf("unexpected metric: %v", point)
+	}
+	if point.Timestamp.Seconds != 1700000000 {
+		t.Errorf("expected timestamp 1700000000, got %d", point.Timestamp.Seconds)
+	}
+	if point.Labels["plugin"] != "cpu" || point.Labels["core"] != "all" {
+		t.Errorf("unexpected labels: %v", point.Labels)
+	}
+}
+
+func newMessage(t *testing.T, typ string, data interface{}) *pluginproto.Message {
+	t.Helper()
+	msg, err := pluginproto.NewMessage(typ, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return msg
+}
+
+// writePlugin 在插件目录中写入清单与可执行脚本
+func writePlugin(t *testing.T, dataDir, name, manifest, script string) {
+	t.Helper()
+	dir := filepath.Join(dataDir, "plugins", name)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "plugin.yaml"), []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "run.sh"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadManifest(t *testing.T) {
+	dataDir := t.TempDir()
+	manager := NewManager(dataDir)
+	received := filepath.Join(dataDir, "received")
+
+	writePlugin(t, dataDir, "http-check", `
+name: http-check
+version: 1.2.0
+description: probe http endpoints
+entrypoint: run.sh
+metrics: [http_up]
+capabilities: [config, metrics]
+config_schema:
+  required: [url]
+  properties:
+    url: {type: string}
+    interval: {type: integer, minimum: 1}
+`, "#!/bin/sh\nwhile read line; do echo \"$line\" >> "+received+"; done\n")
+
+	if err := manager.Load("http-check"); err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Unload("http-check")
+	info := manager.List()[0]
+	if info.Version != "1.2.0" || info.Entrypoint != "run.sh" || len(info.Metrics) != 1 || !strings.Contains(info.ConfigSchema, `"required":["url"]`) {
+		t.Fatalf("unexpected plugin info: %v", info)
+	}
+
+	if err := manager.Start("http-check"); err != nil {
+		t.Fatal(err)
+	}
+	if err := manager.Configure("http-check", map[string]string{"interval": "10"}); !errors.Is(err, pluginpkg.ErrInvalidConfig) {
+		t.Fatalf("expected ErrInvalidConfig, got %v", err)
+	}
+	if err := manager.Configure("http-check", map[string]string{"url": "http://localhost", "interval": "10"}); err != nil {
+		t.Fatal(err)
+	}
+	// 插件收到按 Schema 转换类型的配置
+	var data []byte
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if data, _ = os.ReadFile(received); len(data) > 0 {
+			break
+		}
+	}
+	if !strings.Contains(string(data), `"interval":10`) {
+		t.Errorf("expected typed config, got %q", data)
+	}
+
+	// 清单声明了指标名称时丢弃未声明的指标
+	p := manager.plugins["http-check"]
+	for _, name := range []string{"http_up", "undeclared"} {
+		manager.handleMessage(p, newMessage(t, pluginproto.TypeMetric, map[string]interface{}{"name": name, "value": 1.0}))
+	}
+	var flushed []*pb.MetricPoint
+	manager.Metrics().Start(time.Hour, func(points []*pb.MetricPoint) {
+		flushed = append(flushed, points...)
+	})
+	manager.Metrics().Stop()
+	if len(flushed) != 1 || flushed[0].Name != "http_up" {
+		t.Errorf("expected only declared metric, got %v", flushed)
+	}
+}
+
+func TestLoadIncompatiblePlugin(t *testing.T) {
+	dataDir := t.TempDir()
+	manager := NewManager(dataDir)
+
+	writePlugin(t, dataDir, "other-os", "name: other-os\nversion: 1.0.0\nos: [plan9]\n", "#!/bin/sh\n")
+	writePlugin(t, dataDir, "needs-gpu", "name: needs-gpu\nversion: 1.0.0\ncapabilities: [gpu]\n", "#!/bin/sh\n")
+	writePlugin(t, dataDir, "renamed", "name: other\nversion: 1.0.0\n", "#!/bin/sh\n")
+
+	for _, name := range []string{"other-os", "needs-gpu", "renamed"} {
+		if err := manager.Load(name); err == nil {
+			t.Errorf("expected %s to be rejected", name)
+		}
+	}
+	if len(manager.List()) != 0 {
+		t.Error("expected no plugins to be loaded")
+	}
+}
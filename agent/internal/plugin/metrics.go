@@ -0,0 +1,135 @@
+package plugin
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	pb "github.com/yourusername/agent-platform/proto"
+	"github.com/yourusername/agent-platform/pkg/pluginproto"
+)
+
+// defaultMaxBatchSize 单个批次的最大指标数，达到后立即上报
+const defaultMaxBatchSize = 500
+
+// FlushFunc 将一批指标上报到平台
+type FlushFunc func(points []*pb.MetricPoint)
+
+// MetricCollector 汇总所有插件产生的指标，按固定间隔或批次大小批量上报
+type MetricCollector struct {
+	mu           sync.Mutex
+	points       []*pb.MetricPoint
+	maxBatchSize int
+	flush        FlushFunc
+	stopCh       chan struct{}
+	doneCh       chan struct{}
+}
+
+func NewMetricCollector() *MetricCollector {
+	return &MetricCollector{
+		maxBatchSize: defaultMaxBatchSize,
+	}
+}
+
+// Start 开始按 interval 周期上报，重复调用会先停止上一次的上报循环
+func (c *MetricCollector) Start(interval time.Duration, flush FlushFunc) {
+	c.Stop()
+
+	c.mu.Lock()
+	c.flush = flush
+	c.stopCh = make(chan struct{})
+	c.doneCh = make(chan struct{})
+	stopCh, doneCh := c.stopCh, c.doneCh
+	c.mu.Unlock()
+
+	go func() {
+		defer close(doneCh)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				c.Flush()
+			case <-stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// Stop 停止周期上报，并将剩余指标上报一次
+func (c *MetricCollector) Stop() {
+	c.mu.Lock()
+	stopCh, doneCh := c.stopCh, c.doneCh
+	c.stopCh, c.doneCh = nil, nil
+	c.mu.Unlock()
+
+	if stopCh == nil {
+		return
+	}
+	close(stopCh)
+	<-doneCh
+	c.Flush()
+}
+
+func (c *MetricCollector) Add(point *pb.MetricPoint) {
+	c.mu.Lock()
+	c.points = append(c.points, point)
+	full := len(c.points) >= c.maxBatchSize
+	c.mu.Unlock()
+
+	if full {
+		c.Flush()
+	}
+}
+
+func (c *MetricCollector) Flush() {
+	c.mu.Lock()
+	points := c.points
+	flush := c.flush
+	c.points = nil
+	c.mu.Unlock()
+
+	if len(points) == 0 {
+		return
+	}
+	if flush == nil {
+		// 尚未开始上报，放回缓冲区等待下一次
+		c.mu.Lock()
+		c.points = append(points, c.points...)
+		c.mu.Unlock()
+		return
+	}
+	flush(points)
+}
+
+// parseMetric 将插件发送的 metric 消息转换为指标数据点，并附加 plugin 标签
+func parseMetric(pluginName string, metric *pluginproto.Metric) (*pb.MetricPoint, error) {
+	if metric.Name == "" {
+		return nil, fmt.Errorf("metric name is required")
+	}
+	if metric.Value == nil {
+		return nil, fmt.Errorf("metric %s has no numeric value", metric.Name)
+	}
+
+	ts := time.Now()
+	if metric.Timestamp > 0 {
+		ts = time.Unix(int64(metric.Timestamp), 0)
+	}
+
+	labels := map[string]string{"plugin": pluginName}
+	for k, v := range metric.Labels {
+		labels[k] = v
+	}
+
+	return &pb.MetricPoint{
+		Name:  metric.Name,
+		Value: *metric.Value,
+		Timestamp: &pb.Timestamp{
+			Seconds: ts.Unix(),
+			Nanos:   int32(ts.Nanosecond()),
+		},
+		Labels: labels,
+	}, nil
+}
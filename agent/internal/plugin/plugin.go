@@ -1,147 +1,409 @@
 package plugin
 
 import (
-	"bufio"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
+	"log"
+	"os"
 	"os/exec"
 	"path/filepath"
 	"sync"
+	"syscall"
+	"time"
 
 	pb "github.com/yourusername/agent-platform/proto"
+	"github.com/yourusername/agent-platform/pkg/pluginpkg"
+	"github.com/yourusername/agent-platform/pkg/pluginproto"
+	"google.golang.org/protobuf/proto"
 )
 
+// stderrBufferSize 保留的插件 stderr 输出大小
+const stderrBufferSize = 64 * 1024
+
+// 插件状态
+const (
+	StateStopped      = "stopped"
+	StateRunning      = "running"
+	StateRestarting   = "restarting"   // 异常退出，等待按重启策略重启
+	StateExited       = "exited"       // 已退出，按重启策略不再重启
+	StateCrashLooping = "crashlooping" // 时间窗口内重启次数达到上限，不再重启
+)
+
+// 插件的停止方式
+const (
+	ShutdownClean      = "clean"      // 插件回复 shutdown_ack 后在宽限期内退出
+	ShutdownExited     = "exited"     // 宽限期内退出但没有回复 shutdown_ack
+	ShutdownTerminated = "terminated" // 宽限期后被 SIGTERM 终止
+	ShutdownKilled     = "killed"     // SIGTERM 后仍未退出，被 SIGKILL 终止
+)
+
+// 进程退出相关的超时时间，测试中可调整
+var (
+	terminateTimeout = 5 * time.Second // 发送 SIGTERM 后等待插件退出的时间，超时后 SIGKILL
+	// outputDrainTimeout 插件进程退出后继续读取其输出的时间。
+	// 脱离进程组的子进程可能继续持有 stdout/stderr，超时后不再读取，避免退出处理被阻塞
+	outputDrainTimeout = 2 * time.Second
+)
+
+// MessageHandler 处理插件发送的消息，握手与请求的回复由 Plugin 处理，不会传给 MessageHandler
+type MessageHandler func(p *Plugin, msg *pluginproto.Message)
+
+// ExitHandler 处理插件进程的意外退出，Stop 导致的退出不会调用
+type ExitHandler func(p *Plugin, err error)
+
 type Plugin struct {
-	mu      sync.RWMutex
-	info    *pb.PluginInfo
-	cmd     *exec.Cmd
-	stdin   io.WriteCloser
-	stdout  io.ReadCloser
-	config  map[string]interface{}
-	dataDir string
-	running bool
+	mu        sync.RWMutex
+	info      *pb.PluginInfo
+	cmd       *exec.Cmd
+	stdin     *os.File
+	writer    *pluginproto.Writer
+	stderr    *ringBuffer
+	config    map[string]interface{}
+	manifest  *pluginpkg.Manifest // 没有 plugin.yaml 的插件为 nil
+	dataDir   string
+	running   bool
+	stopping  bool          // 由 Stop 终止进程
+	acked     bool          // 插件已回复 shutdown_ack
+	exited    chan struct{} // 进程退出且输出读取完毕后关闭
+	exitErr   error         // 最近一次退出时 Wait 的返回值
+	startedAt time.Time
+	onMessage MessageHandler
+	onExit    ExitHandler
+
+	// 以下为当前进程的协议状态，每次启动时重置
+	protocol     int                                  // 握手确定的协议版本，0 为旧协议
+	handshake    chan struct{}                        // 收到第一条消息后关闭
+	handshakeErr error                                // 握手失败的原因
+	pending      map[string]chan *pluginproto.Message // 等待回复的请求
+	nextID       uint64
+
+	// 以下由 Manager 的重启逻辑使用
+	restartTimer *time.Timer
+	restartTimes []time.Time
+	backoff      int
 }
 
+// NewPlugin 创建插件，版本等信息在 setManifest 时从插件清单填充
 func NewPlugin(name, dataDir string) *Plugin {
 	return &Plugin{
 		info: &pb.PluginInfo{
-			Name:    name,
-			Version: "1.0.0",
-			Enabled: false,
+			Name:       name,
+			Enabled:    false,
+			Entrypoint: name,
+			State:      StateStopped,
 		},
 		config:  make(map[string]interface{}),
+		stderr:  newRingBuffer(stderrBufferSize),
 		dataDir: dataDir,
 	}
 }
 
+// setManifest 使用插件清单填充插件信息
+func (p *Plugin) setManifest(m *pluginpkg.Manifest) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.manifest = m
+	p.info.Version = m.Version
+	p.info.Description = m.Description
+	p.info.Entrypoint = m.Entrypoint
+	p.info.Os = m.OS
+	p.info.Arch = m.Arch
+	p.info.MinAgentVersion = m.MinAgentVersion
+	p.info.Metrics = m.Metrics
+	p.info.Capabilities = m.Capabilities
+	if m.ConfigSchema != nil {
+		schema, _ := json.Marshal(m.ConfigSchema)
+		p.info.ConfigSchema = string(schema)
+	}
+}
+
+// Manifest 返回插件清单，没有 plugin.yaml 时为 nil
+func (p *Plugin) Manifest() *pluginpkg.Manifest {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.manifest
+}
+
+// Start 启动插件进程，同时清空之前的重启记录
 func (p *Plugin) Start() error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
+	if err := p.start(); err != nil {
+		return err
+	}
+	p.restartTimes = nil
+	p.backoff = 0
+	return nil
+}
+
+// start 启动插件进程，调用方需持有 p.mu
+func (p *Plugin) start() error {
 	if p.running {
 		return fmt.Errorf("plugin already running")
 	}
 
-	pluginPath := filepath.Join(p.dataDir, "plugins", p.info.Name, p.info.Name)
-	p.cmd = exec.Command(pluginPath)
+	pluginPath := filepath.Join(p.dataDir, "plugins", p.info.Name, filepath.FromSlash(p.info.Entrypoint))
+	cmd := exec.Command(pluginPath)
+	cmd.Stderr = p.stderr
+
+	cmd.WaitDelay = outputDrainTimeout
+	setProcessGroup(cmd)
 
-	stdin, err := p.cmd.StdinPipe()
+	// stdin 使用 os.Pipe 以便设置写超时
+	stdinReader, stdin, err := os.Pipe()
 	if err != nil {
 		return fmt.Errorf("failed to create stdin pipe: %w", err)
 	}
-	p.stdin = stdin
+	cmd.Stdin = stdinReader
 
-	stdout, err := p.cmd.StdoutPipe()
+	// stdout 不使用 StdoutPipe：Wait 会等待 StdoutPipe 读到 EOF，而插件派生的子进程可能一直持有 stdout
+	stdout, stdoutWriter, err := os.Pipe()
 	if err != nil {
+		stdinReader.Close()
+		stdin.Close()
 		return fmt.Errorf("failed to create stdout pipe: %w", err)
 	}
-	p.stdout = stdout
+	cmd.Stdout = stdoutWriter
 
-	if err := p.cmd.Start(); err != nil {
+	err = cmd.Start()
+	stdinReader.Close()
+	stdoutWriter.Close()
+	if err != nil {
+		stdin.Close()
+		stdout.Close()
 		return fmt.Errorf("failed to start plugin: %w", err)
 	}
 
+	p.cmd = cmd
+	p.stdin = stdin
+	p.writer = pluginproto.NewWriter(stdin)
 	p.running = true
+	p.stopping = false
+	p.acked = false
+	p.exitErr = nil
+	p.exited = make(chan struct{})
+	p.startedAt = time.Now()
+	p.protocol = 0
+	p.handshake = make(chan struct{})
+	p.handshakeErr = nil
+	p.pending = make(map[string]chan *pluginproto.Message)
 	p.info.Enabled = true
+	p.info.State = StateRunning
+	p.info.ProtocolVersion = 0
+	p.info.Health = ""
+	p.info.HealthMessage = ""
+
+	go p.wait(cmd, stdout, p.exited, p.handshake, p.pending)
 	return nil
 }
 
-func (p *Plugin) Stop() error {
-	p.mu.Lock()
-	defer p.mu.Unlock()
+// wait 回收插件进程，并在输出读取完毕后结束本次运行。
+// 进程退出与 stdout EOF 无关：退出后终止其进程组，超过 outputDrainTimeout 仍未读到 EOF 时关闭 stdout。
+// 只有这一个 goroutine 调用 Wait，进程意外退出时通知 onExit
+func (p *Plugin) wait(cmd *exec.Cmd, stdout *os.File, exited, handshake chan struct{}, pending map[string]chan *pluginproto.Message) {
+	readDone := make(chan struct{})
+	go func() {
+		defer close(readDone)
+		p.readLoop(stdout, handshake, pending)
+	}()
 
-	if !p.running {
-		return nil
+	err := cmd.Wait()
+	// 终止插件留下的子进程，它们持有的 stdout 随之关闭
+	signalGroup(cmd.Process, syscall.SIGKILL)
+	if !waitExited(readDone, outputDrainTimeout) {
+		log.Printf("Plugin %s: output not closed after process exit, discarding it", p.Name())
 	}
+	stdout.Close()
+	<-readDone
 
-	if p.stdin != nil {
-		p.stdin.Close()
+	p.mu.Lock()
+	stopping := p.stopping
+	p.stdin.Close()
+	p.exitErr = err
+	p.running = false
+	p.info.Enabled = false
+	// 进程退出后不会再有回复
+	for id, reply := range pending {
+		close(reply)
+		delete(pending, id)
 	}
+	p.mu.Unlock()
+	close(exited)
 
-	if p.cmd != nil && p.cmd.Process != nil {
-		if err := p.cmd.Process.Kill(); err != nil {
-			return fmt.Errorf("failed to kill plugin process: %w", err)
-		}
-		p.cmd.Wait()
+	if !stopping && p.onExit != nil {
+		p.onExit(p, err)
 	}
+}
 
-	p.running = false
-	p.info.Enabled = false
-	return nil
+// Stop 停止插件进程并等待其退出，同时取消尚未执行的重启
+func (p *Plugin) Stop() error {
+	_, err := p.shutdown()
+	return err
 }
 
-func (p *Plugin) SendConfig(config map[string]interface{}) error {
+// shutdown 发送 shutdown 消息并关闭 stdin，插件应回复 shutdown_ack、保存状态后退出。
+// 宽限期内未退出时向进程组发送 SIGTERM，再等待 terminateTimeout 后 SIGKILL。返回停止方式，插件未运行时为空
+func (p *Plugin) shutdown() (string, error) {
 	p.mu.Lock()
-	defer p.mu.Unlock()
-
+	if p.restartTimer != nil {
+		p.restartTimer.Stop()
+		p.restartTimer = nil
+	}
+	p.info.State = StateStopped
 	if !p.running {
-		return fmt.Errorf("plugin not running")
+		p.mu.Unlock()
+		return "", nil
 	}
 
-	p.config = config
-
-	msg := map[string]interface{}{
-		"type": "config",
-		"data": config,
+	p.stopping = true
+	grace := time.Duration(pluginpkg.DefaultShutdownTimeout) * time.Second
+	if p.manifest != nil {
+		grace = time.Duration(p.manifest.ShutdownTimeout) * time.Second
 	}
+	stdin, writer := p.stdin, p.writer
+	process := p.cmd.Process
+	exited := p.exited
+	p.mu.Unlock()
 
-	data, err := json.Marshal(msg)
-	if err != nil {
-		return fmt.Errorf("failed to marshal config: %w", err)
+	// 不读取 stdin 的插件会阻塞写入，写入占用宽限期
+	deadline := time.Now().Add(grace)
+	msg, _ := pluginproto.NewMessage(pluginproto.TypeShutdown, nil)
+	if err := writeMessage(stdin, writer, msg, deadline); err != nil {
+		log.Printf("Plugin %s: failed to send shutdown: %v", p.Name(), err)
 	}
+	stdin.Close()
 
-	if _, err := p.stdin.Write(append(data, '\n')); err != nil {
-		return fmt.Errorf("failed to write config: %w", err)
+	how := ShutdownExited
+	if !waitExited(exited, time.Until(deadline)) {
+		how = ShutdownTerminated
+		// 不支持 SIGTERM 的平台（Windows）直接 SIGKILL
+		if err := signalGroup(process, syscall.SIGTERM); err != nil || !waitExited(exited, terminateTimeout) {
+			how = ShutdownKilled
+			if err := signalGroup(process, syscall.SIGKILL); err != nil && !errors.Is(err, os.ErrProcessDone) {
+				return how, fmt.Errorf("failed to kill plugin process: %w", err)
+			}
+			// 进程退出后最多再等待 outputDrainTimeout 读取输出
+			if !waitExited(exited, terminateTimeout+outputDrainTimeout) {
+				return how, fmt.Errorf("plugin process did not exit after SIGKILL")
+			}
+		}
 	}
 
-	return nil
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if how == ShutdownExited && p.acked {
+		how = ShutdownClean
+	}
+	return how, nil
 }
 
-func (p *Plugin) ReadData() (map[string]interface{}, error) {
+// kill 立即终止插件进程，进程退出按意外退出处理
+func (p *Plugin) kill() {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
+	if p.running {
+		signalGroup(p.cmd.Process, syscall.SIGKILL)
+	}
+}
+
+// waitExited 等待进程退出，超时返回 false
+func waitExited(exited <-chan struct{}, timeout time.Duration) bool {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-exited:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
 
+// SendConfig 发送配置。握手后的插件需回复 config_ack，插件拒绝配置时返回 pluginpkg.ErrInvalidConfig
+func (p *Plugin) SendConfig(config map[string]interface{}) error {
+	p.mu.Lock()
 	if !p.running {
-		return nil, fmt.Errorf("plugin not running")
+		p.mu.Unlock()
+		return fmt.Errorf("plugin not running")
 	}
+	p.config = config
+	protocol := p.protocol
+	p.mu.Unlock()
 
-	reader := bufio.NewReader(p.stdout)
-	line, err := reader.ReadBytes('\n')
+	if protocol == 0 {
+		return p.send(pluginproto.TypeConfig, config)
+	}
+	reply, err := p.request(pluginproto.TypeConfig, config)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read data: %w", err)
+		return err
+	}
+	var ack pluginproto.Ack
+	if err := reply.Decode(&ack); err != nil {
+		return err
 	}
+	if ack.Error != "" {
+		return fmt.Errorf("%w: %s", pluginpkg.ErrInvalidConfig, ack.Error)
+	}
+	return nil
+}
 
-	var data map[string]interface{}
-	if err := json.Unmarshal(line, &data); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal data: %w", err)
+// send 向插件发送不需要回复的消息，调用方不能持有 p.mu
+func (p *Plugin) send(typ string, data interface{}) error {
+	msg, err := pluginproto.NewMessage(typ, data)
+	if err != nil {
+		return err
 	}
+	p.mu.RLock()
+	stdin, writer := p.stdin, p.writer
+	p.mu.RUnlock()
+	return writeMessage(stdin, writer, msg, time.Now().Add(requestTimeout))
+}
 
-	return data, nil
+// writeMessage 向插件 stdin 写入消息。插件不读取 stdin 时写入会阻塞，到 deadline 后放弃，
+// 因此调用方不能持有 p.mu，以免阻塞 Info、State 与停止流程
+func writeMessage(stdin *os.File, writer *pluginproto.Writer, msg *pluginproto.Message, deadline time.Time) error {
+	// Windows 的匿名管道不支持写超时，忽略错误
+	stdin.SetWriteDeadline(deadline)
+	return writer.Write(msg)
 }
 
+func (p *Plugin) Name() string {
+	return p.info.Name
+}
+
+// Info 返回插件信息的副本
 func (p *Plugin) Info() *pb.PluginInfo {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
-	return p.info
+	return proto.Clone(p.info).(*pb.PluginInfo)
+}
+
+// State 返回插件当前状态
+func (p *Plugin) State() string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.info.State
+}
+
+// setState 更新插件状态并返回之前的状态
+func (p *Plugin) setState(state string) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	previous := p.info.State
+	p.info.State = state
+	return previous
+}
+
+// lastExit 返回最近一次进程退出时 Wait 的返回值
+func (p *Plugin) lastExit() error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.exitErr
+}
+
+// StderrTail 返回插件 stderr 输出的最后 n 行
+func (p *Plugin) StderrTail(n int) []string {
+	return p.stderr.Lines(n)
 }
@@ -0,0 +1,24 @@
+//go:build unix
+
+package plugin
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup 让插件在独立的进程组中运行，信号发送给整个进程组，插件派生的子进程随之终止
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// signalGroup 向插件进程所在的进程组发送信号，进程组已不存在时返回 os.ErrProcessDone
+func signalGroup(process *os.Process, sig syscall.Signal) error {
+	err := syscall.Kill(-process.Pid, sig)
+	if errors.Is(err, syscall.ESRCH) {
+		return os.ErrProcessDone
+	}
+	return err
+}
@@ -0,0 +1,20 @@
+//go:build windows
+
+package plugin
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// Windows 下没有进程组信号，只终止插件进程本身
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// signalGroup 不支持 SIGTERM，调用方随之改用 SIGKILL
+func signalGroup(process *os.Process, sig syscall.Signal) error {
+	if sig == syscall.SIGKILL {
+		return process.Kill()
+	}
+	return process.Signal(sig)
+}
@@ -0,0 +1,232 @@
+package plugin
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yourusername/agent-platform/pkg/pluginproto"
+)
+
+// 协议的超时时间，测试中可调整
+var (
+	handshakeTimeout = 10 * time.Second // 声明了协议版本的插件启动后发送握手的时限
+	requestTimeout   = 10 * time.Second // 插件回复请求的时限
+)
+
+// readLoop 是插件进程 stdout 唯一的 reader，整个进程生命周期内持续读取，避免缓冲数据丢失。
+// 第一条消息为 handshake 时完成握手，否则按旧协议处理；请求的回复交给等待的请求，其余消息交给 onMessage
+func (p *Plugin) readLoop(stdout io.Reader, handshake chan struct{}, pending map[string]chan *pluginproto.Message) {
+	reader := pluginproto.NewReader(stdout)
+	first := true
+	defer func() {
+		if first {
+			close(handshake)
+		}
+	}()
+
+	for {
+		msg, err := reader.Read()
+		if errors.Is(err, pluginproto.ErrInvalidMessage) {
+			log.Printf("Plugin %s: %v", p.Name(), err)
+			continue
+		}
+		if err != nil {
+			if err != io.EOF && !errors.Is(err, os.ErrClosed) {
+				log.Printf("Plugin %s: failed to read output: %v", p.Name(), err)
+			}
+			return
+		}
+
+		if first {
+			first = false
+			if msg.Type == pluginproto.TypeHandshake {
+				p.handleHandshake(msg)
+				close(handshake)
+				continue
+			}
+			close(handshake)
+		}
+
+		switch {
+		case msg.Type == pluginproto.TypeShutdownAck:
+			p.mu.Lock()
+			p.acked = true
+			p.mu.Unlock()
+		case msg.ReplyTo != "":
+			p.mu.Lock()
+			reply, exists := pending[msg.ReplyTo]
+			delete(pending, msg.ReplyTo)
+			p.mu.Unlock()
+			if !exists {
+				log.Printf("Plugin %s: dropping %s reply to unknown request %s", p.Name(), msg.Type, msg.ReplyTo)
+				continue
+			}
+			reply <- msg
+		case p.onMessage != nil:
+			p.onMessage(p, msg)
+		}
+	}
+}
+
+// handleHandshake 检查插件声明的协议版本、名称与能力并回复。
+// 协议版本取双方支持的较低版本；检查失败时回复 error，插件应随之退出
+func (p *Plugin) handleHandshake(msg *pluginproto.Message) {
+	var h pluginproto.Handshake
+	err := msg.Decode(&h)
+	if err == nil {
+		err = checkHandshake(p.Name(), &h)
+	}
+
+	p.mu.Lock()
+	var reply *pluginproto.Message
+	if err != nil {
+		p.handshakeErr = fmt.Errorf("plugin handshake failed: %w", err)
+		reply, _ = pluginproto.NewMessage(pluginproto.TypeError, &pluginproto.Error{Code: "handshake_failed", Message: err.Error()})
+	} else {
+		p.protocol = h.ProtocolVersion
+		if p.protocol > pluginproto.Version {
+			p.protocol = pluginproto.Version
+		}
+		p.info.ProtocolVersion = int32(p.protocol)
+		reply, _ = pluginproto.NewMessage(pluginproto.TypeHandshakeAck, &pluginproto.HandshakeAck{
+			ProtocolVersion: p.protocol,
+			Capabilities:    Capabilities,
+		})
+	}
+	stdin, writer := p.stdin, p.writer
+	p.mu.Unlock()
+
+	reply.ReplyTo = msg.ID
+	if err := writeMessage(stdin, writer, reply, time.Now().Add(requestTimeout)); err != nil {
+		log.Printf("Plugin %s: failed to reply to handshake: %v", p.Name(), err)
+	}
+}
+
+func checkHandshake(name string, h *pluginproto.Handshake) error {
+	if h.ProtocolVersion < 1 {
+		return fmt.Errorf("unsupported protocol version %d", h.ProtocolVersion)
+	}
+	if h.Name != name {
+		return fmt.Errorf("plugin declares name %s, expected %s", h.Name, name)
+	}
+	var missing []string
+	for _, c := range h.Capabilities {
+		if !containsString(Capabilities, c) {
+			missing = append(missing, c)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("unsupported capabilities %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// waitHandshake 等待插件完成握手，用于清单声明了协议版本的插件
+func (p *Plugin) waitHandshake() error {
+	p.mu.RLock()
+	handshake, exited := p.handshake, p.exited
+	p.mu.RUnlock()
+
+	timer := time.NewTimer(handshakeTimeout)
+	defer timer.Stop()
+	select {
+	case <-handshake:
+	case <-exited:
+	case <-timer.C:
+		return fmt.Errorf("plugin did not send handshake within %s", handshakeTimeout)
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.handshakeErr != nil {
+		return p.handshakeErr
+	}
+	if p.protocol == 0 {
+		return fmt.Errorf("plugin did not send handshake")
+	}
+	return nil
+}
+
+// request 向已握手的插件发送请求并等待 reply_to 相同的回复，插件回复 error 时返回 *pluginproto.Error
+func (p *Plugin) request(typ string, data interface{}) (*pluginproto.Message, error) {
+	msg, err := pluginproto.NewMessage(typ, data)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	if !p.running {
+		p.mu.Unlock()
+		return nil, fmt.Errorf("plugin not running")
+	}
+	if p.protocol == 0 {
+		p.mu.Unlock()
+		return nil, fmt.Errorf("plugin does not support %s requests", typ)
+	}
+	p.nextID++
+	msg.ID = strconv.FormatUint(p.nextID, 10)
+	reply := make(chan *pluginproto.Message, 1)
+	pending := p.pending
+	pending[msg.ID] = reply
+	stdin, writer := p.stdin, p.writer
+	p.mu.Unlock()
+
+	defer func() {
+		p.mu.Lock()
+		delete(pending, msg.ID)
+		p.mu.Unlock()
+	}()
+	deadline := time.Now().Add(requestTimeout)
+	if err := writeMessage(stdin, writer, msg, deadline); err != nil {
+		return nil, err
+	}
+
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+	select {
+	case r, ok := <-reply:
+		if !ok {
+			return nil, fmt.Errorf("plugin exited before replying to %s", typ)
+		}
+		if r.Type == pluginproto.TypeError {
+			var e pluginproto.Error
+			if err := r.Decode(&e); err != nil {
+				return nil, err
+			}
+			return nil, &e
+		}
+		return r, nil
+	case <-timer.C:
+		return nil, fmt.Errorf("plugin did not reply to %s within %s", typ, requestTimeout)
+	}
+}
+
+// CheckHealth 请求插件报告健康状态并更新插件信息
+func (p *Plugin) CheckHealth() (*pluginproto.Health, error) {
+	reply, err := p.request(pluginproto.TypeHealth, nil)
+	if err != nil {
+		return nil, err
+	}
+	var health pluginproto.Health
+	if err := reply.Decode(&health); err != nil {
+		return nil, err
+	}
+	p.setHealth(&health)
+	return &health, nil
+}
+
+// setHealth 记录插件的健康状态，返回之前的状态
+func (p *Plugin) setHealth(health *pluginproto.Health) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	previous := p.info.Health
+	p.info.Health = health.Status
+	p.info.HealthMessage = health.Message
+	return previous
+}
@@ -0,0 +1,168 @@
+package plugin
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	pb "github.com/yourusername/agent-platform/proto"
+	"github.com/yourusername/agent-platform/pkg/pluginpkg"
+	"github.com/yourusername/agent-platform/pkg/pluginproto"
+)
+
+// TestHelperPlugin 不是测试，由 writeHelperPlugin 写入的脚本以插件身份运行测试二进制时执行
+func TestHelperPlugin(t *testing.T) {
+	if os.Getenv("GO_WANT_HELPER_PLUGIN") != "1" {
+		return
+	}
+	os.Exit(runHelperPlugin())
+}
+
+// runHelperPlugin 使用 pluginproto 实现协议版本 1 的插件，HELPER_CAPABILITIES 为握手时声明的能力
+func runHelperPlugin() int {
+	conn := pluginproto.NewConn(os.Stdin, os.Stdout)
+	capabilities := []string{"config", "metrics"}
+	if c := os.Getenv("HELPER_CAPABILITIES"); c != "" {
+		capabilities = []string{c}
+	}
+	if _, err := conn.Handshake(pluginproto.Handshake{Name: os.Getenv("HELPER_NAME"), Version: "1.0.0", Capabilities: capabilities}); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	for msg := range conn.Receive() {
+		switch msg.Type {
+		case pluginproto.TypeConfig:
+			var config map[string]interface{}
+			msg.Decode(&config)
+			if _, ok := config["target"]; !ok {
+				conn.Reply(msg, pluginproto.TypeConfigAck, pluginproto.Ack{Error: "target is required"})
+				continue
+			}
+			conn.Reply(msg, pluginproto.TypeConfigAck, pluginproto.Ack{})
+			value := 1.0
+			conn.Send(pluginproto.TypeMetric, pluginproto.Metric{Name: "probe_up", Value: &value, Labels: map[string]string{"target": fmt.Sprint(config["target"])}})
+			conn.Send(pluginproto.TypeLog, pluginproto.Log{Level: "info", Message: "configured"})
+			conn.Send(pluginproto.TypeEvent, pluginproto.Event{Name: "target_changed", Fields: config})
+		case pluginproto.TypeHealth:
+			conn.Reply(msg, pluginproto.TypeHealth, pluginproto.Health{Status: pluginproto.HealthDegraded, Message: "slow target"})
+		case "unknown":
+			conn.Reply(msg, pluginproto.TypeError, pluginproto.Error{Code: "unsupported", Message: "unknown request"})
+		case pluginproto.TypeShutdown:
+			conn.Send(pluginproto.TypeShutdownAck, nil)
+			return 0
+		}
+	}
+	return 0
+}
+
+// writeHelperPlugin 写入以 TestHelperPlugin 运行的插件
+func writeHelperPlugin(t *testing.T, dataDir, name, manifest string) {
+	t.Helper()
+	script := fmt.Sprintf("#!/bin/sh\nGO_WANT_HELPER_PLUGIN=1 HELPER_NAME=%s exec %q -test.run='^TestHelperPlugin$'\n", name, os.Args[0])
+	writePlugin(t, dataDir, name, manifest, script)
+}
+
+func TestPluginProtocol(t *testing.T) {
+	dataDir := t.TempDir()
+	manager := NewManager(dataDir)
+	var mu sync.Mutex
+	var events []string
+	manager.Subscribe(pluginproto.TypeEvent, func(p *Plugin, msg *pluginproto.Message) {
+		var event pluginproto.Event
+		msg.Decode(&event)
+		mu.Lock()
+		events = append(events, p.Name()+":"+event.Name)
+		mu.Unlock()
+	})
+
+	writeHelperPlugin(t, dataDir, "probe", "name: probe\nversion: 1.0.0\nentrypoint: run.sh\nprotocol: 1\nmetrics: [probe_up]\n")
+	if err := manager.Load("probe"); err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Unload("probe")
+	if err := manager.Start("probe"); err != nil {
+		t.Fatal(err)
+	}
+	if info := manager.List()[0]; info.ProtocolVersion != 1 {
+		t.Fatalf("expected protocol version 1, got %v", info)
+	}
+
+	// 插件通过 config_ack 拒绝配置
+	if err := manager.Configure("probe", map[string]string{"interval": "10"}); !errors.Is(err, pluginpkg.ErrInvalidConfig) {
+		t.Fatalf("expected ErrInvalidConfig, got %v", err)
+	}
+	if err := manager.Configure("probe", map[string]string{"target": "db-1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	health, err := manager.CheckHealth("probe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if health.Status != pluginproto.HealthDegraded {
+		t.Errorf("unexpected health: %+v", health)
+	}
+	if info := manager.List()[0]; info.Health != pluginproto.HealthDegraded || info.HealthMessage != "slow target" {
+		t.Errorf("expected health in plugin info, got %v", info)
+	}
+
+	// 插件以 error 回复无法处理的请求
+	var protoErr *pluginproto.Error
+	if _, err := manager.plugins["probe"].request("unknown", nil); !errors.As(err, &protoErr) || protoErr.Code != "unsupported" {
+		t.Errorf("expected plugin error reply, got %v", err)
+	}
+
+	// 回复之前发送的消息按顺序分发给订阅者
+	mu.Lock()
+	got := fmt.Sprint(events)
+	mu.Unlock()
+	if got != "[probe:target_changed]" {
+		t.Errorf("unexpected events: %s", got)
+	}
+	var flushed []*pb.MetricPoint
+	manager.Metrics().Start(time.Hour, func(points []*pb.MetricPoint) {
+		flushed = append(flushed, points...)
+	})
+	manager.Metrics().Stop()
+	if len(flushed) != 1 || flushed[0].Labels["target"] != "db-1" {
+		t.Errorf("unexpected metrics: %v", flushed)
+	}
+}
+
+func TestPluginHandshakeFailure(t *testing.T) {
+	timeout := handshakeTimeout
+	handshakeTimeout = 500 * time.Millisecond
+	t.Cleanup(func() { handshakeTimeout = timeout })
+
+	dataDir := t.TempDir()
+	manager := NewManager(dataDir)
+
+	// 握手声明了 Agent 不支持的能力
+	t.Setenv("HELPER_CAPABILITIES", "gpu")
+	writeHelperPlugin(t, dataDir, "greedy", "name: greedy\nversion: 1.0.0\nentrypoint: run.sh\nprotocol: 1\n")
+	// 声明了协议版本却没有握手
+	writePlugin(t, dataDir, "silent", "name: silent\nversion: 1.0.0\nentrypoint: run.sh\nprotocol: 1\n", "#!/bin/sh\nwhile read line; do :; done\n")
+
+	for _, name := range []string{"greedy", "silent"} {
+		if err := manager.Load(name); err != nil {
+			t.Fatal(err)
+		}
+		if err := manager.Start(name); err == nil {
+			t.Errorf("expected %s to fail handshake", name)
+		}
+		if state := manager.plugins[name].State(); state != StateStopped {
+			t.Errorf("expected %s to be stopped, got %s", name, state)
+		}
+		manager.Unload(name)
+	}
+
+	// 清单要求更高的协议版本
+	writePlugin(t, dataDir, "future", "name: future\nversion: 1.0.0\nprotocol: 99\n", "#!/bin/sh\n")
+	if err := manager.Load("future"); !errors.Is(err, pluginpkg.ErrIncompatible) {
+		t.Errorf("expected ErrIncompatible, got %v", err)
+	}
+}
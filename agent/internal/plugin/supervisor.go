@@ -0,0 +1,249 @@
+package plugin
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	pb "github.com/yourusername/agent-platform/proto"
+	"github.com/yourusername/agent-platform/pkg/pluginpkg"
+)
+
+// stderrTailLines 状态上报中附带的 stderr 行数
+const stderrTailLines = 20
+
+// 重启的退避时间，插件连续运行超过 restartDelayMax 后退避重新从 restartDelayMin 开始。测试中可调整
+var (
+	restartDelayMin = time.Second
+	restartDelayMax = time.Minute
+)
+
+// defaultRestart 没有 plugin.yaml 的插件使用的重启策略
+var defaultRestart = pluginpkg.Restart{Policy: pluginpkg.RestartOnFailure, MaxRestarts: 5, Window: 300}
+
+// StatusHandler 接收插件状态变化
+type StatusHandler func(status *pb.PluginStatus)
+
+// OnStatus 设置插件状态变化的回调，需在加载插件之前调用
+func (m *Manager) OnStatus(handler StatusHandler) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onStatus = handler
+}
+
+// report 补全插件当前状态后上报状态变化，状态未变化且没有错误时不上报
+func (m *Manager) report(p *Plugin, status *pb.PluginStatus) {
+	m.mu.RLock()
+	handler := m.onStatus
+	m.mu.RUnlock()
+
+	info := p.Info()
+	if info.State == status.PreviousState && status.Error == "" {
+		return
+	}
+	log.Printf("Plugin %s: %s -> %s", info.Name, status.PreviousState, info.State)
+	if handler == nil {
+		return
+	}
+
+	now := time.Now()
+	status.PluginName = info.Name
+	status.State = info.State
+	status.Restarts = info.Restarts
+	status.Timestamp = &pb.Timestamp{Seconds: now.Unix(), Nanos: int32(now.Nanosecond())}
+	if info.State != StateRunning && (info.State != StateStopped || status.Shutdown != ShutdownClean) {
+		status.StderrTail = p.StderrTail(stderrTailLines)
+	}
+	handler(status)
+}
+
+// stop 停止插件并上报停止方式与退出码
+func (m *Manager) stop(p *Plugin) error {
+	previous := p.State()
+	how, err := p.shutdown()
+	if err != nil {
+		return err
+	}
+	status := &pb.PluginStatus{PreviousState: previous, Shutdown: how}
+	if how != "" {
+		status.ExitCode = int32(exitCodeOf(p.lastExit()))
+	}
+	m.report(p, status)
+	return nil
+}
+
+// handleExit 处理插件进程的意外退出，按重启策略决定是否以退避延迟重启
+func (m *Manager) handleExit(p *Plugin, err error) {
+	if !m.registered(p) {
+		return
+	}
+
+	status := &pb.PluginStatus{ExitCode: int32(exitCodeOf(err))}
+	if err != nil {
+		status.Error = err.Error()
+	}
+
+	policy := defaultRestart
+	if manifest := p.Manifest(); manifest != nil {
+		policy = manifest.Restart
+	}
+
+	p.mu.Lock()
+	status.PreviousState = p.info.State
+	if status.PreviousState != StateRunning && status.PreviousState != StateRestarting {
+		// 已被 Stop
+		p.mu.Unlock()
+		return
+	}
+
+	if policy.Policy == pluginpkg.RestartNever || (policy.Policy == pluginpkg.RestartOnFailure && err == nil) {
+		p.info.State = StateExited
+		p.mu.Unlock()
+		m.report(p, status)
+		return
+	}
+
+	now := time.Now()
+	window := time.Duration(policy.Window) * time.Second
+	recent := p.restartTimes[:0]
+	for _, t := range p.restartTimes {
+		if now.Sub(t) < window {
+			recent = append(recent, t)
+		}
+	}
+	p.restartTimes = recent
+	if len(recent) >= policy.MaxRestarts {
+		p.info.State = StateCrashLooping
+		p.mu.Unlock()
+		m.report(p, status)
+		return
+	}
+
+	if now.Sub(p.startedAt) >= restartDelayMax {
+		p.backoff = 0
+	}
+	delay := restartDelayMin << uint(p.backoff)
+	if delay > restartDelayMax || delay <= 0 {
+		delay = restartDelayMax
+	} else {
+		p.backoff++
+	}
+	p.info.State = StateRestarting
+	p.restartTimer = time.AfterFunc(delay, func() { m.restart(p) })
+	p.mu.Unlock()
+
+	m.report(p, status)
+}
+
+// restart 重启等待中的插件并重新发送配置，插件已被停止或卸载时放弃
+func (m *Manager) restart(p *Plugin) {
+	if !m.registered(p) {
+		return
+	}
+
+	p.mu.Lock()
+	if p.info.State != StateRestarting {
+		p.mu.Unlock()
+		return
+	}
+	p.restartTimer = nil
+	p.restartTimes = append(p.restartTimes, time.Now())
+	p.info.Restarts++
+	if err := p.start(); err != nil {
+		// 无法启动视为又一次异常退出
+		p.startedAt = time.Now()
+		p.mu.Unlock()
+		m.handleExit(p, err)
+		return
+	}
+	config := p.config
+	p.mu.Unlock()
+
+	m.report(p, &pb.PluginStatus{PreviousState: StateRestarting})
+	if manifest := p.Manifest(); manifest != nil && manifest.Protocol > 0 {
+		if err := p.waitHandshake(); err != nil {
+			// 握手失败按异常退出处理，由退出处理按重启策略决定是否继续重启
+			log.Printf("Plugin %s: %v", p.Name(), err)
+			p.kill()
+			return
+		}
+	}
+	if len(config) > 0 {
+		if err := p.SendConfig(config); err != nil {
+			log.Printf("Failed to resend config to plugin %s: %v", p.Name(), err)
+		}
+	}
+}
+
+// registered 判断插件是否仍由 Manager 管理，卸载或被新版本替换后不再处理其退出
+func (m *Manager) registered(p *Plugin) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.plugins[p.Name()] == p
+}
+
+// exitCodeOf 返回进程退出码，被信号终止或无法启动时为 -1
+func exitCodeOf(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return -1
+}
+
+// ringBuffer 保留最后写入的 size 字节，用于收集插件 stderr
+type ringBuffer struct {
+	mu        sync.Mutex
+	buf       []byte
+	size      int
+	truncated bool // 是否丢弃过较早的输出
+}
+
+func newRingBuffer(size int) *ringBuffer {
+	return &ringBuffer{size: size}
+}
+
+func (b *ringBuffer) Write(data []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	n := len(data)
+	if n >= b.size {
+		data = data[n-b.size:]
+		b.buf = b.buf[:0]
+		b.truncated = true
+	} else if over := len(b.buf) + n - b.size; over > 0 {
+		b.buf = b.buf[over:]
+		b.truncated = true
+	}
+	b.buf = append(b.buf, data...)
+	return n, nil
+}
+
+// Lines 返回最后 n 行，缓冲区开头被截断的不完整行会被丢弃
+func (b *ringBuffer) Lines(n int) []string {
+	b.mu.Lock()
+	data := bytes.TrimRight(b.buf, "\n")
+	truncated := b.truncated
+	text := string(data)
+	b.mu.Unlock()
+
+	if text == "" {
+		return nil
+	}
+	lines := strings.Split(text, "\n")
+	if truncated && len(lines) > 1 {
+		lines = lines[1:]
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines
+}
@@ -0,0 +1,265 @@
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	pb "github.com/yourusername/agent-platform/proto"
+)
+
+// statusRecorder 收集插件状态变化
+type statusRecorder struct {
+	mu       sync.Mutex
+	statuses []*pb.PluginStatus
+}
+
+func (r *statusRecorder) record(status *pb.PluginStatus) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.statuses = append(r.statuses, status)
+}
+
+// waitState 等待第 from 条之后的状态变化中出现指定状态，返回截至该状态的所有状态变化
+func (r *statusRecorder) waitState(t *testing.T, from int, state string) []*pb.PluginStatus {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		r.mu.Lock()
+		statuses := append([]*pb.PluginStatus(nil), r.statuses...)
+		r.mu.Unlock()
+		for i := from; i < len(statuses); i++ {
+			if statuses[i].State == state {
+				return statuses[:i+1]
+			}
+		}
+	}
+	t.Fatalf("timed out waiting for state %s", state)
+	return nil
+}
+
+func shortRestartDelay(t *testing.T) {
+	min, max := restartDelayMin, restartDelayMax
+	restartDelayMin, restartDelayMax = 10*time.Millisecond, 50*time.Millisecond
+	t.Cleanup(func() { restartDelayMin, restartDelayMax = min, max })
+}
+
+func TestRestartUntilCrashLoop(t *testing.T) {
+	shortRestartDelay(t)
+	dataDir := t.TempDir()
+	manager := NewManager(dataDir)
+	recorder := &statusRecorder{}
+	manager.OnStatus(recorder.record)
+
+	writePlugin(t, dataDir, "flaky", "name: flaky\nversion: 1.0.0\nentrypoint: run.sh\nrestart: {max_restarts: 2}\n",
+		"#!/bin/sh\necho \"boom $$\" >&2\nexit 3\n")
+	if err := manager.Load("flaky"); err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Unload("flaky")
+	if err := manager.Start("flaky"); err != nil {
+		t.Fatal(err)
+	}
+
+	statuses := recorder.waitState(t, 0, StateCrashLooping)
+	var states []string
+	for _, s := range statuses {
+		states = append(states, s.PreviousState+">"+s.State)
+	}
+	expected := "stopped>running running>restarting restarting>running running>restarting restarting>running running>crashlooping"
+	if got := strings.Join(states, " "); got != expected {
+		t.Fatalf("unexpected transitions %s", got)
+	}
+
+	last := statuses[len(statuses)-1]
+	if last.ExitCode != 3 || last.Restarts != 2 || last.Error == "" {
+		t.Errorf("unexpected crashloop status: %v", last)
+	}
+	if len(last.StderrTail) != 3 || !strings.HasPrefix(last.StderrTail[2], "boom ") {
+		t.Errorf("unexpected stderr tail: %q", last.StderrTail)
+	}
+	if info := manager.List()[0]; info.State != StateCrashLooping || info.Restarts != 2 {
+		t.Errorf("unexpected plugin info: %v", info)
+	}
+
+	// 手动启动后重新开始计数
+	if err := manager.Start("flaky"); err != nil {
+		t.Fatal(err)
+	}
+	statuses = recorder.waitState(t, len(statuses), StateCrashLooping)
+	if last := statuses[len(statuses)-1]; last.Restarts != 4 {
+		t.Errorf("expected 2 more restarts, got %v", last)
+	}
+}
+
+func TestRestartPolicy(t *testing.T) {
+	shortRestartDelay(t)
+	dataDir := t.TempDir()
+	manager := NewManager(dataDir)
+	recorder := &statusRecorder{}
+	manager.OnStatus(recorder.record)
+
+	// on-failure 策略下正常退出不重启
+	writePlugin(t, dataDir, "oneshot", "name: oneshot\nversion: 1.0.0\nentrypoint: run.sh\n", "#!/bin/sh\nexit 0\n")
+	if err := manager.Load("oneshot"); err != nil {
+		t.Fatal(err)
+	}
+	if err := manager.Start("oneshot"); err != nil {
+		t.Fatal(err)
+	}
+	if last := recorder.waitState(t, 0, StateExited); last[len(last)-1].ExitCode != 0 {
+		t.Errorf("unexpected exit status: %v", last[len(last)-1])
+	}
+	manager.Unload("oneshot")
+
+	// Stop 终止的插件不重启
+	writePlugin(t, dataDir, "daemon", "name: daemon\nversion: 1.0.0\nentrypoint: run.sh\nrestart: {policy: always}\n", "#!/bin/sh\nwhile read line; do :; done\n")
+	if err := manager.Load("daemon"); err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Unload("daemon")
+	if err := manager.Start("daemon"); err != nil {
+		t.Fatal(err)
+	}
+	if err := manager.Stop("daemon"); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if info := manager.plugins["daemon"].Info(); info.State != StateStopped || info.Restarts != 0 {
+		t.Errorf("expected stopped plugin not to restart, got %v", info)
+	}
+}
+
+func TestExitWithChildHoldingOutput(t *testing.T) {
+	dataDir := t.TempDir()
+	manager := NewManager(dataDir)
+	recorder := &statusRecorder{}
+	manager.OnStatus(recorder.record)
+
+	// 插件退出后，后台子进程仍持有 stdout
+	writePlugin(t, dataDir, "forker", "name: forker\nversion: 1.0.0\nentrypoint: run.sh\nrestart: {policy: never}\n", "#!/bin/sh\nsleep 60 &\nexit 3\n")
+	if err := manager.Load("forker"); err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Unload("forker")
+	if err := manager.Start("forker"); err != nil {
+		t.Fatal(err)
+	}
+
+	statuses := recorder.waitState(t, 0, StateExited)
+	if last := statuses[len(statuses)-1]; last.ExitCode != 3 {
+		t.Errorf("unexpected exit status: %v", last)
+	}
+	if info := manager.List()[0]; info.Enabled {
+		t.Errorf("expected exited plugin not to be running, got %v", info)
+	}
+}
+
+func TestRingBuffer(t *testing.T) {
+	b := newRingBuffer(16)
+	b.Write([]byte("first\nsecond\n"))
+	if lines := b.Lines(5); len(lines) != 2 || lines[1] != "second" {
+		t.Errorf("unexpected lines: %q", lines)
+	}
+
+	// 开头被截断的行不返回
+	b.Write([]byte("third\nfourth\n"))
+	if lines := b.Lines(5); len(lines) != 2 || lines[0] != "third" || lines[1] != "fourth" {
+		t.Errorf("unexpected lines after wrap: %q", lines)
+	}
+	if lines := b.Lines(1); len(lines) != 1 || lines[0] != "fourth" {
+		t.Errorf("unexpected last line: %q", lines)
+	}
+}
+
+func TestGracefulShutdown(t *testing.T) {
+	timeout := terminateTimeout
+	terminateTimeout = 100 * time.Millisecond
+	t.Cleanup(func() { terminateTimeout = timeout })
+
+	dataDir := t.TempDir()
+	manager := NewManager(dataDir)
+	recorder := &statusRecorder{}
+	manager.OnStatus(recorder.record)
+	state := filepath.Join(dataDir, "state")
+
+	cases := []struct {
+		name, script, shutdown string
+		exitCode               int32
+	}{
+		// 回复 shutdown_ack 并保存状态后退出
+		{"ack", "#!/bin/sh\nwhile read line; do case \"$line\" in *shutdown*) echo '{\"type\":\"shutdown_ack\"}'; echo saved > " + state + "; exit 0;; esac; done\n", ShutdownClean, 0},
+		// 忽略 shutdown 消息与 stdin，收到 SIGTERM 后退出
+		{"term", "#!/bin/sh\ntrap 'exit 7' TERM\nwhile true; do sleep 0.05; done\n", ShutdownTerminated, 7},
+		// 忽略 SIGTERM
+		{"stubborn", "#!/bin/sh\ntrap '' TERM\nwhile true; do sleep 0.05; done\n", ShutdownKilled, -1},
+		// 忽略 SIGTERM，子进程继承该设置并持有 stdout
+		{"orphan", "#!/bin/sh\ntrap '' TERM\nsleep 60\n", ShutdownKilled, -1},
+	}
+	for _, c := range cases {
+		writePlugin(t, dataDir, c.name, "name: "+c.name+"\nversion: 1.0.0\nentrypoint: run.sh\nshutdown_timeout: 1\n", c.script)
+		if err := manager.Load(c.name); err != nil {
+			t.Fatal(err)
+		}
+		if err := manager.Start(c.name); err != nil {
+			t.Fatal(err)
+		}
+		from := len(recorder.waitState(t, 0, StateRunning))
+		if err := manager.Unload(c.name); err != nil {
+			t.Fatal(err)
+		}
+		statuses := recorder.waitState(t, from, StateStopped)
+		if last := statuses[len(statuses)-1]; last.Shutdown != c.shutdown || last.ExitCode != c.exitCode {
+			t.Errorf("%s: unexpected stop status: %v", c.name, last)
+		}
+		recorder.mu.Lock()
+		recorder.statuses = nil
+		recorder.mu.Unlock()
+	}
+
+	if data, err := os.ReadFile(state); err != nil || string(data) != "saved\n" {
+		t.Errorf("expected plugin to save state before exit, got %q, %v", data, err)
+	}
+}
+
+func TestStopBlockedPlugin(t *testing.T) {
+	timeout := requestTimeout
+	requestTimeout = 200 * time.Millisecond
+	t.Cleanup(func() { requestTimeout = timeout })
+
+	dataDir := t.TempDir()
+	manager := NewManager(dataDir)
+
+	// 不读取 stdin 的插件，写满管道缓冲后写入阻塞
+	writePlugin(t, dataDir, "deaf", "name: deaf\nversion: 1.0.0\nentrypoint: run.sh\nshutdown_timeout: 1\n", "#!/bin/sh\nexec sleep 60\n")
+	if err := manager.Load("deaf"); err != nil {
+		t.Fatal(err)
+	}
+	if err := manager.Start("deaf"); err != nil {
+		t.Fatal(err)
+	}
+	p := manager.plugins["deaf"]
+
+	done := make(chan error, 1)
+	go func() {
+		done <- p.SendConfig(map[string]interface{}{"data": strings.Repeat("x", 1<<20)})
+	}()
+	// 写入阻塞期间仍可读取插件信息
+	time.Sleep(50 * time.Millisecond)
+	if state := p.State(); state != StateRunning {
+		t.Errorf("unexpected state: %s", state)
+	}
+	if err := <-done; err == nil {
+		t.Error("expected write to blocked plugin to time out")
+	}
+
+	start := time.Now()
+	if err := manager.Unload("deaf"); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("stop took %s", elapsed)
+	}
+}
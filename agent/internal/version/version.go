@@ -0,0 +1,4 @@
+package version
+
+// Version Agent 版本号，构建时可通过 -ldflags "-X .../agent/internal/version.Version=x.y.z" 覆盖
+var Version = "0.1.0"
@@ -0,0 +1,370 @@
+// Package pluginpkg 读取与解包插件安装包。安装包为 tar.gz 格式，根目录下包含描述插件的 plugin.yaml
+// 以及清单中 entrypoint 指定的可执行文件，平台上传时读取清单，Agent 校验后解包到插件目录。
+// 清单同时声明插件支持的平台、最低 Agent 版本、配置的 JSON Schema、上报的指标与所需的 Agent 能力
+package pluginpkg
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ManifestFile 安装包中插件清单的文件名
+const ManifestFile = "plugin.yaml"
+
+// MaxExtractSize 解包后文件的总大小上限
+const MaxExtractSize = 256 << 20
+
+var namePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+var (
+	ErrNoManifest    = errors.New("package does not contain " + ManifestFile)
+	ErrUnsafePath    = errors.New("package contains an unsafe path")
+	ErrPackageTooBig = errors.New("package content exceeds the size limit")
+	ErrIncompatible  = errors.New("plugin is not compatible with the agent")
+	ErrInvalidConfig = errors.New("invalid plugin config")
+)
+
+// Manifest 插件清单
+type Manifest struct {
+	Name        string `yaml:"name" json:"name"`
+	Version     string `yaml:"version" json:"version"`
+	Description string `yaml:"description" json:"description,omitempty"`
+	// Entrypoint 插件可执行文件相对插件目录的路径，默认与插件同名
+	Entrypoint string   `yaml:"entrypoint" json:"entrypoint"`
+	OS         []string `yaml:"os" json:"os,omitempty"`     // 支持的操作系统（GOOS），为空时不限制
+	Arch       []string `yaml:"arch" json:"arch,omitempty"` // 支持的架构（GOARCH），为空时不限制
+	// MinAgentVersion 运行插件所需的最低 Agent 版本，如 0.2.0
+	MinAgentVersion string  `yaml:"min_agent_version" json:"min_agent_version,omitempty"`
+	ConfigSchema    *Schema `yaml:"config_schema" json:"config_schema,omitempty"`
+	// Metrics 插件上报的指标名称，声明后 Agent 丢弃未声明的指标
+	Metrics []string `yaml:"metrics" json:"metrics,omitempty"`
+	// Capabilities 插件依赖的 Agent 能力，如 config、metrics
+	Capabilities []string `yaml:"capabilities" json:"capabilities,omitempty"`
+	Restart      Restart  `yaml:"restart" json:"restart"`
+	// ShutdownTimeout 收到 shutdown 消息后退出的宽限期（秒），超时后 Agent 发送 SIGTERM，默认 DefaultShutdownTimeout
+	ShutdownTimeout int `yaml:"shutdown_timeout" json:"shutdown_timeout"`
+	// Protocol 插件使用的 stdio 协议版本，大于 0 时 Agent 启动插件后等待握手；0 为不握手的旧协议
+	Protocol int `yaml:"protocol" json:"protocol,omitempty"`
+}
+
+const (
+	DefaultShutdownTimeout = 5
+	maxShutdownTimeout     = 300
+)
+
+// 插件进程退出后的重启策略
+const (
+	RestartAlways    = "always"     // 任何退出都重启
+	RestartOnFailure = "on-failure" // 仅非零退出码或被信号终止时重启
+	RestartNever     = "never"
+)
+
+// Restart 插件的重启策略，window 内重启次数达到 max_restarts 后不再重启
+type Restart struct {
+	Policy      string `yaml:"policy" json:"policy"`             // 默认 on-failure
+	MaxRestarts int    `yaml:"max_restarts" json:"max_restarts"` // 默认 5
+	Window      int    `yaml:"window" json:"window"`             // 统计重启次数的时间窗口（秒），默认 300
+}
+
+// ParseManifest 解析并校验插件清单
+func ParseManifest(data []byte) (*Manifest, error) {
+	var m Manifest
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", ManifestFile, err)
+	}
+	if !namePattern.MatchString(m.Name) {
+		return nil, fmt.Errorf("invalid plugin name %q", m.Name)
+	}
+	if m.Version == "" {
+		return nil, fmt.Errorf("plugin version is required")
+	}
+	if m.Entrypoint == "" {
+		m.Entrypoint = m.Name
+	}
+	if entry := cleanName(m.Entrypoint); entry == "" || !safePath(m.Entrypoint) {
+		return nil, fmt.Errorf("invalid entrypoint %q", m.Entrypoint)
+	}
+	switch m.Restart.Policy {
+	case "":
+		m.Restart.Policy = RestartOnFailure
+	case RestartAlways, RestartOnFailure, RestartNever:
+	default:
+		return nil, fmt.Errorf("invalid restart policy %q", m.Restart.Policy)
+	}
+	if m.Restart.MaxRestarts < 0 || m.Restart.Window < 0 {
+		return nil, fmt.Errorf("restart max_restarts and window must not be negative")
+	}
+	if m.Restart.MaxRestarts == 0 {
+		m.Restart.MaxRestarts = 5
+	}
+	if m.Restart.Window == 0 {
+		m.Restart.Window = 300
+	}
+	if m.ShutdownTimeout < 0 || m.ShutdownTimeout > maxShutdownTimeout {
+		return nil, fmt.Errorf("shutdown_timeout must be between 0 and %d seconds", maxShutdownTimeout)
+	}
+	if m.ShutdownTimeout == 0 {
+		m.ShutdownTimeout = DefaultShutdownTimeout
+	}
+	if m.Protocol < 0 {
+		return nil, fmt.Errorf("invalid protocol version %d", m.Protocol)
+	}
+	if m.MinAgentVersion != "" {
+		if _, err := parseVersion(m.MinAgentVersion); err != nil {
+			return nil, fmt.Errorf("invalid min_agent_version: %w", err)
+		}
+	}
+	if m.ConfigSchema != nil {
+		if m.ConfigSchema.Type == "" {
+			m.ConfigSchema.Type = "object"
+		}
+		if m.ConfigSchema.Type != "object" {
+			return nil, fmt.Errorf("config_schema must be an object schema")
+		}
+		if err := m.ConfigSchema.check("config_schema"); err != nil {
+			return nil, err
+		}
+	}
+	return &m, nil
+}
+
+// Compatible 检查插件是否支持指定的操作系统、架构与 Agent 版本，agentVersion 为空时不检查版本
+func (m *Manifest) Compatible(goos, goarch, agentVersion string) error {
+	if len(m.OS) > 0 && !contains(m.OS, goos) {
+		return fmt.Errorf("%w: supports os %v, agent runs %s", ErrIncompatible, m.OS, goos)
+	}
+	if len(m.Arch) > 0 && !contains(m.Arch, goarch) {
+		return fmt.Errorf("%w: supports arch %v, agent runs %s", ErrIncompatible, m.Arch, goarch)
+	}
+	if m.MinAgentVersion != "" && agentVersion != "" {
+		older, err := versionLess(agentVersion, m.MinAgentVersion)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrIncompatible, err)
+		}
+		if older {
+			return fmt.Errorf("%w: requires agent %s or later, agent is %s", ErrIncompatible, m.MinAgentVersion, agentVersion)
+		}
+	}
+	return nil
+}
+
+// MissingCapabilities 返回插件依赖但不在 supported 中的能力
+func (m *Manifest) MissingCapabilities(supported []string) []string {
+	var missing []string
+	for _, c := range m.Capabilities {
+		if !contains(supported, c) {
+			missing = append(missing, c)
+		}
+	}
+	return missing
+}
+
+// ValidateConfig 按配置 Schema 将字符串形式的配置转换为对应类型并校验，未声明 Schema 时原样返回
+func (m *Manifest) ValidateConfig(config map[string]string) (map[string]interface{}, error) {
+	if m.ConfigSchema == nil {
+		typed := make(map[string]interface{}, len(config))
+		for k, v := range config {
+			typed[k] = v
+		}
+		return typed, nil
+	}
+	typed, err := m.ConfigSchema.convert(config)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+	}
+	return typed, nil
+}
+
+// parseVersion 解析 x.y.z 形式的版本号，忽略 v 前缀与 - 或 + 之后的部分
+func parseVersion(v string) ([]int, error) {
+	core := strings.TrimPrefix(v, "v")
+	if i := strings.IndexAny(core, "-+"); i >= 0 {
+		core = core[:i]
+	}
+	parts := strings.Split(core, ".")
+	nums := make([]int, len(parts))
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid version %q", v)
+		}
+		nums[i] = n
+	}
+	return nums, nil
+}
+
+func versionLess(a, b string) (bool, error) {
+	va, err := parseVersion(a)
+	if err != nil {
+		return false, err
+	}
+	vb, err := parseVersion(b)
+	if err != nil {
+		return false, err
+	}
+	for i := 0; i < len(va) || i < len(vb); i++ {
+		var x, y int
+		if i < len(va) {
+			x = va[i]
+		}
+		if i < len(vb) {
+			y = vb[i]
+		}
+		if x != y {
+			return x < y, nil
+		}
+	}
+	return false, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// ReadManifest 从 tar.gz 安装包中读取插件清单
+func ReadManifest(r io.Reader) (*Manifest, error) {
+	tr, closeFn, err := openPackage(r)
+	if err != nil {
+		return nil, err
+	}
+	defer closeFn()
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil, ErrNoManifest
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read package: %w", err)
+		}
+		if hdr.Typeflag == tar.TypeReg && cleanName(hdr.Name) == ManifestFile {
+			data, err := io.ReadAll(io.LimitReader(tr, 1<<20))
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s: %w", ManifestFile, err)
+			}
+			return ParseManifest(data)
+		}
+	}
+}
+
+// Extract 将 tar.gz 安装包解包到 dir 并返回其中的插件清单。
+// 只接受普通文件与目录，拒绝绝对路径、包含 .. 的路径与链接
+func Extract(r io.Reader, dir string) (*Manifest, error) {
+	tr, closeFn, err := openPackage(r)
+	if err != nil {
+		return nil, err
+	}
+	defer closeFn()
+
+	var manifest *Manifest
+	var total int64
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read package: %w", err)
+		}
+
+		name := cleanName(hdr.Name)
+		if name == "" {
+			continue
+		}
+		if !safePath(hdr.Name) {
+			return nil, fmt.Errorf("%w: %s", ErrUnsafePath, hdr.Name)
+		}
+		target := filepath.Join(dir, filepath.FromSlash(name))
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return nil, err
+			}
+		case tar.TypeReg:
+			total += hdr.Size
+			if total > MaxExtractSize {
+				return nil, ErrPackageTooBig
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return nil, err
+			}
+			if err := writeFile(target, tr, hdr.FileInfo().Mode().Perm()); err != nil {
+				return nil, err
+			}
+			if name == ManifestFile {
+				data, err := os.ReadFile(target)
+				if err != nil {
+					return nil, err
+				}
+				if manifest, err = ParseManifest(data); err != nil {
+					return nil, err
+				}
+			}
+		default:
+			return nil, fmt.Errorf("%w: %s is not a regular file or directory", ErrUnsafePath, hdr.Name)
+		}
+	}
+
+	if manifest == nil {
+		return nil, ErrNoManifest
+	}
+	return manifest, nil
+}
+
+func openPackage(r io.Reader) (*tar.Reader, func() error, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("package is not a gzip archive: %w", err)
+	}
+	return tar.NewReader(gz), gz.Close, nil
+}
+
+func writeFile(target string, r io.Reader, perm os.FileMode) error {
+	f, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// cleanName 返回归一化的相对路径，根目录返回空字符串
+func cleanName(name string) string {
+	name = path.Clean(strings.TrimPrefix(name, "./"))
+	if name == "." {
+		return ""
+	}
+	return name
+}
+
+func safePath(name string) bool {
+	if strings.HasPrefix(name, "/") || strings.Contains(name, "\\") {
+		return false
+	}
+	for _, part := range strings.Split(name, "/") {
+		if part == ".." {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,160 @@
+package pluginpkg
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type entry struct {
+	name     string
+	body     string
+	typeflag byte
+}
+
+func buildPackage(t *testing.T, entries ...entry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Mode: 0755, Size: int64(len(e.body)), Typeflag: e.typeflag}
+		if e.typeflag == 0 {
+			hdr.Typeflag = tar.TypeReg
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			hdr.Size = 0
+			hdr.Linkname = e.body
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if hdr.Typeflag == tar.TypeReg {
+			tw.Write([]byte(e.body))
+		}
+	}
+	tw.Close()
+	gz.Close()
+	return buf.Bytes()
+}
+
+func TestParseManifest(t *testing.T) {
+	m, err := ParseManifest([]byte("name: disk-check\nversion: 1.2.0\ndescription: check disks\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Name != "disk-check" || m.Version != "1.2.0" || m.Description != "check disks" {
+		t.Errorf("unexpected manifest: %+v", m)
+	}
+
+	for _, data := range []string{"version: 1.0\n", "name: ../evil\nversion: 1.0\n", "name: ok\n"} {
+		if _, err := ParseManifest([]byte(data)); err == nil {
+			t.Errorf("expected %q to be rejected", data)
+		}
+	}
+}
+
+func TestExtract(t *testing.T) {
+	pkg := buildPackage(t,
+		entry{name: "./", typeflag: tar.TypeDir},
+		entry{name: "./plugin.yaml", body: "name: demo\nversion: 2.0.0\n"},
+		entry{name: "./demo", body: "#!/bin/sh\n"},
+		entry{name: "lib/helper.sh", body: "echo\n"},
+	)
+
+	m, err := ReadManifest(bytes.NewReader(pkg))
+	if err != nil || m.Name != "demo" || m.Version != "2.0.0" {
+		t.Fatalf("unexpected manifest: %+v, %v", m, err)
+	}
+
+	dir := t.TempDir()
+	if _, err := Extract(bytes.NewReader(pkg), dir); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(filepath.Join(dir, "demo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm()&0100 == 0 {
+		t.Errorf("expected executable mode, got %v", info.Mode())
+	}
+	if _, err := os.Stat(filepath.Join(dir, "lib", "helper.sh")); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestExtractRejectsUnsafeEntries(t *testing.T) {
+	manifest := entry{name: "plugin.yaml", body: "name: demo\nversion: 1.0.0\n"}
+	cases := map[string][]byte{
+		"parent":   buildPackage(t, manifest, entry{name: "../escape", body: "x"}),
+		"absolute": buildPackage(t, manifest, entry{name: "/etc/escape", body: "x"}),
+		"symlink":  buildPackage(t, manifest, entry{name: "link", body: "/etc/passwd", typeflag: tar.TypeSymlink}),
+	}
+	for name, pkg := range cases {
+		dir := filepath.Join(t.TempDir(), "out")
+		if _, err := Extract(bytes.NewReader(pkg), dir); !errors.Is(err, ErrUnsafePath) {
+			t.Errorf("%s: expected ErrUnsafePath, got %v", name, err)
+		}
+	}
+
+	if _, err := Extract(bytes.NewReader(buildPackage(t, entry{name: "demo", body: "x"})), t.TempDir()); !errors.Is(err, ErrNoManifest) {
+		t.Errorf("expected ErrNoManifest, got %v", err)
+	}
+}
+
+func TestManifestCompatible(t *testing.T) {
+	m, err := ParseManifest([]byte(`
+name: netstat
+version: 1.0.0
+os: [linux]
+arch: [amd64, arm64]
+min_agent_version: 0.2.0
+capabilities: [metrics]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Entrypoint != "netstat" {
+		t.Errorf("expected default entrypoint, got %q", m.Entrypoint)
+	}
+	if m.Restart != (Restart{Policy: RestartOnFailure, MaxRestarts: 5, Window: 300}) {
+		t.Errorf("expected default restart policy, got %+v", m.Restart)
+	}
+	if m.ShutdownTimeout != DefaultShutdownTimeout {
+		t.Errorf("expected default shutdown timeout, got %d", m.ShutdownTimeout)
+	}
+
+	if err := m.Compatible("linux", "arm64", "0.2.1"); err != nil {
+		t.Errorf("expected compatible, got %v", err)
+	}
+	if err := m.Compatible("linux", "amd64", ""); err != nil {
+		t.Errorf("expected version check to be skipped, got %v", err)
+	}
+	for _, c := range [][3]string{{"windows", "amd64", "1.0.0"}, {"linux", "386", "1.0.0"}, {"linux", "amd64", "0.1.9"}, {"linux", "amd64", "dev"}} {
+		if err := m.Compatible(c[0], c[1], c[2]); !errors.Is(err, ErrIncompatible) {
+			t.Errorf("%v: expected ErrIncompatible, got %v", c, err)
+		}
+	}
+
+	if missing := m.MissingCapabilities([]string{"config"}); len(missing) != 1 || missing[0] != "metrics" {
+		t.Errorf("unexpected missing capabilities: %v", missing)
+	}
+
+	for _, data := range []string{
+		"name: x\nversion: 1.0\nentrypoint: ../x\n",
+		"name: x\nversion: 1.0\nmin_agent_version: latest\n",
+		"name: x\nversion: 1.0\nconfig_schema: {type: string}\n",
+		"name: x\nversion: 1.0\nconfig_schema: {properties: {a: {type: date}}}\n",
+		"name: x\nversion: 1.0\nrestart: {policy: sometimes}\n",
+		"name: x\nversion: 1.0\nshutdown_timeout: 3600\n",
+		"name: x\nversion: 1.0\nprotocol: -1\n",
+	} {
+		if _, err := ParseManifest([]byte(data)); err == nil {
+			t.Errorf("expected %q to be rejected", data)
+		}
+	}
+}
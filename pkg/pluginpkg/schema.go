@@ -0,0 +1,244 @@
+package pluginpkg
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"unicode/utf8"
+)
+
+// Schema 插件配置的 JSON Schema，支持 type、enum、default、minimum、maximum、minLength、maxLength、
+// pattern、properties、required、additionalProperties 与 items 关键字
+type Schema struct {
+	Type                 string             `yaml:"type" json:"type,omitempty"` // string、integer、number、boolean、array、object
+	Description          string             `yaml:"description" json:"description,omitempty"`
+	Default              interface{}        `yaml:"default" json:"default,omitempty"`
+	Enum                 []interface{}      `yaml:"enum" json:"enum,omitempty"`
+	Minimum              *float64           `yaml:"minimum" json:"minimum,omitempty"`
+	Maximum              *float64           `yaml:"maximum" json:"maximum,omitempty"`
+	MinLength            *int               `yaml:"minLength" json:"minLength,omitempty"`
+	MaxLength            *int               `yaml:"maxLength" json:"maxLength,omitempty"`
+	Pattern              string             `yaml:"pattern" json:"pattern,omitempty"`
+	Properties           map[string]*Schema `yaml:"properties" json:"properties,omitempty"`
+	Required             []string           `yaml:"required" json:"required,omitempty"`
+	AdditionalProperties *bool              `yaml:"additionalProperties" json:"additionalProperties,omitempty"`
+	Items                *Schema            `yaml:"items" json:"items,omitempty"`
+}
+
+// check 校验 Schema 本身的合法性
+func (s *Schema) check(path string) error {
+	switch s.Type {
+	case "", "string", "integer", "number", "boolean", "array", "object":
+	default:
+		return fmt.Errorf("%s: unsupported type %q", path, s.Type)
+	}
+	if s.Pattern != "" {
+		if _, err := regexp.Compile(s.Pattern); err != nil {
+			return fmt.Errorf("%s: invalid pattern: %w", path, err)
+		}
+	}
+	for name, prop := range s.Properties {
+		if prop == nil {
+			return fmt.Errorf("%s.%s: empty schema", path, name)
+		}
+		if err := prop.check(path + "." + name); err != nil {
+			return err
+		}
+	}
+	if s.Items != nil {
+		if err := s.Items.check(path + "[]"); err != nil {
+			return err
+		}
+	}
+	if s.Default != nil {
+		if err := s.validate(path+".default", normalize(s.Default)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// convert 将字符串形式的配置按属性类型转换后校验，缺省的属性使用 default。
+// array 与 object 类型的值以 JSON 表示
+func (s *Schema) convert(config map[string]string) (map[string]interface{}, error) {
+	typed := make(map[string]interface{}, len(config))
+	for key, raw := range config {
+		prop := s.Properties[key]
+		if prop == nil {
+			typed[key] = raw
+			continue
+		}
+		value, err := prop.parse(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		typed[key] = value
+	}
+	for key, prop := range s.Properties {
+		if _, ok := typed[key]; !ok && prop.Default != nil {
+			typed[key] = normalize(prop.Default)
+		}
+	}
+
+	if err := s.validate("config", typed); err != nil {
+		return nil, err
+	}
+	return typed, nil
+}
+
+func (s *Schema) parse(raw string) (interface{}, error) {
+	switch s.Type {
+	case "integer":
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("expected integer, got %q", raw)
+		}
+		return n, nil
+	case "number":
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("expected number, got %q", raw)
+		}
+		return f, nil
+	case "boolean":
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("expected boolean, got %q", raw)
+		}
+		return b, nil
+	case "array", "object":
+		var v interface{}
+		if err := json.Unmarshal([]byte(raw), &v); err != nil {
+			return nil, fmt.Errorf("expected JSON %s: %v", s.Type, err)
+		}
+		return v, nil
+	default:
+		return raw, nil
+	}
+}
+
+// validate 校验已转换类型的值，数值统一为 float64 或 int64
+func (s *Schema) validate(path string, value interface{}) error {
+	switch s.Type {
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%s: expected string", path)
+		}
+		n := utf8.RuneCountInString(str)
+		if s.MinLength != nil && n < *s.MinLength {
+			return fmt.Errorf("%s: shorter than %d characters", path, *s.MinLength)
+		}
+		if s.MaxLength != nil && n > *s.MaxLength {
+			return fmt.Errorf("%s: longer than %d characters", path, *s.MaxLength)
+		}
+		if s.Pattern != "" && !regexp.MustCompile(s.Pattern).MatchString(str) {
+			return fmt.Errorf("%s: does not match pattern %s", path, s.Pattern)
+		}
+	case "integer", "number":
+		f, ok := toFloat(value)
+		if !ok || (s.Type == "integer" && f != float64(int64(f))) {
+			return fmt.Errorf("%s: expected %s", path, s.Type)
+		}
+		if s.Minimum != nil && f < *s.Minimum {
+			return fmt.Errorf("%s: must be >= %v", path, *s.Minimum)
+		}
+		if s.Maximum != nil && f > *s.Maximum {
+			return fmt.Errorf("%s: must be <= %v", path, *s.Maximum)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s: expected boolean", path)
+		}
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s: expected array", path)
+		}
+		if s.Items != nil {
+			for i, item := range items {
+				if err := s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item); err != nil {
+					return err
+				}
+			}
+		}
+	case "object":
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s: expected object", path)
+		}
+		for _, key := range s.Required {
+			if _, ok := obj[key]; !ok {
+				return fmt.Errorf("%s: missing required property %s", path, key)
+			}
+		}
+		for key, v := range obj {
+			prop := s.Properties[key]
+			if prop == nil {
+				if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+					return fmt.Errorf("%s: unknown property %s", path, key)
+				}
+				continue
+			}
+			if err := prop.validate(path+"."+key, v); err != nil {
+				return err
+			}
+		}
+	}
+
+	if len(s.Enum) > 0 {
+		for _, option := range s.Enum {
+			if equal(normalize(option), value) {
+				return nil
+			}
+		}
+		return fmt.Errorf("%s: must be one of %v", path, s.Enum)
+	}
+	return nil
+}
+
+// normalize 将 YAML 解码出的数值与映射转换为与 JSON 解码一致的类型
+func normalize(v interface{}) interface{} {
+	switch v := v.(type) {
+	case int:
+		return int64(v)
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			out[k] = normalize(item)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = normalize(item)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case int64:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case float64:
+		return v, true
+	default:
+		return 0, false
+	}
+}
+
+func equal(a, b interface{}) bool {
+	fa, okA := toFloat(a)
+	fb, okB := toFloat(b)
+	if okA && okB {
+		return fa == fb
+	}
+	return reflect.DeepEqual(a, b)
+}
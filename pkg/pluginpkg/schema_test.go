@@ -0,0 +1,75 @@
+package pluginpkg
+
+import (
+	"errors"
+	"testing"
+)
+
+const schemaManifest = `
+name: http-check
+version: 1.0.0
+config_schema:
+  type: object
+  required: [url]
+  additionalProperties: false
+  properties:
+    url:
+      type: string
+      pattern: '^https?://'
+    interval:
+      type: integer
+      minimum: 1
+      default: 30
+    method:
+      type: string
+      enum: [GET, HEAD]
+    verify_tls:
+      type: boolean
+    headers:
+      type: array
+      items: {type: string}
+`
+
+func TestValidateConfig(t *testing.T) {
+	m, err := ParseManifest([]byte(schemaManifest))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := m.ValidateConfig(map[string]string{
+		"url":        "https://example.com",
+		"method":     "HEAD",
+		"verify_tls": "false",
+		"headers":    `["Accept: */*"]`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config["interval"] != int64(30) || config["verify_tls"] != false || config["method"] != "HEAD" {
+		t.Errorf("unexpected config: %v", config)
+	}
+	if headers, ok := config["headers"].([]interface{}); !ok || len(headers) != 1 {
+		t.Errorf("unexpected headers: %v", config["headers"])
+	}
+
+	for name, bad := range map[string]map[string]string{
+		"missing required": {"interval": "10"},
+		"pattern":          {"url": "ftp://example.com"},
+		"not an integer":   {"url": "http://a", "interval": "1.5"},
+		"minimum":          {"url": "http://a", "interval": "0"},
+		"enum":             {"url": "http://a", "method": "POST"},
+		"unknown property": {"url": "http://a", "timeout": "5"},
+		"item type":        {"url": "http://a", "headers": `[1]`},
+	} {
+		if _, err := m.ValidateConfig(bad); !errors.Is(err, ErrInvalidConfig) {
+			t.Errorf("%s: expected ErrInvalidConfig, got %v", name, err)
+		}
+	}
+
+	// 未声明 Schema 时原样传递
+	plain, _ := ParseManifest([]byte("name: plain\nversion: 1.0.0\n"))
+	config, err = plain.ValidateConfig(map[string]string{"anything": "1"})
+	if err != nil || config["anything"] != "1" {
+		t.Errorf("unexpected config: %v, %v", config, err)
+	}
+}
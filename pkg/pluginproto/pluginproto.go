@@ -0,0 +1,295 @@
+// Package pluginproto 定义 Agent 与插件进程之间的 stdio 协议。双方每行写入一条 JSON 消息：
+// 插件启动后先发送 handshake 声明协议版本与所需能力，Agent 回复 handshake_ack 后开始交换消息。
+// 需要回复的请求带有 id，回复消息的 reply_to 与请求的 id 相同。
+// 没有握手的插件按版本 0 处理，只支持不带 id 的 metric、config、shutdown 与 shutdown_ack 消息
+package pluginproto
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+)
+
+// Version Agent 支持的最高协议版本
+const Version = 1
+
+// MaxMessageSize 单条消息的最大长度
+const MaxMessageSize = 1024 * 1024
+
+// 消息类型
+const (
+	TypeHandshake    = "handshake"     // 插件 → Agent，第一条消息
+	TypeHandshakeAck = "handshake_ack" // Agent → 插件
+	TypeConfig       = "config"        // Agent → 插件，插件回复 config_ack
+	TypeConfigAck    = "config_ack"
+	TypeShutdown     = "shutdown" // Agent → 插件，插件回复 shutdown_ack 后退出
+	TypeShutdownAck  = "shutdown_ack"
+	TypeHealth       = "health" // Agent 请求时插件以 reply_to 回复，插件也可主动上报
+	TypeMetric       = "metric"
+	TypeLog          = "log"
+	TypeEvent        = "event"
+	TypeError        = "error" // 任一方，回复请求时表示请求失败
+)
+
+// 健康状态
+const (
+	HealthHealthy   = "healthy"
+	HealthDegraded  = "degraded"
+	HealthUnhealthy = "unhealthy"
+)
+
+var (
+	ErrMessageTooLarge = fmt.Errorf("message exceeds %d bytes", MaxMessageSize)
+	ErrInvalidMessage  = errors.New("invalid message")
+)
+
+// Message 协议消息，data 的内容由 type 决定
+type Message struct {
+	Type    string          `json:"type"`
+	ID      string          `json:"id,omitempty"`
+	ReplyTo string          `json:"reply_to,omitempty"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+// NewMessage 创建消息，data 为 nil 时不带数据
+func NewMessage(typ string, data interface{}) (*Message, error) {
+	msg := &Message{Type: typ}
+	if data != nil {
+		raw, err := json.Marshal(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal %s message: %w", typ, err)
+		}
+		msg.Data = raw
+	}
+	return msg, nil
+}
+
+// Decode 将消息数据解码到 v，消息没有数据时不修改 v
+func (m *Message) Decode(v interface{}) error {
+	if len(m.Data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(m.Data, v); err != nil {
+		return fmt.Errorf("invalid %s message: %w", m.Type, err)
+	}
+	return nil
+}
+
+// Handshake 插件声明的协议版本、身份与所需的 Agent 能力
+type Handshake struct {
+	ProtocolVersion int      `json:"protocol_version"`
+	Name            string   `json:"name"`
+	Version         string   `json:"version,omitempty"`
+	Capabilities    []string `json:"capabilities,omitempty"`
+}
+
+// HandshakeAck Agent 选定的协议版本与提供的能力
+type HandshakeAck struct {
+	ProtocolVersion int      `json:"protocol_version"`
+	Capabilities    []string `json:"capabilities"`
+}
+
+// Ack config_ack 与 shutdown_ack 的数据，error 不为空表示插件拒绝了请求
+type Ack struct {
+	Error string `json:"error,omitempty"`
+}
+
+// Metric 指标数据点，timestamp 为 Unix 秒，为空时使用 Agent 收到的时间
+type Metric struct {
+	Name      string            `json:"name"`
+	Value     *float64          `json:"value"`
+	Timestamp float64           `json:"timestamp,omitempty"`
+	Labels    map[string]string `json:"labels,omitempty"`
+}
+
+// Log 插件日志
+type Log struct {
+	Level   string                 `json:"level"` // debug、info、warn、error
+	Message string                 `json:"message"`
+	Fields  map[string]interface{} `json:"fields,omitempty"`
+}
+
+// Event 插件产生的业务事件，如日志文件轮转
+type Event struct {
+	Name    string                 `json:"name"`
+	Message string                 `json:"message,omitempty"`
+	Fields  map[string]interface{} `json:"fields,omitempty"`
+}
+
+// Health 插件健康状态
+type Health struct {
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+// Error 错误消息的数据
+type Error struct {
+	Code    string `json:"code,omitempty"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	if e.Code == "" {
+		return e.Message
+	}
+	return e.Code + ": " + e.Message
+}
+
+// Reader 逐行读取消息
+type Reader struct {
+	scanner *bufio.Scanner
+}
+
+func NewReader(r io.Reader) *Reader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), MaxMessageSize)
+	return &Reader{scanner: scanner}
+}
+
+// Read 读取下一条消息，输入结束时返回 io.EOF。无法解析的行返回 ErrInvalidMessage，之后可以继续读取；
+// 其他错误（包括 ErrMessageTooLarge）之后不能继续读取
+func (r *Reader) Read() (*Message, error) {
+	if !r.scanner.Scan() {
+		err := r.scanner.Err()
+		if errors.Is(err, bufio.ErrTooLong) {
+			return nil, ErrMessageTooLarge
+		}
+		if err == nil {
+			err = io.EOF
+		}
+		return nil, err
+	}
+
+	var msg Message
+	if err := json.Unmarshal(r.scanner.Bytes(), &msg); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMessage, err)
+	}
+	if msg.Type == "" {
+		return nil, fmt.Errorf("%w: missing type", ErrInvalidMessage)
+	}
+	return &msg, nil
+}
+
+// Writer 逐行写入消息，可并发使用
+type Writer struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+func (w *Writer) Write(msg *Message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s message: %w", msg.Type, err)
+	}
+	if len(data) >= MaxMessageSize {
+		return ErrMessageTooLarge
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, err := w.w.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write %s message: %w", msg.Type, err)
+	}
+	return nil
+}
+
+// Conn 插件侧的协议连接，通常使用 NewConn(os.Stdin, os.Stdout)
+type Conn struct {
+	*Reader
+	*Writer
+	mu     sync.Mutex
+	nextID uint64
+}
+
+func NewConn(r io.Reader, w io.Writer) *Conn {
+	return &Conn{Reader: NewReader(r), Writer: NewWriter(w)}
+}
+
+// Handshake 发送握手并等待 Agent 的 handshake_ack，需在发送其他消息之前调用
+func (c *Conn) Handshake(h Handshake) (*HandshakeAck, error) {
+	if h.ProtocolVersion == 0 {
+		h.ProtocolVersion = Version
+	}
+	msg, err := NewMessage(TypeHandshake, h)
+	if err != nil {
+		return nil, err
+	}
+	msg.ID = c.newID()
+	if err := c.Write(msg); err != nil {
+		return nil, err
+	}
+
+	reply, err := c.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read handshake reply: %w", err)
+	}
+	if reply.ReplyTo != msg.ID {
+		return nil, fmt.Errorf("unexpected %s message before handshake reply", reply.Type)
+	}
+	if reply.Type == TypeError {
+		var e Error
+		if err := reply.Decode(&e); err != nil {
+			return nil, err
+		}
+		return nil, &e
+	}
+	var ack HandshakeAck
+	if err := reply.Decode(&ack); err != nil {
+		return nil, err
+	}
+	return &ack, nil
+}
+
+// Receive 在后台持续读取 Agent 发送的消息，跳过无法解析的行。stdin 关闭或读取出错时关闭返回的 channel，
+// 插件应随之退出
+func (c *Conn) Receive() <-chan *Message {
+	messages := make(chan *Message)
+	go func() {
+		defer close(messages)
+		for {
+			msg, err := c.Read()
+			if errors.Is(err, ErrInvalidMessage) {
+				continue
+			}
+			if err != nil {
+				return
+			}
+			messages <- msg
+		}
+	}()
+	return messages
+}
+
+// Send 发送不需要回复的消息
+func (c *Conn) Send(typ string, data interface{}) error {
+	msg, err := NewMessage(typ, data)
+	if err != nil {
+		return err
+	}
+	return c.Write(msg)
+}
+
+// Reply 回复带有 id 的请求
+func (c *Conn) Reply(req *Message, typ string, data interface{}) error {
+	msg, err := NewMessage(typ, data)
+	if err != nil {
+		return err
+	}
+	msg.ReplyTo = req.ID
+	return c.Write(msg)
+}
+
+func (c *Conn) newID() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.nextID++
+	return strconv.FormatUint(c.nextID, 10)
+}
@@ -0,0 +1,96 @@
+package pluginproto
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestReader(t *testing.T) {
+	input := `{"type":"metric","data":{"name":"up","value":1}}
+not json
+{"data":{}}
+{"type":"config_ack","reply_to":"7","data":{"error":"bad"}}
+`
+	r := NewReader(strings.NewReader(input))
+
+	msg, err := r.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var metric Metric
+	if err := msg.Decode(&metric); err != nil || metric.Name != "up" || metric.Value == nil || *metric.Value != 1 {
+		t.Errorf("unexpected metric: %+v, %v", metric, err)
+	}
+
+	// 无法解析的行不影响后续读取
+	for i := 0; i < 2; i++ {
+		if _, err := r.Read(); !errors.Is(err, ErrInvalidMessage) {
+			t.Errorf("expected ErrInvalidMessage, got %v", err)
+		}
+	}
+	msg, err = r.Read()
+	if err != nil || msg.Type != TypeConfigAck || msg.ReplyTo != "7" {
+		t.Fatalf("unexpected message: %+v, %v", msg, err)
+	}
+	if _, err := r.Read(); err != io.EOF {
+		t.Errorf("expected EOF, got %v", err)
+	}
+
+	long := `{"type":"log","data":"` + strings.Repeat("x", MaxMessageSize) + `"}` + "\n"
+	if _, err := NewReader(strings.NewReader(long)).Read(); !errors.Is(err, ErrMessageTooLarge) {
+		t.Errorf("expected ErrMessageTooLarge, got %v", err)
+	}
+}
+
+func TestConnHandshake(t *testing.T) {
+	agentIn, pluginOut := io.Pipe()
+	pluginIn, agentOut := io.Pipe()
+	conn := NewConn(pluginIn, pluginOut)
+
+	// 模拟 Agent：读取握手并回复，然后发送配置
+	go func() {
+		r, w := NewReader(agentIn), NewWriter(agentOut)
+		msg, err := r.Read()
+		if err != nil {
+			return
+		}
+		var h Handshake
+		msg.Decode(&h)
+		reply, _ := NewMessage(TypeHandshakeAck, HandshakeAck{ProtocolVersion: h.ProtocolVersion, Capabilities: []string{"metrics"}})
+		reply.ReplyTo = msg.ID
+		w.Write(reply)
+
+		config, _ := NewMessage(TypeConfig, map[string]int{"interval": 5})
+		config.ID = "1"
+		w.Write(config)
+		agentOut.Close()
+	}()
+
+	ack, err := conn.Handshake(Handshake{Name: "demo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ack.ProtocolVersion != Version || len(ack.Capabilities) != 1 {
+		t.Errorf("unexpected handshake ack: %+v", ack)
+	}
+
+	var received []*Message
+	for msg := range conn.Receive() {
+		received = append(received, msg)
+	}
+	if len(received) != 1 || received[0].Type != TypeConfig || received[0].ID != "1" {
+		t.Errorf("unexpected messages: %v", received)
+	}
+}
+
+func TestConnHandshakeRejected(t *testing.T) {
+	conn := NewConn(strings.NewReader(`{"type":"error","reply_to":"1","data":{"code":"handshake_failed","message":"unsupported capabilities gpu"}}`+"\n"), io.Discard)
+
+	_, err := conn.Handshake(Handshake{Name: "demo", Capabilities: []string{"gpu"}})
+	var protoErr *Error
+	if !errors.As(err, &protoErr) || protoErr.Code != "handshake_failed" {
+		t.Errorf("expected handshake error, got %v", err)
+	}
+}
@@ -0,0 +1,66 @@
+package tasksign
+
+import (
+	"crypto/ed25519"
+	"encoding/binary"
+	"sort"
+	"strconv"
+	"time"
+
+	pb "github.com/yourusername/agent-platform/proto"
+)
+
+// pluginMessagePrefix 插件安装请求签名内容的版本前缀，签名字段变化时需要更新
+const pluginMessagePrefix = "agent-platform-plugin-v1"
+
+// SignPlugin 使用任务签名私钥对插件安装请求签名，签名只对请求中的 agent_id 有效。
+// 签名包含安装包的 SHA-256 与大小，Agent 在接收安装包前验签，解包前校验摘要
+func SignPlugin(key ed25519.PrivateKey, req *pb.InstallPluginRequest, expiresAt time.Time) {
+	req.ExpiresAt = expiresAt.Unix()
+	req.Signature = ed25519.Sign(key, PluginMessage(req.AgentId, req))
+}
+
+// VerifyPlugin 校验插件安装请求的签名与过期时间，agentID 为验签方自身的 ID
+func VerifyPlugin(pub ed25519.PublicKey, agentID string, req *pb.InstallPluginRequest, now time.Time) error {
+	if len(req.Signature) == 0 {
+		return ErrUnsigned
+	}
+	if !ed25519.Verify(pub, PluginMessage(agentID, req), req.Signature) {
+		return ErrInvalidSignature
+	}
+	if now.Unix() > req.ExpiresAt {
+		return ErrExpired
+	}
+	return nil
+}
+
+// PluginMessage 返回发给 agentID 的插件安装请求的待签名内容，编码方式与 Message 相同
+func PluginMessage(agentID string, req *pb.InstallPluginRequest) []byte {
+	var buf []byte
+	write := func(s string) {
+		buf = binary.BigEndian.AppendUint32(buf, uint32(len(s)))
+		buf = append(buf, s...)
+	}
+
+	write(pluginMessagePrefix)
+	write(agentID)
+	write(req.PluginName)
+	write(req.Version)
+	write(req.TransferId)
+	write(req.Sha256)
+	write(strconv.FormatInt(req.Size, 10))
+
+	keys := make([]string, 0, len(req.Config))
+	for k := range req.Config {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	write(strconv.Itoa(len(keys)))
+	for _, k := range keys {
+		write(k)
+		write(req.Config[k])
+	}
+
+	write(strconv.FormatInt(req.ExpiresAt, 10))
+	return buf
+}
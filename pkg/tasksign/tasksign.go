@@ -0,0 +1,142 @@
+// Package tasksign 对平台下发的任务签名与验签。平台使用 Ed25519 私钥对目标 Agent ID、任务 ID、类型、脚本、
+// 超时时间、环境变量、运行身份与资源限制、参数、可执行文件以及过期时间签名，Agent 使用配置中固定的公钥
+// 与自身 ID 验签，防止伪造、篡改任务或将发给其他 Agent 的任务重放过来
+package tasksign
+
+import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	pb "github.com/yourusername/agent-platform/proto"
+)
+
+// messagePrefix 签名内容的版本前缀，签名字段变化时需要更新
+const messagePrefix = "agent-platform-task-v4"
+
+var (
+	ErrUnsigned         = errors.New("task is not signed")
+	ErrExpired          = errors.New("task signature has expired")
+	ErrInvalidSignature = errors.New("invalid task signature")
+)
+
+// Sign 设置任务的过期时间并签名，签名只对 agentID 有效
+func Sign(key ed25519.PrivateKey, agentID string, req *pb.TaskRequest, expiresAt time.Time) {
+	req.ExpiresAt = expiresAt.Unix()
+	req.Signature = ed25519.Sign(key, Message(agentID, req))
+}
+
+// Verify 校验任务签名与过期时间，agentID 为验签方自身的 ID，发给其他 Agent 的任务返回 ErrInvalidSignature
+func Verify(pub ed25519.PublicKey, agentID string, req *pb.TaskRequest, now time.Time) error {
+	if len(req.Signature) == 0 {
+		return ErrUnsigned
+	}
+	if !ed25519.Verify(pub, Message(agentID, req), req.Signature) {
+		return ErrInvalidSignature
+	}
+	if now.Unix() > req.ExpiresAt {
+		return ErrExpired
+	}
+	return nil
+}
+
+// Message 返回发给 agentID 的任务的待签名内容：各字段依次以 4 字节长度前缀编码，环境变量按键排序
+func Message(agentID string, req *pb.TaskRequest) []byte {
+	var buf []byte
+	write := func(s string) {
+		buf = binary.BigEndian.AppendUint32(buf, uint32(len(s)))
+		buf = append(buf, s...)
+	}
+
+	write(messagePrefix)
+	write(agentID)
+	write(req.TaskId)
+	write(req.Type.String())
+	write(req.Script)
+	write(strconv.Itoa(int(req.Timeout)))
+
+	keys := make([]string, 0, len(req.Env))
+	for k := range req.Env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	write(strconv.Itoa(len(keys)))
+	for _, k := range keys {
+		write(k)
+		write(req.Env[k])
+	}
+
+	write(req.RunAsUser)
+	write(req.RunAsGroup)
+	write(req.WorkDir)
+	write(req.Umask)
+	limits := req.GetLimits()
+	for _, v := range []uint64{limits.GetCpuSeconds(), limits.GetAddressSpace(), limits.GetOpenFiles(), limits.GetProcesses()} {
+		write(strconv.FormatUint(v, 10))
+	}
+
+	write(strconv.Itoa(len(req.Args)))
+	for _, arg := range req.Args {
+		write(arg)
+	}
+	write(string(req.File))
+	write(req.FileName)
+	write(req.FileSha256)
+
+	write(strconv.FormatInt(req.ExpiresAt, 10))
+	return buf
+}
+
+// LoadPrivateKey 加载 PKCS#8 PEM 格式的 Ed25519 私钥，任务签名与审计检查点签名共用。
+// 可用 openssl genpkey -algorithm ed25519 -out key.pem 生成
+func LoadPrivateKey(path string) (ed25519.PrivateKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+	edKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s is not an Ed25519 private key", path)
+	}
+	return edKey, nil
+}
+
+// LoadPublicKey 加载 PKIX PEM 格式的 Ed25519 公钥
+func LoadPublicKey(path string) (ed25519.PublicKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key: %w", err)
+	}
+	edKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%s is not an Ed25519 public key", path)
+	}
+	return edKey, nil
+}
+
+func readPEM(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found in %s", path)
+	}
+	return block, nil
+}
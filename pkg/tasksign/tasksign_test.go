@@ -0,0 +1,138 @@
+package tasksign
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	pb "github.com/yourusername/agent-platform/proto"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	pub, key, _ := ed25519.GenerateKey(rand.Reader)
+	now := time.Now()
+
+	req := &pb.TaskRequest{
+		TaskId:    "task-1",
+		Type:      pb.TaskType_TASK_TYPE_SHELL,
+		Script:    "echo $GREETING",
+		Timeout:   30,
+		Env:       map[string]string{"GREETING": "hello", "LANG": "C"},
+		RunAsUser: "nobody",
+		Limits:    &pb.ResourceLimits{CpuSeconds: 10},
+		Args:      []string{"--verbose"},
+	}
+	Sign(key, "agent-1", req, now.Add(time.Minute))
+	if err := Verify(pub, "agent-1", req, now); err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+
+	if err := Verify(pub, "agent-1", req, now.Add(2*time.Minute)); !errors.Is(err, ErrExpired) {
+		t.Errorf("expected ErrExpired, got %v", err)
+	}
+
+	// 篡改任一签名字段都会导致验签失败
+	tampered := []func(r *pb.TaskRequest){
+		func(r *pb.TaskRequest) { r.Script = "rm -rf /" },
+		func(r *pb.TaskRequest) { r.Type = pb.TaskType_TASK_TYPE_PYTHON },
+		func(r *pb.TaskRequest) { r.Env["GREETING"] = "bye" },
+		func(r *pb.TaskRequest) { r.ExpiresAt += 3600 },
+		func(r *pb.TaskRequest) { r.TaskId = "task-2" },
+		func(r *pb.TaskRequest) { r.RunAsUser = "root" },
+		func(r *pb.TaskRequest) { r.Limits = &pb.ResourceLimits{CpuSeconds: 1000} },
+		func(r *pb.TaskRequest) { r.Args = append(r.Args, "--force") },
+		func(r *pb.TaskRequest) { r.File = []byte("#!/bin/sh\nreboot\n") },
+	}
+	for i, tamper := range tampered {
+		copied := &pb.TaskRequest{
+			TaskId: req.TaskId, Type: req.Type, Script: req.Script, Timeout: req.Timeout,
+			Env: map[string]string{"GREETING": "hello", "LANG": "C"}, ExpiresAt: req.ExpiresAt, Signature: req.Signature,
+			RunAsUser: req.RunAsUser, Limits: &pb.ResourceLimits{CpuSeconds: 10}, Args: []string{"--verbose"},
+		}
+		tamper(copied)
+		if err := Verify(pub, "agent-1", copied, now); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("tamper %d: expected ErrInvalidSignature, got %v", i, err)
+		}
+	}
+
+	// 签名只对目标 Agent 有效
+	if err := Verify(pub, "agent-2", req, now); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("expected ErrInvalidSignature for another agent, got %v", err)
+	}
+
+	if err := Verify(pub, "agent-1", &pb.TaskRequest{TaskId: "task-3"}, now); !errors.Is(err, ErrUnsigned) {
+		t.Errorf("expected ErrUnsigned, got %v", err)
+	}
+}
+
+func TestSignPlugin(t *testing.T) {
+	pub, key, _ := ed25519.GenerateKey(rand.Reader)
+	now := time.Now()
+
+	req := &pb.InstallPluginRequest{
+		AgentId:    "agent-1",
+		PluginName: "cpu",
+		Version:    "1.1.0",
+		Sha256:     "8f434346648f6b96df89dda901c5176b10a6d83961dd3c1ac88b59b2dc327aa4",
+		Size:       1024,
+		TransferId: "plugin-1",
+		Config:     map[string]string{"interval": "10"},
+	}
+	SignPlugin(key, req, now.Add(time.Minute))
+	if err := VerifyPlugin(pub, "agent-1", req, now); err != nil {
+		t.Fatalf("VerifyPlugin failed: %v", err)
+	}
+	if err := VerifyPlugin(pub, "agent-2", req, now); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("expected ErrInvalidSignature for another agent, got %v", err)
+	}
+	if err := VerifyPlugin(pub, "agent-1", req, now.Add(2*time.Minute)); !errors.Is(err, ErrExpired) {
+		t.Errorf("expected ErrExpired, got %v", err)
+	}
+
+	// 替换安装包或修改配置都会导致验签失败
+	req.Sha256 = "0000000000000000000000000000000000000000000000000000000000000000"
+	if err := VerifyPlugin(pub, "agent-1", req, now); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("expected ErrInvalidSignature for replaced package, got %v", err)
+	}
+	req.Signature = nil
+	if err := VerifyPlugin(pub, "agent-1", req, now); !errors.Is(err, ErrUnsigned) {
+		t.Errorf("expected ErrUnsigned, got %v", err)
+	}
+}
+
+func TestLoadKeys(t *testing.T) {
+	pub, key, _ := ed25519.GenerateKey(rand.Reader)
+	dir := t.TempDir()
+
+	keyDER, _ := x509.MarshalPKCS8PrivateKey(key)
+	pubDER, _ := x509.MarshalPKIXPublicKey(pub)
+	keyFile := filepath.Join(dir, "task.key")
+	pubFile := filepath.Join(dir, "task.pub")
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0600)
+	os.WriteFile(pubFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}), 0644)
+
+	loadedKey, err := LoadPrivateKey(keyFile)
+	if err != nil {
+		t.Fatalf("LoadPrivateKey failed: %v", err)
+	}
+	loadedPub, err := LoadPublicKey(pubFile)
+	if err != nil {
+		t.Fatalf("LoadPublicKey failed: %v", err)
+	}
+	if !loadedKey.Equal(key) || !loadedPub.Equal(pub) {
+		t.Fatal("loaded keys do not match")
+	}
+
+	if _, err := LoadPublicKey(keyFile); err == nil {
+		t.Error("expected error loading a private key as public key")
+	}
+	if _, err := LoadPrivateKey(filepath.Join(dir, "missing.key")); err == nil {
+		t.Error("expected error loading a missing key")
+	}
+}
@@ -0,0 +1,212 @@
+// Package tlsutil 提供 Agent 与管理平台共用的 mTLS 配置，证书文件更新后自动重新加载
+package tlsutil
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// reloadCheckInterval 检查证书文件是否变化的最小间隔
+const reloadCheckInterval = 10 * time.Second
+
+// Reloader 从磁盘加载证书、私钥与 CA，文件变化后在下一次握手时自动重新加载，无需重启进程。
+// 重新加载失败时继续使用旧的证书
+type Reloader struct {
+	certFile string
+	keyFile  string
+	caFile   string
+
+	mu            sync.Mutex
+	cert          *tls.Certificate
+	pool          *x509.CertPool
+	modTimes      [3]time.Time
+	lastCheck     time.Time
+	checkInterval time.Duration
+}
+
+// NewReloader 加载证书。certFile/keyFile 与 caFile 均可为空，分别表示不提供证书、不校验对端证书
+func NewReloader(certFile, keyFile, caFile string) (*Reloader, error) {
+	if (certFile == "") != (keyFile == "") {
+		return nil, errors.New("cert_file and key_file must be set together")
+	}
+
+	r := &Reloader{
+		certFile:      certFile,
+		keyFile:       keyFile,
+		caFile:        caFile,
+		checkInterval: reloadCheckInterval,
+	}
+	modTimes, err := r.stat()
+	if err != nil {
+		return nil, err
+	}
+	if err := r.load(modTimes); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Certificate 返回当前证书，未配置证书时返回 nil
+func (r *Reloader) Certificate() *tls.Certificate {
+	r.maybeReload()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.cert
+}
+
+// CAPool 返回当前 CA，未配置 CA 时返回 nil
+func (r *Reloader) CAPool() *x509.CertPool {
+	r.maybeReload()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.pool
+}
+
+func (r *Reloader) maybeReload() {
+	r.mu.Lock()
+	if time.Since(r.lastCheck) < r.checkInterval {
+		r.mu.Unlock()
+		return
+	}
+	r.lastCheck = time.Now()
+	current := r.modTimes
+	r.mu.Unlock()
+
+	modTimes, err := r.stat()
+	if err != nil {
+		log.Printf("Failed to check certificate files: %v", err)
+		return
+	}
+	if modTimes == current {
+		return
+	}
+
+	if err := r.load(modTimes); err != nil {
+		log.Printf("Failed to reload certificates, keeping previous ones: %v", err)
+		return
+	}
+	log.Printf("Certificates reloaded")
+}
+
+func (r *Reloader) stat() ([3]time.Time, error) {
+	var modTimes [3]time.Time
+	for i, path := range []string{r.certFile, r.keyFile, r.caFile} {
+		if path == "" {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return modTimes, err
+		}
+		modTimes[i] = info.ModTime()
+	}
+	return modTimes, nil
+}
+
+func (r *Reloader) load(modTimes [3]time.Time) error {
+	var cert *tls.Certificate
+	if r.certFile != "" {
+		pair, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+		if err != nil {
+			return fmt.Errorf("failed to load key pair: %w", err)
+		}
+		cert = &pair
+	}
+
+	var pool *x509.CertPool
+	if r.caFile != "" {
+		data, err := os.ReadFile(r.caFile)
+		if err != nil {
+			return fmt.Errorf("failed to read CA file: %w", err)
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return fmt.Errorf("no certificates found in %s", r.caFile)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cert = cert
+	r.pool = pool
+	r.modTimes = modTimes
+	return nil
+}
+
+// ServerConfig 返回服务端 TLS 配置。配置了 CA 时要求并校验客户端证书（mTLS）
+func ServerConfig(r *Reloader) *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		// 每次握手使用最新的证书与 CA
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			cert := r.Certificate()
+			if cert == nil {
+				return nil, errors.New("server certificate not configured")
+			}
+
+			cfg := &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*cert},
+				NextProtos:   []string{"h2"},
+				ClientAuth:   tls.NoClientCert,
+			}
+			if pool := r.CAPool(); pool != nil {
+				cfg.ClientCAs = pool
+				cfg.ClientAuth = tls.RequireAndVerifyClientCert
+			}
+			return cfg, nil
+		},
+	}
+}
+
+// ClientConfig 返回客户端 TLS 配置。serverName 为空时使用连接地址中的主机名校验服务端证书；
+// 未配置 CA 时使用系统根证书
+func ClientConfig(r *Reloader, serverName string) *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: serverName,
+		// 服务端证书由 VerifyConnection 使用最新的 CA 校验，以支持 CA 热更新
+		InsecureSkipVerify: true,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			if cert := r.Certificate(); cert != nil {
+				return cert, nil
+			}
+			return &tls.Certificate{}, nil
+		},
+		VerifyConnection: func(cs tls.ConnectionState) error {
+			if len(cs.PeerCertificates) == 0 {
+				return errors.New("server presented no certificate")
+			}
+
+			opts := x509.VerifyOptions{
+				Roots:         r.CAPool(),
+				DNSName:       cs.ServerName,
+				Intermediates: x509.NewCertPool(),
+			}
+			for _, cert := range cs.PeerCertificates[1:] {
+				opts.Intermediates.AddCert(cert)
+			}
+			_, err := cs.PeerCertificates[0].Verify(opts)
+			return err
+		},
+	}
+}
+
+// Identities 返回证书中可作为身份的名称：CN 与 DNS/URI 类型的 SAN
+func Identities(cert *x509.Certificate) []string {
+	var ids []string
+	if cert.Subject.CommonName != "" {
+		ids = append(ids, cert.Subject.CommonName)
+	}
+	ids = append(ids, cert.DNSNames...)
+	for _, uri := range cert.URIs {
+		ids = append(ids, uri.String())
+	}
+	return ids
+}
@@ -0,0 +1,202 @@
+package tlsutil
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCA(t *testing.T) *testCA {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCA{cert: cert, key: key}
+}
+
+// issue 签发证书并写入 dir，返回证书与私钥路径
+func (ca *testCA) issue(t *testing.T, dir, name string, serial int64, dnsNames ...string) (string, string) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     dnsNames,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+
+	certFile := filepath.Join(dir, name+".crt")
+	keyFile := filepath.Join(dir, name+".key")
+	writePEM(t, certFile, "CERTIFICATE", der)
+	writePEM(t, keyFile, "EC PRIVATE KEY", keyDER)
+	return certFile, keyFile
+}
+
+func (ca *testCA) write(t *testing.T, dir string) string {
+	path := filepath.Join(dir, "ca.crt")
+	writePEM(t, path, "CERTIFICATE", ca.cert.Raw)
+	return path
+}
+
+func writePEM(t *testing.T, path, blockType string, der []byte) {
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// handshake 在本地 TCP 连接上完成一次 TLS 握手，返回服务端看到的客户端证书 CN
+func handshake(t *testing.T, serverCfg, clientCfg *tls.Config) (string, error) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lis.Close()
+
+	errCh := make(chan error, 1)
+	go func() {
+		conn, err := net.Dial("tcp", lis.Addr().String())
+		if err != nil {
+			errCh <- err
+			return
+		}
+		defer conn.Close()
+		client := tls.Client(conn, clientCfg)
+		err = client.Handshake()
+		if err == nil {
+			// TLS 1.3 中服务端在客户端完成握手后才校验客户端证书，读一次以获知结果
+			client.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+			client.Read(make([]byte, 1))
+		}
+		errCh <- err
+	}()
+
+	conn, err := lis.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	server := tls.Server(conn, serverCfg)
+	if err := server.Handshake(); err != nil {
+		<-errCh
+		return "", err
+	}
+	if err := <-errCh; err != nil {
+		return "", err
+	}
+
+	peers := server.ConnectionState().PeerCertificates
+	if len(peers) == 0 {
+		return "", nil
+	}
+	return peers[0].Subject.CommonName, nil
+}
+
+func TestMutualTLSAndReload(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	caFile := ca.write(t, dir)
+	serverCert, serverKey := ca.issue(t, dir, "platform", 2, "localhost")
+	agentCert, agentKey := ca.issue(t, dir, "agent-1", 3)
+
+	serverReloader, err := NewReloader(serverCert, serverKey, caFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	agentReloader, err := NewReloader(agentCert, agentKey, caFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	agentReloader.checkInterval = 0
+
+	serverCfg := ServerConfig(serverReloader)
+	clientCfg := ClientConfig(agentReloader, "localhost")
+
+	cn, err := handshake(t, serverCfg, clientCfg)
+	if err != nil {
+		t.Fatalf("handshake failed: %v", err)
+	}
+	if cn != "agent-1" {
+		t.Errorf("expected client identity agent-1, got %q", cn)
+	}
+
+	// 服务端名称不匹配时拒绝连接
+	if _, err := handshake(t, serverCfg, ClientConfig(agentReloader, "other-host")); err == nil {
+		t.Error("expected handshake with wrong server name to fail")
+	}
+
+	// 没有客户端证书时拒绝连接
+	anonymous, _ := NewReloader("", "", caFile)
+	if _, err := handshake(t, serverCfg, ClientConfig(anonymous, "localhost")); err == nil {
+		t.Error("expected handshake without client certificate to fail")
+	}
+
+	// 证书文件更新后自动重新加载
+	newCert, newKey := ca.issue(t, dir, "agent-2", 4)
+	os.Rename(newCert, agentCert)
+	os.Rename(newKey, agentKey)
+	future := time.Now().Add(time.Minute)
+	os.Chtimes(agentCert, future, future)
+
+	cn, err = handshake(t, serverCfg, clientCfg)
+	if err != nil {
+		t.Fatalf("handshake after reload failed: %v", err)
+	}
+	if cn != "agent-2" {
+		t.Errorf("expected reloaded identity agent-2, got %q", cn)
+	}
+}
+
+func TestNewReloaderValidation(t *testing.T) {
+	if _, err := NewReloader("cert.pem", "", ""); err == nil {
+		t.Error("expected error when key_file is missing")
+	}
+	if _, err := NewReloader("", "", filepath.Join(t.TempDir(), "missing.crt")); err == nil {
+		t.Error("expected error for missing CA file")
+	}
+}
+
+func TestIdentities(t *testing.T) {
+	cert := &x509.Certificate{
+		Subject:  pkix.Name{CommonName: "agent-1"},
+		DNSNames: []string{"agent-1.example.com"},
+	}
+	ids := Identities(cert)
+	if len(ids) != 2 || ids[0] != "agent-1" || ids[1] != "agent-1.example.com" {
+		t.Errorf("unexpected identities: %v", ids)
+	}
+}
@@ -6,12 +6,21 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
+	"github.com/yourusername/agent-platform/pkg/tasksign"
+	"github.com/yourusername/agent-platform/pkg/tlsutil"
 	"github.com/yourusername/agent-platform/platform/internal/api"
+	"github.com/yourusername/agent-platform/platform/internal/audit"
 	"github.com/yourusername/agent-platform/platform/internal/config"
 	"github.com/yourusername/agent-platform/platform/internal/database"
 	grpcserver "github.com/yourusername/agent-platform/platform/internal/grpc"
+	"github.com/yourusername/agent-platform/platform/internal/models"
 	"github.com/yourusername/agent-platform/platform/internal/monitor"
+	"github.com/yourusername/agent-platform/platform/internal/server"
+	"github.com/yourusername/agent-platform/platform/internal/service"
+	"github.com/yourusername/agent-platform/platform/internal/session"
+	"google.golang.org/grpc/credentials"
 )
 
 func main() {
@@ -42,8 +51,55 @@ func main() {
 	// 启动监控
 	monitor.StartMonitoring()
 
+	// Agent 会话注册表，gRPC 服务与 REST API 共享
+	sessions := session.NewRegistry()
+
+	// 脚本策略
+	policyGroups := make(map[string]service.PolicyRules, len(cfg.Policy.Groups))
+	for group, rules := range cfg.Policy.Groups {
+		policyGroups[group] = policyRules(rules)
+	}
+	policyService, err := service.NewPolicyService(db, policyRules(cfg.Policy.Default), policyGroups)
+	if err != nil {
+		log.Fatalf("Failed to load script policy: %v", err)
+	}
+
+	// 启动任务分发器
+	dispatcher := service.NewTaskDispatcher(db, sessions)
+	if cfg.TaskSign.KeyFile != "" {
+		key, err := tasksign.LoadPrivateKey(cfg.TaskSign.KeyFile)
+		if err != nil {
+			log.Fatalf("Failed to load task signing key: %v", err)
+		}
+		dispatcher.SetSigningKey(key, time.Duration(cfg.TaskSign.TTL)*time.Second)
+	}
+	dispatcher.Start()
+	taskLogs := service.NewTaskLogService(db)
+	jobService := service.NewJobService(db, dispatcher, policyService)
+	jobService.Resume()
+	metricService := service.NewMetricService(db)
+
+	// 启动 Agent 存活检测
+	agentService := service.NewAgentService(db, sessions,
+		time.Duration(cfg.Heartbeat.Interval)*time.Second, cfg.Heartbeat.MaxMissed)
+	agentService.Start()
+	enrollmentService := service.NewEnrollmentService(db, sessions, cfg.Enrollment.AllowUnenrolled)
+	authService := service.NewAuthService(db, time.Duration(cfg.Auth.SessionTTL)*time.Second)
+	if err := authService.EnsureAdmin(cfg.Auth.AdminUsername, cfg.Auth.AdminPassword, cfg.Auth.AdminPasswordFile); err != nil {
+		log.Fatalf("Failed to create initial admin user: %v", err)
+	}
+
 	// 启动 gRPC 服务器
-	grpcServer := grpcserver.NewServer(cfg.Server.GRPCPort, db)
+	handler := grpcserver.NewAgentServiceHandler(db, sessions, dispatcher, taskLogs, metricService, agentService, enrollmentService)
+	var creds credentials.TransportCredentials
+	if tlsCfg := cfg.Server.TLS; tlsCfg.CertFile != "" {
+		reloader, err := tlsutil.NewReloader(tlsCfg.CertFile, tlsCfg.KeyFile, tlsCfg.ClientCAFile)
+		if err != nil {
+			log.Fatalf("Failed to load TLS certificates: %v", err)
+		}
+		creds = credentials.NewTLS(tlsutil.ServerConfig(reloader))
+	}
+	grpcServer := server.NewServer(cfg.Server.GRPCPort, handler, creds)
 	go func() {
 		log.Printf("Starting gRPC server on %s", cfg.Server.GRPCPort)
 		if err := grpcServer.Start(); err != nil {
@@ -51,8 +107,31 @@ func main() {
 		}
 	}()
 
+	// 审计日志，配置签名私钥时定期生成签名检查点
+	auditService := audit.NewService(db)
+	if err := auditService.InitChain(); err != nil {
+		log.Fatalf("Failed to initialize audit chain: %v", err)
+	}
+	if cfg.Audit.SigningKeyFile != "" {
+		key, err := tasksign.LoadPrivateKey(cfg.Audit.SigningKeyFile)
+		if err != nil {
+			log.Fatalf("Failed to load audit signing key: %v", err)
+		}
+		auditService.StartCheckpoints(key, time.Duration(cfg.Audit.CheckpointInterval)*time.Second)
+	}
+	// 被平台或 Agent 策略拒绝的任务写入审计日志，审批人驳回已由请求审计记录
+	dispatcher.OnTaskFinished(func(task *models.Task) {
+		if task.Status != service.TaskStatusRejected || !task.PolicyRejected {
+			return
+		}
+		if err := auditService.LogTaskRejected(task); err != nil {
+			log.Printf("Failed to write audit log for rejected task %s: %v", task.TaskID, err)
+		}
+	})
+
 	// 启动 HTTP API 服务器
-	router := api.SetupRouter(db)
+	pluginPackages := service.NewPluginRepository(db, cfg.Plugins.Dir)
+	router := api.SetupRouter(db, sessions, dispatcher, taskLogs, jobService, enrollmentService, authService, auditService, policyService, pluginPackages)
 	go func() {
 		log.Printf("Starting HTTP server on %s", cfg.Server.HTTPPort)
 		if err := router.Run(cfg.Server.HTTPPort); err != nil {
@@ -67,4 +146,16 @@ func main() {
 
 	log.Println("Server shutting down")
 	grpcServer.Stop()
+	dispatcher.Stop()
+	agentService.Stop()
+	auditService.Stop()
+}
+
+func policyRules(rules config.PolicyRules) service.PolicyRules {
+	return service.PolicyRules{
+		DenyPatterns:    rules.DenyPatterns,
+		AllowedTypes:    rules.AllowedTypes,
+		MaxTimeout:      rules.MaxTimeout,
+		RequireApproval: rules.RequireApproval,
+	}
 }
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"encoding/json"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
@@ -18,7 +19,7 @@ func NewAgentHandler(db *gorm.DB) *AgentHandler {
 
 func (h *AgentHandler) List(c *gin.Context) {
 	var agents []models.Agent
-	result := h.db.Find(&agents)
+	result := scopeAgents(c, h.db).Find(&agents)
 	if result.Error != nil {
 		Error(c, 500, result.Error.Error())
 		return
@@ -40,6 +41,10 @@ func (h *AgentHandler) Get(c *gin.Context) {
 		Error(c, 404, "agent not found")
 		return
 	}
+	if !canAccessAgent(c, &agent) {
+		Error(c, 403, "permission denied")
+		return
+	}
 
 	Success(c, agent)
 }
@@ -51,7 +56,17 @@ func (h *AgentHandler) Delete(c *gin.Context) {
 		return
 	}
 
-	result := h.db.Delete(&models.Agent{}, id)
+	var agent models.Agent
+	if err := h.db.First(&agent, id).Error; err != nil {
+		Error(c, 404, "agent not found")
+		return
+	}
+	if !canAccessAgent(c, &agent) {
+		Error(c, 403, "permission denied")
+		return
+	}
+
+	result := h.db.Delete(&agent)
 	if result.Error != nil {
 		Error(c, 500, result.Error.Error())
 		return
@@ -59,3 +74,111 @@ func (h *AgentHandler) Delete(c *gin.Context) {
 
 	Success(c, nil)
 }
+
+type UpdateLabelsRequest struct {
+	Labels map[string]string `json:"labels"`
+}
+
+// UpdateLabels 设置 Agent 标签，供批量作业的标签选择器使用
+func (h *AgentHandler) UpdateLabels(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		Error(c, 400, "invalid agent id")
+		return
+	}
+
+	var req UpdateLabelsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		Error(c, 400, err.Error())
+		return
+	}
+
+	labels, _ := json.Marshal(req.Labels)
+
+	var agent models.Agent
+	if err := h.db.First(&agent, id).Error; err != nil {
+		Error(c, 404, "agent not found")
+		return
+	}
+	if !canAccessAgent(c, &agent) {
+		Error(c, 403, "permission denied")
+		return
+	}
+
+	if err := h.db.Model(&agent).Update("labels", string(labels)).Error; err != nil {
+		Error(c, 500, err.Error())
+		return
+	}
+
+	Success(c, agent)
+}
+
+type UpdateGroupRequest struct {
+	Group string `json:"group"`
+}
+
+// UpdateGroup 设置 Agent 所属分组，用户只能操作其可访问分组内的 Agent
+func (h *AgentHandler) UpdateGroup(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		Error(c, 400, "invalid agent id")
+		return
+	}
+
+	var req UpdateGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		Error(c, 400, err.Error())
+		return
+	}
+
+	var agent models.Agent
+	if err := h.db.First(&agent, id).Error; err != nil {
+		Error(c, 404, "agent not found")
+		return
+	}
+	// 受限用户只能在自己可访问的分组之间移动 Agent
+	if principal := currentPrincipal(c); principal != nil &&
+		(!principal.CanAccessGroup(agent.Group) || !principal.CanAccessGroup(req.Group)) {
+		Error(c, 403, "permission denied")
+		return
+	}
+
+	if err := h.db.Model(&agent).Update("group_name", req.Group).Error; err != nil {
+		Error(c, 500, err.Error())
+		return
+	}
+
+	Success(c, agent)
+}
+
+// Events 返回 Agent 的事件记录（如上下线），按时间倒序
+func (h *AgentHandler) Events(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		Error(c, 400, "invalid agent id")
+		return
+	}
+
+	var agent models.Agent
+	if err := h.db.First(&agent, id).Error; err != nil {
+		Error(c, 404, "agent not found")
+		return
+	}
+	if !canAccessAgent(c, &agent) {
+		Error(c, 403, "permission denied")
+		return
+	}
+
+	query := h.db.Where("agent_id = ?", agent.AgentID)
+	if eventType := c.Query("type"); eventType != "" {
+		query = query.Where("type = ?", eventType)
+	}
+
+	var events []models.AgentEvent
+	if err := query.Order("created_at DESC, id DESC").Limit(200).Find(&events).Error; err != nil {
+		Error(c, 500, err.Error())
+		return
+	}
+
+	Success(c, events)
+}
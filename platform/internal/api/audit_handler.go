@@ -0,0 +1,130 @@
+package api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/agent-platform/platform/internal/audit"
+	"github.com/yourusername/agent-platform/platform/internal/models"
+)
+
+type AuditHandler struct {
+	audit *audit.Service
+}
+
+func NewAuditHandler(auditService *audit.Service) *AuditHandler {
+	return &AuditHandler{audit: auditService}
+}
+
+// AuditPage 分页的审计日志
+type AuditPage struct {
+	Items    []models.AuditLog `json:"items"`
+	Total    int64             `json:"total"`
+	Page     int               `json:"page"`
+	PageSize int               `json:"page_size"`
+}
+
+// List 按用户、操作与时间范围分页查询审计日志
+func (h *AuditHandler) List(c *gin.Context) {
+	filter, ok := parseAuditFilter(c)
+	if !ok {
+		return
+	}
+
+	logs, total, err := h.audit.Search(filter)
+	if err != nil {
+		Error(c, 500, err.Error())
+		return
+	}
+
+	Success(c, AuditPage{Items: logs, Total: total, Page: filter.Page, PageSize: filter.PageSize})
+}
+
+// Export 以 CSV 或 JSONL（format=csv|jsonl，默认 jsonl）导出符合条件的全部审计日志
+func (h *AuditHandler) Export(c *gin.Context) {
+	filter, ok := parseAuditFilter(c)
+	if !ok {
+		return
+	}
+
+	format := c.DefaultQuery("format", "jsonl")
+	if format != "csv" && format != "jsonl" {
+		Error(c, 400, "format must be csv or jsonl")
+		return
+	}
+
+	filename := fmt.Sprintf("audit-%s.%s", time.Now().Format("20060102-150405"), format)
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+
+	var err error
+	if format == "csv" {
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		w := csv.NewWriter(c.Writer)
+		w.Write([]string{"id", "created_at", "user_id", "action", "resource", "status", "ip", "user_agent", "details"})
+		err = h.audit.Export(filter, func(log *models.AuditLog) error {
+			return w.Write([]string{
+				strconv.FormatUint(uint64(log.ID), 10),
+				log.CreatedAt.Format(time.RFC3339),
+				log.UserID,
+				log.Action,
+				log.Resource,
+				log.Status,
+				log.IP,
+				log.UserAgent,
+				log.Details,
+			})
+		})
+		w.Flush()
+	} else {
+		c.Header("Content-Type", "application/x-ndjson")
+		enc := json.NewEncoder(c.Writer)
+		err = h.audit.Export(filter, func(log *models.AuditLog) error {
+			return enc.Encode(log)
+		})
+	}
+
+	// 响应已开始写入，只能中断连接
+	if err != nil {
+		c.Error(err)
+		c.Abort()
+	}
+}
+
+// Verify 校验审计日志哈希链与签名检查点，报告第一处断链
+func (h *AuditHandler) Verify(c *gin.Context) {
+	result, err := h.audit.Verify()
+	if err != nil {
+		Error(c, 500, err.Error())
+		return
+	}
+
+	Success(c, result)
+}
+
+func parseAuditFilter(c *gin.Context) (*audit.Filter, bool) {
+	filter := &audit.Filter{
+		UserID: c.Query("user_id"),
+		Action: c.Query("action"),
+	}
+
+	for param, target := range map[string]*time.Time{"start_time": &filter.Since, "end_time": &filter.Until} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			Error(c, 400, "invalid "+param+", expected RFC3339")
+			return nil, false
+		}
+		*target = t
+	}
+
+	filter.Page, _ = strconv.Atoi(c.Query("page"))
+	filter.PageSize, _ = strconv.Atoi(c.Query("page_size"))
+	return filter, true
+}
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/agent-platform/platform/internal/audit"
+	"github.com/yourusername/agent-platform/platform/internal/models"
+)
+
+func TestAuditLogMiddlewareAndExport(t *testing.T) {
+	db := setupTestDB(t)
+	db.AutoMigrate(&models.AuditLog{}, &models.AuditChainStart{})
+	auditService := audit.NewService(db)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	group := router.Group("", AuditLog(auditService), func(c *gin.Context) {
+		c.Set("user_id", "alice")
+	})
+	group.POST("/tasks/:id/cancel", func(c *gin.Context) {
+		Error(c, 409, "task already finished")
+	})
+	group.POST("/tasks", func(c *gin.Context) {
+		// 处理函数仍能读取完整的请求体
+		var req CreateTaskRequest
+		assert.NoError(t, c.ShouldBindJSON(&req))
+		assert.Equal(t, "rm -rf /tmp/x", req.Script)
+		Success(c, nil)
+	})
+	group.GET("/tasks", func(c *gin.Context) { Success(c, nil) })
+
+	body, _ := json.Marshal(CreateTaskRequest{AgentID: "agent-1", Type: "shell", Script: "rm -rf /tmp/x"})
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/tasks", bytes.NewReader(body)))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/tasks/7/cancel", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/tasks", nil))
+
+	var logs []models.AuditLog
+	db.Order("id").Find(&logs)
+	assert.Len(t, logs, 2, "GET requests are not audited")
+
+	assert.Equal(t, "alice", logs[0].UserID)
+	assert.Equal(t, "POST /tasks", logs[0].Action)
+	assert.Equal(t, "200", logs[0].Status)
+	assert.Contains(t, logs[0].Details, `"agent_id":"agent-1"`)
+	assert.Contains(t, logs[0].Details, "script_sha256")
+	assert.NotContains(t, logs[0].Details, "rm -rf")
+
+	assert.Equal(t, "POST /tasks/:id/cancel", logs[1].Action)
+	assert.Equal(t, "/tasks/7/cancel", logs[1].Resource)
+	assert.Equal(t, "409", logs[1].Status)
+
+	// 查询与导出
+	handler := NewAuditHandler(auditService)
+	router.GET("/audit", handler.List)
+	router.GET("/audit/export", handler.Export)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/audit?action=POST+/tasks/:id/cancel", nil))
+	var resp Response
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	data, _ := json.Marshal(resp.Data)
+	var page AuditPage
+	json.Unmarshal(data, &page)
+	assert.Equal(t, int64(1), page.Total)
+	assert.Equal(t, audit.DefaultPageSize, page.PageSize)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/audit/export?format=csv", nil))
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	assert.Len(t, lines, 3)
+	assert.True(t, strings.HasPrefix(lines[0], "id,created_at,user_id"))
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/audit/export?user_id=alice", nil))
+	lines = strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	assert.Len(t, lines, 2)
+	var first models.AuditLog
+	assert.NoError(t, json.Unmarshal([]byte(lines[0]), &first))
+	assert.Equal(t, "POST /tasks", first.Action)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/audit?start_time=yesterday", nil))
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, 400, resp.Code)
+}
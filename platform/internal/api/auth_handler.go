@@ -0,0 +1,118 @@
+package api
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/agent-platform/platform/internal/models"
+	"github.com/yourusername/agent-platform/platform/internal/service"
+	"gorm.io/gorm"
+)
+
+type AuthHandler struct {
+	auth *service.AuthService
+}
+
+func NewAuthHandler(auth *service.AuthService) *AuthHandler {
+	return &AuthHandler{auth: auth}
+}
+
+type LoginRequest struct {
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+type TokenResponse struct {
+	Token string           `json:"token"`
+	Info  *models.APIToken `json:"info"`
+}
+
+// Login 校验用户名密码并返回登录令牌
+func (h *AuthHandler) Login(c *gin.Context) {
+	var req LoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		Error(c, 400, err.Error())
+		return
+	}
+
+	token, record, err := h.auth.Login(req.Username, req.Password)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidLogin) {
+			Error(c, 401, err.Error())
+			return
+		}
+		Error(c, 500, err.Error())
+		return
+	}
+
+	Success(c, TokenResponse{Token: token, Info: record})
+}
+
+// Logout 使当前令牌失效
+func (h *AuthHandler) Logout(c *gin.Context) {
+	if err := h.auth.Logout(requestToken(c)); err != nil {
+		Error(c, 500, err.Error())
+		return
+	}
+
+	Success(c, nil)
+}
+
+// Me 返回当前用户的身份、角色与可访问分组
+func (h *AuthHandler) Me(c *gin.Context) {
+	Success(c, currentPrincipal(c))
+}
+
+type CreateAPITokenRequest struct {
+	Name       string `json:"name" binding:"required"`
+	TTLSeconds int    `json:"ttl_seconds"` // 0 表示永不过期
+}
+
+// CreateToken 为当前用户创建 API 令牌，明文令牌只在响应中返回一次
+func (h *AuthHandler) CreateToken(c *gin.Context) {
+	var req CreateAPITokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		Error(c, 400, err.Error())
+		return
+	}
+
+	token, record, err := h.auth.CreateAPIToken(currentPrincipal(c).UserID, req.Name,
+		time.Duration(req.TTLSeconds)*time.Second)
+	if err != nil {
+		Error(c, 400, err.Error())
+		return
+	}
+
+	Success(c, TokenResponse{Token: token, Info: record})
+}
+
+func (h *AuthHandler) ListTokens(c *gin.Context) {
+	tokens, err := h.auth.ListTokens(currentPrincipal(c).UserID)
+	if err != nil {
+		Error(c, 500, err.Error())
+		return
+	}
+
+	Success(c, tokens)
+}
+
+func (h *AuthHandler) DeleteToken(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		Error(c, 400, "invalid token id")
+		return
+	}
+
+	if err := h.auth.DeleteToken(currentPrincipal(c).UserID, uint(id)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			Error(c, 404, "token not found")
+			return
+		}
+		Error(c, 500, err.Error())
+		return
+	}
+
+	Success(c, nil)
+}
@@ -0,0 +1,132 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/agent-platform/platform/internal/models"
+	"github.com/yourusername/agent-platform/platform/internal/service"
+)
+
+func TestAuthMiddlewareAndScope(t *testing.T) {
+	db := setupTestDB(t)
+	db.AutoMigrate(&models.User{}, &models.APIToken{})
+	auth := service.NewAuthService(db, time.Hour)
+
+	db.Create(&models.Agent{AgentID: "web-1", Group: "web"})
+	db.Create(&models.Agent{AgentID: "db-1", Group: "db"})
+
+	password := "viewer-pass"
+	role := models.RoleViewer
+	groups := []string{"web"}
+	_, err := auth.CreateUser(&service.UserSpec{Username: "viewer", Password: &password, Role: &role, Groups: &groups})
+	assert.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/login", NewAuthHandler(auth).Login)
+	protected := router.Group("", Auth(auth))
+	agentHandler := NewAgentHandler(db)
+	protected.GET("/agents", agentHandler.List)
+	protected.DELETE("/agents/:id", RequireRole(models.RoleAdmin), agentHandler.Delete)
+
+	do := func(method, path, token string, body interface{}) Response {
+		var buf bytes.Buffer
+		if body != nil {
+			json.NewEncoder(&buf).Encode(body)
+		}
+		req := httptest.NewRequest(method, path, &buf)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var resp Response
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp
+	}
+
+	// 未认证
+	assert.Equal(t, 401, do("GET", "/agents", "", nil).Code)
+	assert.Equal(t, 401, do("POST", "/login", "", LoginRequest{Username: "viewer", Password: "wrong"}).Code)
+
+	resp := do("POST", "/login", "", LoginRequest{Username: "viewer", Password: password})
+	assert.Equal(t, 0, resp.Code)
+	data, _ := json.Marshal(resp.Data)
+	var login TokenResponse
+	json.Unmarshal(data, &login)
+	assert.NotEmpty(t, login.Token)
+
+	// 只能看到可访问分组内的 Agent
+	resp = do("GET", "/agents", login.Token, nil)
+	assert.Equal(t, 0, resp.Code)
+	data, _ = json.Marshal(resp.Data)
+	var agents []models.Agent
+	json.Unmarshal(data, &agents)
+	assert.Len(t, agents, 1)
+	assert.Equal(t, "web-1", agents[0].AgentID)
+
+	// viewer 不能删除 Agent
+	assert.Equal(t, 403, do("DELETE", "/agents/1", login.Token, nil).Code)
+}
+
+func TestTaskHandler_CreateOutsideScope(t *testing.T) {
+	db := setupTestDB(t)
+	db.AutoMigrate(&models.Task{})
+	db.Create(&models.Agent{AgentID: "db-1", Group: "db"})
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/tasks", func(c *gin.Context) {
+		c.Set(principalKey, &service.Principal{Username: "op", Role: models.RoleOperator, Groups: []string{"web"}})
+	}, NewTaskHandler(db, nil, nil, nil, nil).Create)
+
+	body, _ := json.Marshal(CreateTaskRequest{AgentID: "db-1", Type: "shell", Script: "uptime"})
+	req := httptest.NewRequest("POST", "/tasks", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var resp Response
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, 403, resp.Code)
+
+	var count int64
+	db.Model(&models.Task{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+}
+
+func TestAuthHandler_LogoutQueryToken(t *testing.T) {
+	db := setupTestDB(t)
+	db.AutoMigrate(&models.User{}, &models.APIToken{})
+	auth := service.NewAuthService(db, time.Hour)
+
+	password := "viewer-pass"
+	_, err := auth.CreateUser(&service.UserSpec{Username: "viewer", Password: &password})
+	assert.NoError(t, err)
+	token, _, err := auth.Login("viewer", password)
+	assert.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	protected := router.Group("", Auth(auth))
+	protected.POST("/logout", NewAuthHandler(auth).Logout)
+
+	// 使用 access_token 查询参数认证时，注销同样吊销该令牌
+	req := httptest.NewRequest("POST", "/logout?access_token="+token, nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var resp Response
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, 0, resp.Code)
+
+	_, err = auth.Authenticate(token)
+	assert.ErrorIs(t, err, service.ErrInvalidToken)
+}
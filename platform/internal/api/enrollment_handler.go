@@ -0,0 +1,105 @@
+package api
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/agent-platform/platform/internal/models"
+	"github.com/yourusername/agent-platform/platform/internal/service"
+	"gorm.io/gorm"
+)
+
+type EnrollmentHandler struct {
+	db         *gorm.DB
+	enrollment *service.EnrollmentService
+}
+
+func NewEnrollmentHandler(db *gorm.DB, enrollment *service.EnrollmentService) *EnrollmentHandler {
+	return &EnrollmentHandler{db: db, enrollment: enrollment}
+}
+
+type CreateEnrollmentTokenRequest struct {
+	TTLSeconds  int    `json:"ttl_seconds"`
+	Description string `json:"description"`
+	AgentID     string `json:"agent_id"` // 绑定的 agent_id，重新登记已登记或已吊销的 Agent 时必须设置
+}
+
+type CreateEnrollmentTokenResponse struct {
+	Token string                  `json:"token"`
+	Info  *models.EnrollmentToken `json:"info"`
+}
+
+// CreateToken 创建一次性引导令牌，明文令牌只在响应中返回一次
+func (h *EnrollmentHandler) CreateToken(c *gin.Context) {
+	var req CreateEnrollmentTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		Error(c, 400, err.Error())
+		return
+	}
+
+	token, record, err := h.enrollment.CreateToken(time.Duration(req.TTLSeconds)*time.Second, req.Description, req.AgentID)
+	if err != nil {
+		Error(c, 400, err.Error())
+		return
+	}
+
+	Success(c, CreateEnrollmentTokenResponse{Token: token, Info: record})
+}
+
+func (h *EnrollmentHandler) ListTokens(c *gin.Context) {
+	var tokens []models.EnrollmentToken
+	if err := h.db.Order("created_at DESC").Find(&tokens).Error; err != nil {
+		Error(c, 500, err.Error())
+		return
+	}
+
+	Success(c, tokens)
+}
+
+// DeleteToken 作废尚未使用的引导令牌
+func (h *EnrollmentHandler) DeleteToken(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		Error(c, 400, "invalid token id")
+		return
+	}
+
+	if err := h.enrollment.DeleteToken(uint(id)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			Error(c, 404, "token not found or already used")
+			return
+		}
+		Error(c, 500, err.Error())
+		return
+	}
+
+	Success(c, nil)
+}
+
+// Revoke 吊销 Agent 凭据并断开其连接
+func (h *EnrollmentHandler) Revoke(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		Error(c, 400, "invalid agent id")
+		return
+	}
+
+	var agent models.Agent
+	if err := h.db.First(&agent, id).Error; err != nil {
+		Error(c, 404, "agent not found")
+		return
+	}
+	if !canAccessAgent(c, &agent) {
+		Error(c, 403, "permission denied")
+		return
+	}
+
+	if err := h.enrollment.Revoke(agent.AgentID); err != nil {
+		Error(c, 500, err.Error())
+		return
+	}
+
+	Success(c, nil)
+}
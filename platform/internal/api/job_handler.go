@@ -0,0 +1,143 @@
+package api
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/agent-platform/platform/internal/models"
+	"github.com/yourusername/agent-platform/platform/internal/service"
+	"gorm.io/gorm"
+)
+
+type JobHandler struct {
+	db         *gorm.DB
+	jobService *service.JobService
+}
+
+func NewJobHandler(db *gorm.DB, jobService *service.JobService) *JobHandler {
+	return &JobHandler{db: db, jobService: jobService}
+}
+
+type CreateJobRequest struct {
+	Name                 string   `json:"name"`
+	AgentIDs             []string `json:"agent_ids"`
+	Selector             string   `json:"selector"`
+	Type                 string   `json:"type" binding:"required"`
+	Script               string   `json:"script" binding:"required"`
+	Timeout              int      `json:"timeout"`
+	Concurrency          int      `json:"concurrency"`
+	BatchSize            int      `json:"batch_size"`
+	StopOnFailurePercent int      `json:"stop_on_failure_percent"`
+}
+
+func (h *JobHandler) Create(c *gin.Context) {
+	var req CreateJobRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		Error(c, 400, err.Error())
+		return
+	}
+
+	job, err := h.jobService.Create(&service.JobSpec{
+		Name:                 req.Name,
+		AgentIDs:             req.AgentIDs,
+		Selector:             req.Selector,
+		Type:                 req.Type,
+		Script:               req.Script,
+		Timeout:              req.Timeout,
+		Concurrency:          req.Concurrency,
+		BatchSize:            req.BatchSize,
+		StopOnFailurePercent: req.StopOnFailurePercent,
+		Groups:               scopedGroups(c),
+		CreatedBy:            c.GetString("user_id"),
+	})
+	if errors.Is(err, service.ErrForbidden) {
+		Error(c, 403, "permission denied for target agents")
+		return
+	}
+	if errors.Is(err, service.ErrPolicyRejected) {
+		Error(c, 403, err.Error())
+		return
+	}
+	if err != nil {
+		Error(c, 400, err.Error())
+		return
+	}
+
+	Success(c, job)
+}
+
+func (h *JobHandler) List(c *gin.Context) {
+	var jobs []models.Job
+	query := h.scopeJobs(c, h.db.Order("created_at DESC"))
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	if err := query.Find(&jobs).Error; err != nil {
+		Error(c, 500, err.Error())
+		return
+	}
+
+	Success(c, jobs)
+}
+
+func (h *JobHandler) Get(c *gin.Context) {
+	job, ok := h.loadJob(c)
+	if !ok {
+		return
+	}
+
+	summary, err := h.jobService.Summary(job)
+	if err != nil {
+		Error(c, 500, err.Error())
+		return
+	}
+
+	Success(c, summary)
+}
+
+func (h *JobHandler) Cancel(c *gin.Context) {
+	job, ok := h.loadJob(c)
+	if !ok {
+		return
+	}
+
+	if err := h.jobService.Cancel(job.JobID); err != nil {
+		Error(c, 409, err.Error())
+		return
+	}
+
+	Success(c, nil)
+}
+
+func (h *JobHandler) loadJob(c *gin.Context) (*models.Job, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		Error(c, 400, "invalid job id")
+		return nil, false
+	}
+
+	var job models.Job
+	if err := h.scopeJobs(c, h.db).First(&job, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			Error(c, 404, "job not found")
+		} else {
+			Error(c, 500, err.Error())
+		}
+		return nil, false
+	}
+	return &job, true
+}
+
+// scopeJobs 受限用户只能访问全部子任务都在其可访问分组内的作业
+func (h *JobHandler) scopeJobs(c *gin.Context, query *gorm.DB) *gorm.DB {
+	groups := scopedGroups(c)
+	if groups == nil {
+		return query
+	}
+	accessible := h.db.Model(&models.Agent{}).Select("agent_id").Where("group_name IN ?", groups)
+	outside := h.db.Model(&models.Task{}).Select("job_id").
+		Where("job_id <> '' AND agent_id NOT IN (?)", accessible)
+	return query.Where("job_id NOT IN (?)", outside)
+}
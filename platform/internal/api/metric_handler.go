@@ -22,7 +22,7 @@ func (h *MetricHandler) Query(c *gin.Context) {
 	startTime := c.Query("start_time")
 	endTime := c.Query("end_time")
 
-	query := h.db.Model(&models.Metric{})
+	query := scopeByAgentID(c, h.db, h.db.Model(&models.Metric{}))
 
 	if agentID != "" {
 		query = query.Where("agent_id = ?", agentID)
@@ -1,15 +1,23 @@
 package api
 
 import (
+	"bytes"
+	"io"
+	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 	"github.com/yourusername/agent-platform/platform/internal/audit"
 	"github.com/yourusername/agent-platform/platform/internal/models"
+	"github.com/yourusername/agent-platform/platform/internal/service"
 )
 
+// principalKey 认证通过后请求者在 gin.Context 中的键
+const principalKey = "principal"
+
 func Logger() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		start := time.Now()
@@ -40,19 +48,102 @@ func CORS() gin.HandlerFunc {
 	}
 }
 
+// maxAuditBody 审计时读取的请求体上限
+const maxAuditBody = 1 << 20
+
+// AuditLog 记录修改类请求（POST/PUT/PATCH/DELETE）的操作者、路由、结果与脱敏的请求体摘要。
+// 需在 Auth 之前注册，以便记录认证失败的请求
 func AuditLog(auditService *audit.Service) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		default:
+			c.Next()
+			return
+		}
+
+		var body []byte
+		if c.Request.Body != nil {
+			body, _ = io.ReadAll(io.LimitReader(c.Request.Body, maxAuditBody))
+			c.Request.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), c.Request.Body))
+		}
+
 		c.Next()
 
+		// 以路由模板作为操作名，便于按操作过滤
+		action := c.FullPath()
+		if action == "" {
+			action = c.Request.URL.Path
+		}
+		status := strconv.Itoa(c.Writer.Status())
+		if code, ok := c.Get(responseCodeKey); ok {
+			status = strconv.Itoa(code.(int))
+		}
+
 		log := &models.AuditLog{
 			UserID:    c.GetString("user_id"),
-			Action:    c.Request.Method + " " + c.Request.URL.Path,
+			Action:    c.Request.Method + " " + action,
 			Resource:  c.Request.URL.Path,
+			Details:   audit.Summarize(body),
 			IP:        c.ClientIP(),
 			UserAgent: c.Request.UserAgent(),
-			Status:    strconv.Itoa(c.Writer.Status()),
+			Status:    status,
+		}
+
+		if err := auditService.Log(log); err != nil {
+			logrus.WithError(err).Error("Failed to write audit log")
+		}
+	}
+}
+
+// Auth 校验 Authorization: Bearer <token> 中的登录令牌或 API 令牌，并设置 user_id 与请求者。
+// EventSource 无法设置请求头，因此也接受 access_token 查询参数
+func Auth(auth *service.AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, err := auth.Authenticate(requestToken(c))
+		if err != nil {
+			Error(c, 401, "unauthorized")
+			c.Abort()
+			return
 		}
 
-		auditService.Log(log)
+		c.Set(principalKey, principal)
+		c.Set("user_id", principal.Username)
+		c.Next()
+	}
+}
+
+// requestToken 返回请求携带的令牌
+func requestToken(c *gin.Context) string {
+	if token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "); token != "" {
+		return token
+	}
+	return c.Query("access_token")
+}
+
+// RequireRole 要求请求者具备 role 及以上的角色
+func RequireRole(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal := currentPrincipal(c)
+		if principal == nil || !principal.HasRole(role) {
+			Error(c, 403, "permission denied")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireUnscoped 要求请求者不受 Agent 分组限制，用于用户管理等全局操作，
+// 避免受限的管理员为自己或他人扩大权限
+func RequireUnscoped() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal := currentPrincipal(c)
+		if principal == nil || principal.Scoped() {
+			Error(c, 403, "permission denied")
+			c.Abort()
+			return
+		}
+		c.Next()
 	}
 }
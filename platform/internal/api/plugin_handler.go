@@ -1,25 +1,32 @@
 package api
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/yourusername/agent-platform/pkg/pluginpkg"
 	"github.com/yourusername/agent-platform/platform/internal/service"
+	"gorm.io/gorm"
 )
 
 type PluginHandler struct {
+	db            *gorm.DB
 	pluginService *service.PluginService
 }
 
-func NewPluginHandler(pluginService *service.PluginService) *PluginHandler {
+func NewPluginHandler(db *gorm.DB, pluginService *service.PluginService) *PluginHandler {
 	return &PluginHandler{
+		db:            db,
 		pluginService: pluginService,
 	}
 }
 
+// InstallPluginRequest 安装插件，version 为空时安装插件仓库中最后上传的版本
 type InstallPluginRequest struct {
 	AgentID    string            `json:"agent_id" binding:"required"`
 	PluginName string            `json:"plugin_name" binding:"required"`
+	Version    string            `json:"version"`
 	Config     map[string]string `json:"config"`
 }
 
@@ -34,8 +41,19 @@ func (h *PluginHandler) InstallPlugin(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if !authorizeAgent(c, h.db, req.AgentID) {
+		return
+	}
 
-	if err := h.pluginService.InstallPlugin(req.AgentID, req.PluginName, req.Config); err != nil {
+	if err := h.pluginService.InstallPlugin(req.AgentID, req.PluginName, req.Version, req.Config); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, pluginpkg.ErrIncompatible) || errors.Is(err, pluginpkg.ErrInvalidConfig) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -49,6 +67,9 @@ func (h *PluginHandler) UninstallPlugin(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if !authorizeAgent(c, h.db, req.AgentID) {
+		return
+	}
 
 	if err := h.pluginService.UninstallPlugin(req.AgentID, req.PluginName); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -64,6 +85,9 @@ func (h *PluginHandler) ListPlugins(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "agent_id is required"})
 		return
 	}
+	if !authorizeAgent(c, h.db, agentID) {
+		return
+	}
 
 	plugins, err := h.pluginService.ListPlugins(agentID)
 	if err != nil {
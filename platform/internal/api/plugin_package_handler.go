@@ -0,0 +1,107 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/agent-platform/platform/internal/service"
+	"gorm.io/gorm"
+)
+
+type PluginPackageHandler struct {
+	packages *service.PluginRepository
+}
+
+func NewPluginPackageHandler(packages *service.PluginRepository) *PluginPackageHandler {
+	return &PluginPackageHandler{packages: packages}
+}
+
+// Upload 上传插件安装包，multipart 表单字段 file 为包含 plugin.yaml 的 tar.gz
+func (h *PluginPackageHandler) Upload(c *gin.Context) {
+	// 为 multipart 边界与其他表单字段预留 1 MiB
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, service.MaxPluginPackageSize+1<<20)
+	header, err := c.FormFile("file")
+	if err != nil {
+		Error(c, 400, "file is required: "+err.Error())
+		return
+	}
+	f, err := header.Open()
+	if err != nil {
+		Error(c, 500, err.Error())
+		return
+	}
+	defer f.Close()
+
+	pkg, err := h.packages.Upload(f, c.GetString("user_id"))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrPluginPackageExists):
+			Error(c, 409, err.Error())
+		case errors.Is(err, service.ErrInvalidPluginPackage), errors.Is(err, service.ErrPluginPackageTooLarge):
+			Error(c, 400, err.Error())
+		default:
+			Error(c, 500, err.Error())
+		}
+		return
+	}
+
+	Success(c, pkg)
+}
+
+func (h *PluginPackageHandler) List(c *gin.Context) {
+	pkgs, err := h.packages.List(c.Query("name"))
+	if err != nil {
+		Error(c, 500, err.Error())
+		return
+	}
+
+	Success(c, pkgs)
+}
+
+func (h *PluginPackageHandler) Get(c *gin.Context) {
+	id, ok := parsePluginPackageID(c)
+	if !ok {
+		return
+	}
+
+	pkg, err := h.packages.Get(id)
+	if err != nil {
+		pluginPackageError(c, err)
+		return
+	}
+
+	Success(c, pkg)
+}
+
+func (h *PluginPackageHandler) Delete(c *gin.Context) {
+	id, ok := parsePluginPackageID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.packages.Delete(id); err != nil {
+		pluginPackageError(c, err)
+		return
+	}
+
+	Success(c, nil)
+}
+
+func parsePluginPackageID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		Error(c, 400, "invalid plugin package id")
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func pluginPackageError(c *gin.Context, err error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		Error(c, 404, "plugin package not found")
+		return
+	}
+	Error(c, 500, err.Error())
+}
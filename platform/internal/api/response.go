@@ -20,7 +20,11 @@ func Success(c *gin.Context, data interface{}) {
 	})
 }
 
+// responseCodeKey 错误响应的业务码在 gin.Context 中的键，审计日志据此记录请求结果
+const responseCodeKey = "response_code"
+
 func Error(c *gin.Context, code int, message string) {
+	c.Set(responseCodeKey, code)
 	c.JSON(http.StatusOK, Response{
 		Code:    code,
 		Message: message,
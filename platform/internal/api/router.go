@@ -2,33 +2,161 @@ package api
 
 import (
 	"github.com/gin-gonic/gin"
+	"github.com/yourusername/agent-platform/platform/internal/audit"
+	"github.com/yourusername/agent-platform/platform/internal/models"
+	"github.com/yourusername/agent-platform/platform/internal/service"
+	"github.com/yourusername/agent-platform/platform/internal/session"
 	"gorm.io/gorm"
 )
 
-func SetupRouter(db *gorm.DB) *gin.Engine {
+// SetupRouter 注册 REST API。除登录与健康检查外均需认证：
+// viewer 只读，operator 可下发任务、作业，管理脚本库、脚本模板、插件仓库与插件，admin 可删除 Agent、管理登记令牌与用户、查看审计日志。
+// 所有修改类请求都会写入审计日志
+func SetupRouter(db *gorm.DB, sessions *session.Registry, dispatcher *service.TaskDispatcher, taskLogs *service.TaskLogService, jobService *service.JobService, enrollment *service.EnrollmentService, auth *service.AuthService, auditService *audit.Service, policy *service.PolicyService, pluginPackages *service.PluginRepository) *gin.Engine {
 	r := gin.Default()
 
 	r.Use(Logger())
 	r.Use(CORS())
 
-	api := r.Group("/api/v1")
+	operator := RequireRole(models.RoleOperator)
+	admin := RequireRole(models.RoleAdmin)
+
+	public := r.Group("/api/v1", AuditLog(auditService))
 	{
+		authHandler := NewAuthHandler(auth)
+		public.POST("/auth/login", authHandler.Login)
+		public.GET("/monitor/health", NewMonitorHandler().HealthCheck)
+	}
+
+	api := r.Group("/api/v1", AuditLog(auditService), Auth(auth))
+	{
+		// 当前用户与 API 令牌
+		authGroup := api.Group("/auth")
+		{
+			handler := NewAuthHandler(auth)
+			authGroup.POST("/logout", handler.Logout)
+			authGroup.GET("/me", handler.Me)
+			authGroup.POST("/tokens", handler.CreateToken)
+			authGroup.GET("/tokens", handler.ListTokens)
+			authGroup.DELETE("/tokens/:id", handler.DeleteToken)
+		}
+
+		// 用户管理
+		users := api.Group("/users", admin, RequireUnscoped())
+		{
+			handler := NewUserHandler(db, auth)
+			users.GET("", handler.List)
+			users.POST("", handler.Create)
+			users.PUT("/:id", handler.Update)
+			users.DELETE("/:id", handler.Delete)
+		}
+
+		// 审计日志
+		auditGroup := api.Group("/audit", admin, RequireUnscoped())
+		{
+			handler := NewAuditHandler(auditService)
+			auditGroup.GET("", handler.List)
+			auditGroup.GET("/export", handler.Export)
+			auditGroup.GET("/verify", handler.Verify)
+		}
+
 		// Agent 管理
 		agents := api.Group("/agents")
 		{
 			handler := NewAgentHandler(db)
 			agents.GET("", handler.List)
 			agents.GET("/:id", handler.Get)
-			agents.DELETE("/:id", handler.Delete)
+			agents.DELETE("/:id", admin, handler.Delete)
+			agents.PUT("/:id/labels", operator, handler.UpdateLabels)
+			agents.PUT("/:id/group", admin, handler.UpdateGroup)
+			agents.GET("/:id/events", handler.Events)
+
+			enrollmentHandler := NewEnrollmentHandler(db, enrollment)
+			agents.POST("/:id/revoke", admin, enrollmentHandler.Revoke)
+		}
+
+		// Agent 登记令牌
+		tokens := api.Group("/enrollment-tokens", admin, RequireUnscoped())
+		{
+			handler := NewEnrollmentHandler(db, enrollment)
+			tokens.POST("", handler.CreateToken)
+			tokens.GET("", handler.ListTokens)
+			tokens.DELETE("/:id", handler.DeleteToken)
 		}
 
 		// 任务管理
+		templateService := service.NewTemplateService(db)
+		scriptService := service.NewScriptService(db)
 		tasks := api.Group("/tasks")
 		{
-			handler := NewTaskHandler(db)
-			tasks.POST("", handler.Create)
+			handler := NewTaskHandler(db, dispatcher, policy, templateService, scriptService)
+			tasks.POST("", operator, handler.Create)
 			tasks.GET("", handler.List)
 			tasks.GET("/:id", handler.Get)
+			tasks.POST("/:id/cancel", operator, handler.Cancel)
+			tasks.POST("/:id/approve", operator, handler.Approve)
+			tasks.POST("/:id/reject", operator, handler.Reject)
+
+			logHandler := NewTaskLogHandler(db, taskLogs)
+			tasks.GET("/:id/logs", logHandler.List)
+			tasks.GET("/:id/logs/stream", logHandler.Stream)
+		}
+
+		// 脚本模板
+		templates := api.Group("/templates")
+		{
+			handler := NewTemplateHandler(templateService)
+			templates.GET("", handler.List)
+			templates.GET("/:id", handler.Get)
+			templates.POST("", operator, handler.Create)
+			templates.PUT("/:id", operator, handler.Update)
+			templates.DELETE("/:id", operator, handler.Delete)
+		}
+
+		// 脚本库
+		scripts := api.Group("/scripts")
+		{
+			handler := NewScriptHandler(scriptService)
+			scripts.GET("", handler.List)
+			scripts.GET("/:id", handler.Get)
+			scripts.POST("", operator, handler.Create)
+			scripts.PUT("/:id", operator, handler.Update)
+			scripts.DELETE("/:id", operator, handler.Delete)
+			scripts.GET("/:id/versions", handler.Versions)
+			scripts.GET("/:id/versions/:version", handler.GetVersion)
+			scripts.DELETE("/:id/versions/:version", operator, handler.DeleteVersion)
+			scripts.GET("/:id/diff", handler.Diff)
+		}
+
+		// 批量作业
+		jobs := api.Group("/jobs")
+		{
+			handler := NewJobHandler(db, jobService)
+			jobs.POST("", operator, handler.Create)
+			jobs.GET("", handler.List)
+			jobs.GET("/:id", handler.Get)
+			jobs.POST("/:id/cancel", operator, handler.Cancel)
+		}
+
+		// 插件仓库
+		packages := api.Group("/plugin-packages")
+		{
+			handler := NewPluginPackageHandler(pluginPackages)
+			packages.GET("", handler.List)
+			packages.GET("/:id", handler.Get)
+			packages.POST("", operator, handler.Upload)
+			packages.DELETE("/:id", operator, handler.Delete)
+		}
+
+		// 插件管理
+		plugins := api.Group("/plugins")
+		{
+			pluginService := service.NewPluginService(db, sessions, pluginPackages)
+			pluginService.SetSigner(dispatcher.SignPlugin)
+			handler := NewPluginHandler(db, pluginService)
+			plugins.GET("", handler.ListPlugins)
+			plugins.POST("/install", operator, handler.InstallPlugin)
+			plugins.POST("/uninstall", operator, handler.UninstallPlugin)
 		}
 
 		// 指标查询
@@ -38,12 +166,11 @@ func SetupRouter(db *gorm.DB) *gin.Engine {
 			metrics.GET("", handler.Query)
 		}
 
-		// 监控和健康检查
+		// 监控
 		monitor := api.Group("/monitor")
 		{
 			handler := NewMonitorHandler()
 			monitor.GET("/metrics", handler.GetMetrics)
-			monitor.GET("/health", handler.HealthCheck)
 		}
 	}
 
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/agent-platform/platform/internal/models"
+	"github.com/yourusername/agent-platform/platform/internal/service"
+	"gorm.io/gorm"
+)
+
+// currentPrincipal 返回认证中间件设置的请求者，未经过认证中间件时返回 nil
+func currentPrincipal(c *gin.Context) *service.Principal {
+	if v, ok := c.Get(principalKey); ok {
+		return v.(*service.Principal)
+	}
+	return nil
+}
+
+// scopedGroups 返回请求者可访问的 Agent 分组，为 nil 表示不限制
+func scopedGroups(c *gin.Context) []string {
+	if principal := currentPrincipal(c); principal != nil && principal.Scoped() {
+		return principal.Groups
+	}
+	return nil
+}
+
+// canAccessAgent 判断请求者能否访问该 Agent
+func canAccessAgent(c *gin.Context, agent *models.Agent) bool {
+	principal := currentPrincipal(c)
+	return principal == nil || principal.CanAccessGroup(agent.Group)
+}
+
+// authorizeAgent 校验请求者能否操作 agentID 对应的 Agent，不能时写入 403 响应
+func authorizeAgent(c *gin.Context, db *gorm.DB, agentID string) bool {
+	groups := scopedGroups(c)
+	if groups == nil {
+		return true
+	}
+
+	var count int64
+	if err := db.Model(&models.Agent{}).
+		Where("agent_id = ? AND group_name IN ?", agentID, groups).
+		Count(&count).Error; err != nil {
+		Error(c, 500, err.Error())
+		return false
+	}
+	if count == 0 {
+		Error(c, 403, "permission denied for agent "+agentID)
+		return false
+	}
+	return true
+}
+
+// scopeAgents 将 agents 表的查询限制在请求者可访问的分组内
+func scopeAgents(c *gin.Context, query *gorm.DB) *gorm.DB {
+	if groups := scopedGroups(c); groups != nil {
+		return query.Where("group_name IN ?", groups)
+	}
+	return query
+}
+
+// scopeByAgentID 将带 agent_id 列的查询限制在请求者可访问的 Agent 内
+func scopeByAgentID(c *gin.Context, db, query *gorm.DB) *gorm.DB {
+	if groups := scopedGroups(c); groups != nil {
+		return query.Where("agent_id IN (?)",
+			db.Model(&models.Agent{}).Select("agent_id").Where("group_name IN ?", groups))
+	}
+	return query
+}
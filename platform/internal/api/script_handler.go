@@ -0,0 +1,226 @@
+package api
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/agent-platform/platform/internal/service"
+	"gorm.io/gorm"
+)
+
+type ScriptHandler struct {
+	scripts *service.ScriptService
+}
+
+func NewScriptHandler(scripts *service.ScriptService) *ScriptHandler {
+	return &ScriptHandler{scripts: scripts}
+}
+
+// ScriptRequest 创建或更新脚本，script 或 type 与最新版本不同时生成新版本
+type ScriptRequest struct {
+	Name        string   `json:"name" binding:"required"`
+	Description string   `json:"description"`
+	Type        string   `json:"type" binding:"required"`
+	Script      string   `json:"script" binding:"required"`
+	Tags        []string `json:"tags"`
+	Comment     string   `json:"comment"` // 版本说明
+}
+
+func (h *ScriptHandler) List(c *gin.Context) {
+	scripts, err := h.scripts.List(c.Query("tag"))
+	if err != nil {
+		Error(c, 500, err.Error())
+		return
+	}
+
+	Success(c, scripts)
+}
+
+func (h *ScriptHandler) Get(c *gin.Context) {
+	id, ok := parseScriptID(c)
+	if !ok {
+		return
+	}
+
+	script, err := h.scripts.Get(id)
+	if err != nil {
+		scriptError(c, err)
+		return
+	}
+
+	Success(c, script)
+}
+
+func (h *ScriptHandler) Create(c *gin.Context) {
+	var req ScriptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		Error(c, 400, err.Error())
+		return
+	}
+
+	script, err := h.scripts.Create(req.spec(c))
+	if err != nil {
+		Error(c, 400, err.Error())
+		return
+	}
+
+	Success(c, script)
+}
+
+func (h *ScriptHandler) Update(c *gin.Context) {
+	id, ok := parseScriptID(c)
+	if !ok {
+		return
+	}
+
+	var req ScriptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		Error(c, 400, err.Error())
+		return
+	}
+
+	script, err := h.scripts.Update(id, req.spec(c))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			Error(c, 404, "script not found")
+			return
+		}
+		Error(c, 400, err.Error())
+		return
+	}
+
+	Success(c, script)
+}
+
+func (h *ScriptHandler) Delete(c *gin.Context) {
+	id, ok := parseScriptID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.scripts.Delete(id); err != nil {
+		scriptError(c, err)
+		return
+	}
+
+	Success(c, nil)
+}
+
+func (h *ScriptHandler) Versions(c *gin.Context) {
+	id, ok := parseScriptID(c)
+	if !ok {
+		return
+	}
+
+	versions, err := h.scripts.Versions(id)
+	if err != nil {
+		scriptError(c, err)
+		return
+	}
+
+	Success(c, versions)
+}
+
+func (h *ScriptHandler) GetVersion(c *gin.Context) {
+	id, ok := parseScriptID(c)
+	if !ok {
+		return
+	}
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil {
+		Error(c, 400, "invalid script version")
+		return
+	}
+
+	v, err := h.scripts.GetVersion(id, version)
+	if err != nil {
+		scriptError(c, err)
+		return
+	}
+
+	Success(c, v)
+}
+
+func (h *ScriptHandler) DeleteVersion(c *gin.Context) {
+	id, ok := parseScriptID(c)
+	if !ok {
+		return
+	}
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil {
+		Error(c, 400, "invalid script version")
+		return
+	}
+
+	if err := h.scripts.DeleteVersion(id, version); err != nil {
+		scriptError(c, err)
+		return
+	}
+
+	Success(c, nil)
+}
+
+// Diff 比较两个版本，from 与 to 缺省时比较最新版本与上一版本
+func (h *ScriptHandler) Diff(c *gin.Context) {
+	id, ok := parseScriptID(c)
+	if !ok {
+		return
+	}
+	script, err := h.scripts.Get(id)
+	if err != nil {
+		scriptError(c, err)
+		return
+	}
+
+	from, err := strconv.Atoi(c.DefaultQuery("from", strconv.Itoa(script.LatestVersion-1)))
+	if err != nil {
+		Error(c, 400, "invalid from version")
+		return
+	}
+	to, err := strconv.Atoi(c.DefaultQuery("to", strconv.Itoa(script.LatestVersion)))
+	if err != nil {
+		Error(c, 400, "invalid to version")
+		return
+	}
+
+	diff, err := h.scripts.Diff(id, from, to)
+	if err != nil {
+		scriptError(c, err)
+		return
+	}
+
+	Success(c, diff)
+}
+
+func (r *ScriptRequest) spec(c *gin.Context) *service.ScriptSpec {
+	return &service.ScriptSpec{
+		Name:        r.Name,
+		Description: r.Description,
+		Type:        r.Type,
+		Script:      r.Script,
+		Tags:        r.Tags,
+		Comment:     r.Comment,
+		CreatedBy:   c.GetString("user_id"),
+	}
+}
+
+func parseScriptID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		Error(c, 400, "invalid script id")
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func scriptError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		Error(c, 404, "script or version not found")
+	case errors.Is(err, service.ErrScriptInUse), errors.Is(err, service.ErrLatestScriptVersion):
+		Error(c, 409, err.Error())
+	default:
+		Error(c, 500, err.Error())
+	}
+}
@@ -1,26 +1,52 @@
 package api
 
 import (
+	"encoding/json"
+	"errors"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/yourusername/agent-platform/platform/internal/models"
+	"github.com/yourusername/agent-platform/platform/internal/service"
 	"gorm.io/gorm"
 )
 
 type TaskHandler struct {
-	db *gorm.DB
+	db         *gorm.DB
+	dispatcher *service.TaskDispatcher
+	policy     *service.PolicyService
+	templates  *service.TemplateService
+	scripts    *service.ScriptService
 }
 
-func NewTaskHandler(db *gorm.DB) *TaskHandler {
-	return &TaskHandler{db: db}
+// NewTaskHandler 创建任务处理器，policy 为 nil 时不做脚本策略检查，
+// templates、scripts 为 nil 时不支持按模板、脚本库创建任务
+func NewTaskHandler(db *gorm.DB, dispatcher *service.TaskDispatcher, policy *service.PolicyService, templates *service.TemplateService, scripts *service.ScriptService) *TaskHandler {
+	return &TaskHandler{db: db, dispatcher: dispatcher, policy: policy, templates: templates, scripts: scripts}
 }
 
+// CreateTaskRequest 直接指定 type 与 script，通过 script_ref（script_id@version）引用脚本库，
+// 或通过 template_id 与 params 由模板渲染脚本。type 为 file 时以 file（base64）代替 script
 type CreateTaskRequest struct {
-	AgentID string `json:"agent_id" binding:"required"`
-	Type    string `json:"type" binding:"required"`
-	Script  string `json:"script" binding:"required"`
-	Timeout int    `json:"timeout"`
+	AgentID    string                 `json:"agent_id" binding:"required"`
+	Type       string                 `json:"type"`
+	Script     string                 `json:"script"`
+	ScriptRef  string                 `json:"script_ref"` // 省略版本时使用最新版本
+	TemplateID uint                   `json:"template_id"`
+	Params     map[string]interface{} `json:"params"`
+	Env        map[string]string      `json:"env"`
+	Args       []string               `json:"args"`
+	File       []byte                 `json:"file"`
+	FileName   string                 `json:"file_name"`
+	FileSHA256 string                 `json:"file_sha256"` // 提供时校验文件内容
+	Timeout    int                    `json:"timeout"`
+	// 以下为可选的运行身份与资源限制，为空时继承 Agent 的设置
+	RunAsUser  string                `json:"run_as_user"`
+	RunAsGroup string                `json:"run_as_group"`
+	WorkDir    string                `json:"work_dir"`
+	Umask      string                `json:"umask"` // 八进制，如 "022"
+	Limits     models.ResourceLimits `json:"limits"`
 }
 
 func (h *TaskHandler) Create(c *gin.Context) {
@@ -30,17 +56,133 @@ func (h *TaskHandler) Create(c *gin.Context) {
 		return
 	}
 
+	var rendered *service.RenderedTemplate
+	var version *models.ScriptVersion
+	if req.ScriptRef != "" && req.TemplateID != 0 {
+		Error(c, 400, "script_ref and template_id are mutually exclusive")
+		return
+	}
+	if req.ScriptRef != "" {
+		if h.scripts == nil {
+			Error(c, 400, "script library is not supported")
+			return
+		}
+		var err error
+		version, err = h.scripts.Resolve(req.ScriptRef)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				Error(c, 404, "script version not found")
+				return
+			}
+			if errors.Is(err, service.ErrInvalidScriptRef) {
+				Error(c, 400, err.Error())
+				return
+			}
+			Error(c, 500, err.Error())
+			return
+		}
+		req.Type, req.Script = version.Type, version.Script
+	} else if req.TemplateID != 0 {
+		if h.templates == nil {
+			Error(c, 400, "script templates are not supported")
+			return
+		}
+		var err error
+		rendered, err = h.templates.Render(req.TemplateID, req.Params)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				Error(c, 404, "template not found")
+				return
+			}
+			Error(c, 400, err.Error())
+			return
+		}
+		req.Type, req.Script = rendered.Type, rendered.Script
+	} else if req.Script == "" && req.Type != service.TaskTypeFile {
+		Error(c, 400, "script, script_ref or template_id is required")
+		return
+	}
+
+	if _, err := service.ParseTaskType(req.Type); err != nil {
+		Error(c, 400, err.Error())
+		return
+	}
+	for key := range req.Env {
+		if key == "" || strings.ContainsAny(key, "=\x00") {
+			Error(c, 400, "invalid env name: "+key)
+			return
+		}
+	}
+	if req.Umask != "" {
+		if v, err := strconv.ParseUint(req.Umask, 8, 32); err != nil || v > 0o777 {
+			Error(c, 400, "invalid umask: "+req.Umask)
+			return
+		}
+	}
+	if !authorizeAgent(c, h.db, req.AgentID) {
+		return
+	}
+
 	task := &models.Task{
-		AgentID: req.AgentID,
-		Type:    req.Type,
-		Script:  req.Script,
-		Timeout: req.Timeout,
-		Status:  "pending",
+		AgentID:    req.AgentID,
+		Type:       req.Type,
+		Script:     req.Script,
+		Timeout:    req.Timeout,
+		RunAsUser:  req.RunAsUser,
+		RunAsGroup: req.RunAsGroup,
+		WorkDir:    req.WorkDir,
+		Umask:      req.Umask,
+		Limits:     req.Limits,
+		TemplateID: req.TemplateID,
+		File:       req.File,
+		FileName:   req.FileName,
+		FileSHA256: req.FileSHA256,
+		CreatedBy:  c.GetString("user_id"),
+	}
+	if err := service.PrepareTaskFile(task); err != nil {
+		Error(c, 400, err.Error())
+		return
+	}
+	if len(req.Args) > 0 {
+		args, _ := json.Marshal(req.Args)
+		task.Args = string(args)
+	}
+	if len(req.Env) > 0 {
+		env, _ := json.Marshal(req.Env)
+		task.Env = string(env)
+	}
+	if version != nil {
+		task.ScriptID, task.ScriptVersion = version.ScriptID, version.Version
+	}
+	if rendered != nil {
+		params, _ := json.Marshal(rendered.Params)
+		task.Params = string(params)
+		task.Secrets = rendered.Secrets
 	}
 
-	result := h.db.Create(task)
-	if result.Error != nil {
-		Error(c, 500, result.Error.Error())
+	submit := h.dispatcher.Submit
+	if h.policy != nil {
+		decision, err := h.policy.Evaluate(task.AgentID, task.Type, service.TaskPolicyContent(task), task.Timeout)
+		if err != nil {
+			Error(c, 500, err.Error())
+			return
+		}
+		if !decision.Allowed {
+			// 被拒绝的任务同样保存下来，便于追溯
+			if err := h.dispatcher.Reject(task, decision.Reason); err != nil {
+				Error(c, 500, err.Error())
+				return
+			}
+			Error(c, 403, "rejected by script policy: "+decision.Reason)
+			return
+		}
+		if decision.RequireApproval {
+			submit = h.dispatcher.SubmitForApproval
+		}
+	}
+
+	if err := submit(task); err != nil {
+		Error(c, 500, err.Error())
 		return
 	}
 
@@ -51,7 +193,7 @@ func (h *TaskHandler) List(c *gin.Context) {
 	agentID := c.Query("agent_id")
 
 	var tasks []models.Task
-	query := h.db.Order("created_at DESC")
+	query := scopeByAgentID(c, h.db, h.db.Order("created_at DESC"))
 	if agentID != "" {
 		query = query.Where("agent_id = ?", agentID)
 	}
@@ -78,6 +220,99 @@ func (h *TaskHandler) Get(c *gin.Context) {
 		Error(c, 404, "task not found")
 		return
 	}
+	if !authorizeAgent(c, h.db, task.AgentID) {
+		return
+	}
+
+	Success(c, task)
+}
+
+func (h *TaskHandler) Cancel(c *gin.Context) {
+	task, ok := h.loadTask(c)
+	if !ok {
+		return
+	}
+
+	if err := h.dispatcher.Cancel(task); err != nil {
+		if errors.Is(err, service.ErrTaskFinished) {
+			Error(c, 409, err.Error())
+			return
+		}
+		Error(c, 500, err.Error())
+		return
+	}
 
 	Success(c, task)
 }
+
+type RejectTaskRequest struct {
+	Reason string `json:"reason"`
+}
+
+// Approve 批准等待审批的任务，审批人不能是任务的创建者
+func (h *TaskHandler) Approve(c *gin.Context) {
+	task, ok := h.loadTask(c)
+	if !ok {
+		return
+	}
+	approver := c.GetString("user_id")
+	if task.CreatedBy != "" && task.CreatedBy == approver {
+		Error(c, 403, "task must be approved by another user")
+		return
+	}
+
+	if err := h.dispatcher.Approve(task, approver); err != nil {
+		if errors.Is(err, service.ErrTaskNotAwaiting) {
+			Error(c, 409, err.Error())
+			return
+		}
+		Error(c, 500, err.Error())
+		return
+	}
+
+	Success(c, task)
+}
+
+// Reject 驳回等待审批的任务
+func (h *TaskHandler) Reject(c *gin.Context) {
+	task, ok := h.loadTask(c)
+	if !ok {
+		return
+	}
+
+	var req RejectTaskRequest
+	c.ShouldBindJSON(&req)
+	reason := "rejected by " + c.GetString("user_id")
+	if req.Reason != "" {
+		reason += ": " + req.Reason
+	}
+
+	if err := h.dispatcher.Reject(task, reason); err != nil {
+		if errors.Is(err, service.ErrTaskNotAwaiting) {
+			Error(c, 409, err.Error())
+			return
+		}
+		Error(c, 500, err.Error())
+		return
+	}
+
+	Success(c, task)
+}
+
+func (h *TaskHandler) loadTask(c *gin.Context) (*models.Task, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		Error(c, 400, "invalid task id")
+		return nil, false
+	}
+
+	var task models.Task
+	if err := h.db.First(&task, id).Error; err != nil {
+		Error(c, 404, "task not found")
+		return nil, false
+	}
+	if !authorizeAgent(c, h.db, task.AgentID) {
+		return nil, false
+	}
+	return &task, true
+}
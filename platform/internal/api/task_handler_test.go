@@ -3,6 +3,7 @@ package api
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -10,6 +11,8 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"github.com/yourusername/agent-platform/platform/internal/models"
+	"github.com/yourusername/agent-platform/platform/internal/service"
+	"github.com/yourusername/agent-platform/platform/internal/session"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
@@ -18,7 +21,7 @@ func setupTaskTestDB(t *testing.T) *gorm.DB {
 	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
 	assert.NoError(t, err)
 
-	err = db.AutoMigrate(&models.Task{})
+	err = db.AutoMigrate(&models.Task{}, &models.TaskSecret{})
 	assert.NoError(t, err)
 
 	return db
@@ -26,7 +29,7 @@ func setupTaskTestDB(t *testing.T) *gorm.DB {
 
 func TestTaskHandler_Create(t *testing.T) {
 	db := setupTaskTestDB(t)
-	handler := NewTaskHandler(db)
+	handler := NewTaskHandler(db, service.NewTaskDispatcher(db, session.NewRegistry()), nil, nil, nil)
 
 	gin.SetMode(gin.TestMode)
 	router := gin.New()
@@ -51,11 +54,37 @@ func TestTaskHandler_Create(t *testing.T) {
 	err := json.Unmarshal(w.Body.Bytes(), &resp)
 	assert.NoError(t, err)
 	assert.Equal(t, 0, resp.Code)
+
+	// Agent 不在线，任务应生成 task_id 并保持 pending
+	var task models.Task
+	assert.NoError(t, db.First(&task).Error)
+	assert.NotEmpty(t, task.TaskID)
+	assert.Equal(t, "pending", task.Status)
+}
+
+func TestTaskHandler_CreateInvalidType(t *testing.T) {
+	db := setupTaskTestDB(t)
+	handler := NewTaskHandler(db, service.NewTaskDispatcher(db, session.NewRegistry()), nil, nil, nil)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/tasks", handler.Create)
+
+	body, _ := json.Marshal(CreateTaskRequest{AgentID: "agent-1", Type: "ruby", Script: "puts 1"})
+	req := httptest.NewRequest("POST", "/tasks", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var resp Response
+	err := json.Unmarshal(w.Body.Bytes(), &resp)
+	assert.NoError(t, err)
+	assert.Equal(t, 400, resp.Code)
 }
 
 func TestTaskHandler_List(t *testing.T) {
 	db := setupTaskTestDB(t)
-	handler := NewTaskHandler(db)
+	handler := NewTaskHandler(db, service.NewTaskDispatcher(db, session.NewRegistry()), nil, nil, nil)
 
 	tasks := []models.Task{
 		{AgentID: "agent-1", Type: "shell", Script: "test1", Status: "pending"},
@@ -83,7 +112,7 @@ func TestTaskHandler_List(t *testing.T) {
 
 func TestTaskHandler_Get(t *testing.T) {
 	db := setupTaskTestDB(t)
-	handler := NewTaskHandler(db)
+	handler := NewTaskHandler(db, service.NewTaskDispatcher(db, session.NewRegistry()), nil, nil, nil)
 
 	task := models.Task{AgentID: "agent-1", Type: "shell", Script: "test", Status: "pending"}
 	db.Create(&task)
@@ -103,3 +132,216 @@ func TestTaskHandler_Get(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, 0, resp.Code)
 }
+
+func TestTaskHandler_Cancel(t *testing.T) {
+	db := setupTaskTestDB(t)
+	handler := NewTaskHandler(db, service.NewTaskDispatcher(db, session.NewRegistry()), nil, nil, nil)
+
+	task := models.Task{TaskID: "task-1", AgentID: "agent-1", Type: "shell", Script: "sleep 60", Status: "pending"}
+	db.Create(&task)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/tasks/:id/cancel", handler.Cancel)
+
+	req := httptest.NewRequest("POST", "/tasks/1/cancel", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var resp Response
+	err := json.Unmarshal(w.Body.Bytes(), &resp)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, resp.Code)
+
+	db.First(&task, task.ID)
+	assert.Equal(t, "cancelled", task.Status)
+
+	// 已结束的任务不能再次取消
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/tasks/1/cancel", nil))
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	assert.Equal(t, 409, resp.Code)
+}
+
+func TestTaskHandler_CreateWithPolicy(t *testing.T) {
+	db := setupTaskTestDB(t)
+	assert.NoError(t, db.AutoMigrate(&models.Agent{}))
+	db.Create(&models.Agent{AgentID: "agent-1", Group: "prod"})
+	policy, err := service.NewPolicyService(db, service.PolicyRules{DenyPatterns: []string{`rm\s+-rf`}},
+		map[string]service.PolicyRules{"prod": {RequireApproval: true}})
+	assert.NoError(t, err)
+	handler := NewTaskHandler(db, service.NewTaskDispatcher(db, session.NewRegistry()), policy, nil, nil)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", c.GetHeader("X-User"))
+	})
+	router.POST("/tasks", handler.Create)
+	router.POST("/tasks/:id/approve", handler.Approve)
+
+	do := func(path, user string, body interface{}) Response {
+		data, _ := json.Marshal(body)
+		req := httptest.NewRequest("POST", path, bytes.NewBuffer(data))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-User", user)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var resp Response
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp
+	}
+
+	// 命中禁止规则的任务被拒绝，但仍保存下来
+	resp := do("/tasks", "alice", CreateTaskRequest{AgentID: "agent-1", Type: "shell", Script: "rm -rf /tmp/x"})
+	assert.Equal(t, 403, resp.Code)
+	var task models.Task
+	assert.NoError(t, db.Where("status = ?", "rejected").First(&task).Error)
+	assert.Equal(t, "alice", task.CreatedBy)
+
+	// 需要审批的任务等待其他用户批准
+	resp = do("/tasks", "alice", CreateTaskRequest{AgentID: "agent-1", Type: "shell", Script: "uptime"})
+	assert.Equal(t, 0, resp.Code)
+	var pending models.Task
+	assert.NoError(t, db.Where("status = ?", "awaiting_approval").First(&pending).Error)
+
+	path := fmt.Sprintf("/tasks/%d/approve", pending.ID)
+	assert.Equal(t, 403, do(path, "alice", nil).Code)
+	assert.Equal(t, 0, do(path, "bob", nil).Code)
+	db.First(&pending, pending.ID)
+	assert.Equal(t, "pending", pending.Status)
+	assert.Equal(t, "bob", pending.ApprovedBy)
+}
+
+func TestTaskHandler_CreateFromTemplate(t *testing.T) {
+	db := setupTaskTestDB(t)
+	assert.NoError(t, db.AutoMigrate(&models.ScriptTemplate{}))
+	templates := service.NewTemplateService(db)
+	tpl, err := templates.Create(&service.TemplateSpec{
+		Name:   "backup",
+		Type:   "shell",
+		Script: "backup.sh {{quote .path}} --password {{quote .password}}",
+		Params: []models.TemplateParam{
+			{Name: "path", Type: models.ParamTypeString, Required: true},
+			{Name: "password", Type: models.ParamTypeSecret, Required: true},
+		},
+	})
+	assert.NoError(t, err)
+	handler := NewTaskHandler(db, service.NewTaskDispatcher(db, session.NewRegistry()), nil, templates, nil)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/tasks", handler.Create)
+
+	do := func(body CreateTaskRequest) Response {
+		data, _ := json.Marshal(body)
+		req := httptest.NewRequest("POST", "/tasks", bytes.NewBuffer(data))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var resp Response
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp
+	}
+
+	resp := do(CreateTaskRequest{
+		AgentID:    "agent-1",
+		TemplateID: tpl.ID,
+		Params:     map[string]interface{}{"path": "/data", "password": "hunter2"},
+		Env:        map[string]string{"BACKUP_KEY": "hunter2"},
+	})
+	assert.Equal(t, 0, resp.Code)
+
+	var task models.Task
+	assert.NoError(t, db.First(&task).Error)
+	assert.Equal(t, tpl.ID, task.TemplateID)
+	assert.Equal(t, "shell", task.Type)
+	assert.Equal(t, "backup.sh '/data' --password '"+service.SecretMask+"'", task.Script)
+	assert.NotContains(t, task.Params, "hunter2")
+	assert.NotContains(t, task.Env, "hunter2")
+
+	var secret models.TaskSecret
+	assert.NoError(t, db.Where("task_id = ?", task.TaskID).First(&secret).Error)
+	assert.Equal(t, "backup.sh '/data' --password 'hunter2'", secret.Script)
+
+	assert.Equal(t, 400, do(CreateTaskRequest{AgentID: "agent-1", TemplateID: tpl.ID}).Code)
+	assert.Equal(t, 404, do(CreateTaskRequest{AgentID: "agent-1", TemplateID: 99}).Code)
+	assert.Equal(t, 400, do(CreateTaskRequest{AgentID: "agent-1", Type: "shell"}).Code)
+	assert.Equal(t, 400, do(CreateTaskRequest{AgentID: "agent-1", Type: "shell", Script: "env", Env: map[string]string{"A=B": "x"}}).Code)
+}
+
+func TestTaskHandler_CreateFromScript(t *testing.T) {
+	db := setupTaskTestDB(t)
+	assert.NoError(t, db.AutoMigrate(&models.Script{}, &models.ScriptVersion{}))
+	scripts := service.NewScriptService(db)
+	script, err := scripts.Create(&service.ScriptSpec{Name: "uptime", Type: "shell", Script: "uptime"})
+	assert.NoError(t, err)
+	_, err = scripts.Update(script.ID, &service.ScriptSpec{Name: "uptime", Type: "python", Script: "import os; print(os.getloadavg())"})
+	assert.NoError(t, err)
+	handler := NewTaskHandler(db, service.NewTaskDispatcher(db, session.NewRegistry()), nil, nil, scripts)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/tasks", handler.Create)
+
+	do := func(body CreateTaskRequest) Response {
+		data, _ := json.Marshal(body)
+		req := httptest.NewRequest("POST", "/tasks", bytes.NewBuffer(data))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var resp Response
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp
+	}
+
+	assert.Equal(t, 0, do(CreateTaskRequest{AgentID: "agent-1", ScriptRef: fmt.Sprintf("%d@1", script.ID)}).Code)
+	assert.Equal(t, 0, do(CreateTaskRequest{AgentID: "agent-1", ScriptRef: fmt.Sprint(script.ID)}).Code)
+
+	var tasks []models.Task
+	db.Order("id").Find(&tasks)
+	assert.Len(t, tasks, 2)
+	assert.Equal(t, 1, tasks[0].ScriptVersion)
+	assert.Equal(t, "shell", tasks[0].Type)
+	assert.Equal(t, "uptime", tasks[0].Script)
+	assert.Equal(t, script.ID, tasks[1].ScriptID)
+	assert.Equal(t, 2, tasks[1].ScriptVersion)
+	assert.Equal(t, "python", tasks[1].Type)
+
+	assert.Equal(t, 404, do(CreateTaskRequest{AgentID: "agent-1", ScriptRef: fmt.Sprintf("%d@5", script.ID)}).Code)
+	assert.Equal(t, 400, do(CreateTaskRequest{AgentID: "agent-1", ScriptRef: "uptime"}).Code)
+	assert.Equal(t, 400, do(CreateTaskRequest{AgentID: "agent-1", ScriptRef: "1", TemplateID: 1}).Code)
+}
+
+func TestTaskHandler_CreateFileTask(t *testing.T) {
+	db := setupTaskTestDB(t)
+	handler := NewTaskHandler(db, service.NewTaskDispatcher(db, session.NewRegistry()), nil, nil, nil)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/tasks", handler.Create)
+
+	do := func(body string) Response {
+		req := httptest.NewRequest("POST", "/tasks", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var resp Response
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp
+	}
+
+	// file 字段为 base64 编码的文件内容
+	resp := do(`{"agent_id":"agent-1","type":"file","file":"IyEvYmluL3NoCmVjaG8gaGkK","file_name":"hi.sh","args":["-v"]}`)
+	assert.Equal(t, 0, resp.Code)
+
+	var task models.Task
+	assert.NoError(t, db.First(&task).Error)
+	assert.Equal(t, "#!/bin/sh\necho hi\n", string(task.File))
+	assert.Equal(t, "hi.sh", task.FileName)
+	assert.Equal(t, `["-v"]`, task.Args)
+	assert.Len(t, task.FileSHA256, 64)
+
+	assert.Equal(t, 400, do(`{"agent_id":"agent-1","type":"file"}`).Code)
+	assert.Equal(t, 400, do(`{"agent_id":"agent-1","type":"file","file":"aGk=","file_sha256":"00"}`).Code)
+}
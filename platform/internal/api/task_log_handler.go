@@ -0,0 +1,150 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/agent-platform/platform/internal/models"
+	"github.com/yourusername/agent-platform/platform/internal/service"
+	"gorm.io/gorm"
+)
+
+// sseKeepAlive SSE 心跳间隔，同时用于检查任务是否已结束
+const sseKeepAlive = 15 * time.Second
+
+type TaskLogHandler struct {
+	db       *gorm.DB
+	taskLogs *service.TaskLogService
+}
+
+func NewTaskLogHandler(db *gorm.DB, taskLogs *service.TaskLogService) *TaskLogHandler {
+	return &TaskLogHandler{db: db, taskLogs: taskLogs}
+}
+
+func (h *TaskLogHandler) List(c *gin.Context) {
+	task, ok := h.loadTask(c)
+	if !ok {
+		return
+	}
+
+	afterSeq, _ := strconv.ParseInt(c.Query("after_seq"), 10, 64)
+	logs, err := h.taskLogs.List(task.TaskID, afterSeq)
+	if err != nil {
+		Error(c, 500, err.Error())
+		return
+	}
+
+	Success(c, logs)
+}
+
+// Stream 以 Server-Sent Events 推送任务日志。
+// 先回放已保存的日志，再推送实时日志；断线重连时根据 Last-Event-ID 续传
+func (h *TaskLogHandler) Stream(c *gin.Context) {
+	task, ok := h.loadTask(c)
+	if !ok {
+		return
+	}
+
+	lastSeq, _ := strconv.ParseInt(c.GetHeader("Last-Event-ID"), 10, 64)
+	if lastSeq == 0 {
+		lastSeq, _ = strconv.ParseInt(c.Query("after_seq"), 10, 64)
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	// 先订阅再回放，避免两者之间产生的日志丢失
+	ch, unsubscribe := h.taskLogs.Subscribe(task.TaskID)
+	defer unsubscribe()
+
+	replay := func() error {
+		logs, err := h.taskLogs.List(task.TaskID, lastSeq)
+		if err != nil {
+			return err
+		}
+		for i := range logs {
+			writeLogEvent(c, &logs[i])
+			lastSeq = logs[i].Seq
+		}
+		c.Writer.Flush()
+		return nil
+	}
+
+	// finished 在任务已结束时补齐剩余日志并发送 end 事件
+	finished := func() bool {
+		var current models.Task
+		if err := h.db.Select("status").First(&current, task.ID).Error; err != nil {
+			return true
+		}
+		if !service.IsTaskFinished(current.Status) {
+			return false
+		}
+		replay()
+		fmt.Fprintf(c.Writer, "event: end\ndata: {\"status\":%q}\n\n", current.Status)
+		c.Writer.Flush()
+		return true
+	}
+
+	if err := replay(); err != nil {
+		return
+	}
+	if finished() {
+		return
+	}
+
+	ticker := time.NewTicker(sseKeepAlive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case entry, ok := <-ch:
+			if !ok {
+				// 任务结束或消费过慢，未结束时由客户端携带 Last-Event-ID 重连
+				finished()
+				return
+			}
+			if entry.Seq <= lastSeq {
+				continue
+			}
+			writeLogEvent(c, entry)
+			lastSeq = entry.Seq
+			c.Writer.Flush()
+		case <-ticker.C:
+			if finished() {
+				return
+			}
+			fmt.Fprint(c.Writer, ": ping\n\n")
+			c.Writer.Flush()
+		}
+	}
+}
+
+func (h *TaskLogHandler) loadTask(c *gin.Context) (*models.Task, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		Error(c, 400, "invalid task id")
+		return nil, false
+	}
+
+	var task models.Task
+	if err := h.db.First(&task, id).Error; err != nil {
+		Error(c, 404, "task not found")
+		return nil, false
+	}
+	if !authorizeAgent(c, h.db, task.AgentID) {
+		return nil, false
+	}
+	return &task, true
+}
+
+func writeLogEvent(c *gin.Context, entry *models.TaskLog) {
+	data, _ := json.Marshal(entry)
+	fmt.Fprintf(c.Writer, "id: %d\nevent: log\ndata: %s\n\n", entry.Seq, data)
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	pb "github.com/yourusername/agent-platform/proto"
+	"github.com/yourusername/agent-platform/platform/internal/models"
+	"github.com/yourusername/agent-platform/platform/internal/service"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTaskLogTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+
+	err = db.AutoMigrate(&models.Task{}, &models.TaskLog{})
+	assert.NoError(t, err)
+
+	return db
+}
+
+func TestTaskLogHandler_StreamFinishedTask(t *testing.T) {
+	db := setupTaskLogTestDB(t)
+	taskLogs := service.NewTaskLogService(db)
+	handler := NewTaskLogHandler(db, taskLogs)
+
+	db.Create(&models.Task{TaskID: "task-1", AgentID: "agent-1", Type: "shell", Status: "completed"})
+	taskLogs.Append(&pb.TaskLog{TaskId: "task-1", Seq: 1, Output: "line 1\n"})
+	taskLogs.Append(&pb.TaskLog{TaskId: "task-1", Seq: 2, Output: "line 2\n", IsStderr: true})
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/tasks/:id/logs/stream", handler.Stream)
+
+	req := httptest.NewRequest("GET", "/tasks/1/logs/stream", nil)
+	req.Header.Set("Last-Event-ID", "1")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	body := w.Body.String()
+	assert.NotContains(t, body, "line 1")
+	assert.Contains(t, body, "id: 2\nevent: log\n")
+	assert.Contains(t, body, `"stream":"stderr"`)
+	assert.True(t, strings.HasSuffix(body, "event: end\ndata: {\"status\":\"completed\"}\n\n"))
+}
+
+func TestTaskLogHandler_List(t *testing.T) {
+	db := setupTaskLogTestDB(t)
+	taskLogs := service.NewTaskLogService(db)
+	handler := NewTaskLogHandler(db, taskLogs)
+
+	db.Create(&models.Task{TaskID: "task-1", AgentID: "agent-1", Type: "shell", Status: "running"})
+	taskLogs.Append(&pb.TaskLog{TaskId: "task-1", Seq: 1, Output: "hello\n"})
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/tasks/:id/logs", handler.List)
+
+	req := httptest.NewRequest("GET", "/tasks/1/logs", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "hello")
+}
@@ -0,0 +1,131 @@
+package api
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/agent-platform/platform/internal/models"
+	"github.com/yourusername/agent-platform/platform/internal/service"
+	"gorm.io/gorm"
+)
+
+type TemplateHandler struct {
+	templates *service.TemplateService
+}
+
+func NewTemplateHandler(templates *service.TemplateService) *TemplateHandler {
+	return &TemplateHandler{templates: templates}
+}
+
+type TemplateRequest struct {
+	Name        string                 `json:"name" binding:"required"`
+	Description string                 `json:"description"`
+	Type        string                 `json:"type" binding:"required"`
+	Script      string                 `json:"script" binding:"required"`
+	Params      []models.TemplateParam `json:"params"`
+}
+
+func (h *TemplateHandler) List(c *gin.Context) {
+	templates, err := h.templates.List()
+	if err != nil {
+		Error(c, 500, err.Error())
+		return
+	}
+
+	Success(c, templates)
+}
+
+func (h *TemplateHandler) Get(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		Error(c, 400, "invalid template id")
+		return
+	}
+
+	tpl, err := h.templates.Get(uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			Error(c, 404, "template not found")
+			return
+		}
+		Error(c, 500, err.Error())
+		return
+	}
+
+	Success(c, tpl)
+}
+
+func (h *TemplateHandler) Create(c *gin.Context) {
+	var req TemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		Error(c, 400, err.Error())
+		return
+	}
+
+	tpl, err := h.templates.Create(&service.TemplateSpec{
+		Name:        req.Name,
+		Description: req.Description,
+		Type:        req.Type,
+		Script:      req.Script,
+		Params:      req.Params,
+		CreatedBy:   c.GetString("user_id"),
+	})
+	if err != nil {
+		Error(c, 400, err.Error())
+		return
+	}
+
+	Success(c, tpl)
+}
+
+func (h *TemplateHandler) Update(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		Error(c, 400, "invalid template id")
+		return
+	}
+
+	var req TemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		Error(c, 400, err.Error())
+		return
+	}
+
+	tpl, err := h.templates.Update(uint(id), &service.TemplateSpec{
+		Name:        req.Name,
+		Description: req.Description,
+		Type:        req.Type,
+		Script:      req.Script,
+		Params:      req.Params,
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			Error(c, 404, "template not found")
+			return
+		}
+		Error(c, 400, err.Error())
+		return
+	}
+
+	Success(c, tpl)
+}
+
+func (h *TemplateHandler) Delete(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		Error(c, 400, "invalid template id")
+		return
+	}
+
+	if err := h.templates.Delete(uint(id)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			Error(c, 404, "template not found")
+			return
+		}
+		Error(c, 500, err.Error())
+		return
+	}
+
+	Success(c, nil)
+}
@@ -0,0 +1,120 @@
+package api
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/agent-platform/platform/internal/models"
+	"github.com/yourusername/agent-platform/platform/internal/service"
+	"gorm.io/gorm"
+)
+
+type UserHandler struct {
+	db   *gorm.DB
+	auth *service.AuthService
+}
+
+func NewUserHandler(db *gorm.DB, auth *service.AuthService) *UserHandler {
+	return &UserHandler{db: db, auth: auth}
+}
+
+type CreateUserRequest struct {
+	Username string   `json:"username" binding:"required"`
+	Password string   `json:"password" binding:"required"`
+	Role     string   `json:"role" binding:"required"`
+	Groups   []string `json:"groups"`
+}
+
+type UpdateUserRequest struct {
+	Password *string   `json:"password"`
+	Role     *string   `json:"role"`
+	Groups   *[]string `json:"groups"`
+	Disabled *bool     `json:"disabled"`
+}
+
+func (h *UserHandler) List(c *gin.Context) {
+	var users []models.User
+	if err := h.db.Order("id").Find(&users).Error; err != nil {
+		Error(c, 500, err.Error())
+		return
+	}
+
+	Success(c, users)
+}
+
+func (h *UserHandler) Create(c *gin.Context) {
+	var req CreateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		Error(c, 400, err.Error())
+		return
+	}
+
+	user, err := h.auth.CreateUser(&service.UserSpec{
+		Username: req.Username,
+		Password: &req.Password,
+		Role:     &req.Role,
+		Groups:   &req.Groups,
+	})
+	if err != nil {
+		Error(c, 400, err.Error())
+		return
+	}
+
+	Success(c, user)
+}
+
+// Update 修改用户角色、分组、密码或禁用状态，未提供的字段保持不变
+func (h *UserHandler) Update(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		Error(c, 400, "invalid user id")
+		return
+	}
+
+	var req UpdateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		Error(c, 400, err.Error())
+		return
+	}
+
+	user, err := h.auth.UpdateUser(uint(id), &service.UserSpec{
+		Password: req.Password,
+		Role:     req.Role,
+		Groups:   req.Groups,
+		Disabled: req.Disabled,
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			Error(c, 404, "user not found")
+			return
+		}
+		Error(c, 400, err.Error())
+		return
+	}
+
+	Success(c, user)
+}
+
+func (h *UserHandler) Delete(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		Error(c, 400, "invalid user id")
+		return
+	}
+	if uint(id) == currentPrincipal(c).UserID {
+		Error(c, 400, "cannot delete the current user")
+		return
+	}
+
+	if err := h.auth.DeleteUser(uint(id)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			Error(c, 404, "user not found")
+			return
+		}
+		Error(c, 500, err.Error())
+		return
+	}
+
+	Success(c, nil)
+}
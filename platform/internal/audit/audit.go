@@ -1,20 +1,109 @@
 package audit
 
 import (
+	"crypto/ed25519"
+	"sync"
+	"time"
+
 	"github.com/yourusername/agent-platform/platform/internal/models"
 	"gorm.io/gorm"
 )
 
+const (
+	// DefaultPageSize 查询审计日志的默认分页大小
+	DefaultPageSize = 50
+	// MaxPageSize 查询审计日志的最大分页大小
+	MaxPageSize = 500
+
+	exportBatchSize = 500
+)
+
+// Filter 审计日志查询条件，零值字段不参与过滤
+type Filter struct {
+	UserID   string
+	Action   string
+	Since    time.Time
+	Until    time.Time
+	Page     int // 从 1 开始
+	PageSize int
+}
+
 type Service struct {
 	db *gorm.DB
+
+	// mu 串行化写入，保证哈希链按写入顺序连接
+	mu sync.Mutex
+
+	signingKey ed25519.PrivateKey
+	stopCh     chan struct{}
 }
 
 func NewService(db *gorm.DB) *Service {
 	return &Service{db: db}
 }
 
+// Log 写入审计日志，并将其链接到上一条日志的哈希之后
 func (s *Service) Log(log *models.AuditLog) error {
-	return s.db.Create(log).Error
+	if log.CreatedAt.IsZero() {
+		log.CreatedAt = time.Now()
+	}
+	// 数据库只保存到微秒，截断后写入与读回的内容一致
+	log.CreatedAt = log.CreatedAt.Truncate(time.Microsecond)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var last models.AuditLog
+		if err := tx.Select("hash").Order("id DESC").Limit(1).Find(&last).Error; err != nil {
+			return err
+		}
+		log.PrevHash = last.Hash
+		log.Hash = entryHash(log)
+		if err := tx.Create(log).Error; err != nil {
+			return err
+		}
+		// 第一条带哈希的日志即为哈希链的起点
+		if last.Hash == "" {
+			return ensureChainStart(tx, log.ID)
+		}
+		return nil
+	})
+}
+
+// InitChain 记录哈希链的起点，应在启动时调用：已有带哈希的日志时以其中第一条为起点，
+// 否则已有日志均视为启用哈希链之前写入。已记录起点时不做修改
+func (s *Service) InitChain() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var first models.AuditLog
+		result := tx.Select("id").Where("hash <> ''").Order("id").Limit(1).Find(&first)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected > 0 {
+			return ensureChainStart(tx, first.ID)
+		}
+
+		var maxID uint
+		if err := tx.Model(&models.AuditLog{}).Select("COALESCE(MAX(id), 0)").Scan(&maxID).Error; err != nil {
+			return err
+		}
+		return ensureChainStart(tx, maxID+1)
+	})
+}
+
+func ensureChainStart(tx *gorm.DB, firstID uint) error {
+	var count int64
+	if err := tx.Model(&models.AuditChainStart{}).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	return tx.Create(&models.AuditChainStart{FirstID: firstID}).Error
 }
 
 func (s *Service) Query(userID, action string, limit int) ([]models.AuditLog, error) {
@@ -34,3 +123,65 @@ func (s *Service) Query(userID, action string, limit int) ([]models.AuditLog, er
 	err := query.Find(&logs).Error
 	return logs, err
 }
+
+// Search 按条件分页查询审计日志，按时间倒序，同时返回符合条件的总数。
+// filter 中的分页参数会被修正为实际使用的值
+func (s *Service) Search(filter *Filter) ([]models.AuditLog, int64, error) {
+	query := s.filtered(filter)
+
+	var total int64
+	if err := query.Model(&models.AuditLog{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.PageSize <= 0 {
+		filter.PageSize = DefaultPageSize
+	}
+	if filter.PageSize > MaxPageSize {
+		filter.PageSize = MaxPageSize
+	}
+
+	var logs []models.AuditLog
+	err := query.Order("created_at DESC, id DESC").
+		Offset((filter.Page - 1) * filter.PageSize).Limit(filter.PageSize).
+		Find(&logs).Error
+	return logs, total, err
+}
+
+// Export 按时间顺序分批读取符合条件的全部审计日志，忽略分页参数
+func (s *Service) Export(filter *Filter, fn func(log *models.AuditLog) error) error {
+	var logs []models.AuditLog
+	var fnErr error
+	err := s.filtered(filter).Order("id").FindInBatches(&logs, exportBatchSize, func(tx *gorm.DB, batch int) error {
+		for i := range logs {
+			if fnErr = fn(&logs[i]); fnErr != nil {
+				return fnErr
+			}
+		}
+		return nil
+	}).Error
+	if fnErr != nil {
+		return fnErr
+	}
+	return err
+}
+
+func (s *Service) filtered(filter *Filter) *gorm.DB {
+	query := s.db
+	if filter.UserID != "" {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if !filter.Since.IsZero() {
+		query = query.Where("created_at >= ?", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		query = query.Where("created_at <= ?", filter.Until)
+	}
+	return query
+}
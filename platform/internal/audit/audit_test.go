@@ -1,6 +1,7 @@
 package audit
 
 import (
+	"encoding/json"
 	"testing"
 	"time"
 
@@ -14,7 +15,7 @@ func setupTestDB(t *testing.T) *gorm.DB {
 	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
 	assert.NoError(t, err)
 
-	err = db.AutoMigrate(&models.AuditLog{})
+	err = db.AutoMigrate(&models.AuditLog{}, &models.AuditChainStart{}, &models.AuditCheckpoint{})
 	assert.NoError(t, err)
 
 	return db
@@ -68,3 +69,52 @@ func TestService_Query(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, 2, len(result))
 }
+
+func TestService_SearchAndExport(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewService(db)
+
+	base := time.Now().Add(-time.Hour)
+	for i := 0; i < 5; i++ {
+		db.Create(&models.AuditLog{UserID: "alice", Action: "POST /api/v1/tasks", CreatedAt: base.Add(time.Duration(i) * time.Minute)})
+	}
+	db.Create(&models.AuditLog{UserID: "bob", Action: "DELETE /api/v1/agents/:id", CreatedAt: base})
+
+	filter := &Filter{UserID: "alice", PageSize: 2, Page: 2}
+	logs, total, err := service.Search(filter)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), total)
+	assert.Len(t, logs, 2)
+	// 按时间倒序，第二页为第 3、4 条
+	assert.True(t, logs[0].CreatedAt.Equal(base.Add(2*time.Minute)))
+
+	logs, total, err = service.Search(&Filter{Since: base.Add(3 * time.Minute)})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+	assert.Len(t, logs, 2)
+
+	var exported []string
+	err = service.Export(&Filter{Action: "DELETE /api/v1/agents/:id"}, func(log *models.AuditLog) error {
+		exported = append(exported, log.UserID)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"bob"}, exported)
+}
+
+func TestSummarize(t *testing.T) {
+	summary := Summarize([]byte(`{"agent_ids":["a","b"],"script":"echo hi","password":"p","config":{"api_key":"x"},"timeout":30}`))
+
+	var fields map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(summary), &fields))
+	assert.Equal(t, []interface{}{"a", "b"}, fields["agent_ids"])
+	assert.Len(t, fields["script_sha256"], 64)
+	assert.Equal(t, float64(7), fields["script_bytes"])
+	assert.NotContains(t, summary, "echo hi")
+	assert.Equal(t, "[REDACTED]", fields["password"])
+	assert.NotContains(t, summary, `"x"`)
+	assert.Equal(t, float64(30), fields["timeout"])
+
+	assert.Equal(t, "", Summarize(nil))
+	assert.Equal(t, `{"body_bytes":3}`, Summarize([]byte("abc")))
+}
@@ -0,0 +1,243 @@
+package audit
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/yourusername/agent-platform/platform/internal/models"
+	"gorm.io/gorm"
+)
+
+// DefaultCheckpointInterval 未配置时生成签名检查点的间隔
+const DefaultCheckpointInterval = time.Hour
+
+var errChainBroken = errors.New("audit chain broken")
+
+// VerifyResult 审计日志哈希链的校验结果
+type VerifyResult struct {
+	Valid       bool   `json:"valid"`
+	Checked     int64  `json:"checked"`     // 校验的日志条数
+	Unchained   int64  `json:"unchained"`   // 启用哈希链之前写入、无法校验的日志条数
+	Checkpoints int    `json:"checkpoints"` // 校验的检查点个数
+	BrokenID    uint   `json:"broken_id,omitempty"`
+	Reason      string `json:"reason,omitempty"`
+	PublicKey   string `json:"public_key,omitempty"` // 检查点签名公钥（base64），供外部独立校验
+}
+
+// entryHash 计算日志内容与上一条哈希的 SHA-256，字段顺序固定
+func entryHash(entry *models.AuditLog) string {
+	content, _ := json.Marshal(struct {
+		PrevHash  string `json:"prev_hash"`
+		UserID    string `json:"user_id"`
+		Action    string `json:"action"`
+		Resource  string `json:"resource"`
+		Details   string `json:"details"`
+		IP        string `json:"ip"`
+		UserAgent string `json:"user_agent"`
+		Status    string `json:"status"`
+		CreatedAt string `json:"created_at"`
+	}{
+		PrevHash:  entry.PrevHash,
+		UserID:    entry.UserID,
+		Action:    entry.Action,
+		Resource:  entry.Resource,
+		Details:   entry.Details,
+		IP:        entry.IP,
+		UserAgent: entry.UserAgent,
+		Status:    entry.Status,
+		CreatedAt: entry.CreatedAt.UTC().Format(time.RFC3339Nano),
+	})
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// Verify 按写入顺序遍历审计日志，校验每条日志的哈希及其与上一条的链接，
+// 再校验检查点的签名以及检查点记录的链末端是否仍然存在，报告第一处断链
+func (s *Service) Verify() (*VerifyResult, error) {
+	result := &VerifyResult{Valid: true}
+	if s.signingKey != nil {
+		result.PublicKey = base64.StdEncoding.EncodeToString(s.signingKey.Public().(ed25519.PublicKey))
+	}
+
+	var start models.AuditChainStart
+	found := s.db.Limit(1).Find(&start)
+	if found.Error != nil {
+		return nil, found.Error
+	}
+
+	prevHash := ""
+	var logs []models.AuditLog
+	err := s.db.Order("id").FindInBatches(&logs, exportBatchSize, func(tx *gorm.DB, batch int) error {
+		for i := range logs {
+			entry := &logs[i]
+			if found.RowsAffected == 0 {
+				result.fail(entry.ID, "chain start marker is missing")
+				return errChainBroken
+			}
+			// 启用哈希链之前的日志没有哈希，只允许出现在起点之前
+			if entry.ID < start.FirstID {
+				if entry.Hash != "" {
+					result.fail(entry.ID, "entry before the chain start has a hash, the chain start marker was modified")
+					return errChainBroken
+				}
+				result.Unchained++
+				continue
+			}
+			result.Checked++
+
+			switch {
+			case entry.Hash == "":
+				result.fail(entry.ID, "hash missing, the entry was rewritten after the chain started")
+			case entry.PrevHash != prevHash:
+				result.fail(entry.ID, "previous hash mismatch, an earlier entry was deleted or reordered")
+			case entry.Hash != entryHash(entry):
+				result.fail(entry.ID, "content hash mismatch, the entry was modified")
+			}
+			if !result.Valid {
+				return errChainBroken
+			}
+			prevHash = entry.Hash
+		}
+		return nil
+	}).Error
+	if err != nil && !errors.Is(err, errChainBroken) {
+		return nil, err
+	}
+	if !result.Valid {
+		return result, nil
+	}
+
+	if err := s.verifyCheckpoints(result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (s *Service) verifyCheckpoints(result *VerifyResult) error {
+	var checkpoints []models.AuditCheckpoint
+	if err := s.db.Order("id").Find(&checkpoints).Error; err != nil {
+		return err
+	}
+
+	for _, cp := range checkpoints {
+		result.Checkpoints++
+
+		if s.signingKey != nil {
+			signature, err := base64.StdEncoding.DecodeString(cp.Signature)
+			if err != nil || !ed25519.Verify(s.signingKey.Public().(ed25519.PublicKey), checkpointMessage(&cp), signature) {
+				result.fail(cp.LastID, fmt.Sprintf("checkpoint %d has an invalid signature", cp.ID))
+				return nil
+			}
+		}
+
+		var entry models.AuditLog
+		found := s.db.Limit(1).Find(&entry, cp.LastID)
+		if found.Error != nil {
+			return found.Error
+		}
+		if found.RowsAffected == 0 || entry.Hash != cp.LastHash {
+			result.fail(cp.LastID, fmt.Sprintf("entry covered by checkpoint %d was deleted or rewritten", cp.ID))
+			return nil
+		}
+
+		var count int64
+		if err := s.db.Model(&models.AuditLog{}).Where("id <= ?", cp.LastID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count != cp.Count {
+			result.fail(cp.LastID, fmt.Sprintf("checkpoint %d expected %d entries, found %d", cp.ID, cp.Count, count))
+			return nil
+		}
+	}
+	return nil
+}
+
+func (r *VerifyResult) fail(id uint, reason string) {
+	r.Valid = false
+	r.BrokenID = id
+	r.Reason = reason
+}
+
+// StartCheckpoints 按 interval 为哈希链的末端生成 Ed25519 签名检查点，没有新日志时跳过
+func (s *Service) StartCheckpoints(key ed25519.PrivateKey, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultCheckpointInterval
+	}
+	s.signingKey = key
+	s.stopCh = make(chan struct{})
+
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := s.Checkpoint(); err != nil {
+					log.Printf("Failed to write audit checkpoint: %v", err)
+				}
+			case <-s.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+func (s *Service) Stop() {
+	if s.stopCh != nil {
+		close(s.stopCh)
+	}
+}
+
+// Checkpoint 为当前链末端生成签名检查点
+func (s *Service) Checkpoint() error {
+	if s.signingKey == nil {
+		return errors.New("checkpoint signing key not configured")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var last models.AuditLog
+	result := s.db.Where("hash <> ''").Order("id DESC").Limit(1).Find(&last)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil
+	}
+
+	var previous models.AuditCheckpoint
+	if err := s.db.Order("id DESC").Limit(1).Find(&previous).Error; err != nil {
+		return err
+	}
+	if previous.LastID == last.ID {
+		return nil
+	}
+
+	var count int64
+	if err := s.db.Model(&models.AuditLog{}).Where("id <= ?", last.ID).Count(&count).Error; err != nil {
+		return err
+	}
+
+	cp := &models.AuditCheckpoint{
+		LastID:    last.ID,
+		LastHash:  last.Hash,
+		Count:     count,
+		CreatedAt: time.Now().Truncate(time.Microsecond),
+	}
+	cp.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(s.signingKey, checkpointMessage(cp)))
+	return s.db.Create(cp).Error
+}
+
+// checkpointMessage 检查点的签名内容
+func checkpointMessage(cp *models.AuditCheckpoint) []byte {
+	return []byte(fmt.Sprintf("audit-checkpoint:%d:%s:%d:%s",
+		cp.LastID, cp.LastHash, cp.Count, cp.CreatedAt.UTC().Format(time.RFC3339Nano)))
+}
@@ -0,0 +1,144 @@
+package audit
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/agent-platform/platform/internal/models"
+	"gorm.io/gorm"
+)
+
+func setupChain(t *testing.T, n int) (*gorm.DB, *Service) {
+	db := setupTestDB(t)
+	service := NewService(db)
+	for i := 0; i < n; i++ {
+		assert.NoError(t, service.Log(&models.AuditLog{UserID: "alice", Action: "POST /api/v1/tasks", Status: "200"}))
+	}
+	return db, service
+}
+
+func TestVerify_DetectsTampering(t *testing.T) {
+	db, service := setupChain(t, 5)
+
+	result, err := service.Verify()
+	assert.NoError(t, err)
+	assert.True(t, result.Valid)
+	assert.Equal(t, int64(5), result.Checked)
+
+	// 修改内容
+	db.Model(&models.AuditLog{}).Where("id = ?", 3).Update("user_id", "mallory")
+	result, err = service.Verify()
+	assert.NoError(t, err)
+	assert.False(t, result.Valid)
+	assert.Equal(t, uint(3), result.BrokenID)
+
+	// 删除中间的日志
+	db, service = setupChain(t, 5)
+	db.Delete(&models.AuditLog{}, 2)
+	result, err = service.Verify()
+	assert.NoError(t, err)
+	assert.False(t, result.Valid)
+	assert.Equal(t, uint(3), result.BrokenID)
+}
+
+func TestVerify_LegacyEntries(t *testing.T) {
+	db := setupTestDB(t)
+	db.Create(&models.AuditLog{UserID: "legacy", Action: "GET /"})
+	service := NewService(db)
+	assert.NoError(t, service.Log(&models.AuditLog{UserID: "alice"}))
+
+	result, err := service.Verify()
+	assert.NoError(t, err)
+	assert.True(t, result.Valid)
+	assert.Equal(t, int64(1), result.Unchained)
+	assert.Equal(t, int64(1), result.Checked)
+}
+
+func TestVerify_BlankedHashes(t *testing.T) {
+	// 清空全部日志的哈希，不能伪装成启用哈希链之前的日志
+	db, service := setupChain(t, 3)
+	db.Model(&models.AuditLog{}).Where("1 = 1").Updates(map[string]interface{}{"hash": "", "prev_hash": ""})
+	result, err := service.Verify()
+	assert.NoError(t, err)
+	assert.False(t, result.Valid)
+	assert.Equal(t, uint(1), result.BrokenID)
+
+	// 同时删除起点记录
+	db.Where("1 = 1").Delete(&models.AuditChainStart{})
+	result, err = service.Verify()
+	assert.NoError(t, err)
+	assert.False(t, result.Valid)
+	assert.Contains(t, result.Reason, "marker is missing")
+
+	// 将起点移到末尾之后
+	db, service = setupChain(t, 3)
+	db.Model(&models.AuditChainStart{}).Where("1 = 1").Update("first_id", 10)
+	result, err = service.Verify()
+	assert.NoError(t, err)
+	assert.False(t, result.Valid)
+	assert.Equal(t, uint(1), result.BrokenID)
+}
+
+func TestInitChain(t *testing.T) {
+	db := setupTestDB(t)
+	db.Create(&models.AuditLog{UserID: "legacy", Action: "GET /"})
+	db.Create(&models.AuditLog{UserID: "legacy", Action: "GET /"})
+	service := NewService(db)
+
+	// 启动时记录起点，尚未写入新日志也能校验
+	assert.NoError(t, service.InitChain())
+	result, err := service.Verify()
+	assert.NoError(t, err)
+	assert.True(t, result.Valid)
+	assert.Equal(t, int64(2), result.Unchained)
+
+	assert.NoError(t, service.Log(&models.AuditLog{UserID: "alice"}))
+	assert.NoError(t, service.InitChain())
+	var start models.AuditChainStart
+	db.First(&start)
+	assert.Equal(t, uint(3), start.FirstID)
+
+	result, err = service.Verify()
+	assert.NoError(t, err)
+	assert.True(t, result.Valid)
+	assert.Equal(t, int64(2), result.Unchained)
+	assert.Equal(t, int64(1), result.Checked)
+}
+
+func TestCheckpoint_DetectsTruncation(t *testing.T) {
+	db, service := setupChain(t, 3)
+	_, key, _ := ed25519.GenerateKey(rand.Reader)
+	service.signingKey = key
+
+	assert.NoError(t, service.Checkpoint())
+	// 没有新日志时不重复生成
+	assert.NoError(t, service.Checkpoint())
+	var count int64
+	db.Model(&models.AuditCheckpoint{}).Count(&count)
+	assert.Equal(t, int64(1), count)
+
+	result, err := service.Verify()
+	assert.NoError(t, err)
+	assert.True(t, result.Valid)
+	assert.Equal(t, 1, result.Checkpoints)
+	assert.NotEmpty(t, result.PublicKey)
+
+	// 删除末尾日志，哈希链本身仍然完整，但与检查点不符
+	db.Delete(&models.AuditLog{}, 3)
+	result, err = service.Verify()
+	assert.NoError(t, err)
+	assert.False(t, result.Valid)
+	assert.Equal(t, uint(3), result.BrokenID)
+
+	// 伪造检查点
+	db, service = setupChain(t, 2)
+	service.signingKey = key
+	assert.NoError(t, service.Checkpoint())
+	db.Model(&models.AuditCheckpoint{}).Where("id = ?", 1).Update("count", 1)
+	result, err = service.Verify()
+	assert.NoError(t, err)
+	assert.False(t, result.Valid)
+	assert.Contains(t, result.Reason, "invalid signature")
+}
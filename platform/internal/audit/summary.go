@@ -0,0 +1,96 @@
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+const (
+	// maxSummaryString 摘要中单个字符串的最大长度
+	maxSummaryString = 256
+	// maxSummaryItems 摘要中数组保留的最大元素数
+	maxSummaryItems = 100
+
+	redacted = "[REDACTED]"
+)
+
+// sensitiveKeys 字段名包含这些词时不记录其值
+var sensitiveKeys = []string{"password", "token", "secret", "credential", "key"}
+
+// Summarize 生成请求体的脱敏摘要，写入审计日志的 Details：
+// 脚本只记录 SHA-256 与长度，敏感字段的值被替换，嵌套对象只记录键名，
+// 目标 Agent 等其余字段原样保留（过长时截断）。非 JSON 请求体只记录长度
+func Summarize(body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(body, &fields); err != nil {
+		data, _ := json.Marshal(map[string]interface{}{"body_bytes": len(body)})
+		return string(data)
+	}
+
+	summary := make(map[string]interface{}, len(fields))
+	for key, value := range fields {
+		switch {
+		case key == "script":
+			script, _ := value.(string)
+			sum := sha256.Sum256([]byte(script))
+			summary["script_sha256"] = hex.EncodeToString(sum[:])
+			summary["script_bytes"] = len(script)
+		case isSensitive(key):
+			summary[key] = redacted
+		default:
+			summary[key] = summarizeValue(value)
+		}
+	}
+
+	data, _ := json.Marshal(summary)
+	return string(data)
+}
+
+func summarizeValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		if len(v) > maxSummaryString {
+			return v[:maxSummaryString] + "..."
+		}
+		return v
+	case []interface{}:
+		if len(v) > maxSummaryItems {
+			v = v[:maxSummaryItems]
+		}
+		items := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			if _, ok := item.(map[string]interface{}); ok {
+				item = redacted
+			}
+			items = append(items, summarizeValue(item))
+		}
+		return items
+	case map[string]interface{}:
+		// 嵌套对象（如插件配置、环境变量）可能包含敏感值，只记录键名
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		return map[string]interface{}{"keys": keys}
+	default:
+		return v
+	}
+}
+
+func isSensitive(key string) bool {
+	key = strings.ToLower(key)
+	for _, word := range sensitiveKeys {
+		if strings.Contains(key, word) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,30 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/yourusername/agent-platform/platform/internal/models"
+)
+
+// ActionTaskRejected 任务被脚本策略拒绝
+const ActionTaskRejected = "task.rejected"
+
+// LogTaskRejected 记录任务被平台或 Agent 的脚本策略拒绝，UserID 为任务创建者，
+// 与请求审计一样只记录脚本的摘要
+func (s *Service) LogTaskRejected(task *models.Task) error {
+	body, _ := json.Marshal(map[string]interface{}{
+		"task_id":  task.TaskID,
+		"agent_id": task.AgentID,
+		"type":     task.Type,
+		"script":   task.Script,
+		"reason":   task.Stderr,
+	})
+	return s.Log(&models.AuditLog{
+		UserID:   task.CreatedBy,
+		Action:   ActionTaskRejected,
+		Resource: fmt.Sprintf("/api/v1/tasks/%d", task.ID),
+		Details:  Summarize(body),
+		Status:   "rejected",
+	})
+}
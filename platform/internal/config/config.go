@@ -7,15 +7,31 @@ import (
 )
 
 type Config struct {
-	Server   ServerConfig   `yaml:"server"`
-	Database DatabaseConfig `yaml:"database"`
-	Redis    RedisConfig    `yaml:"redis"`
-	Log      LogConfig      `yaml:"log"`
+	Server     ServerConfig     `yaml:"server"`
+	Database   DatabaseConfig   `yaml:"database"`
+	Redis      RedisConfig      `yaml:"redis"`
+	Heartbeat  HeartbeatConfig  `yaml:"heartbeat"`
+	Enrollment EnrollmentConfig `yaml:"enrollment"`
+	Auth       AuthConfig       `yaml:"auth"`
+	Audit      AuditConfig      `yaml:"audit"`
+	Policy     PolicyConfig     `yaml:"policy"`
+	TaskSign   TaskSignConfig   `yaml:"task_signing"`
+	Plugins    PluginsConfig    `yaml:"plugins"`
+	Log        LogConfig        `yaml:"log"`
 }
 
 type ServerConfig struct {
-	GRPCPort string `yaml:"grpc_port"`
-	HTTPPort string `yaml:"http_port"`
+	GRPCPort string    `yaml:"grpc_port"`
+	HTTPPort string    `yaml:"http_port"`
+	TLS      TLSConfig `yaml:"tls"`
+}
+
+// TLSConfig gRPC 服务的 TLS 配置。配置 client_ca_file 后要求 Agent 提供由该 CA 签发的证书（mTLS），
+// 并以证书 CN/SAN 作为 Agent 身份。证书文件更新后自动重新加载
+type TLSConfig struct {
+	CertFile     string `yaml:"cert_file"`
+	KeyFile      string `yaml:"key_file"`
+	ClientCAFile string `yaml:"client_ca_file"`
 }
 
 type DatabaseConfig struct {
@@ -33,6 +49,61 @@ type RedisConfig struct {
 	DB       int    `yaml:"db"`
 }
 
+// HeartbeatConfig Agent 存活检测配置
+type HeartbeatConfig struct {
+	Interval  int `yaml:"interval"`   // Agent 心跳间隔（秒）
+	MaxMissed int `yaml:"max_missed"` // 连续丢失多少次心跳后判定离线
+}
+
+// EnrollmentConfig Agent 登记配置
+type EnrollmentConfig struct {
+	// AllowUnenrolled 允许未携带凭据的 Agent 注册，仅用于迁移尚未登记的已有 Agent
+	AllowUnenrolled bool `yaml:"allow_unenrolled"`
+}
+
+// AuthConfig REST API 认证配置
+type AuthConfig struct {
+	SessionTTL int `yaml:"session_ttl"` // 登录令牌有效期（秒），默认 12 小时
+	// 没有任何用户时创建的初始管理员，未配置密码时生成随机密码并写入 admin_password_file
+	AdminUsername     string `yaml:"admin_username"`
+	AdminPassword     string `yaml:"admin_password"`
+	AdminPasswordFile string `yaml:"admin_password_file"` // 权限 0600，默认 data/admin-password
+
+}
+
+// AuditConfig 审计日志配置
+type AuditConfig struct {
+	// SigningKeyFile 签名检查点使用的 Ed25519 私钥（PKCS#8 PEM），为空时不生成检查点
+	SigningKeyFile     string `yaml:"signing_key_file"`
+	CheckpointInterval int    `yaml:"checkpoint_interval"` // 生成检查点的间隔（秒），默认 3600
+}
+
+// PolicyConfig 脚本策略配置。default 对所有 Agent 生效，groups 按 Agent 分组追加规则：
+// 禁止规则取并集，超时上限取较小值，任一要求审批即需审批，分组配置了 allowed_types 时以分组为准
+type PolicyConfig struct {
+	Default PolicyRules            `yaml:"default"`
+	Groups  map[string]PolicyRules `yaml:"groups"`
+}
+
+type PolicyRules struct {
+	DenyPatterns    []string `yaml:"deny_patterns"`    // 脚本匹配任一正则时拒绝
+	AllowedTypes    []string `yaml:"allowed_types"`    // 允许的脚本类型，为空时不限制；file 类型必须显式列出
+	MaxTimeout      int      `yaml:"max_timeout"`      // 允许的最大超时时间（秒），0 表示不限制
+	RequireApproval bool     `yaml:"require_approval"` // 任务需经其他用户审批后才下发
+}
+
+// TaskSignConfig 任务签名配置，Agent 使用对应公钥验签
+type TaskSignConfig struct {
+	// KeyFile 任务签名使用的 Ed25519 私钥（PKCS#8 PEM），为空时下发未签名的任务
+	KeyFile string `yaml:"key_file"`
+	TTL     int    `yaml:"ttl"` // 签名有效期（秒），默认 300
+}
+
+// PluginsConfig 插件仓库配置
+type PluginsConfig struct {
+	Dir string `yaml:"dir"` // 插件安装包的存放目录，默认 data/plugins
+}
+
 type LogConfig struct {
 	Level  string `yaml:"level"`
 	Format string `yaml:"format"`
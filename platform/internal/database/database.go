@@ -26,7 +26,7 @@ func Connect(cfg *Config) (*gorm.DB, error) {
 	}
 
 	// 自动迁移
-	if err := db.AutoMigrate(&models.Agent{}, &models.Task{}, &models.Metric{}, &models.AuditLog{}); err != nil {
+	if err := db.AutoMigrate(&models.Agent{}, &models.AgentEvent{}, &models.Task{}, &models.TaskLog{}, &models.Job{}, &models.Metric{}, &models.AuditLog{}, &models.AuditChainStart{}, &models.AuditCheckpoint{}, &models.EnrollmentToken{}, &models.User{}, &models.APIToken{}, &models.ScriptTemplate{}, &models.TaskSecret{}, &models.Script{}, &models.ScriptVersion{}, &models.PluginPackage{}); err != nil {
 		return nil, fmt.Errorf("failed to migrate: %w", err)
 	}
 
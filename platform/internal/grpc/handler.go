@@ -1,26 +1,86 @@
 package grpc
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"io"
 	"log"
+	"net"
 
 	pb "github.com/yourusername/agent-platform/proto"
-	"github.com/yourusername/agent-platform/platform/internal/models"
+	"github.com/yourusername/agent-platform/platform/internal/service"
+	"github.com/yourusername/agent-platform/platform/internal/session"
+	"github.com/yourusername/agent-platform/pkg/tlsutil"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
 	"gorm.io/gorm"
 )
 
 type AgentServiceHandler struct {
 	pb.UnimplementedAgentServiceServer
-	db *gorm.DB
+	db         *gorm.DB
+	sessions   *session.Registry
+	dispatcher *service.TaskDispatcher
+	taskLogs   *service.TaskLogService
+	metrics    *service.MetricService
+	agents     *service.AgentService
+	enrollment *service.EnrollmentService
 }
 
-func NewAgentServiceHandler(db *gorm.DB) *AgentServiceHandler {
+func NewAgentServiceHandler(db *gorm.DB, sessions *session.Registry, dispatcher *service.TaskDispatcher, taskLogs *service.TaskLogService, metrics *service.MetricService, agents *service.AgentService, enrollment *service.EnrollmentService) *AgentServiceHandler {
 	return &AgentServiceHandler{
-		db: db,
+		db:         db,
+		sessions:   sessions,
+		dispatcher: dispatcher,
+		taskLogs:   taskLogs,
+		metrics:    metrics,
+		agents:     agents,
+		enrollment: enrollment,
 	}
 }
 
+// Enroll 使用一次性引导令牌登记 Agent 并签发凭据
+func (h *AgentServiceHandler) Enroll(ctx context.Context, req *pb.EnrollRequest) (*pb.EnrollResponse, error) {
+	if req.AgentId == "" || req.BootstrapToken == "" {
+		return nil, status.Error(codes.InvalidArgument, "agent_id and bootstrap_token are required")
+	}
+	if ids := certIdentities(ctx); ids != nil && !contains(ids, req.AgentId) {
+		return nil, status.Error(codes.PermissionDenied, "agent_id does not match client certificate")
+	}
+
+	credential, err := h.enrollment.Enroll(req.AgentId, req.BootstrapToken)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidBootstrapToken) || errors.Is(err, service.ErrAgentAlreadyEnrolled) {
+			return nil, status.Error(codes.PermissionDenied, err.Error())
+		}
+		log.Printf("Failed to enroll agent %s: %v", req.AgentId, err)
+		return nil, status.Error(codes.Internal, "failed to enroll agent")
+	}
+	return &pb.EnrollResponse{Credential: credential}, nil
+}
+
 func (h *AgentServiceHandler) Connect(stream pb.AgentService_ConnectServer) error {
+	sess := session.NewSession(stream)
+	defer h.sessions.Remove(sess)
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- h.receive(stream, sess)
+	}()
+
+	// 会话被替换或被主动断开时结束处理函数，从而关闭底层流
+	select {
+	case err := <-errCh:
+		return err
+	case <-sess.Done():
+		return status.Error(codes.Aborted, "session closed by server")
+	}
+}
+
+func (h *AgentServiceHandler) receive(stream pb.AgentService_ConnectServer, sess *session.Session) error {
 	for {
 		msg, err := stream.Recv()
 		if err == io.EOF {
@@ -32,21 +92,33 @@ func (h *AgentServiceHandler) Connect(stream pb.AgentService_ConnectServer) erro
 
 		switch m := msg.Message.(type) {
 		case *pb.AgentMessage_Register:
-			if err := h.handleRegister(stream, m.Register); err != nil {
+			if err := h.handleRegister(stream, sess, m.Register); err != nil {
 				log.Printf("Error handling register: %v", err)
 			}
 		case *pb.AgentMessage_Heartbeat:
-			if err := h.handleHeartbeat(stream, m.Heartbeat); err != nil {
+			if err := h.handleHeartbeat(sess, m.Heartbeat); err != nil {
 				log.Printf("Error handling heartbeat: %v", err)
 			}
 		case *pb.AgentMessage_TaskResult:
-			if err := h.handleTaskResult(stream, m.TaskResult); err != nil {
+			if err := h.handleTaskResult(sess, m.TaskResult); err != nil {
 				log.Printf("Error handling task result: %v", err)
 			}
+		case *pb.AgentMessage_TaskAck:
+			if err := h.handleTaskAck(sess, m.TaskAck); err != nil {
+				log.Printf("Error handling task ack: %v", err)
+			}
 		case *pb.AgentMessage_TaskLog:
-			if err := h.handleTaskLog(stream, m.TaskLog); err != nil {
+			if err := h.handleTaskLog(sess, m.TaskLog); err != nil {
 				log.Printf("Error handling task log: %v", err)
 			}
+		case *pb.AgentMessage_MetricBatch:
+			if err := h.handleMetricBatch(sess, m.MetricBatch); err != nil {
+				log.Printf("Error handling metric batch: %v", err)
+			}
+		case *pb.AgentMessage_PluginStatus:
+			if err := h.handlePluginStatus(sess, m.PluginStatus); err != nil {
+				log.Printf("Error handling plugin status: %v", err)
+			}
 		case *pb.AgentMessage_InstallPluginResponse:
 			if err := h.handleInstallPluginResponse(m.InstallPluginResponse); err != nil {
 				log.Printf("Error handling install plugin response: %v", err)
@@ -63,21 +135,106 @@ func (h *AgentServiceHandler) Connect(stream pb.AgentService_ConnectServer) erro
 	}
 }
 
-func (h *AgentServiceHandler) handleRegister(stream pb.AgentService_ConnectServer, register *pb.AgentRegister) error {
-	// 处理 Agent 注册逻辑
+func (h *AgentServiceHandler) handleRegister(stream pb.AgentService_ConnectServer, sess *session.Session, register *pb.AgentRegister) error {
+	if register.AgentId == "" {
+		rejectRegister(sess, "agent_id is required")
+		return fmt.Errorf("register without agent_id")
+	}
+
+	// 启用 mTLS 时，agent_id 必须与客户端证书中的身份一致
+	if ids := certIdentities(stream.Context()); ids != nil && !contains(ids, register.AgentId) {
+		rejectRegister(sess, "agent_id does not match client certificate")
+		return fmt.Errorf("agent_id %s does not match client certificate %v", register.AgentId, ids)
+	}
+
+	// 校验登记时签发的凭据
+	if err := h.enrollment.Verify(register.AgentId, register.Credential); err != nil {
+		rejectRegister(sess, err.Error())
+		return fmt.Errorf("agent %s rejected: %w", register.AgentId, err)
+	}
+
+	// 将当前流登记到会话注册表，之后平台即可向该 Agent 推送消息
+	h.sessions.Add(register.AgentId, sess)
 	log.Printf("Agent registered: %s", register.AgentId)
-	return stream.Send(&pb.ServerMessage{
+
+	// 保存资产信息，Agent 随即变为 online
+	if err := h.agents.Register(register, peerIP(stream)); err != nil {
+		log.Printf("Failed to save inventory of agent %s: %v", register.AgentId, err)
+	}
+	if err := sess.Send(&pb.ServerMessage{
 		Message: &pb.ServerMessage_RegisterResponse{
 			RegisterResponse: &pb.Response{
 				Success: true,
 			},
 		},
+	}); err != nil {
+		return err
+	}
+
+	// 下发 Agent 离线期间排队的任务
+	go h.dispatcher.DispatchPending(register.AgentId)
+	return nil
+}
+
+// rejectRegister 拒绝注册并关闭连接
+func rejectRegister(sess *session.Session, reason string) {
+	sess.Send(&pb.ServerMessage{
+		Message: &pb.ServerMessage_RegisterResponse{
+			RegisterResponse: &pb.Response{
+				Success: false,
+				Error:   reason,
+			},
+		},
 	})
+	sess.Close()
 }
 
-func (h *AgentServiceHandler) handleHeartbeat(stream pb.AgentService_ConnectServer, heartbeat *pb.Heartbeat) error {
-	// 处理心跳逻辑
-	return stream.Send(&pb.ServerMessage{
+// certIdentities 返回客户端证书中的身份（CN 与 SAN），未使用 mTLS 时返回 nil
+func certIdentities(ctx context.Context) []string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil
+	}
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(tlsInfo.State.VerifiedChains) == 0 || len(tlsInfo.State.VerifiedChains[0]) == 0 {
+		return nil
+	}
+	return tlsutil.Identities(tlsInfo.State.VerifiedChains[0][0])
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// peerIP 返回连接对端的 IP 地址
+func peerIP(stream pb.AgentService_ConnectServer) string {
+	p, ok := peer.FromContext(stream.Context())
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+func (h *AgentServiceHandler) handleHeartbeat(sess *session.Session, heartbeat *pb.Heartbeat) error {
+	// 以注册时的身份为准，未注册的连接不处理心跳
+	agentID := sess.AgentID()
+	if agentID == "" {
+		return fmt.Errorf("heartbeat from unregistered agent")
+	}
+	if err := h.agents.Heartbeat(agentID); err != nil {
+		return err
+	}
+
+	return sess.Send(&pb.ServerMessage{
 		Message: &pb.ServerMessage_HeartbeatAck{
 			HeartbeatAck: &pb.Response{
 				Success: true,
@@ -86,22 +243,26 @@ func (h *AgentServiceHandler) handleHeartbeat(stream pb.AgentService_ConnectServ
 	})
 }
 
-func (h *AgentServiceHandler) handleTaskResult(stream pb.AgentService_ConnectServer, result *pb.TaskResult) error {
-	// 更新任务结果
-	taskResult := h.db.Model(&models.Task{}).
-		Where("task_id = ?", result.TaskId).
-		Updates(map[string]interface{}{
-			"exit_code": result.ExitCode,
-			"stdout":    result.Stdout,
-			"stderr":    result.Stderr,
-			"status":    "completed",
-		})
-
-	if taskResult.Error != nil {
-		return taskResult.Error
+func (h *AgentServiceHandler) handleTaskResult(sess *session.Session, result *pb.TaskResult) error {
+	// 以注册时的身份为准，只接受下发给该 Agent 的任务结果
+	agentID := sess.AgentID()
+	if agentID == "" {
+		return fmt.Errorf("task result from unregistered agent")
+	}
+	// 写入脱敏时保留的日志末尾，之后结束订阅
+	if h.dispatcher.CheckInFlight(agentID, result.TaskId) == nil {
+		for _, taskLog := range h.dispatcher.FlushLog(result.TaskId) {
+			if err := h.taskLogs.Append(taskLog); err != nil {
+				log.Printf("Failed to save task log of %s: %v", result.TaskId, err)
+			}
+		}
 	}
+	if err := h.dispatcher.HandleResult(agentID, result); err != nil {
+		return err
+	}
+	h.taskLogs.Finish(result.TaskId)
 
-	return stream.Send(&pb.ServerMessage{
+	return sess.Send(&pb.ServerMessage{
 		Message: &pb.ServerMessage_RegisterResponse{
 			RegisterResponse: &pb.Response{
 				Success: true,
@@ -110,14 +271,53 @@ func (h *AgentServiceHandler) handleTaskResult(stream pb.AgentService_ConnectSer
 	})
 }
 
-func (h *AgentServiceHandler) handleTaskLog(stream pb.AgentService_ConnectServer, taskLog *pb.TaskLog) error {
-	// 处理任务日志逻辑
-	log.Printf("Task %s log: %s", taskLog.TaskId, taskLog.Output)
+func (h *AgentServiceHandler) handleTaskAck(sess *session.Session, ack *pb.TaskAck) error {
+	agentID := sess.AgentID()
+	if agentID == "" {
+		return fmt.Errorf("task ack from unregistered agent")
+	}
+	return h.dispatcher.MarkRunning(agentID, ack)
+}
+
+func (h *AgentServiceHandler) handleTaskLog(sess *session.Session, taskLog *pb.TaskLog) error {
+	agentID := sess.AgentID()
+	if agentID == "" {
+		return fmt.Errorf("task log from unregistered agent")
+	}
+	if err := h.dispatcher.CheckInFlight(agentID, taskLog.TaskId); err != nil {
+		return err
+	}
+
+	// secret 参数值脱敏后持久化并推送给实时订阅者，跨日志块的 secret 同样被掩盖
+	output, ok := h.dispatcher.MaskLog(taskLog)
+	if !ok || output == "" {
+		return nil
+	}
+	taskLog.Output = output
+	return h.taskLogs.Append(taskLog)
+}
+
+func (h *AgentServiceHandler) handleMetricBatch(sess *session.Session, batch *pb.MetricBatch) error {
+	// 以注册时的身份为准，忽略批次中自带的 agent_id
+	agentID := sess.AgentID()
+	if agentID == "" {
+		return fmt.Errorf("metric batch from unregistered agent")
+	}
+	return h.metrics.Ingest(agentID, batch)
+}
+
+func (h *AgentServiceHandler) handlePluginStatus(sess *session.Session, status *pb.PluginStatus) error {
+	agentID := sess.AgentID()
+	if agentID == "" {
+		return fmt.Errorf("plugin status from unregistered agent")
+	}
+	h.agents.PluginStatus(agentID, status)
 	return nil
 }
 
 func (h *AgentServiceHandler) handleInstallPluginResponse(response *pb.InstallPluginResponse) error {
-	log.Printf("Install plugin response: success=%v, message=%s", response.Success, response.Message)
+	log.Printf("Install plugin response: plugin=%s, version=%s, success=%v, message=%s, error=%s",
+		response.PluginName, response.Version, response.Success, response.Message, response.Error)
 	return nil
 }
 
@@ -5,17 +5,27 @@ import (
 )
 
 type Agent struct {
-	ID            uint      `gorm:"primaryKey" json:"id"`
-	AgentID       string    `gorm:"uniqueIndex;not null" json:"agent_id"`
-	Hostname      string    `json:"hostname"`
-	IP            string    `json:"ip"`
-	OS            string    `json:"os"`
-	Arch          string    `json:"arch"`
-	Version       string    `json:"version"`
-	Status        string    `json:"status"`
-	LastHeartbeat time.Time `json:"last_heartbeat"`
-	CreatedAt     time.Time `json:"created_at"`
-	UpdatedAt     time.Time `json:"updated_at"`
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	AgentID        string     `gorm:"uniqueIndex;not null" json:"agent_id"`
+	Hostname       string     `json:"hostname"`
+	IP             string     `json:"ip"`
+	OS             string     `json:"os"`
+	OSVersion      string     `json:"os_version"`
+	Arch           string     `json:"arch"`
+	Version        string     `json:"version"`
+	KernelVersion  string     `json:"kernel_version"`
+	CPUCount       int        `json:"cpu_count"`
+	MemoryTotal    int64      `json:"memory_total"` // 字节
+	BootTime       *time.Time `json:"boot_time"`
+	Labels         string     `json:"labels"`                               // JSON 编码的标签，如 {"env":"prod"}
+	Group          string     `gorm:"column:group_name;index" json:"group"` // 所属分组，用于限制用户可操作的 Agent
+	Status         string     `json:"status"`
+	CredentialHash string     `json:"-"` // 登记时签发的凭据哈希，注册时校验
+	EnrolledAt     *time.Time `json:"enrolled_at"`
+	RevokedAt      *time.Time `json:"revoked_at"`
+	LastHeartbeat  time.Time  `json:"last_heartbeat"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
 }
 
 func (Agent) TableName() string {
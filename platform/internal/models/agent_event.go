@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// AgentEvent 记录 Agent 的状态变化等事件
+type AgentEvent struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	AgentID   string    `gorm:"index" json:"agent_id"`
+	Type      string    `gorm:"index" json:"type"`
+	Details   string    `json:"details"` // JSON 编码的事件内容
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+}
+
+func (AgentEvent) TableName() string {
+	return "agent_events"
+}
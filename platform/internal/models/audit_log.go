@@ -11,9 +11,38 @@ type AuditLog struct {
 	IP        string    `json:"ip"`
 	UserAgent string    `json:"user_agent"`
 	Status    string    `json:"status"`
+	PrevHash  string    `json:"prev_hash"` // 上一条日志的哈希，构成哈希链
+	Hash      string    `json:"hash"`      // 本条日志内容与 PrevHash 的哈希
 	CreatedAt time.Time `gorm:"index" json:"created_at"`
 }
 
 func (AuditLog) TableName() string {
 	return "audit_logs"
 }
+
+// AuditChainStart 记录哈希链的起点，整张表只有一行。
+// ID 小于 FirstID 的日志是启用哈希链之前写入的，之后的日志必须带哈希
+type AuditChainStart struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	FirstID   uint      `json:"first_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (AuditChainStart) TableName() string {
+	return "audit_chain_start"
+}
+
+// AuditCheckpoint 审计日志的签名检查点，记录某一时刻哈希链的末端，
+// 用于发现末尾日志被删除或整条链被重写
+type AuditCheckpoint struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	LastID    uint      `json:"last_id"`   // 检查点覆盖的最后一条日志
+	LastHash  string    `json:"last_hash"` // 该日志的哈希
+	Count     int64     `json:"count"`     // 截至该日志的日志条数
+	Signature string    `json:"signature"` // Ed25519 签名（base64）
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (AuditCheckpoint) TableName() string {
+	return "audit_checkpoints"
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// EnrollmentToken 一次性引导令牌，Agent 首次启动时用于换取凭据。只保存令牌的哈希
+type EnrollmentToken struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	TokenHash   string     `gorm:"uniqueIndex;not null" json:"-"`
+	Description string     `json:"description"`
+	AgentID     string     `gorm:"index" json:"agent_id"` // 绑定的 agent_id，为空时可登记任意尚未登记的 Agent
+	ExpiresAt   time.Time  `json:"expires_at"`
+	UsedAt      *time.Time `json:"used_at"`
+	UsedBy      string     `json:"used_by"` // 使用该令牌登记的 agent_id
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+func (EnrollmentToken) TableName() string {
+	return "enrollment_tokens"
+}
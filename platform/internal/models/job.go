@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+)
+
+// Job 批量任务，向多个 Agent 下发同一脚本，每个 Agent 对应一个子任务
+type Job struct {
+	ID                   uint       `gorm:"primaryKey" json:"id"`
+	JobID                string     `gorm:"uniqueIndex;not null" json:"job_id"`
+	Name                 string     `json:"name"`
+	Type                 string     `json:"type"`
+	Script               string     `gorm:"type:text" json:"script"`
+	Timeout              int        `json:"timeout"`
+	Selector             string     `json:"selector"`                // 标签选择器，如 env=prod,role=web
+	Concurrency          int        `json:"concurrency"`             // 同时执行的子任务上限，0 表示不限制
+	BatchSize            int        `json:"batch_size"`              // 滚动批次大小，0 表示不分批
+	StopOnFailurePercent int        `json:"stop_on_failure_percent"` // 已结束子任务中的失败比例超过该值时停止，0 表示不停止
+	Status               string     `json:"status"`                  // running, completed, failed, stopped, cancelled
+	CreatedAt            time.Time  `json:"created_at"`
+	UpdatedAt            time.Time  `json:"updated_at"`
+	CompletedAt          *time.Time `json:"completed_at"`
+}
+
+func (Job) TableName() string {
+	return "jobs"
+}
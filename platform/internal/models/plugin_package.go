@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+)
+
+// PluginPackage 插件仓库中的安装包，文件保存在平台本地磁盘，以 SHA-256 命名
+type PluginPackage struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	Name        string    `gorm:"uniqueIndex:idx_plugin_package_version;not null" json:"name"`
+	Version     string    `gorm:"uniqueIndex:idx_plugin_package_version;not null" json:"version"`
+	Description string    `json:"description"`
+	SHA256      string    `json:"sha256"`
+	Size        int64     `json:"size"`
+	Path        string    `json:"-"`                         // 安装包在平台磁盘上的路径
+	Manifest    string    `gorm:"type:text" json:"manifest"` // JSON 编码的插件清单
+	UploadedBy  string    `json:"uploaded_by"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func (PluginPackage) TableName() string {
+	return "plugin_packages"
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+)
+
+// Script 脚本库中的脚本，内容保存在 ScriptVersion 中，每次修改内容或解释器生成新版本
+type Script struct {
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	Name          string    `gorm:"uniqueIndex;not null" json:"name"`
+	Description   string    `json:"description"`
+	Type          string    `json:"type"` // 最新版本的解释器：shell, bash, python, perl, powershell
+	Tags          string    `json:"tags"` // JSON 编码的标签列表，如 ["backup","db"]
+	LatestVersion int       `json:"latest_version"`
+	CreatedBy     string    `json:"created_by"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+func (Script) TableName() string {
+	return "scripts"
+}
+
+// ScriptVersion 脚本的一个版本，创建后不可修改
+type ScriptVersion struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	ScriptID  uint      `gorm:"uniqueIndex:idx_script_version;not null" json:"script_id"`
+	Version   int       `gorm:"uniqueIndex:idx_script_version;not null" json:"version"`
+	Type      string    `json:"type"`
+	Script    string    `gorm:"type:text" json:"script"`
+	Comment   string    `json:"comment"` // 版本说明
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (ScriptVersion) TableName() string {
+	return "script_versions"
+}
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"time"
+)
+
+// 模板参数类型
+const (
+	ParamTypeString = "string"
+	ParamTypeInt    = "int"
+	ParamTypeEnum   = "enum"
+	ParamTypeSecret = "secret" // 保存的任务与日志中以掩码代替
+)
+
+// ScriptTemplate 脚本模板，Script 为 text/template 格式，以 {{.name}} 引用参数
+type ScriptTemplate struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	Name        string    `gorm:"uniqueIndex;not null" json:"name"`
+	Description string    `json:"description"`
+	Type        string    `json:"type"` // shell, bash, python, perl, powershell
+	Script      string    `gorm:"type:text" json:"script"`
+	Params      string    `gorm:"type:text" json:"params"` // JSON 编码的参数定义列表
+	CreatedBy   string    `json:"created_by"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func (ScriptTemplate) TableName() string {
+	return "script_templates"
+}
+
+// TemplateParam 模板参数定义
+type TemplateParam struct {
+	Name        string   `json:"name"`
+	Type        string   `json:"type"` // string, int, enum, secret
+	Required    bool     `json:"required"`
+	Default     string   `json:"default,omitempty"`
+	Options     []string `json:"options,omitempty"` // enum 的可选值
+	Description string   `json:"description,omitempty"`
+}
+
+// TaskSecret 含 secret 参数的任务的明文脚本与环境变量，仅用于下发与输出脱敏，任务结束后删除
+type TaskSecret struct {
+	ID        uint      `gorm:"primaryKey" json:"-"`
+	TaskID    string    `gorm:"uniqueIndex;not null" json:"-"`
+	Script    string    `gorm:"type:text" json:"-"`
+	Env       string    `gorm:"type:text" json:"-"`
+	Values    string    `gorm:"type:text" json:"-"` // JSON 编码的 secret 参数值，用于脱敏
+	CreatedAt time.Time `json:"-"`
+}
+
+func (TaskSecret) TableName() string {
+	return "task_secrets"
+}
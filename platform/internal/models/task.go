@@ -5,20 +5,52 @@ import (
 )
 
 type Task struct {
-	ID        uint      `gorm:"primaryKey" json:"id"`
-	TaskID    string    `gorm:"uniqueIndex;not null" json:"task_id"`
-	AgentID   string    `gorm:"index;not null" json:"agent_id"`
-	Type      string    `json:"type"`  // shell, python
-	Script    string    `gorm:"type:text" json:"script"`
-	Timeout   int       `json:"timeout"`
-	Status    string    `json:"status"`  // pending, running, completed, failed
-	ExitCode  int       `json:"exit_code"`
-	Stdout    string    `gorm:"type:text" json:"stdout"`
-	Stderr    string    `gorm:"type:text" json:"stderr"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	StartedAt *time.Time `json:"started_at"`
-	CompletedAt *time.Time `json:"completed_at"`
+	ID             uint           `gorm:"primaryKey" json:"id"`
+	TaskID         string         `gorm:"uniqueIndex;not null" json:"task_id"`
+	AgentID        string         `gorm:"index;not null" json:"agent_id"`
+	JobID          string         `gorm:"index" json:"job_id,omitempty"`
+	Batch          int            `json:"batch,omitempty"`
+	Type           string         `json:"type"` // shell, bash, python, perl, powershell, file
+	Script         string         `gorm:"type:text" json:"script"`
+	Timeout        int            `json:"timeout"`
+	RunAsUser      string         `json:"run_as_user,omitempty"`
+	RunAsGroup     string         `json:"run_as_group,omitempty"`
+	WorkDir        string         `json:"work_dir,omitempty"`
+	Umask          string         `json:"umask,omitempty"`
+	Limits         ResourceLimits `gorm:"embedded;embeddedPrefix:limit_" json:"limits"`
+	Args           string         `gorm:"type:text" json:"args,omitempty"` // JSON 编码的参数列表
+	FileName       string         `json:"file_name,omitempty"`             // file 类型任务的文件名
+	FileSHA256     string         `json:"file_sha256,omitempty"`
+	File           []byte         `json:"-"`                              // file 类型任务的文件内容
+	Env            string         `gorm:"type:text" json:"env,omitempty"` // JSON 编码的环境变量，secret 参数值以掩码代替
+	TemplateID     uint           `json:"template_id,omitempty"`
+	ScriptID       uint           `gorm:"index" json:"script_id,omitempty"`  // 来自脚本库时为脚本 ID
+	ScriptVersion  int            `json:"script_version,omitempty"`          // 执行的脚本版本
+	Params         string         `gorm:"type:text" json:"params,omitempty"` // JSON 编码的模板参数，secret 参数值以掩码代替
+	HasSecrets     bool           `json:"has_secrets,omitempty"`             // 明文保存在 task_secrets 中，任务结束后删除
+	Status         string         `json:"status"`                            // awaiting_approval, queued, pending, dispatched, running, completed, failed, timeout, cancelled, rejected
+	CreatedBy      string         `json:"created_by"`
+	ApprovedBy     string         `json:"approved_by,omitempty"`
+	PolicyRejected bool           `json:"policy_rejected,omitempty"` // 被平台或 Agent 拒绝执行，而不是被审批人驳回
+	ExitCode       int            `json:"exit_code"`
+	Stdout         string         `gorm:"type:text" json:"stdout"`
+	Stderr         string         `gorm:"type:text" json:"stderr"`
+	LimitExceeded  string         `json:"limit_exceeded,omitempty"` // 因资源限制被终止时为触发的限制
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	StartedAt      *time.Time     `json:"started_at"`
+	CompletedAt    *time.Time     `json:"completed_at"`
+
+	// Secrets 创建任务时需要脱敏的 secret 参数值，不落库
+	Secrets []string `gorm:"-" json:"-"`
+}
+
+// ResourceLimits 任务的资源限制，0 表示不限制
+type ResourceLimits struct {
+	CPUSeconds   uint64 `json:"cpu_seconds"`
+	AddressSpace uint64 `json:"address_space"` // 字节
+	OpenFiles    uint64 `json:"open_files"`
+	Processes    uint64 `json:"processes"`
 }
 
 func (Task) TableName() string {
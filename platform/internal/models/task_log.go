@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+type TaskLog struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	TaskID    string    `gorm:"uniqueIndex:idx_task_log_seq;not null" json:"task_id"`
+	Seq       int64     `gorm:"uniqueIndex:idx_task_log_seq" json:"seq"`
+	Stream    string    `json:"stream"` // stdout, stderr
+	Output    string    `gorm:"type:text" json:"output"`
+	Timestamp time.Time `json:"timestamp"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (TaskLog) TableName() string {
+	return "task_logs"
+}
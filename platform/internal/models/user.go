@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// 用户角色，权限依次递增
+const (
+	RoleViewer   = "viewer"   // 只读
+	RoleOperator = "operator" // 下发任务、作业，管理插件与标签
+	RoleAdmin    = "admin"    // 删除 Agent、管理登记令牌与用户
+)
+
+type User struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	Username     string    `gorm:"uniqueIndex;not null" json:"username"`
+	PasswordHash string    `json:"-"`
+	Role         string    `json:"role"`
+	Groups       string    `json:"groups"` // JSON 编码的可访问 Agent 分组，如 ["web","db"]，为空表示不限制
+	Disabled     bool      `json:"disabled"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+func (User) TableName() string {
+	return "users"
+}
+
+// 访问令牌类型
+const (
+	TokenKindSession = "session" // 登录时签发，有效期由配置决定
+	TokenKindAPI     = "api"     // 用户为脚本、集成创建的长期令牌
+)
+
+// APIToken 用户的访问令牌，只保存令牌的哈希
+type APIToken struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	UserID     uint       `gorm:"index;not null" json:"user_id"`
+	Name       string     `json:"name"`
+	Kind       string     `json:"kind"`
+	TokenHash  string     `gorm:"uniqueIndex;not null" json:"-"`
+	ExpiresAt  *time.Time `json:"expires_at"` // 为空表示永不过期
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func (APIToken) TableName() string {
+	return "api_tokens"
+}
@@ -30,9 +30,17 @@ func GetMetrics() *Metrics {
 	globalMetrics.mu.RLock()
 	defer globalMetrics.mu.RUnlock()
 
-	m := &Metrics{}
-	*m = *globalMetrics
-	return m
+	return &Metrics{
+		Goroutines:      globalMetrics.Goroutines,
+		MemoryAlloc:     globalMetrics.MemoryAlloc,
+		MemorySys:       globalMetrics.MemorySys,
+		GCPauseTotal:    globalMetrics.GCPauseTotal,
+		ActiveAgents:    globalMetrics.ActiveAgents,
+		TotalRequests:   globalMetrics.TotalRequests,
+		FailedRequests:  globalMetrics.FailedRequests,
+		AvgResponseTime: globalMetrics.AvgResponseTime,
+		LastUpdate:      globalMetrics.LastUpdate,
+	}
 }
 
 func UpdateSystemMetrics() {
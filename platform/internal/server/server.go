@@ -5,26 +5,28 @@ import (
 	"net"
 
 	pb "github.com/yourusername/agent-platform/proto"
-	grpcHandler "github.com/yourusername/agent-platform/platform/internal/grpc"
 	"google.golang.org/grpc"
-	"gorm.io/gorm"
+	"google.golang.org/grpc/credentials"
 )
 
 type Server struct {
 	addr       string
 	grpcServer *grpc.Server
 	listener   net.Listener
-	db         *gorm.DB
 }
 
-func NewServer(addr string, db *gorm.DB) *Server {
+// NewServer 创建 gRPC 服务，creds 为 nil 时不启用 TLS
+func NewServer(addr string, handler pb.AgentServiceServer, creds credentials.TransportCredentials) *Server {
+	var opts []grpc.ServerOption
+	if creds != nil {
+		opts = append(opts, grpc.Creds(creds))
+	}
+
 	s := &Server{
 		addr:       addr,
-		grpcServer: grpc.NewServer(),
-		db:         db,
+		grpcServer: grpc.NewServer(opts...),
 	}
 
-	handler := grpcHandler.NewAgentServiceHandler(db)
 	pb.RegisterAgentServiceServer(s.grpcServer, handler)
 
 	return s
@@ -2,17 +2,20 @@ package server
 
 import (
 	"testing"
+
+	grpcHandler "github.com/yourusername/agent-platform/platform/internal/grpc"
+	"github.com/yourusername/agent-platform/platform/internal/session"
 )
 
 func TestNewServer(t *testing.T) {
-	srv := NewServer(":50051", nil)
+	srv := NewServer(":50051", grpcHandler.NewAgentServiceHandler(nil, session.NewRegistry(), nil, nil, nil, nil, nil), nil)
 	if srv == nil {
 		t.Fatal("NewServer returned nil")
 	}
 }
 
 func TestServerStart(t *testing.T) {
-	srv := NewServer(":0", nil)
+	srv := NewServer(":0", grpcHandler.NewAgentServiceHandler(nil, session.NewRegistry(), nil, nil, nil, nil, nil), nil)
 	if srv == nil {
 		t.Fatal("NewServer returned nil")
 	}
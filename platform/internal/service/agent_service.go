@@ -0,0 +1,370 @@
+package service
+
+import (
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	pb "github.com/yourusername/agent-platform/proto"
+	"github.com/yourusername/agent-platform/platform/internal/models"
+	"github.com/yourusername/agent-platform/platform/internal/monitor"
+	"github.com/yourusername/agent-platform/platform/internal/session"
+	"gorm.io/gorm"
+)
+
+// Agent 状态
+const (
+	AgentStatusOnline  = "online"
+	AgentStatusOffline = "offline"
+)
+
+// Agent 事件类型
+const (
+	AgentEventStatusChanged    = "status_changed"
+	AgentEventInventoryChanged = "inventory_changed"
+	AgentEventEnrolled         = "enrolled"
+	AgentEventRevoked          = "revoked"
+	AgentEventPluginStatus     = "plugin_status"
+)
+
+// bootTimeTolerance 开机时间的允许误差，/proc/stat 中的 btime 会随系统时钟校准轻微漂移
+const bootTimeTolerance = time.Minute
+
+const (
+	// DefaultHeartbeatInterval Agent 默认心跳间隔
+	DefaultHeartbeatInterval = 30 * time.Second
+	// DefaultMaxMissedHeartbeats 连续丢失多少次心跳后判定 Agent 离线
+	DefaultMaxMissedHeartbeats = 3
+)
+
+// AgentStatusEvent Agent 在线状态变化事件
+type AgentStatusEvent struct {
+	AgentID string    `json:"agent_id"`
+	From    string    `json:"from"`
+	To      string    `json:"to"`
+	At      time.Time `json:"at"`
+}
+
+// InventoryChange 资产信息中单个字段的变化
+type InventoryChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// PluginStatusEvent Agent 上报的插件状态变化，插件崩溃时附带退出码与 stderr 的最后几行，
+// 停止时附带停止方式（clean、exited、terminated、killed）
+type PluginStatusEvent struct {
+	Plugin        string   `json:"plugin"`
+	State         string   `json:"state"`
+	PreviousState string   `json:"previous_state"`
+	ExitCode      int32    `json:"exit_code"`
+	Error         string   `json:"error,omitempty"`
+	Restarts      int32    `json:"restarts"`
+	StderrTail    []string `json:"stderr_tail,omitempty"`
+	Shutdown      string   `json:"shutdown,omitempty"`
+}
+
+// AgentStatusFunc 在 Agent 状态变化后被调用
+type AgentStatusFunc func(event *AgentStatusEvent)
+
+// AgentService 维护 Agent 的在线状态：注册与心跳时置为 online，
+// 后台定期检查，连续丢失心跳的 Agent 被置为 offline
+type AgentService struct {
+	db                *gorm.DB
+	sessions          *session.Registry
+	heartbeatInterval time.Duration
+	maxMissed         int
+	stopCh            chan struct{}
+	mu                sync.RWMutex
+	statusHooks       []AgentStatusFunc
+}
+
+func NewAgentService(db *gorm.DB, sessions *session.Registry, heartbeatInterval time.Duration, maxMissed int) *AgentService {
+	if heartbeatInterval <= 0 {
+		heartbeatInterval = DefaultHeartbeatInterval
+	}
+	if maxMissed <= 0 {
+		maxMissed = DefaultMaxMissedHeartbeats
+	}
+
+	return &AgentService{
+		db:                db,
+		sessions:          sessions,
+		heartbeatInterval: heartbeatInterval,
+		maxMissed:         maxMissed,
+		stopCh:            make(chan struct{}),
+	}
+}
+
+// Register 保存 Agent 注册时上报的资产信息并将其置为 online。
+// 资产信息与上次不同时记录 inventory_changed 事件，用于追踪主机重装、升级与重启。
+// Agent 未上报 IP 时使用连接的对端地址
+func (s *AgentService) Register(register *pb.AgentRegister, peerIP string) error {
+	now := time.Now()
+	reported := agentFromRegister(register, peerIP)
+
+	var agent models.Agent
+	result := s.db.Where("agent_id = ?", register.AgentId).Limit(1).Find(&agent)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		reported.Status = AgentStatusOnline
+		reported.LastHeartbeat = now
+		if err := s.db.Create(reported).Error; err != nil {
+			return err
+		}
+		s.statusChanged(register.AgentId, "", AgentStatusOnline, now)
+		return nil
+	}
+
+	previous := agent.Status
+	changes := diffInventory(&agent, reported)
+	if err := s.db.Model(&agent).Updates(map[string]interface{}{
+		"hostname":       reported.Hostname,
+		"ip":             reported.IP,
+		"os":             reported.OS,
+		"os_version":     reported.OSVersion,
+		"arch":           reported.Arch,
+		"version":        reported.Version,
+		"kernel_version": reported.KernelVersion,
+		"cpu_count":      reported.CPUCount,
+		"memory_total":   reported.MemoryTotal,
+		"boot_time":      reported.BootTime,
+		"status":         AgentStatusOnline,
+		"last_heartbeat": now,
+	}).Error; err != nil {
+		return err
+	}
+
+	if len(changes) > 0 {
+		log.Printf("Agent %s inventory changed: %d fields", register.AgentId, len(changes))
+		s.recordEvent(register.AgentId, AgentEventInventoryChanged, map[string]interface{}{"changes": changes}, now)
+	}
+	if previous != AgentStatusOnline {
+		s.statusChanged(register.AgentId, previous, AgentStatusOnline, now)
+	}
+	return nil
+}
+
+func agentFromRegister(register *pb.AgentRegister, peerIP string) *models.Agent {
+	agent := &models.Agent{
+		AgentID:       register.AgentId,
+		Hostname:      register.Hostname,
+		IP:            register.Ip,
+		OS:            register.Os,
+		OSVersion:     register.OsVersion,
+		Arch:          register.Arch,
+		Version:       register.Version,
+		KernelVersion: register.KernelVersion,
+		CPUCount:      int(register.CpuCount),
+		MemoryTotal:   int64(register.MemoryTotal),
+	}
+	if agent.IP == "" {
+		agent.IP = peerIP
+	}
+	if register.BootTime != nil {
+		bootTime := time.Unix(register.BootTime.Seconds, 0)
+		agent.BootTime = &bootTime
+	}
+	return agent
+}
+
+// diffInventory 比较已保存与新上报的资产信息，返回发生变化的字段
+func diffInventory(old, reported *models.Agent) map[string]InventoryChange {
+	changes := make(map[string]InventoryChange)
+	compare := func(field string, from, to interface{}) {
+		if from != to {
+			changes[field] = InventoryChange{From: from, To: to}
+		}
+	}
+
+	compare("hostname", old.Hostname, reported.Hostname)
+	compare("ip", old.IP, reported.IP)
+	compare("os", old.OS, reported.OS)
+	compare("os_version", old.OSVersion, reported.OSVersion)
+	compare("arch", old.Arch, reported.Arch)
+	compare("version", old.Version, reported.Version)
+	compare("kernel_version", old.KernelVersion, reported.KernelVersion)
+	compare("cpu_count", old.CPUCount, reported.CPUCount)
+	compare("memory_total", old.MemoryTotal, reported.MemoryTotal)
+
+	if !sameBootTime(old.BootTime, reported.BootTime) {
+		changes["boot_time"] = InventoryChange{From: old.BootTime, To: reported.BootTime}
+	}
+	return changes
+}
+
+func sameBootTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	diff := a.Sub(*b)
+	return diff > -bootTimeTolerance && diff < bootTimeTolerance
+}
+
+// Heartbeat 记录 Agent 的心跳时间，离线的 Agent 恢复为 online。
+// 以平台收到心跳的时间为准，避免 Agent 时钟偏差影响判定
+func (s *AgentService) Heartbeat(agentID string) error {
+	now := time.Now()
+
+	result := s.db.Model(&models.Agent{}).
+		Where("agent_id = ? AND status = ?", agentID, AgentStatusOnline).
+		Update("last_heartbeat", now)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		return nil
+	}
+	return s.setOnline(agentID, now)
+}
+
+// setOnline 将 Agent 置为 online，不存在时创建记录
+func (s *AgentService) setOnline(agentID string, now time.Time) error {
+	var agent models.Agent
+	result := s.db.Where("agent_id = ?", agentID).Limit(1).Find(&agent)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		agent = models.Agent{
+			AgentID:       agentID,
+			Status:        AgentStatusOnline,
+			LastHeartbeat: now,
+		}
+		if err := s.db.Create(&agent).Error; err != nil {
+			return err
+		}
+		s.statusChanged(agentID, "", AgentStatusOnline, now)
+		return nil
+	}
+
+	previous := agent.Status
+	if err := s.db.Model(&agent).Updates(map[string]interface{}{
+		"status":         AgentStatusOnline,
+		"last_heartbeat": now,
+	}).Error; err != nil {
+		return err
+	}
+	if previous != AgentStatusOnline {
+		s.statusChanged(agentID, previous, AgentStatusOnline, now)
+	}
+	return nil
+}
+
+// OnStatusChange 注册 Agent 状态变化时的回调
+func (s *AgentService) OnStatusChange(fn AgentStatusFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.statusHooks = append(s.statusHooks, fn)
+}
+
+// statusChanged 记录状态变化事件、更新在线数量并通知回调
+func (s *AgentService) statusChanged(agentID, from, to string, at time.Time) {
+	event := &AgentStatusEvent{AgentID: agentID, From: from, To: to, At: at}
+	log.Printf("Agent %s status changed: %q -> %q", agentID, from, to)
+
+	s.recordEvent(agentID, AgentEventStatusChanged, event, at)
+	s.updateActiveAgents()
+
+	s.mu.RLock()
+	hooks := s.statusHooks
+	s.mu.RUnlock()
+	for _, hook := range hooks {
+		hook(event)
+	}
+}
+
+// PluginStatus 记录 Agent 上报的插件状态变化
+func (s *AgentService) PluginStatus(agentID string, status *pb.PluginStatus) {
+	if status.State == "crashlooping" {
+		log.Printf("Plugin %s on agent %s is crash looping after %d restarts: %s",
+			status.PluginName, agentID, status.Restarts, status.Error)
+	}
+	s.recordEvent(agentID, AgentEventPluginStatus, &PluginStatusEvent{
+		Plugin:        status.PluginName,
+		State:         status.State,
+		PreviousState: status.PreviousState,
+		ExitCode:      status.ExitCode,
+		Error:         status.Error,
+		Restarts:      status.Restarts,
+		StderrTail:    status.StderrTail,
+		Shutdown:      status.Shutdown,
+	}, timestampToTime(status.Timestamp))
+}
+
+func (s *AgentService) recordEvent(agentID, eventType string, details interface{}, at time.Time) {
+	data, _ := json.Marshal(details)
+	if err := s.db.Create(&models.AgentEvent{
+		AgentID:   agentID,
+		Type:      eventType,
+		Details:   string(data),
+		CreatedAt: at,
+	}).Error; err != nil {
+		log.Printf("Failed to record %s event of agent %s: %v", eventType, agentID, err)
+	}
+}
+
+// Start 启动离线检查
+func (s *AgentService) Start() {
+	s.updateActiveAgents()
+
+	ticker := time.NewTicker(s.heartbeatInterval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.reap()
+			case <-s.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+func (s *AgentService) Stop() {
+	close(s.stopCh)
+}
+
+// reap 将超过 maxMissed 个心跳周期未上报心跳的 Agent 置为 offline，并关闭其残留连接
+func (s *AgentService) reap() {
+	now := time.Now()
+	cutoff := now.Add(-time.Duration(s.maxMissed) * s.heartbeatInterval)
+
+	var agents []models.Agent
+	if err := s.db.Where("status = ? AND last_heartbeat < ?", AgentStatusOnline, cutoff).
+		Find(&agents).Error; err != nil {
+		log.Printf("Failed to load stale agents: %v", err)
+		return
+	}
+
+	for _, agent := range agents {
+		// 条件更新，避免覆盖检查期间刚到达的心跳
+		result := s.db.Model(&models.Agent{}).
+			Where("agent_id = ? AND status = ? AND last_heartbeat < ?", agent.AgentID, AgentStatusOnline, cutoff).
+			Update("status", AgentStatusOffline)
+		if result.Error != nil {
+			log.Printf("Failed to mark agent %s offline: %v", agent.AgentID, result.Error)
+			continue
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+
+		s.sessions.Disconnect(agent.AgentID)
+		s.statusChanged(agent.AgentID, AgentStatusOnline, AgentStatusOffline, now)
+	}
+	s.updateActiveAgents()
+}
+
+func (s *AgentService) updateActiveAgents() {
+	var count int64
+	if err := s.db.Model(&models.Agent{}).Where("status = ?", AgentStatusOnline).Count(&count).Error; err != nil {
+		log.Printf("Failed to count online agents: %v", err)
+		return
+	}
+	monitor.UpdateActiveAgents(int(count))
+}
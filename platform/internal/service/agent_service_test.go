@@ -0,0 +1,150 @@
+package service
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	pb "github.com/yourusername/agent-platform/proto"
+	"github.com/yourusername/agent-platform/platform/internal/models"
+	"github.com/yourusername/agent-platform/platform/internal/monitor"
+	"github.com/yourusername/agent-platform/platform/internal/session"
+)
+
+func TestAgentService_HeartbeatAndReap(t *testing.T) {
+	db := setupTestDB()
+	db.AutoMigrate(&models.Agent{}, &models.AgentEvent{})
+	sessions := session.NewRegistry()
+	service := NewAgentService(db, sessions, time.Second, 2)
+
+	var events []*AgentStatusEvent
+	service.OnStatusChange(func(event *AgentStatusEvent) {
+		events = append(events, event)
+	})
+
+	// 首次心跳创建记录并置为 online
+	assert.NoError(t, service.Heartbeat("agent-1"))
+	assert.NoError(t, service.Heartbeat("agent-1"))
+	var agent models.Agent
+	db.Where("agent_id = ?", "agent-1").First(&agent)
+	assert.Equal(t, AgentStatusOnline, agent.Status)
+	assert.Len(t, events, 1)
+	assert.Equal(t, 1, monitor.GetMetrics().ActiveAgents)
+
+	// 心跳仍在有效期内，不会被置为 offline
+	service.reap()
+	db.Where("agent_id = ?", "agent-1").First(&agent)
+	assert.Equal(t, AgentStatusOnline, agent.Status)
+
+	// 连续丢失心跳后置为 offline，并关闭残留连接
+	stream := &mockStream{}
+	sess := session.NewSession(stream)
+	sessions.Add("agent-1", sess)
+	db.Model(&models.Agent{}).Where("agent_id = ?", "agent-1").
+		Update("last_heartbeat", time.Now().Add(-3*time.Second))
+	service.reap()
+
+	db.Where("agent_id = ?", "agent-1").First(&agent)
+	assert.Equal(t, AgentStatusOffline, agent.Status)
+	assert.False(t, sessions.IsConnected("agent-1"))
+	assert.Len(t, events, 2)
+	assert.Equal(t, AgentStatusOnline, events[1].From)
+	assert.Equal(t, AgentStatusOffline, events[1].To)
+	assert.Equal(t, 0, monitor.GetMetrics().ActiveAgents)
+
+	// 恢复心跳后重新上线
+	assert.NoError(t, service.Heartbeat("agent-1"))
+	db.Where("agent_id = ?", "agent-1").First(&agent)
+	assert.Equal(t, AgentStatusOnline, agent.Status)
+
+	var count int64
+	db.Model(&models.AgentEvent{}).Where("agent_id = ? AND type = ?", "agent-1", AgentEventStatusChanged).Count(&count)
+	assert.Equal(t, int64(3), count)
+}
+
+func TestAgentService_RegisterInventory(t *testing.T) {
+	db := setupTestDB()
+	db.AutoMigrate(&models.Agent{}, &models.AgentEvent{})
+	service := NewAgentService(db, session.NewRegistry(), time.Second, 2)
+
+	register := &pb.AgentRegister{
+		AgentId:       "agent-1",
+		Hostname:      "host-1",
+		Os:            "linux",
+		Arch:          "amd64",
+		Version:       "0.1.0",
+		KernelVersion: "5.15.0",
+		CpuCount:      4,
+		MemoryTotal:   8 << 30,
+		BootTime:      &pb.Timestamp{Seconds: 1700000000},
+	}
+	assert.NoError(t, service.Register(register, "10.0.0.5"))
+
+	var agent models.Agent
+	db.Where("agent_id = ?", "agent-1").First(&agent)
+	assert.Equal(t, "host-1", agent.Hostname)
+	assert.Equal(t, "10.0.0.5", agent.IP)
+	assert.Equal(t, 4, agent.CPUCount)
+	assert.Equal(t, int64(8<<30), agent.MemoryTotal)
+	assert.Equal(t, int64(1700000000), agent.BootTime.Unix())
+	assert.Equal(t, AgentStatusOnline, agent.Status)
+
+	// 资产信息未变（开机时间在误差范围内）不记录事件
+	register.BootTime = &pb.Timestamp{Seconds: 1700000001}
+	assert.NoError(t, service.Register(register, "10.0.0.5"))
+	var count int64
+	db.Model(&models.AgentEvent{}).Where("type = ?", AgentEventInventoryChanged).Count(&count)
+	assert.Equal(t, int64(0), count)
+
+	// 升级并重装后记录变化
+	register.Version = "0.2.0"
+	register.KernelVersion = "6.1.0"
+	register.BootTime = &pb.Timestamp{Seconds: 1800000000}
+	assert.NoError(t, service.Register(register, "10.0.0.5"))
+
+	var event models.AgentEvent
+	db.Where("type = ?", AgentEventInventoryChanged).First(&event)
+	var details struct {
+		Changes map[string]InventoryChange `json:"changes"`
+	}
+	assert.NoError(t, json.Unmarshal([]byte(event.Details), &details))
+	assert.Len(t, details.Changes, 3)
+	assert.Equal(t, "0.1.0", details.Changes["version"].From)
+	assert.Equal(t, "6.1.0", details.Changes["kernel_version"].To)
+
+	db.Where("agent_id = ?", "agent-1").First(&agent)
+	assert.Equal(t, "0.2.0", agent.Version)
+}
+
+func TestAgentService_PluginStatus(t *testing.T) {
+	db := setupTestDB()
+	db.AutoMigrate(&models.Agent{}, &models.AgentEvent{})
+	service := NewAgentService(db, session.NewRegistry(), time.Second, 2)
+
+	service.PluginStatus("agent-1", &pb.PluginStatus{
+		PluginName:    "disk-check",
+		State:         "crashlooping",
+		PreviousState: "running",
+		ExitCode:      2,
+		Restarts:      5,
+		StderrTail:    []string{"panic: disk not found"},
+		Timestamp:     &pb.Timestamp{Seconds: 1700000000},
+	})
+
+	var event models.AgentEvent
+	assert.NoError(t, db.Where("agent_id = ? AND type = ?", "agent-1", AgentEventPluginStatus).First(&event).Error)
+	assert.Equal(t, int64(1700000000), event.CreatedAt.Unix())
+	var details PluginStatusEvent
+	assert.NoError(t, json.Unmarshal([]byte(event.Details), &details))
+	assert.Equal(t, "disk-check", details.Plugin)
+	assert.Equal(t, "crashlooping", details.State)
+	assert.Equal(t, int32(2), details.ExitCode)
+	assert.Equal(t, []string{"panic: disk not found"}, details.StderrTail)
+
+	// 停止时记录停止方式
+	service.PluginStatus("agent-1", &pb.PluginStatus{PluginName: "disk-check", State: "stopped", PreviousState: "running", Shutdown: "killed", ExitCode: -1})
+	var stopped models.AgentEvent
+	assert.NoError(t, db.Where("type = ?", AgentEventPluginStatus).Order("id DESC").First(&stopped).Error)
+	assert.Contains(t, stopped.Details, `"shutdown":"killed"`)
+}
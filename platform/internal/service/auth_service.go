@@ -0,0 +1,351 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/yourusername/agent-platform/platform/internal/models"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidLogin = errors.New("invalid username or password")
+	ErrInvalidToken = errors.New("invalid or expired token")
+	ErrForbidden    = errors.New("permission denied")
+)
+
+const (
+	// DefaultSessionTTL 登录令牌默认有效期
+	DefaultSessionTTL = 12 * time.Hour
+	// minPasswordLength 密码最小长度
+	minPasswordLength = 8
+	// DefaultAdminPasswordFile 未配置时保存生成的初始管理员密码的文件
+	DefaultAdminPasswordFile = "data/admin-password"
+	// lastUsedInterval 令牌最后使用时间的更新间隔，避免每个请求都写数据库
+	lastUsedInterval = time.Minute
+
+	sessionTokenPrefix = "st_"
+	apiTokenPrefix     = "at_"
+)
+
+// roleLevels 角色的权限等级，高等级包含低等级的全部权限
+var roleLevels = map[string]int{
+	models.RoleViewer:   1,
+	models.RoleOperator: 2,
+	models.RoleAdmin:    3,
+}
+
+// Principal 已认证的请求者
+type Principal struct {
+	UserID   uint     `json:"user_id"`
+	Username string   `json:"username"`
+	Role     string   `json:"role"`
+	Groups   []string `json:"groups"` // 可访问的 Agent 分组，为空表示不限制
+}
+
+// HasRole 判断是否具备 role 及以上的权限
+func (p *Principal) HasRole(role string) bool {
+	return roleLevels[p.Role] >= roleLevels[role]
+}
+
+// Scoped 判断是否只能访问部分 Agent 分组
+func (p *Principal) Scoped() bool {
+	return len(p.Groups) > 0
+}
+
+// CanAccessGroup 判断是否可以操作属于 group 的 Agent，受限用户不能操作未分组的 Agent
+func (p *Principal) CanAccessGroup(group string) bool {
+	if !p.Scoped() {
+		return true
+	}
+	for _, g := range p.Groups {
+		if g == group {
+			return true
+		}
+	}
+	return false
+}
+
+// UserSpec 创建或更新用户的参数，更新时为 nil 的字段保持不变
+type UserSpec struct {
+	Username string
+	Password *string
+	Role     *string
+	Groups   *[]string
+	Disabled *bool
+}
+
+// AuthService 管理用户与访问令牌。密码使用 bcrypt 保存，令牌只保存哈希
+type AuthService struct {
+	db         *gorm.DB
+	sessionTTL time.Duration
+}
+
+func NewAuthService(db *gorm.DB, sessionTTL time.Duration) *AuthService {
+	if sessionTTL <= 0 {
+		sessionTTL = DefaultSessionTTL
+	}
+	return &AuthService{
+		db:         db,
+		sessionTTL: sessionTTL,
+	}
+}
+
+// EnsureAdmin 没有任何用户时创建初始管理员。未配置密码时生成随机密码，
+// 写入权限为 0600 的 passwordFile（不覆盖已有文件），不输出到日志
+func (s *AuthService) EnsureAdmin(username, password, passwordFile string) error {
+	var count int64
+	if err := s.db.Model(&models.User{}).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	if username == "" {
+		username = "admin"
+	}
+	generated := password == ""
+	if generated {
+		if passwordFile == "" {
+			passwordFile = DefaultAdminPasswordFile
+		}
+		password = randomSecret()[:16]
+		if err := writePasswordFile(passwordFile, password); err != nil {
+			return err
+		}
+	}
+	role := models.RoleAdmin
+	if _, err := s.CreateUser(&UserSpec{Username: username, Password: &password, Role: &role}); err != nil {
+		if generated {
+			os.Remove(passwordFile)
+		}
+		return err
+	}
+
+	if generated {
+		log.Printf("Created initial admin user %q, generated password written to %s, change it after first login and delete the file", username, passwordFile)
+	} else {
+		log.Printf("Created initial admin user %q", username)
+	}
+	return nil
+}
+
+// writePasswordFile 以 0600 权限创建文件并写入密码，文件已存在时返回错误
+func writePasswordFile(path, password string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create directory for admin password file: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create admin password file: %w", err)
+	}
+	if _, err := f.WriteString(password + "\n"); err != nil {
+		f.Close()
+		os.Remove(path)
+		return fmt.Errorf("failed to write admin password file: %w", err)
+	}
+	return f.Close()
+}
+
+func (s *AuthService) CreateUser(spec *UserSpec) (*models.User, error) {
+	if spec.Username == "" {
+		return nil, fmt.Errorf("username is required")
+	}
+	if spec.Password == nil {
+		return nil, fmt.Errorf("password is required")
+	}
+
+	user := &models.User{Username: spec.Username, Role: models.RoleViewer}
+	if err := applyUserSpec(user, spec); err != nil {
+		return nil, err
+	}
+	if err := s.db.Create(user).Error; err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+	return user, nil
+}
+
+// UpdateUser 更新用户。角色、分组与禁用状态在下一次请求时即生效
+func (s *AuthService) UpdateUser(id uint, spec *UserSpec) (*models.User, error) {
+	var user models.User
+	if err := s.db.First(&user, id).Error; err != nil {
+		return nil, err
+	}
+	if err := applyUserSpec(&user, spec); err != nil {
+		return nil, err
+	}
+	if err := s.db.Save(&user).Error; err != nil {
+		return nil, fmt.Errorf("failed to update user: %w", err)
+	}
+
+	// 修改密码或禁用后已签发的登录令牌失效
+	if spec.Password != nil || (spec.Disabled != nil && *spec.Disabled) {
+		s.db.Where("user_id = ? AND kind = ?", id, models.TokenKindSession).Delete(&models.APIToken{})
+	}
+	return &user, nil
+}
+
+// DeleteUser 删除用户及其全部令牌
+func (s *AuthService) DeleteUser(id uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&models.User{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return tx.Where("user_id = ?", id).Delete(&models.APIToken{}).Error
+	})
+}
+
+// Login 校验用户名密码并签发登录令牌
+func (s *AuthService) Login(username, password string) (string, *models.APIToken, error) {
+	var user models.User
+	result := s.db.Where("username = ?", username).Limit(1).Find(&user)
+	if result.Error != nil {
+		return "", nil, result.Error
+	}
+	if result.RowsAffected == 0 || user.Disabled ||
+		bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) != nil {
+		return "", nil, ErrInvalidLogin
+	}
+
+	return s.issueToken(user.ID, models.TokenKindSession, "login", s.sessionTTL)
+}
+
+// CreateAPIToken 为用户创建 API 令牌，ttl 为 0 时永不过期。返回的明文令牌只在此时可见
+func (s *AuthService) CreateAPIToken(userID uint, name string, ttl time.Duration) (string, *models.APIToken, error) {
+	if ttl < 0 {
+		return "", nil, fmt.Errorf("ttl must not be negative")
+	}
+	return s.issueToken(userID, models.TokenKindAPI, name, ttl)
+}
+
+// ListTokens 返回用户的 API 令牌
+func (s *AuthService) ListTokens(userID uint) ([]models.APIToken, error) {
+	var tokens []models.APIToken
+	err := s.db.Where("user_id = ? AND kind = ?", userID, models.TokenKindAPI).
+		Order("created_at DESC").Find(&tokens).Error
+	return tokens, err
+}
+
+// DeleteToken 删除用户自己的令牌
+func (s *AuthService) DeleteToken(userID, tokenID uint) error {
+	result := s.db.Where("user_id = ?", userID).Delete(&models.APIToken{}, tokenID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// Logout 使令牌失效
+func (s *AuthService) Logout(token string) error {
+	return s.db.Where("token_hash = ?", hashSecret(token)).Delete(&models.APIToken{}).Error
+}
+
+// Authenticate 校验令牌并返回对应的请求者
+func (s *AuthService) Authenticate(token string) (*Principal, error) {
+	if token == "" {
+		return nil, ErrInvalidToken
+	}
+
+	var record models.APIToken
+	result := s.db.Where("token_hash = ?", hashSecret(token)).Limit(1).Find(&record)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	now := time.Now()
+	if result.RowsAffected == 0 || (record.ExpiresAt != nil && now.After(*record.ExpiresAt)) {
+		return nil, ErrInvalidToken
+	}
+
+	var user models.User
+	result = s.db.Limit(1).Find(&user, record.UserID)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 || user.Disabled {
+		return nil, ErrInvalidToken
+	}
+
+	if record.LastUsedAt == nil || now.Sub(*record.LastUsedAt) >= lastUsedInterval {
+		s.db.Model(&record).Update("last_used_at", now)
+	}
+	return principalFromUser(&user), nil
+}
+
+func (s *AuthService) issueToken(userID uint, kind, name string, ttl time.Duration) (string, *models.APIToken, error) {
+	prefix := apiTokenPrefix
+	if kind == models.TokenKindSession {
+		prefix = sessionTokenPrefix
+	}
+	token := prefix + randomSecret()
+
+	record := &models.APIToken{
+		UserID:    userID,
+		Name:      name,
+		Kind:      kind,
+		TokenHash: hashSecret(token),
+	}
+	if ttl > 0 {
+		expiresAt := time.Now().Add(ttl)
+		record.ExpiresAt = &expiresAt
+	}
+	if err := s.db.Create(record).Error; err != nil {
+		return "", nil, fmt.Errorf("failed to create token: %w", err)
+	}
+	return token, record, nil
+}
+
+func applyUserSpec(user *models.User, spec *UserSpec) error {
+	if spec.Role != nil {
+		if _, ok := roleLevels[*spec.Role]; !ok {
+			return fmt.Errorf("unknown role: %s", *spec.Role)
+		}
+		user.Role = *spec.Role
+	}
+	if spec.Password != nil {
+		if len(*spec.Password) < minPasswordLength {
+			return fmt.Errorf("password must be at least %d characters", minPasswordLength)
+		}
+		hash, err := bcrypt.GenerateFromPassword([]byte(*spec.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return err
+		}
+		user.PasswordHash = string(hash)
+	}
+	if spec.Groups != nil {
+		user.Groups = ""
+		if len(*spec.Groups) > 0 {
+			groups, _ := json.Marshal(*spec.Groups)
+			user.Groups = string(groups)
+		}
+	}
+	if spec.Disabled != nil {
+		user.Disabled = *spec.Disabled
+	}
+	return nil
+}
+
+func principalFromUser(user *models.User) *Principal {
+	p := &Principal{
+		UserID:   user.ID,
+		Username: user.Username,
+		Role:     user.Role,
+	}
+	if user.Groups != "" {
+		json.Unmarshal([]byte(user.Groups), &p.Groups)
+	}
+	return p
+}
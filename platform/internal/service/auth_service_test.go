@@ -0,0 +1,149 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/agent-platform/platform/internal/models"
+)
+
+func TestAuthService_LoginAndAuthenticate(t *testing.T) {
+	db := setupTestDB()
+	db.AutoMigrate(&models.User{}, &models.APIToken{})
+	service := NewAuthService(db, time.Hour)
+
+	password := "s3cret-pass"
+	role := models.RoleOperator
+	groups := []string{"web"}
+	user, err := service.CreateUser(&UserSpec{Username: "alice", Password: &password, Role: &role, Groups: &groups})
+	assert.NoError(t, err)
+	assert.NotEqual(t, password, user.PasswordHash)
+
+	_, _, err = service.Login("alice", "wrong-password")
+	assert.ErrorIs(t, err, ErrInvalidLogin)
+
+	token, record, err := service.Login("alice", password)
+	assert.NoError(t, err)
+	assert.Equal(t, models.TokenKindSession, record.Kind)
+
+	principal, err := service.Authenticate(token)
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", principal.Username)
+	assert.True(t, principal.HasRole(models.RoleViewer))
+	assert.True(t, principal.HasRole(models.RoleOperator))
+	assert.False(t, principal.HasRole(models.RoleAdmin))
+	assert.True(t, principal.CanAccessGroup("web"))
+	assert.False(t, principal.CanAccessGroup("db"))
+	assert.False(t, principal.CanAccessGroup(""))
+
+	// 禁用用户后令牌立即失效
+	disabled := true
+	_, err = service.UpdateUser(user.ID, &UserSpec{Disabled: &disabled})
+	assert.NoError(t, err)
+	_, err = service.Authenticate(token)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	_, _, err = service.Login("alice", password)
+	assert.ErrorIs(t, err, ErrInvalidLogin)
+}
+
+func TestAuthService_APITokens(t *testing.T) {
+	db := setupTestDB()
+	db.AutoMigrate(&models.User{}, &models.APIToken{})
+	service := NewAuthService(db, time.Hour)
+
+	assert.NoError(t, service.EnsureAdmin("root", "admin-password", ""))
+	// 已有用户时不再创建
+	assert.NoError(t, service.EnsureAdmin("other", "admin-password", ""))
+	var count int64
+	db.Model(&models.User{}).Count(&count)
+	assert.Equal(t, int64(1), count)
+
+	var admin models.User
+	db.Where("username = ?", "root").First(&admin)
+
+	token, record, err := service.CreateAPIToken(admin.ID, "ci", 0)
+	assert.NoError(t, err)
+	assert.Nil(t, record.ExpiresAt)
+
+	principal, err := service.Authenticate(token)
+	assert.NoError(t, err)
+	assert.True(t, principal.HasRole(models.RoleAdmin))
+	assert.False(t, principal.Scoped())
+
+	tokens, err := service.ListTokens(admin.ID)
+	assert.NoError(t, err)
+	assert.Len(t, tokens, 1)
+
+	assert.NoError(t, service.DeleteToken(admin.ID, record.ID))
+	_, err = service.Authenticate(token)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	// 过期令牌无效
+	token, record, _ = service.CreateAPIToken(admin.ID, "short", time.Hour)
+	db.Model(record).Update("expires_at", time.Now().Add(-time.Second))
+	_, err = service.Authenticate(token)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	_, err = service.CreateUser(&UserSpec{Username: "bob", Password: strPtr("bob-password"), Role: strPtr("root")})
+	assert.Error(t, err)
+}
+
+func strPtr(s string) *string {
+	return &s
+}
+
+func TestAuthService_EnsureAdminGeneratedPassword(t *testing.T) {
+	db := setupTestDB()
+	db.AutoMigrate(&models.User{}, &models.APIToken{})
+	service := NewAuthService(db, time.Hour)
+
+	passwordFile := filepath.Join(t.TempDir(), "data", "admin-password")
+	assert.NoError(t, service.EnsureAdmin("root", "", passwordFile))
+
+	info, err := os.Stat(passwordFile)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	content, err := os.ReadFile(passwordFile)
+	assert.NoError(t, err)
+	_, _, err = service.Login("root", strings.TrimSpace(string(content)))
+	assert.NoError(t, err)
+}
+
+func TestAuthService_LastUsedThrottled(t *testing.T) {
+	db := setupTestDB()
+	db.AutoMigrate(&models.User{}, &models.APIToken{})
+	service := NewAuthService(db, time.Hour)
+
+	password := "s3cret-pass"
+	_, err := service.CreateUser(&UserSpec{Username: "alice", Password: &password})
+	assert.NoError(t, err)
+	token, record, err := service.Login("alice", password)
+	assert.NoError(t, err)
+
+	_, err = service.Authenticate(token)
+	assert.NoError(t, err)
+	var stored models.APIToken
+	db.First(&stored, record.ID)
+	assert.NotNil(t, stored.LastUsedAt)
+
+	// 间隔内不再更新
+	recent := time.Now().Add(-lastUsedInterval / 2)
+	db.Model(&stored).Update("last_used_at", recent)
+	_, err = service.Authenticate(token)
+	assert.NoError(t, err)
+	db.First(&stored, record.ID)
+	assert.WithinDuration(t, recent, *stored.LastUsedAt, time.Millisecond)
+
+	// 超过间隔后更新
+	stale := time.Now().Add(-2 * lastUsedInterval)
+	db.Model(&stored).Update("last_used_at", stale)
+	_, err = service.Authenticate(token)
+	assert.NoError(t, err)
+	db.First(&stored, record.ID)
+	assert.True(t, stored.LastUsedAt.After(recent))
+}
@@ -0,0 +1,216 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/yourusername/agent-platform/platform/internal/models"
+	"github.com/yourusername/agent-platform/platform/internal/session"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidBootstrapToken = errors.New("invalid or expired bootstrap token")
+	ErrInvalidCredential     = errors.New("invalid agent credential")
+	ErrAgentRevoked          = errors.New("agent has been revoked")
+	ErrAgentAlreadyEnrolled  = errors.New("agent is already enrolled or revoked, a bootstrap token bound to it is required")
+)
+
+const (
+	// DefaultBootstrapTokenTTL 引导令牌默认有效期
+	DefaultBootstrapTokenTTL = time.Hour
+	// MaxBootstrapTokenTTL 引导令牌最长有效期
+	MaxBootstrapTokenTTL = 7 * 24 * time.Hour
+
+	bootstrapTokenPrefix = "bt_"
+	credentialPrefix     = "ac_"
+)
+
+// EnrollmentService 管理 Agent 登记：管理员创建一次性引导令牌，Agent 首次启动时用令牌换取凭据，
+// 之后每次注册都需要携带该凭据。令牌与凭据只保存哈希
+type EnrollmentService struct {
+	db              *gorm.DB
+	sessions        *session.Registry
+	allowUnenrolled bool
+}
+
+// NewEnrollmentService allowUnenrolled 为 true 时允许未携带凭据的 Agent 注册，仅用于迁移已有部署
+func NewEnrollmentService(db *gorm.DB, sessions *session.Registry, allowUnenrolled bool) *EnrollmentService {
+	return &EnrollmentService{
+		db:              db,
+		sessions:        sessions,
+		allowUnenrolled: allowUnenrolled,
+	}
+}
+
+// CreateToken 创建引导令牌，返回的明文令牌只在此时可见。
+// agentID 不为空时令牌只能登记该 Agent，用于重新登记已登记或已吊销的 Agent
+func (s *EnrollmentService) CreateToken(ttl time.Duration, description, agentID string) (string, *models.EnrollmentToken, error) {
+	if ttl <= 0 {
+		ttl = DefaultBootstrapTokenTTL
+	}
+	if ttl > MaxBootstrapTokenTTL {
+		return "", nil, fmt.Errorf("ttl must not exceed %s", MaxBootstrapTokenTTL)
+	}
+
+	token := bootstrapTokenPrefix + randomSecret()
+	record := &models.EnrollmentToken{
+		TokenHash:   hashSecret(token),
+		Description: description,
+		AgentID:     agentID,
+		ExpiresAt:   time.Now().Add(ttl),
+	}
+	if err := s.db.Create(record).Error; err != nil {
+		return "", nil, fmt.Errorf("failed to create bootstrap token: %w", err)
+	}
+	return token, record, nil
+}
+
+// DeleteToken 删除尚未使用的引导令牌
+func (s *EnrollmentService) DeleteToken(id uint) error {
+	result := s.db.Where("used_at IS NULL").Delete(&models.EnrollmentToken{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// Enroll 校验并消耗引导令牌，为 Agent 签发新凭据。已登记或已吊销的 Agent 只能使用绑定到该 Agent 的令牌
+// 重新登记，旧凭据随之失效，避免持有任意令牌者顶替已登记的 Agent
+func (s *EnrollmentService) Enroll(agentID, token string) (string, error) {
+	if agentID == "" {
+		return "", fmt.Errorf("agent_id is required")
+	}
+
+	credential := credentialPrefix + randomSecret()
+	now := time.Now()
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// 条件更新保证令牌只能使用一次
+		result := tx.Model(&models.EnrollmentToken{}).
+			Where("token_hash = ? AND used_at IS NULL AND expires_at > ? AND (agent_id = '' OR agent_id = ?)", hashSecret(token), now, agentID).
+			Updates(map[string]interface{}{
+				"used_at": now,
+				"used_by": agentID,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInvalidBootstrapToken
+		}
+
+		var agent models.Agent
+		found := tx.Where("agent_id = ?", agentID).Limit(1).Find(&agent)
+		if found.Error != nil {
+			return found.Error
+		}
+		if found.RowsAffected == 0 {
+			return tx.Create(&models.Agent{
+				AgentID:        agentID,
+				Status:         AgentStatusOffline,
+				CredentialHash: hashSecret(credential),
+				EnrolledAt:     &now,
+			}).Error
+		}
+		if agent.CredentialHash != "" || agent.RevokedAt != nil {
+			var record models.EnrollmentToken
+			if err := tx.Where("token_hash = ?", hashSecret(token)).First(&record).Error; err != nil {
+				return err
+			}
+			// 返回错误时事务回滚，令牌仍可使用
+			if record.AgentID != agentID {
+				return ErrAgentAlreadyEnrolled
+			}
+		}
+		return tx.Model(&agent).Updates(map[string]interface{}{
+			"credential_hash": hashSecret(credential),
+			"enrolled_at":     now,
+			"revoked_at":      nil,
+		}).Error
+	})
+	if err != nil {
+		return "", err
+	}
+
+	log.Printf("Agent %s enrolled", agentID)
+	s.recordEvent(agentID, AgentEventEnrolled, now)
+	return credential, nil
+}
+
+// Verify 校验 Agent 注册时携带的凭据
+func (s *EnrollmentService) Verify(agentID, credential string) error {
+	var agent models.Agent
+	result := s.db.Where("agent_id = ?", agentID).Limit(1).Find(&agent)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 && agent.RevokedAt != nil {
+		return ErrAgentRevoked
+	}
+	// 只有尚未登记的 Agent 可以不带凭据注册，已登记的 Agent 必须出示凭据
+	if credential == "" && s.allowUnenrolled && (result.RowsAffected == 0 || agent.CredentialHash == "") {
+		return nil
+	}
+	if result.RowsAffected == 0 {
+		return ErrInvalidCredential
+	}
+	if agent.CredentialHash == "" || credential == "" ||
+		subtle.ConstantTimeCompare([]byte(agent.CredentialHash), []byte(hashSecret(credential))) != 1 {
+		return ErrInvalidCredential
+	}
+	return nil
+}
+
+// Revoke 吊销 Agent 的凭据并关闭其连接，之后需要新的引导令牌重新登记
+func (s *EnrollmentService) Revoke(agentID string) error {
+	now := time.Now()
+	result := s.db.Model(&models.Agent{}).
+		Where("agent_id = ?", agentID).
+		Updates(map[string]interface{}{
+			"credential_hash": "",
+			"revoked_at":      now,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	s.sessions.Disconnect(agentID)
+	log.Printf("Agent %s revoked", agentID)
+	s.recordEvent(agentID, AgentEventRevoked, now)
+	return nil
+}
+
+func (s *EnrollmentService) recordEvent(agentID, eventType string, at time.Time) {
+	if err := s.db.Create(&models.AgentEvent{
+		AgentID:   agentID,
+		Type:      eventType,
+		Details:   "{}",
+		CreatedAt: at,
+	}).Error; err != nil {
+		log.Printf("Failed to record %s event of agent %s: %v", eventType, agentID, err)
+	}
+}
+
+func randomSecret() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,148 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/agent-platform/platform/internal/models"
+	"github.com/yourusername/agent-platform/platform/internal/session"
+)
+
+func TestEnrollmentService_EnrollAndVerify(t *testing.T) {
+	db := setupTestDB()
+	db.AutoMigrate(&models.Agent{}, &models.AgentEvent{}, &models.EnrollmentToken{})
+	service := NewEnrollmentService(db, session.NewRegistry(), false)
+
+	token, record, err := service.CreateToken(0, "rack 1", "")
+	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(DefaultBootstrapTokenTTL), record.ExpiresAt, time.Minute)
+
+	// 未登记的 Agent 不能注册
+	assert.ErrorIs(t, service.Verify("agent-1", ""), ErrInvalidCredential)
+
+	credential, err := service.Enroll("agent-1", token)
+	assert.NoError(t, err)
+	assert.NoError(t, service.Verify("agent-1", credential))
+	assert.ErrorIs(t, service.Verify("agent-1", "ac_wrong"), ErrInvalidCredential)
+	assert.ErrorIs(t, service.Verify("agent-1", ""), ErrInvalidCredential)
+
+	// 令牌只能使用一次
+	_, err = service.Enroll("agent-2", token)
+	assert.ErrorIs(t, err, ErrInvalidBootstrapToken)
+
+	db.First(record, record.ID)
+	assert.NotNil(t, record.UsedAt)
+	assert.Equal(t, "agent-1", record.UsedBy)
+
+	// 已使用的令牌不能删除
+	assert.Error(t, service.DeleteToken(record.ID))
+
+	var events []models.AgentEvent
+	db.Where("agent_id = ? AND type = ?", "agent-1", AgentEventEnrolled).Find(&events)
+	assert.Len(t, events, 1)
+}
+
+func TestEnrollmentService_ExpiredToken(t *testing.T) {
+	db := setupTestDB()
+	db.AutoMigrate(&models.Agent{}, &models.AgentEvent{}, &models.EnrollmentToken{})
+	service := NewEnrollmentService(db, session.NewRegistry(), false)
+
+	token, record, err := service.CreateToken(time.Hour, "", "")
+	assert.NoError(t, err)
+	db.Model(record).Update("expires_at", time.Now().Add(-time.Second))
+
+	_, err = service.Enroll("agent-1", token)
+	assert.ErrorIs(t, err, ErrInvalidBootstrapToken)
+
+	_, _, err = service.CreateToken(MaxBootstrapTokenTTL+time.Hour, "", "")
+	assert.Error(t, err)
+}
+
+func TestEnrollmentService_Revoke(t *testing.T) {
+	db := setupTestDB()
+	db.AutoMigrate(&models.Agent{}, &models.AgentEvent{}, &models.EnrollmentToken{})
+	sessions := session.NewRegistry()
+	service := NewEnrollmentService(db, sessions, true)
+
+	token, _, _ := service.CreateToken(time.Hour, "", "")
+	credential, err := service.Enroll("agent-1", token)
+	assert.NoError(t, err)
+
+	sessions.Add("agent-1", session.NewSession(&mockStream{}))
+	assert.NoError(t, service.Revoke("agent-1"))
+	assert.False(t, sessions.IsConnected("agent-1"))
+
+	// 吊销后旧凭据失效，即使允许未登记 Agent 也不能注册
+	assert.ErrorIs(t, service.Verify("agent-1", credential), ErrAgentRevoked)
+	assert.ErrorIs(t, service.Verify("agent-1", ""), ErrAgentRevoked)
+
+	// 未绑定的令牌不能撤销吊销
+	token, _, _ = service.CreateToken(time.Hour, "", "")
+	_, err = service.Enroll("agent-1", token)
+	assert.ErrorIs(t, err, ErrAgentAlreadyEnrolled)
+	assert.ErrorIs(t, service.Verify("agent-1", ""), ErrAgentRevoked)
+
+	// 使用绑定到该 Agent 的新令牌重新登记
+	token, _, _ = service.CreateToken(time.Hour, "", "agent-1")
+	credential, err = service.Enroll("agent-1", token)
+	assert.NoError(t, err)
+	assert.NoError(t, service.Verify("agent-1", credential))
+
+	// 允许未登记的 Agent 不带凭据注册
+	assert.NoError(t, service.Verify("agent-2", ""))
+}
+
+func TestEnrollmentService_ReenrollRequiresBoundToken(t *testing.T) {
+	db := setupTestDB()
+	db.AutoMigrate(&models.Agent{}, &models.AgentEvent{}, &models.EnrollmentToken{})
+	service := NewEnrollmentService(db, session.NewRegistry(), false)
+
+	token, _, _ := service.CreateToken(time.Hour, "", "")
+	credential, err := service.Enroll("agent-1", token)
+	assert.NoError(t, err)
+
+	// 未绑定的令牌不能顶替已登记的 Agent，失败时令牌不被消耗
+	token, record, _ := service.CreateToken(time.Hour, "", "")
+	_, err = service.Enroll("agent-1", token)
+	assert.ErrorIs(t, err, ErrAgentAlreadyEnrolled)
+	assert.NoError(t, service.Verify("agent-1", credential))
+	db.First(record, record.ID)
+	assert.Nil(t, record.UsedAt)
+
+	// 绑定到其他 Agent 的令牌不能使用
+	bound, _, _ := service.CreateToken(time.Hour, "", "agent-2")
+	_, err = service.Enroll("agent-1", bound)
+	assert.ErrorIs(t, err, ErrInvalidBootstrapToken)
+
+	// 绑定到该 Agent 的令牌可以重新登记，旧凭据失效
+	bound, _, _ = service.CreateToken(time.Hour, "", "agent-1")
+	newCredential, err := service.Enroll("agent-1", bound)
+	assert.NoError(t, err)
+	assert.NoError(t, service.Verify("agent-1", newCredential))
+	assert.ErrorIs(t, service.Verify("agent-1", credential), ErrInvalidCredential)
+
+	// 迁移前已存在但尚未登记的 Agent 可以使用未绑定的令牌
+	db.Create(&models.Agent{AgentID: "legacy", Status: AgentStatusOffline})
+	_, err = service.Enroll("legacy", token)
+	assert.NoError(t, err)
+}
+
+func TestEnrollmentService_AllowUnenrolled(t *testing.T) {
+	db := setupTestDB()
+	db.AutoMigrate(&models.Agent{}, &models.AgentEvent{}, &models.EnrollmentToken{})
+	service := NewEnrollmentService(db, session.NewRegistry(), true)
+
+	token, _, _ := service.CreateToken(time.Hour, "", "")
+	credential, err := service.Enroll("agent-1", token)
+	assert.NoError(t, err)
+
+	// 已登记的 Agent 不带凭据注册被拒绝，不能被冒充
+	assert.ErrorIs(t, service.Verify("agent-1", ""), ErrInvalidCredential)
+	assert.NoError(t, service.Verify("agent-1", credential))
+
+	// 尚未登记的 Agent 可以不带凭据注册
+	db.Create(&models.Agent{AgentID: "legacy", Status: AgentStatusOffline})
+	assert.NoError(t, service.Verify("legacy", ""))
+	assert.NoError(t, service.Verify("new-agent", ""))
+}
@@ -0,0 +1,447 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yourusername/agent-platform/platform/internal/models"
+	"gorm.io/gorm"
+)
+
+// 作业状态
+const (
+	JobStatusRunning   = "running"
+	JobStatusCompleted = "completed"
+	JobStatusFailed    = "failed"
+	JobStatusStopped   = "stopped" // 失败比例超过阈值后停止
+	JobStatusCancelled = "cancelled"
+)
+
+var ErrNoTargetAgents = errors.New("no target agents")
+
+// JobSpec 创建作业的参数
+type JobSpec struct {
+	Name                 string
+	AgentIDs             []string
+	Selector             string
+	Type                 string
+	Script               string
+	Timeout              int
+	Concurrency          int
+	BatchSize            int
+	StopOnFailurePercent int
+	// Groups 限制目标 Agent 所属分组，为空时不限制
+	Groups []string
+	// CreatedBy 创建作业的用户，记录到每个子任务
+	CreatedBy string
+}
+
+// JobAgentStatus 作业中单个 Agent 的执行情况
+type JobAgentStatus struct {
+	AgentID  string `json:"agent_id"`
+	TaskID   string `json:"task_id"`
+	Batch    int    `json:"batch"`
+	Status   string `json:"status"`
+	ExitCode int    `json:"exit_code"`
+}
+
+// JobSummary 作业的聚合视图
+type JobSummary struct {
+	Job         models.Job       `json:"job"`
+	Total       int              `json:"total"`
+	Counts      map[string]int   `json:"counts"`
+	SuccessRate float64          `json:"success_rate"`
+	ExitCodes   map[int]int      `json:"exit_codes"`
+	Agents      []JobAgentStatus `json:"agents"`
+}
+
+// JobService 管理批量作业：按目标 Agent 拆分子任务，
+// 根据并发数与滚动批次逐步下发，并在失败比例超限时停止
+type JobService struct {
+	db         *gorm.DB
+	dispatcher *TaskDispatcher
+	policy     *PolicyService
+	mu         sync.Mutex
+}
+
+// NewJobService 创建作业服务，policy 为 nil 时不做脚本策略检查
+func NewJobService(db *gorm.DB, dispatcher *TaskDispatcher, policy *PolicyService) *JobService {
+	s := &JobService{
+		db:         db,
+		dispatcher: dispatcher,
+		policy:     policy,
+	}
+
+	dispatcher.OnTaskFinished(func(task *models.Task) {
+		if task.JobID != "" {
+			go s.advance(task.JobID)
+		}
+	})
+	return s
+}
+
+// Create 创建作业并开始下发第一批子任务
+func (s *JobService) Create(spec *JobSpec) (*models.Job, error) {
+	if _, err := ParseScriptType(spec.Type); err != nil {
+		return nil, err
+	}
+	if spec.StopOnFailurePercent < 0 || spec.StopOnFailurePercent > 100 {
+		return nil, fmt.Errorf("stop_on_failure_percent must be between 0 and 100")
+	}
+
+	agentIDs, err := s.resolveTargets(spec)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkPolicy(spec, agentIDs); err != nil {
+		return nil, err
+	}
+
+	job := &models.Job{
+		JobID:                generateID("job"),
+		Name:                 spec.Name,
+		Type:                 spec.Type,
+		Script:               spec.Script,
+		Timeout:              spec.Timeout,
+		Selector:             spec.Selector,
+		Concurrency:          spec.Concurrency,
+		BatchSize:            spec.BatchSize,
+		StopOnFailurePercent: spec.StopOnFailurePercent,
+		Status:               JobStatusRunning,
+	}
+	if job.Timeout <= 0 {
+		job.Timeout = DefaultTaskTimeout
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(job).Error; err != nil {
+			return err
+		}
+
+		tasks := make([]models.Task, 0, len(agentIDs))
+		for i, agentID := range agentIDs {
+			task := specTask(spec)
+			task.TaskID = generateID("task")
+			task.AgentID = agentID
+			task.JobID = job.JobID
+			if job.BatchSize > 0 {
+				task.Batch = i / job.BatchSize
+			}
+			task.Timeout = job.Timeout
+			task.Status = TaskStatusQueued
+			tasks = append(tasks, *task)
+		}
+		return tx.Create(&tasks).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create job: %w", err)
+	}
+
+	s.advance(job.JobID)
+	return job, nil
+}
+
+// specTask 返回作业子任务的公共内容，策略检查与创建子任务使用相同的内容
+func specTask(spec *JobSpec) *models.Task {
+	return &models.Task{
+		Type:      spec.Type,
+		Script:    spec.Script,
+		CreatedBy: spec.CreatedBy,
+	}
+}
+
+// checkPolicy 对每个目标 Agent 评估脚本策略。作业不支持审批流程，
+// 任一目标拒绝或要求审批时整个作业都不会创建
+func (s *JobService) checkPolicy(spec *JobSpec, agentIDs []string) error {
+	if s.policy == nil {
+		return nil
+	}
+	content := TaskPolicyContent(specTask(spec))
+	for _, agentID := range agentIDs {
+		decision, err := s.policy.Evaluate(agentID, spec.Type, content, spec.Timeout)
+		if err != nil {
+			return err
+		}
+		if !decision.Allowed {
+			return fmt.Errorf("%w: agent %s: %s", ErrPolicyRejected, agentID, decision.Reason)
+		}
+		if decision.RequireApproval {
+			return fmt.Errorf("%w: agent %s requires approval, submit it as a single task", ErrPolicyRejected, agentID)
+		}
+	}
+	return nil
+}
+
+// Cancel 取消作业：排队中的子任务直接取消，执行中的子任务通知 Agent 终止
+func (s *JobService) Cancel(jobID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var job models.Job
+	if err := s.db.Where("job_id = ?", jobID).First(&job).Error; err != nil {
+		return err
+	}
+	if job.Status != JobStatusRunning {
+		return fmt.Errorf("job is not running")
+	}
+
+	if err := s.finish(&job, JobStatusCancelled); err != nil {
+		return err
+	}
+
+	var tasks []models.Task
+	if err := s.db.Where("job_id = ? AND status IN ?", jobID,
+		[]string{TaskStatusQueued, TaskStatusPending, TaskStatusDispatched, TaskStatusRunning}).Find(&tasks).Error; err != nil {
+		return err
+	}
+	for i := range tasks {
+		if err := s.dispatcher.Cancel(&tasks[i]); err != nil {
+			log.Printf("Failed to cancel task %s of job %s: %v", tasks[i].TaskID, jobID, err)
+		}
+	}
+	return nil
+}
+
+// Summary 返回作业的聚合视图
+func (s *JobService) Summary(job *models.Job) (*JobSummary, error) {
+	var tasks []models.Task
+	if err := s.db.Where("job_id = ?", job.JobID).Order("batch ASC, id ASC").Find(&tasks).Error; err != nil {
+		return nil, err
+	}
+
+	summary := &JobSummary{
+		Job:       *job,
+		Total:     len(tasks),
+		Counts:    make(map[string]int),
+		ExitCodes: make(map[int]int),
+		Agents:    make([]JobAgentStatus, 0, len(tasks)),
+	}
+
+	finished := 0
+	for _, task := range tasks {
+		summary.Counts[task.Status]++
+		if task.Status == TaskStatusCompleted || task.Status == TaskStatusFailed {
+			summary.ExitCodes[task.ExitCode]++
+		}
+		if IsTaskFinished(task.Status) {
+			finished++
+		}
+		summary.Agents = append(summary.Agents, JobAgentStatus{
+			AgentID:  task.AgentID,
+			TaskID:   task.TaskID,
+			Batch:    task.Batch,
+			Status:   task.Status,
+			ExitCode: task.ExitCode,
+		})
+	}
+	if finished > 0 {
+		summary.SuccessRate = float64(summary.Counts[TaskStatusCompleted]) / float64(finished) * 100
+	}
+	return summary, nil
+}
+
+// Resume 在平台重启后继续推进所有运行中的作业
+func (s *JobService) Resume() {
+	var jobs []models.Job
+	if err := s.db.Where("status = ?", JobStatusRunning).Find(&jobs).Error; err != nil {
+		log.Printf("Failed to load running jobs: %v", err)
+		return
+	}
+	for _, job := range jobs {
+		s.advance(job.JobID)
+	}
+}
+
+// advance 根据子任务状态推进作业：检查失败比例、判定是否结束，并按并发与批次下发排队中的子任务
+func (s *JobService) advance(jobID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var job models.Job
+	if err := s.db.Where("job_id = ?", jobID).First(&job).Error; err != nil {
+		log.Printf("Failed to load job %s: %v", jobID, err)
+		return
+	}
+	if job.Status != JobStatusRunning {
+		return
+	}
+
+	var tasks []models.Task
+	if err := s.db.Where("job_id = ?", jobID).Order("batch ASC, id ASC").Find(&tasks).Error; err != nil {
+		log.Printf("Failed to load tasks of job %s: %v", jobID, err)
+		return
+	}
+
+	failed, inFlight, unfinished := 0, 0, 0
+	currentBatch := -1
+	for _, task := range tasks {
+		switch task.Status {
+		case TaskStatusFailed, TaskStatusTimeout, TaskStatusRejected:
+			failed++
+		case TaskStatusPending, TaskStatusDispatched, TaskStatusRunning:
+			inFlight++
+		}
+		if !IsTaskFinished(task.Status) {
+			unfinished++
+			if currentBatch == -1 || task.Batch < currentBatch {
+				currentBatch = task.Batch
+			}
+		}
+	}
+
+	// 已结束子任务中的失败比例超过阈值，停止下发剩余子任务
+	finished := len(tasks) - unfinished
+	if job.StopOnFailurePercent > 0 && finished > 0 && failed*100 > job.StopOnFailurePercent*finished {
+		if err := s.db.Model(&models.Task{}).
+			Where("job_id = ? AND status = ?", jobID, TaskStatusQueued).
+			Updates(map[string]interface{}{
+				"status":       TaskStatusCancelled,
+				"completed_at": time.Now(),
+			}).Error; err != nil {
+			log.Printf("Failed to cancel queued tasks of job %s: %v", jobID, err)
+		}
+		if err := s.finish(&job, JobStatusStopped); err != nil {
+			log.Printf("Failed to stop job %s: %v", jobID, err)
+		}
+		log.Printf("Job %s stopped: %d/%d finished tasks failed", jobID, failed, finished)
+		return
+	}
+
+	if unfinished == 0 {
+		status := JobStatusCompleted
+		if failed > 0 {
+			status = JobStatusFailed
+		}
+		if err := s.finish(&job, status); err != nil {
+			log.Printf("Failed to finish job %s: %v", jobID, err)
+		}
+		return
+	}
+
+	for i := range tasks {
+		task := &tasks[i]
+		if task.Status != TaskStatusQueued {
+			continue
+		}
+		if job.Concurrency > 0 && inFlight >= job.Concurrency {
+			break
+		}
+		// 滚动批次：前一批全部结束后才下发下一批
+		if job.BatchSize > 0 && task.Batch != currentBatch {
+			break
+		}
+		if err := s.dispatcher.Release(task); err != nil {
+			log.Printf("Failed to release task %s of job %s: %v", task.TaskID, jobID, err)
+			continue
+		}
+		inFlight++
+	}
+}
+
+func (s *JobService) finish(job *models.Job, status string) error {
+	now := time.Now()
+	job.Status = status
+	job.CompletedAt = &now
+	return s.db.Model(job).Updates(map[string]interface{}{
+		"status":       status,
+		"completed_at": now,
+	}).Error
+}
+
+// resolveTargets 根据显式列出的 Agent 或标签选择器确定目标 Agent，并限制在 spec.Groups 范围内
+func (s *JobService) resolveTargets(spec *JobSpec) ([]string, error) {
+	if len(spec.AgentIDs) > 0 {
+		seen := make(map[string]bool)
+		agentIDs := make([]string, 0, len(spec.AgentIDs))
+		for _, id := range spec.AgentIDs {
+			if id == "" || seen[id] {
+				continue
+			}
+			seen[id] = true
+			agentIDs = append(agentIDs, id)
+		}
+		if len(agentIDs) == 0 {
+			return nil, ErrNoTargetAgents
+		}
+		if len(spec.Groups) > 0 {
+			var count int64
+			if err := s.db.Model(&models.Agent{}).
+				Where("agent_id IN ? AND group_name IN ?", agentIDs, spec.Groups).
+				Count(&count).Error; err != nil {
+				return nil, fmt.Errorf("failed to load agents: %w", err)
+			}
+			if int(count) != len(agentIDs) {
+				return nil, ErrForbidden
+			}
+		}
+		return agentIDs, nil
+	}
+
+	selector, err := ParseSelector(spec.Selector)
+	if err != nil {
+		return nil, err
+	}
+	if len(selector) == 0 {
+		return nil, fmt.Errorf("agent_ids or selector is required")
+	}
+
+	var agents []models.Agent
+	query := s.db
+	if len(spec.Groups) > 0 {
+		query = query.Where("group_name IN ?", spec.Groups)
+	}
+	if err := query.Find(&agents).Error; err != nil {
+		return nil, fmt.Errorf("failed to load agents: %w", err)
+	}
+
+	var agentIDs []string
+	for _, agent := range agents {
+		if MatchLabels(agent.Labels, selector) {
+			agentIDs = append(agentIDs, agent.AgentID)
+		}
+	}
+	if len(agentIDs) == 0 {
+		return nil, ErrNoTargetAgents
+	}
+	sort.Strings(agentIDs)
+	return agentIDs, nil
+}
+
+// ParseSelector 解析形如 key1=value1,key2=value2 的标签选择器
+func ParseSelector(selector string) (map[string]string, error) {
+	result := make(map[string]string)
+	for _, part := range strings.Split(selector, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+			return nil, fmt.Errorf("invalid selector term: %s", part)
+		}
+		result[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+	return result, nil
+}
+
+// MatchLabels 判断 JSON 编码的标签是否满足选择器的全部条件
+func MatchLabels(labelsJSON string, selector map[string]string) bool {
+	labels := make(map[string]string)
+	if labelsJSON != "" {
+		if err := json.Unmarshal([]byte(labelsJSON), &labels); err != nil {
+			return false
+		}
+	}
+	for k, v := range selector {
+		if labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,237 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	pb "github.com/yourusername/agent-platform/proto"
+	"github.com/yourusername/agent-platform/platform/internal/models"
+	"github.com/yourusername/agent-platform/platform/internal/session"
+	"gorm.io/gorm"
+)
+
+func setupJobTestDB(t *testing.T) *gorm.DB {
+	db := setupTestDB()
+	assert.NoError(t, db.AutoMigrate(&models.Agent{}, &models.Job{}))
+
+	// 内存数据库每个连接相互独立，回调在其他 goroutine 中访问数据库时需共用一个连接
+	sqlDB, err := db.DB()
+	assert.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	return db
+}
+
+func jobTaskStatuses(db *gorm.DB, jobID string) map[string]string {
+	var tasks []models.Task
+	db.Where("job_id = ?", jobID).Find(&tasks)
+
+	statuses := make(map[string]string)
+	for _, task := range tasks {
+		statuses[task.AgentID] = task.Status
+	}
+	return statuses
+}
+
+func TestJobService_RollingBatches(t *testing.T) {
+	db := setupJobTestDB(t)
+	sessions := session.NewRegistry()
+	for _, id := range []string{"agent-1", "agent-2", "agent-3"} {
+		sessions.Add(id, session.NewSession(&mockStream{}))
+	}
+	dispatcher := NewTaskDispatcher(db, sessions)
+	jobService := NewJobService(db, dispatcher, nil)
+
+	job, err := jobService.Create(&JobSpec{
+		AgentIDs:  []string{"agent-1", "agent-2", "agent-3"},
+		Type:      "shell",
+		Script:    "uptime",
+		BatchSize: 2,
+	})
+	assert.NoError(t, err)
+
+	statuses := jobTaskStatuses(db, job.JobID)
+	assert.Equal(t, TaskStatusDispatched, statuses["agent-1"])
+	assert.Equal(t, TaskStatusDispatched, statuses["agent-2"])
+	assert.Equal(t, TaskStatusQueued, statuses["agent-3"])
+
+	// 第一批全部结束后下发第二批
+	var tasks []models.Task
+	db.Where("job_id = ? AND batch = 0", job.JobID).Find(&tasks)
+	for _, task := range tasks {
+		assert.NoError(t, dispatcher.HandleResult(task.AgentID, &pb.TaskResult{TaskId: task.TaskID, Status: pb.TaskStatus_TASK_STATUS_COMPLETED}))
+	}
+	assert.Eventually(t, func() bool {
+		return jobTaskStatuses(db, job.JobID)["agent-3"] == TaskStatusDispatched
+	}, 2*time.Second, 20*time.Millisecond)
+
+	var last models.Task
+	db.Where("job_id = ? AND agent_id = ?", job.JobID, "agent-3").First(&last)
+	assert.NoError(t, dispatcher.HandleResult(last.AgentID, &pb.TaskResult{TaskId: last.TaskID, ExitCode: 2, Status: pb.TaskStatus_TASK_STATUS_COMPLETED}))
+
+	assert.Eventually(t, func() bool {
+		var current models.Job
+		db.First(&current, job.ID)
+		return current.Status == JobStatusFailed
+	}, 2*time.Second, 20*time.Millisecond)
+
+	summary, err := jobService.Summary(job)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, summary.Total)
+	assert.Equal(t, 2, summary.ExitCodes[0])
+	assert.Equal(t, 1, summary.ExitCodes[2])
+	assert.InDelta(t, 66.67, summary.SuccessRate, 0.01)
+}
+
+func TestJobService_StopOnFailure(t *testing.T) {
+	db := setupJobTestDB(t)
+	sessions := session.NewRegistry()
+	sessions.Add("agent-1", session.NewSession(&mockStream{}))
+	dispatcher := NewTaskDispatcher(db, sessions)
+	jobService := NewJobService(db, dispatcher, nil)
+
+	job, err := jobService.Create(&JobSpec{
+		AgentIDs:             []string{"agent-1", "agent-2", "agent-3", "agent-4"},
+		Type:                 "shell",
+		Script:               "false",
+		Concurrency:          1,
+		StopOnFailurePercent: 20,
+	})
+	assert.NoError(t, err)
+
+	var first models.Task
+	db.Where("job_id = ? AND agent_id = ?", job.JobID, "agent-1").First(&first)
+	assert.Equal(t, TaskStatusDispatched, first.Status)
+	assert.NoError(t, dispatcher.HandleResult(first.AgentID, &pb.TaskResult{TaskId: first.TaskID, ExitCode: 1, Status: pb.TaskStatus_TASK_STATUS_COMPLETED}))
+
+	// 1/4 失败超过 20%，剩余子任务不再下发
+	assert.Eventually(t, func() bool {
+		var current models.Job
+		db.First(&current, job.ID)
+		return current.Status == JobStatusStopped
+	}, 2*time.Second, 20*time.Millisecond)
+
+	statuses := jobTaskStatuses(db, job.JobID)
+	assert.Equal(t, TaskStatusCancelled, statuses["agent-2"])
+	assert.Equal(t, TaskStatusCancelled, statuses["agent-4"])
+}
+
+func TestJobService_StopOnFailureOfFinished(t *testing.T) {
+	db := setupJobTestDB(t)
+	sessions := session.NewRegistry()
+	sessions.Add("agent-1", session.NewSession(&mockStream{}))
+	sessions.Add("agent-2", session.NewSession(&mockStream{}))
+	dispatcher := NewTaskDispatcher(db, sessions)
+	jobService := NewJobService(db, dispatcher, nil)
+
+	job, err := jobService.Create(&JobSpec{
+		AgentIDs:             []string{"agent-1", "agent-2", "agent-3", "agent-4", "agent-5"},
+		Type:                 "shell",
+		Script:               "uptime",
+		Concurrency:          2,
+		StopOnFailurePercent: 40,
+	})
+	assert.NoError(t, err)
+
+	var tasks []models.Task
+	db.Where("job_id = ? AND agent_id IN ?", job.JobID, []string{"agent-1", "agent-2"}).Order("agent_id").Find(&tasks)
+	assert.Len(t, tasks, 2)
+	assert.NoError(t, dispatcher.HandleResult(tasks[0].AgentID, &pb.TaskResult{TaskId: tasks[0].TaskID, Status: pb.TaskStatus_TASK_STATUS_COMPLETED}))
+	assert.Eventually(t, func() bool {
+		return jobTaskStatuses(db, job.JobID)["agent-3"] == TaskStatusPending
+	}, 2*time.Second, 20*time.Millisecond)
+	assert.NoError(t, dispatcher.HandleResult(tasks[1].AgentID, &pb.TaskResult{TaskId: tasks[1].TaskID, ExitCode: 1, Status: pb.TaskStatus_TASK_STATUS_COMPLETED}))
+
+	// 已结束的 2 个子任务中 1 个失败，超过 40%（按全部 5 个子任务计算只有 20%）
+	assert.Eventually(t, func() bool {
+		var current models.Job
+		db.First(&current, job.ID)
+		return current.Status == JobStatusStopped
+	}, 2*time.Second, 20*time.Millisecond)
+	assert.Equal(t, TaskStatusCancelled, jobTaskStatuses(db, job.JobID)["agent-5"])
+}
+
+func TestJobService_OfflineAgentTimesOut(t *testing.T) {
+	db := setupJobTestDB(t)
+	sessions := session.NewRegistry()
+	sessions.Add("agent-1", session.NewSession(&mockStream{}))
+	dispatcher := NewTaskDispatcher(db, sessions)
+	jobService := NewJobService(db, dispatcher, nil)
+
+	job, err := jobService.Create(&JobSpec{
+		AgentIDs:    []string{"agent-1", "agent-2", "agent-3"},
+		Type:        "shell",
+		Script:      "uptime",
+		Timeout:     1,
+		Concurrency: 2,
+	})
+	assert.NoError(t, err)
+
+	// agent-2 离线，子任务停留在 pending 并占用一个并发
+	statuses := jobTaskStatuses(db, job.JobID)
+	assert.Equal(t, TaskStatusDispatched, statuses["agent-1"])
+	assert.Equal(t, TaskStatusPending, statuses["agent-2"])
+	assert.Equal(t, TaskStatusQueued, statuses["agent-3"])
+
+	var first models.Task
+	db.Where("job_id = ? AND agent_id = ?", job.JobID, "agent-1").First(&first)
+	assert.NoError(t, dispatcher.HandleResult("agent-1", &pb.TaskResult{TaskId: first.TaskID, Status: pb.TaskStatus_TASK_STATUS_COMPLETED}))
+	assert.Eventually(t, func() bool {
+		return jobTaskStatuses(db, job.JobID)["agent-3"] == TaskStatusPending
+	}, 2*time.Second, 20*time.Millisecond)
+
+	// 超过期限后离线 Agent 的子任务超时，作业得以结束
+	db.Model(&models.Task{}).Where("job_id = ? AND status = ?", job.JobID, TaskStatusPending).
+		UpdateColumn("updated_at", time.Now().Add(-time.Hour))
+	dispatcher.checkTimeouts()
+	assert.Eventually(t, func() bool {
+		var current models.Job
+		db.First(&current, job.ID)
+		return current.Status == JobStatusFailed
+	}, 2*time.Second, 20*time.Millisecond)
+	statuses = jobTaskStatuses(db, job.JobID)
+	assert.Equal(t, TaskStatusTimeout, statuses["agent-2"])
+	assert.Equal(t, TaskStatusTimeout, statuses["agent-3"])
+}
+
+func TestJobService_Selector(t *testing.T) {
+	db := setupJobTestDB(t)
+	db.Create(&models.Agent{AgentID: "web-1", Labels: `{"env":"prod","role":"web"}`})
+	db.Create(&models.Agent{AgentID: "web-2", Labels: `{"env":"staging","role":"web"}`})
+	db.Create(&models.Agent{AgentID: "db-1", Labels: `{"env":"prod","role":"db"}`})
+
+	jobService := NewJobService(db, NewTaskDispatcher(db, session.NewRegistry()), nil)
+
+	job, err := jobService.Create(&JobSpec{Selector: "env=prod, role=web", Type: "shell", Script: "uptime"})
+	assert.NoError(t, err)
+
+	statuses := jobTaskStatuses(db, job.JobID)
+	assert.Len(t, statuses, 1)
+	// Agent 不在线，子任务转为 pending 等待重连
+	assert.Equal(t, TaskStatusPending, statuses["web-1"])
+
+	_, err = jobService.Create(&JobSpec{Selector: "env=dev", Type: "shell", Script: "uptime"})
+	assert.ErrorIs(t, err, ErrNoTargetAgents)
+
+	_, err = jobService.Create(&JobSpec{Selector: "env", Type: "shell", Script: "uptime"})
+	assert.Error(t, err)
+}
+
+func TestJobService_GroupScope(t *testing.T) {
+	db := setupJobTestDB(t)
+	db.Create(&models.Agent{AgentID: "web-1", Group: "web", Labels: `{"env":"prod"}`})
+	db.Create(&models.Agent{AgentID: "db-1", Group: "db", Labels: `{"env":"prod"}`})
+
+	jobService := NewJobService(db, NewTaskDispatcher(db, session.NewRegistry()), nil)
+
+	// 选择器只匹配可访问分组内的 Agent
+	job, err := jobService.Create(&JobSpec{Selector: "env=prod", Type: "shell", Script: "uptime", Groups: []string{"web"}})
+	assert.NoError(t, err)
+	statuses := jobTaskStatuses(db, job.JobID)
+	assert.Len(t, statuses, 1)
+	assert.Contains(t, statuses, "web-1")
+
+	// 显式列出分组外的 Agent 时拒绝
+	_, err = jobService.Create(&JobSpec{AgentIDs: []string{"web-1", "db-1"}, Type: "shell", Script: "uptime", Groups: []string{"web"}})
+	assert.ErrorIs(t, err, ErrForbidden)
+}
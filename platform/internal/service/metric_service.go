@@ -0,0 +1,61 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+
+	pb "github.com/yourusername/agent-platform/proto"
+	"github.com/yourusername/agent-platform/platform/internal/models"
+	"gorm.io/gorm"
+)
+
+// metricInsertBatchSize 批量写入指标时每条 INSERT 的行数
+const metricInsertBatchSize = 200
+
+// MetricService 将 Agent 上报的插件指标写入 metrics 表
+type MetricService struct {
+	db *gorm.DB
+}
+
+func NewMetricService(db *gorm.DB) *MetricService {
+	return &MetricService{db: db}
+}
+
+// Ingest 批量保存一次上报的指标，标签以 JSON 形式存入 Labels
+func (s *MetricService) Ingest(agentID string, batch *pb.MetricBatch) error {
+	if len(batch.Metrics) == 0 {
+		return nil
+	}
+
+	metrics := make([]models.Metric, 0, len(batch.Metrics))
+	for _, point := range batch.Metrics {
+		if point.Name == "" {
+			continue
+		}
+
+		var labels string
+		if len(point.Labels) > 0 {
+			data, err := json.Marshal(point.Labels)
+			if err != nil {
+				return fmt.Errorf("failed to encode labels of metric %s: %w", point.Name, err)
+			}
+			labels = string(data)
+		}
+
+		metrics = append(metrics, models.Metric{
+			AgentID:   agentID,
+			Name:      point.Name,
+			Value:     point.Value,
+			Labels:    labels,
+			Timestamp: timestampToTime(point.Timestamp),
+		})
+	}
+	if len(metrics) == 0 {
+		return nil
+	}
+
+	if err := s.db.CreateInBatches(metrics, metricInsertBatchSize).Error; err != nil {
+		return fmt.Errorf("failed to save metrics: %w", err)
+	}
+	return nil
+}
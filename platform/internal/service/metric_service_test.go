@@ -0,0 +1,34 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	pb "github.com/yourusername/agent-platform/proto"
+	"github.com/yourusername/agent-platform/platform/internal/models"
+)
+
+func TestMetricService_Ingest(t *testing.T) {
+	db := setupTestDB()
+	db.AutoMigrate(&models.Metric{})
+	service := NewMetricService(db)
+
+	err := service.Ingest("agent-1", &pb.MetricBatch{
+		AgentId: "agent-1",
+		Metrics: []*pb.MetricPoint{
+			{Name: "cpu_usage", Value: 12.5, Timestamp: &pb.Timestamp{Seconds: 1700000000}, Labels: map[string]string{"plugin": "cpu"}},
+			{Name: "memory_usage", Value: 40},
+			{Name: "", Value: 1},
+		},
+	})
+	assert.NoError(t, err)
+
+	var metrics []models.Metric
+	db.Order("id ASC").Find(&metrics)
+	assert.Len(t, metrics, 2)
+	assert.Equal(t, "agent-1", metrics[0].AgentID)
+	assert.Equal(t, 12.5, metrics[0].Value)
+	assert.Equal(t, `{"plugin":"cpu"}`, metrics[0].Labels)
+	assert.Equal(t, int64(1700000000), metrics[0].Timestamp.Unix())
+	assert.Empty(t, metrics[1].Labels)
+}
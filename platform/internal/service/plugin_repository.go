@@ -0,0 +1,172 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/yourusername/agent-platform/pkg/pluginpkg"
+	"github.com/yourusername/agent-platform/platform/internal/models"
+	"gorm.io/gorm"
+)
+
+const (
+	// DefaultPluginDir 未配置时插件安装包的存放目录
+	DefaultPluginDir = "data/plugins"
+	// MaxPluginPackageSize 插件安装包的大小上限
+	MaxPluginPackageSize = 64 << 20
+)
+
+var (
+	ErrPluginPackageExists   = errors.New("plugin package version already exists")
+	ErrPluginPackageTooLarge = fmt.Errorf("plugin package exceeds %d bytes", MaxPluginPackageSize)
+	ErrInvalidPluginPackage  = errors.New("invalid plugin package")
+)
+
+// PluginRepository 插件仓库。上传的 tar.gz 安装包以 SHA-256 命名保存在本地目录，
+// 插件名称与版本取自包内的 plugin.yaml，同一插件的同一版本只能上传一次
+type PluginRepository struct {
+	db  *gorm.DB
+	dir string
+}
+
+// NewPluginRepository 创建插件仓库，dir 为空时使用 DefaultPluginDir
+func NewPluginRepository(db *gorm.DB, dir string) *PluginRepository {
+	if dir == "" {
+		dir = DefaultPluginDir
+	}
+	return &PluginRepository{db: db, dir: dir}
+}
+
+// Upload 保存安装包并登记到仓库
+func (r *PluginRepository) Upload(src io.Reader, uploadedBy string) (*models.PluginPackage, error) {
+	if err := os.MkdirAll(r.dir, 0750); err != nil {
+		return nil, fmt.Errorf("failed to create plugin directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(r.dir, ".upload-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hash), io.LimitReader(src, MaxPluginPackageSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to save plugin package: %w", err)
+	}
+	if size > MaxPluginPackageSize {
+		return nil, ErrPluginPackageTooLarge
+	}
+
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	manifest, err := pluginpkg.ReadManifest(tmp)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPluginPackage, err)
+	}
+
+	var count int64
+	if err := r.db.Model(&models.PluginPackage{}).
+		Where("name = ? AND version = ?", manifest.Name, manifest.Version).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, ErrPluginPackageExists
+	}
+
+	sum := hex.EncodeToString(hash.Sum(nil))
+	path := filepath.Join(r.dir, sum+".tar.gz")
+	if err := tmp.Close(); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return nil, fmt.Errorf("failed to store plugin package: %w", err)
+	}
+
+	manifestJSON, _ := json.Marshal(manifest)
+	pkg := &models.PluginPackage{
+		Name:        manifest.Name,
+		Version:     manifest.Version,
+		Description: manifest.Description,
+		SHA256:      sum,
+		Size:        size,
+		Path:        path,
+		Manifest:    string(manifestJSON),
+		UploadedBy:  uploadedBy,
+	}
+	if err := r.db.Create(pkg).Error; err != nil {
+		os.Remove(path)
+		return nil, fmt.Errorf("failed to create plugin package: %w", err)
+	}
+	return pkg, nil
+}
+
+// List 返回仓库中的安装包，name 不为空时只返回该插件的版本，新上传的在前
+func (r *PluginRepository) List(name string) ([]models.PluginPackage, error) {
+	var pkgs []models.PluginPackage
+	query := r.db.Order("name ASC, id DESC")
+	if name != "" {
+		query = query.Where("name = ?", name)
+	}
+	if err := query.Find(&pkgs).Error; err != nil {
+		return nil, err
+	}
+	return pkgs, nil
+}
+
+func (r *PluginRepository) Get(id uint) (*models.PluginPackage, error) {
+	var pkg models.PluginPackage
+	if err := r.db.First(&pkg, id).Error; err != nil {
+		return nil, err
+	}
+	return &pkg, nil
+}
+
+// Find 查找插件的指定版本，version 为空时返回最后上传的版本
+func (r *PluginRepository) Find(name, version string) (*models.PluginPackage, error) {
+	var pkg models.PluginPackage
+	query := r.db.Where("name = ?", name)
+	if version != "" {
+		query = query.Where("version = ?", version)
+	}
+	if err := query.Order("id DESC").First(&pkg).Error; err != nil {
+		return nil, err
+	}
+	return &pkg, nil
+}
+
+// Delete 删除安装包及其文件，已安装到 Agent 的插件不受影响
+func (r *PluginRepository) Delete(id uint) error {
+	pkg, err := r.Get(id)
+	if err != nil {
+		return err
+	}
+	if err := r.db.Delete(pkg).Error; err != nil {
+		return err
+	}
+	if err := os.Remove(pkg.Path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove plugin package file: %w", err)
+	}
+	return nil
+}
+
+// PackageManifest 返回安装包的插件清单
+func PackageManifest(pkg *models.PluginPackage) (*pluginpkg.Manifest, error) {
+	var manifest pluginpkg.Manifest
+	if err := json.Unmarshal([]byte(pkg.Manifest), &manifest); err != nil {
+		return nil, fmt.Errorf("invalid manifest of plugin package %s@%s: %w", pkg.Name, pkg.Version, err)
+	}
+	return &manifest, nil
+}
+
+// Open 打开安装包文件
+func (r *PluginRepository) Open(pkg *models.PluginPackage) (*os.File, error) {
+	return os.Open(pkg.Path)
+}
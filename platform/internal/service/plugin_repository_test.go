@@ -0,0 +1,147 @@
+package service
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/agent-platform/pkg/pluginpkg"
+	"github.com/yourusername/agent-platform/pkg/tasksign"
+	"github.com/yourusername/agent-platform/platform/internal/models"
+	"github.com/yourusername/agent-platform/platform/internal/session"
+	"gorm.io/gorm"
+)
+
+// buildPluginPackage 生成插件安装包，payload 作为插件可执行文件的内容，extra 追加到 plugin.yaml
+func buildPluginPackage(t *testing.T, name, version string, payload []byte, extra ...string) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	files := map[string][]byte{
+		"plugin.yaml": []byte("name: " + name + "\nversion: " + version + "\ndescription: test plugin\n" + strings.Join(extra, "\n")),
+		name:          payload,
+	}
+	for file, body := range files {
+		assert.NoError(t, tw.WriteHeader(&tar.Header{Name: file, Mode: 0755, Size: int64(len(body)), Typeflag: tar.TypeReg}))
+		tw.Write(body)
+	}
+	tw.Close()
+	gz.Close()
+	return buf.Bytes()
+}
+
+func newTestPluginRepository(t *testing.T) (*PluginRepository, *gorm.DB) {
+	db := setupTestDB()
+	assert.NoError(t, db.AutoMigrate(&models.PluginPackage{}, &models.Agent{}))
+	return NewPluginRepository(db, t.TempDir()), db
+}
+
+func TestPluginRepository_Upload(t *testing.T) {
+	repo, _ := newTestPluginRepository(t)
+
+	data := buildPluginPackage(t, "disk-check", "1.0.0", []byte("#!/bin/sh\n"))
+	pkg, err := repo.Upload(bytes.NewReader(data), "alice")
+	assert.NoError(t, err)
+	assert.Equal(t, "disk-check", pkg.Name)
+	assert.Equal(t, "1.0.0", pkg.Version)
+	assert.Equal(t, "test plugin", pkg.Description)
+	assert.Equal(t, int64(len(data)), pkg.Size)
+	sum := sha256.Sum256(data)
+	assert.Equal(t, hex.EncodeToString(sum[:]), pkg.SHA256)
+	stored, err := os.ReadFile(pkg.Path)
+	assert.NoError(t, err)
+	assert.Equal(t, data, stored)
+
+	// 同一版本不能重复上传
+	_, err = repo.Upload(bytes.NewReader(data), "alice")
+	assert.ErrorIs(t, err, ErrPluginPackageExists)
+
+	_, err = repo.Upload(bytes.NewReader([]byte("not a package")), "alice")
+	assert.ErrorIs(t, err, ErrInvalidPluginPackage)
+
+	v2, err := repo.Upload(bytes.NewReader(buildPluginPackage(t, "disk-check", "2.0.0", []byte("#!/bin/sh\n# v2\n"))), "alice")
+	assert.NoError(t, err)
+
+	latest, err := repo.Find("disk-check", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "2.0.0", latest.Version)
+	found, err := repo.Find("disk-check", "1.0.0")
+	assert.NoError(t, err)
+	assert.Equal(t, pkg.ID, found.ID)
+
+	assert.NoError(t, repo.Delete(v2.ID))
+	_, err = os.Stat(v2.Path)
+	assert.True(t, os.IsNotExist(err))
+	pkgs, err := repo.List("disk-check")
+	assert.NoError(t, err)
+	assert.Len(t, pkgs, 1)
+}
+
+func TestPluginService_InstallPackage(t *testing.T) {
+	repo, db := newTestPluginRepository(t)
+	db.Create(&models.Agent{AgentID: "agent-1", OS: "linux", Arch: "amd64", Version: "0.1.0"})
+	sessions := session.NewRegistry()
+	stream := &mockStream{}
+	sessions.Add("agent-1", session.NewSession(stream))
+	plugins := NewPluginService(db, sessions, repo)
+	dispatcher := NewTaskDispatcher(db, sessions)
+	pub, key, _ := ed25519.GenerateKey(rand.Reader)
+	dispatcher.SetSigningKey(key, time.Minute)
+	plugins.SetSigner(dispatcher.SignPlugin)
+
+	// 随机内容无法压缩，安装包需要分多块发送
+	payload := make([]byte, pluginChunkSize+1024)
+	rand.Read(payload)
+	data := buildPluginPackage(t, "disk-check", "1.0.0", payload)
+	pkg, err := repo.Upload(bytes.NewReader(data), "alice")
+	assert.NoError(t, err)
+
+	assert.NoError(t, plugins.InstallPlugin("agent-1", "disk-check", "", map[string]string{"interval": "10"}))
+
+	sent := stream.messages()
+	assert.Len(t, sent, 3)
+	req := sent[0].GetInstallPlugin()
+	assert.Equal(t, "1.0.0", req.Version)
+	assert.Equal(t, pkg.SHA256, req.Sha256)
+	assert.Equal(t, pkg.Size, req.Size)
+	assert.NotEmpty(t, req.TransferId)
+	assert.NoError(t, tasksign.VerifyPlugin(pub, "agent-1", req, time.Now()))
+	assert.ErrorIs(t, tasksign.VerifyPlugin(pub, "agent-2", req, time.Now()), tasksign.ErrInvalidSignature)
+
+	var received []byte
+	for i, msg := range sent[1:] {
+		chunk := msg.GetPluginChunk()
+		assert.Equal(t, req.TransferId, chunk.TransferId)
+		assert.Equal(t, int64(len(received)), chunk.Offset)
+		assert.Equal(t, i == 1, chunk.Last)
+		received = append(received, chunk.Data...)
+	}
+	assert.Equal(t, data, received)
+
+	// 仓库中没有指定的版本
+	assert.ErrorIs(t, plugins.InstallPlugin("agent-1", "disk-check", "9.9.9", nil), gorm.ErrRecordNotFound)
+
+	// 按清单检查 Agent 平台、版本与配置，不满足时不下发
+	_, err = repo.Upload(bytes.NewReader(buildPluginPackage(t, "win-only", "1.0.0", []byte("x"), "os: [windows]")), "alice")
+	assert.NoError(t, err)
+	assert.ErrorIs(t, plugins.InstallPlugin("agent-1", "win-only", "", nil), pluginpkg.ErrIncompatible)
+	_, err = repo.Upload(bytes.NewReader(buildPluginPackage(t, "newer", "1.0.0", []byte("x"), "min_agent_version: 0.2.0")), "alice")
+	assert.NoError(t, err)
+	assert.ErrorIs(t, plugins.InstallPlugin("agent-1", "newer", "", nil), pluginpkg.ErrIncompatible)
+
+	pkg, err = repo.Upload(bytes.NewReader(buildPluginPackage(t, "probe", "1.0.0", []byte("x"),
+		"config_schema: {required: [target], properties: {target: {type: string}}}")), "alice")
+	assert.NoError(t, err)
+	assert.Contains(t, pkg.Manifest, `"required":["target"]`)
+	assert.ErrorIs(t, plugins.InstallPlugin("agent-1", "probe", "", map[string]string{}), pluginpkg.ErrInvalidConfig)
+	assert.Len(t, stream.messages(), 3)
+}
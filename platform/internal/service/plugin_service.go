@@ -1,65 +1,185 @@
 package service
 
 import (
+	"errors"
 	"fmt"
+	"io"
 
 	pb "github.com/yourusername/agent-platform/proto"
+	"github.com/yourusername/agent-platform/platform/internal/models"
+	"github.com/yourusername/agent-platform/platform/internal/session"
 	"gorm.io/gorm"
 )
 
+// pluginChunkSize 插件安装包每个分块的大小
+const pluginChunkSize = 256 << 10
+
 type PluginService struct {
-	db *gorm.DB
+	db       *gorm.DB
+	sessions *session.Registry
+	packages *PluginRepository
+	sign     func(req *pb.InstallPluginRequest) // 为 nil 时下发未签名的安装请求
 }
 
-func NewPluginService(db *gorm.DB) *PluginService {
-	return &PluginService{db: db}
+// NewPluginService 创建插件服务，packages 为 nil 时只能安装 Agent 上已有的插件
+func NewPluginService(db *gorm.DB, sessions *session.Registry, packages *PluginRepository) *PluginService {
+	return &PluginService{db: db, sessions: sessions, packages: packages}
 }
 
-func (s *PluginService) InstallPlugin(agentID, pluginName string, config map[string]string) error {
-	// 验证 Agent 是否存在
-	var count int64
-	if err := s.db.Table("agents").Where("agent_id = ?", agentID).Count(&count).Error; err != nil {
+// SetSigner 设置安装请求的签名函数，通常为 TaskDispatcher.SignPlugin，
+// 固定了任务签名公钥的 Agent 拒绝未签名的安装请求
+func (s *PluginService) SetSigner(sign func(req *pb.InstallPluginRequest)) {
+	s.sign = sign
+}
+
+// InstallPlugin 在 Agent 上安装插件。插件仓库中有该插件时，按清单检查 Agent 的平台与版本并校验配置，
+// 然后发送安装请求，再通过 gRPC 流分块发送安装包，version 为空时安装最后上传的版本；
+// 仓库中没有该插件且未指定版本时，Agent 加载插件目录中已有的插件
+func (s *PluginService) InstallPlugin(agentID, pluginName, version string, config map[string]string) error {
+	var agent models.Agent
+	if err := s.db.Where("agent_id = ?", agentID).First(&agent).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("agent not found: %s", agentID)
+		}
 		return fmt.Errorf("failed to check agent: %w", err)
 	}
-	if count == 0 {
-		return fmt.Errorf("agent not found: %s", agentID)
+
+	req := &pb.InstallPluginRequest{
+		AgentId:    agentID,
+		PluginName: pluginName,
+		Config:     config,
+		Version:    version,
+	}
+	var pkg *models.PluginPackage
+	if s.packages != nil {
+		var err error
+		pkg, err = s.packages.Find(pluginName, version)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+	}
+	if pkg == nil {
+		if version != "" {
+			return fmt.Errorf("plugin package %s@%s: %w", pluginName, version, gorm.ErrRecordNotFound)
+		}
+		if s.sign != nil {
+			s.sign(req)
+		}
+		return s.sessions.Send(agentID, &pb.ServerMessage{
+			Message: &pb.ServerMessage_InstallPlugin{InstallPlugin: req},
+		})
 	}
 
-	// 这里实际上会通过 gRPC 流发送安装指令给 Agent
-	// 具体实现在 gRPC handler 中
-	return nil
+	manifest, err := PackageManifest(pkg)
+	if err != nil {
+		return err
+	}
+	if err := manifest.Compatible(agent.OS, agent.Arch, agent.Version); err != nil {
+		return err
+	}
+	if _, err := manifest.ValidateConfig(config); err != nil {
+		return err
+	}
+
+	req.Version = pkg.Version
+	req.Sha256 = pkg.SHA256
+	req.Size = pkg.Size
+	req.TransferId = generateID("plugin")
+	if s.sign != nil {
+		s.sign(req)
+	}
+	return s.sendPackage(agentID, req, pkg)
 }
 
-func (s *PluginService) UninstallPlugin(agentID, pluginName string) error {
-	// 验证 Agent 是否存在
-	var count int64
-	if err := s.db.Table("agents").Where("agent_id = ?", agentID).Count(&count).Error; err != nil {
-		return fmt.Errorf("failed to check agent: %w", err)
+// sendPackage 发送安装请求与安装包分块，分块在同一个流上按顺序发送
+func (s *PluginService) sendPackage(agentID string, req *pb.InstallPluginRequest, pkg *models.PluginPackage) error {
+	f, err := s.packages.Open(pkg)
+	if err != nil {
+		return fmt.Errorf("failed to open plugin package: %w", err)
 	}
-	if count == 0 {
-		return fmt.Errorf("agent not found: %s", agentID)
+	defer f.Close()
+
+	if err := s.sessions.Send(agentID, &pb.ServerMessage{
+		Message: &pb.ServerMessage_InstallPlugin{InstallPlugin: req},
+	}); err != nil {
+		return err
 	}
 
-	// 这里实际上会通过 gRPC 流发送卸载指令给 Agent
-	return nil
+	buf := make([]byte, pluginChunkSize)
+	var offset int64
+	for {
+		n, err := io.ReadFull(f, buf)
+		if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+			return fmt.Errorf("failed to read plugin package: %w", err)
+		}
+		last := offset+int64(n) >= pkg.Size
+		if n == 0 && !last {
+			return fmt.Errorf("plugin package is shorter than %d bytes", pkg.Size)
+		}
+		if err := s.sessions.Send(agentID, &pb.ServerMessage{
+			Message: &pb.ServerMessage_PluginChunk{
+				PluginChunk: &pb.PluginPackageChunk{
+					TransferId: req.TransferId,
+					PluginName: req.PluginName,
+					Offset:     offset,
+					Data:       append([]byte(nil), buf[:n]...),
+					Last:       last,
+				},
+			},
+		}); err != nil {
+			return err
+		}
+		offset += int64(n)
+		if last {
+			return nil
+		}
+	}
 }
 
-func (s *PluginService) ListPlugins(agentID string) ([]*pb.PluginInfo, error) {
-	// 验证 Agent 是否存在
-	var count int64
-	if err := s.db.Table("agents").Where("agent_id = ?", agentID).Count(&count).Error; err != nil {
-		return nil, fmt.Errorf("failed to check agent: %w", err)
+func (s *PluginService) UninstallPlugin(agentID, pluginName string) error {
+	if err := s.checkAgent(agentID); err != nil {
+		return err
 	}
-	if count == 0 {
-		return nil, fmt.Errorf("agent not found: %s", agentID)
+
+	return s.sessions.Send(agentID, &pb.ServerMessage{
+		Message: &pb.ServerMessage_UninstallPlugin{
+			UninstallPlugin: &pb.UninstallPluginRequest{
+				AgentId:    agentID,
+				PluginName: pluginName,
+			},
+		},
+	})
+}
+
+func (s *PluginService) ListPlugins(agentID string) ([]*pb.PluginInfo, error) {
+	if err := s.checkAgent(agentID); err != nil {
+		return nil, err
 	}
 
-	// 这里实际上会通过 gRPC 流请求 Agent 的插件列表
+	// 插件列表由 Agent 通过 ListPluginsResponse 异步上报
+	if err := s.sessions.Send(agentID, &pb.ServerMessage{
+		Message: &pb.ServerMessage_ListPlugins{
+			ListPlugins: &pb.ListPluginsRequest{
+				AgentId: agentID,
+			},
+		},
+	}); err != nil {
+		return nil, err
+	}
 	return nil, nil
 }
 
 func (s *PluginService) UpdatePluginConfig(agentID, pluginName string, config map[string]string) error {
-	// 验证 Agent 是否存在
+	if err := s.checkAgent(agentID); err != nil {
+		return err
+	}
+
+	// 这里实际上会通过 gRPC 流发送配置更新指令给 Agent
+	return nil
+}
+
+// checkAgent 验证 Agent 是否存在
+func (s *PluginService) checkAgent(agentID string) error {
 	var count int64
 	if err := s.db.Table("agents").Where("agent_id = ?", agentID).Count(&count).Error; err != nil {
 		return fmt.Errorf("failed to check agent: %w", err)
@@ -67,7 +187,5 @@ func (s *PluginService) UpdatePluginConfig(agentID, pluginName string, config ma
 	if count == 0 {
 		return fmt.Errorf("agent not found: %s", agentID)
 	}
-
-	// 这里实际上会通过 gRPC 流发送配置更新指令给 Agent
 	return nil
 }
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/yourusername/agent-platform/platform/internal/models"
+	"gorm.io/gorm"
+)
+
+var ErrPolicyRejected = errors.New("rejected by script policy")
+
+// PolicyRules 一组脚本策略规则，零值表示不限制
+type PolicyRules struct {
+	DenyPatterns    []string // 脚本匹配任一正则时拒绝
+	AllowedTypes    []string // 允许的脚本解释器类型，为空时不限制；file 类型必须显式列出
+	MaxTimeout      int      // 允许的最大超时时间（秒），0 表示不限制
+	RequireApproval bool     // 任务需经其他用户审批后才下发
+}
+
+// PolicyDecision 策略评估结果
+type PolicyDecision struct {
+	Allowed         bool
+	RequireApproval bool
+	Reason          string // 拒绝原因
+}
+
+type compiledRules struct {
+	deny            []*regexp.Regexp
+	allowedTypes    map[string]bool
+	maxTimeout      int
+	requireApproval bool
+}
+
+// PolicyService 在任务创建时按目标 Agent 所属分组评估脚本策略。
+// 分组规则与默认规则合并：禁止规则取并集，超时上限取较小值，任一要求审批即需审批，
+// 分组配置了允许的类型时以分组为准
+type PolicyService struct {
+	db       *gorm.DB
+	defaults *compiledRules
+	groups   map[string]*compiledRules
+}
+
+func NewPolicyService(db *gorm.DB, defaults PolicyRules, groups map[string]PolicyRules) (*PolicyService, error) {
+	s := &PolicyService{
+		db:     db,
+		groups: make(map[string]*compiledRules, len(groups)),
+	}
+
+	var err error
+	if s.defaults, err = compileRules(defaults); err != nil {
+		return nil, fmt.Errorf("default policy: %w", err)
+	}
+	for group, rules := range groups {
+		compiled, err := compileRules(rules)
+		if err != nil {
+			return nil, fmt.Errorf("policy for group %s: %w", group, err)
+		}
+		s.groups[group] = compiled
+	}
+	return s, nil
+}
+
+// Evaluate 评估在 agentID 上执行任务是否被允许，content 中的每一项都按禁止规则检查，
+// 通常由 TaskPolicyContent 生成。timeout 为 0 时按默认超时时间评估
+func (s *PolicyService) Evaluate(agentID, taskType string, content []string, timeout int) (*PolicyDecision, error) {
+	var agent models.Agent
+	if err := s.db.Select("group_name").Where("agent_id = ?", agentID).Limit(1).Find(&agent).Error; err != nil {
+		return nil, fmt.Errorf("failed to load agent: %w", err)
+	}
+	return s.evaluate(agent.Group, taskType, content, timeout), nil
+}
+
+// TaskPolicyContent 返回任务中需按禁止规则检查的内容：脚本、file 类型的文件内容、参数与环境变量。
+// 参数以空格连接后整体检查，避免将命令拆成多个参数绕过规则
+func TaskPolicyContent(task *models.Task) []string {
+	content := []string{task.Script}
+	if len(task.File) > 0 {
+		content = append(content, string(task.File))
+	}
+	if task.Args != "" {
+		var args []string
+		json.Unmarshal([]byte(task.Args), &args)
+		content = append(content, strings.Join(args, " "))
+	}
+	if task.Env != "" {
+		var env map[string]string
+		json.Unmarshal([]byte(task.Env), &env)
+		for name, value := range env {
+			content = append(content, name+"="+value)
+		}
+	}
+	return content
+}
+
+func (s *PolicyService) evaluate(group, taskType string, content []string, timeout int) *PolicyDecision {
+	if timeout <= 0 {
+		timeout = DefaultTaskTimeout
+	}
+
+	rules := []*compiledRules{s.defaults}
+	if groupRules, ok := s.groups[group]; ok {
+		rules = append(rules, groupRules)
+	}
+
+	allowedTypes := s.defaults.allowedTypes
+	decision := &PolicyDecision{Allowed: true}
+	for _, r := range rules {
+		for _, re := range r.deny {
+			for _, c := range content {
+				if re.MatchString(c) {
+					return &PolicyDecision{Reason: fmt.Sprintf("script matches deny pattern %q", re.String())}
+				}
+			}
+		}
+		if r.maxTimeout > 0 && timeout > r.maxTimeout {
+			return &PolicyDecision{Reason: fmt.Sprintf("timeout %ds exceeds maximum %ds", timeout, r.maxTimeout)}
+		}
+		if len(r.allowedTypes) > 0 {
+			allowedTypes = r.allowedTypes
+		}
+		decision.RequireApproval = decision.RequireApproval || r.requireApproval
+	}
+
+	if len(allowedTypes) > 0 && !allowedTypes[taskType] {
+		return &PolicyDecision{Reason: fmt.Sprintf("script type %s is not allowed", taskType)}
+	}
+	// file 类型可以执行任意程序，需在 allowed_types 中显式允许
+	if taskType == TaskTypeFile && !allowedTypes[taskType] {
+		return &PolicyDecision{Reason: fmt.Sprintf("task type %s must be listed in allowed_types", taskType)}
+	}
+	return decision
+}
+
+func compileRules(rules PolicyRules) (*compiledRules, error) {
+	compiled := &compiledRules{
+		maxTimeout:      rules.MaxTimeout,
+		requireApproval: rules.RequireApproval,
+	}
+	for _, pattern := range rules.DenyPatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid deny pattern %q: %w", pattern, err)
+		}
+		compiled.deny = append(compiled.deny, re)
+	}
+	if len(rules.AllowedTypes) > 0 {
+		compiled.allowedTypes = make(map[string]bool, len(rules.AllowedTypes))
+		for _, t := range rules.AllowedTypes {
+			compiled.allowedTypes[t] = true
+		}
+	}
+	return compiled, nil
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/agent-platform/platform/internal/models"
+)
+
+func TestPolicyService_Evaluate(t *testing.T) {
+	db := setupTestDB()
+	assert.NoError(t, db.AutoMigrate(&models.Agent{}))
+	db.Create(&models.Agent{AgentID: "agent-prod", Group: "prod"})
+	db.Create(&models.Agent{AgentID: "agent-dev", Group: "dev"})
+
+	policy, err := NewPolicyService(db, PolicyRules{
+		DenyPatterns: []string{`rm\s+-rf\s+/`},
+		AllowedTypes: []string{"shell"},
+		MaxTimeout:   600,
+	}, map[string]PolicyRules{
+		"prod": {DenyPatterns: []string{`shutdown`}, MaxTimeout: 60, RequireApproval: true},
+		"dev":  {AllowedTypes: []string{"shell", "python"}},
+	})
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		agentID  string
+		taskType string
+		script   string
+		timeout  int
+		allowed  bool
+		approval bool
+	}{
+		{"default allows shell", "agent-unknown", "shell", "uptime", 30, true, false},
+		{"default deny pattern", "agent-dev", "shell", "rm -rf /", 30, false, false},
+		{"default type not allowed", "agent-unknown", "python", "print(1)", 30, false, false},
+		{"group overrides allowed types", "agent-dev", "python", "print(1)", 30, true, false},
+		{"group deny pattern", "agent-prod", "shell", "shutdown -h now", 30, false, false},
+		{"group deny does not leak", "agent-dev", "shell", "shutdown -h now", 30, true, false},
+		{"smaller timeout wins", "agent-prod", "shell", "uptime", 120, false, false},
+		{"default timeout is checked", "agent-prod", "shell", "uptime", 0, false, false},
+		{"group requires approval", "agent-prod", "shell", "uptime", 30, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision, err := policy.Evaluate(tt.agentID, tt.taskType, []string{tt.script}, tt.timeout)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.allowed, decision.Allowed, decision.Reason)
+			assert.Equal(t, tt.approval, decision.RequireApproval)
+			if !tt.allowed {
+				assert.NotEmpty(t, decision.Reason)
+			}
+		})
+	}
+}
+
+func TestPolicyService_TaskContent(t *testing.T) {
+	db := setupTestDB()
+	assert.NoError(t, db.AutoMigrate(&models.Agent{}))
+	db.Create(&models.Agent{AgentID: "agent-files", Group: "files"})
+
+	policy, err := NewPolicyService(db, PolicyRules{
+		DenyPatterns: []string{`rm\s+-rf\s+/`},
+	}, map[string]PolicyRules{
+		"files": {AllowedTypes: []string{"shell", TaskTypeFile}},
+	})
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		task    *models.Task
+		allowed bool
+	}{
+		{"args are checked", &models.Task{AgentID: "agent-files", Type: "shell", Script: `"$@"`, Args: `["rm","-rf","/"]`}, false},
+		{"env is checked", &models.Task{AgentID: "agent-files", Type: "shell", Script: "sh -c \"$CMD\"", Env: `{"CMD":"rm -rf /"}`}, false},
+		{"file content is checked", &models.Task{AgentID: "agent-files", Type: TaskTypeFile, File: []byte("#!/bin/sh\nrm -rf /\n")}, false},
+		{"file allowed when listed", &models.Task{AgentID: "agent-files", Type: TaskTypeFile, File: []byte("#!/bin/sh\nuptime\n")}, true},
+		{"file requires opt-in", &models.Task{AgentID: "agent-unknown", Type: TaskTypeFile, File: []byte("#!/bin/sh\nuptime\n")}, false},
+		{"other types unrestricted", &models.Task{AgentID: "agent-unknown", Type: "python", Script: "print(1)", Args: `["--verbose"]`}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision, err := policy.Evaluate(tt.task.AgentID, tt.task.Type, TaskPolicyContent(tt.task), 30)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.allowed, decision.Allowed, decision.Reason)
+		})
+	}
+}
+
+func TestPolicyService_InvalidPattern(t *testing.T) {
+	_, err := NewPolicyService(setupTestDB(), PolicyRules{}, map[string]PolicyRules{
+		"prod": {DenyPatterns: []string{`(`}},
+	})
+	assert.Error(t, err)
+}
@@ -0,0 +1,301 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/yourusername/agent-platform/platform/internal/models"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrScriptInUse         = errors.New("script version is referenced by tasks")
+	ErrLatestScriptVersion = errors.New("cannot delete the latest script version")
+	ErrInvalidScriptRef    = errors.New("invalid script reference, expected script_id or script_id@version")
+)
+
+// ScriptSpec 创建或更新脚本的参数
+type ScriptSpec struct {
+	Name        string
+	Description string
+	Type        string
+	Script      string
+	Tags        []string
+	Comment     string // 新版本的说明
+	CreatedBy   string
+}
+
+// ScriptDiff 两个版本之间的差异，Diff 为 unified diff 格式
+type ScriptDiff struct {
+	From     int    `json:"from"`
+	To       int    `json:"to"`
+	FromType string `json:"from_type"`
+	ToType   string `json:"to_type"`
+	Diff     string `json:"diff"`
+}
+
+// ScriptService 管理脚本库。脚本的每个版本创建后不可修改，任务记录执行的脚本 ID 与版本，
+// 被任务引用的版本不能删除
+type ScriptService struct {
+	db *gorm.DB
+}
+
+func NewScriptService(db *gorm.DB) *ScriptService {
+	return &ScriptService{db: db}
+}
+
+// Create 创建脚本及其第 1 个版本
+func (s *ScriptService) Create(spec *ScriptSpec) (*models.Script, error) {
+	if err := validateScriptSpec(spec); err != nil {
+		return nil, err
+	}
+
+	script := &models.Script{
+		Name:          spec.Name,
+		Description:   spec.Description,
+		Type:          spec.Type,
+		Tags:          encodeTags(spec.Tags),
+		LatestVersion: 1,
+		CreatedBy:     spec.CreatedBy,
+	}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(script).Error; err != nil {
+			return err
+		}
+		return tx.Create(newScriptVersion(script.ID, 1, spec)).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create script: %w", err)
+	}
+	return script, nil
+}
+
+// Update 更新脚本的名称、描述与标签，内容或解释器与最新版本不同时生成新版本
+func (s *ScriptService) Update(id uint, spec *ScriptSpec) (*models.Script, error) {
+	if err := validateScriptSpec(spec); err != nil {
+		return nil, err
+	}
+
+	var script models.Script
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&script, id).Error; err != nil {
+			return err
+		}
+		latest, err := findScriptVersion(tx, id, script.LatestVersion)
+		if err != nil {
+			return err
+		}
+		if latest.Type != spec.Type || latest.Script != spec.Script {
+			if err := tx.Create(newScriptVersion(id, script.LatestVersion+1, spec)).Error; err != nil {
+				return err
+			}
+			script.LatestVersion++
+		}
+
+		script.Name = spec.Name
+		script.Description = spec.Description
+		script.Type = spec.Type
+		script.Tags = encodeTags(spec.Tags)
+		return tx.Save(&script).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update script: %w", err)
+	}
+	return &script, nil
+}
+
+// Delete 删除脚本及其全部版本，任一版本被任务引用时返回 ErrScriptInUse
+func (s *ScriptService) Delete(id uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var script models.Script
+		if err := tx.First(&script, id).Error; err != nil {
+			return err
+		}
+
+		var count int64
+		if err := tx.Model(&models.Task{}).Where("script_id = ?", id).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrScriptInUse
+		}
+
+		if err := tx.Where("script_id = ?", id).Delete(&models.ScriptVersion{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&script).Error
+	})
+}
+
+func (s *ScriptService) Get(id uint) (*models.Script, error) {
+	var script models.Script
+	if err := s.db.First(&script, id).Error; err != nil {
+		return nil, err
+	}
+	return &script, nil
+}
+
+// List 按名称排序返回脚本，tag 不为空时只返回带该标签的脚本
+func (s *ScriptService) List(tag string) ([]models.Script, error) {
+	var scripts []models.Script
+	if err := s.db.Order("name ASC").Find(&scripts).Error; err != nil {
+		return nil, err
+	}
+	if tag == "" {
+		return scripts, nil
+	}
+
+	filtered := make([]models.Script, 0, len(scripts))
+	for _, script := range scripts {
+		var tags []string
+		json.Unmarshal([]byte(script.Tags), &tags)
+		if containsString(tags, tag) {
+			filtered = append(filtered, script)
+		}
+	}
+	return filtered, nil
+}
+
+// Versions 返回脚本的全部版本，新版本在前
+func (s *ScriptService) Versions(id uint) ([]models.ScriptVersion, error) {
+	if _, err := s.Get(id); err != nil {
+		return nil, err
+	}
+	var versions []models.ScriptVersion
+	err := s.db.Where("script_id = ?", id).Order("version DESC").Find(&versions).Error
+	return versions, err
+}
+
+func (s *ScriptService) GetVersion(id uint, version int) (*models.ScriptVersion, error) {
+	return findScriptVersion(s.db, id, version)
+}
+
+// DeleteVersion 删除脚本的历史版本。最新版本与被任务引用的版本不能删除
+func (s *ScriptService) DeleteVersion(id uint, version int) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var script models.Script
+		if err := tx.First(&script, id).Error; err != nil {
+			return err
+		}
+		if version == script.LatestVersion {
+			return ErrLatestScriptVersion
+		}
+
+		var count int64
+		if err := tx.Model(&models.Task{}).Where("script_id = ? AND script_version = ?", id, version).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrScriptInUse
+		}
+
+		result := tx.Where("script_id = ? AND version = ?", id, version).Delete(&models.ScriptVersion{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+// Diff 比较脚本的两个版本
+func (s *ScriptService) Diff(id uint, from, to int) (*ScriptDiff, error) {
+	script, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	a, err := findScriptVersion(s.db, id, from)
+	if err != nil {
+		return nil, err
+	}
+	b, err := findScriptVersion(s.db, id, to)
+	if err != nil {
+		return nil, err
+	}
+
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(a.Script),
+		B:        difflib.SplitLines(b.Script),
+		FromFile: fmt.Sprintf("%s@%d", script.Name, from),
+		ToFile:   fmt.Sprintf("%s@%d", script.Name, to),
+		Context:  3,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to diff script versions: %w", err)
+	}
+	return &ScriptDiff{From: from, To: to, FromType: a.Type, ToType: b.Type, Diff: diff}, nil
+}
+
+// Resolve 解析 script_id@version 形式的引用，省略版本时使用最新版本
+func (s *ScriptService) Resolve(ref string) (*models.ScriptVersion, error) {
+	idPart, versionPart, hasVersion := strings.Cut(ref, "@")
+	id, err := strconv.ParseUint(idPart, 10, 32)
+	if err != nil || id == 0 {
+		return nil, ErrInvalidScriptRef
+	}
+
+	if !hasVersion {
+		script, err := s.Get(uint(id))
+		if err != nil {
+			return nil, err
+		}
+		return findScriptVersion(s.db, script.ID, script.LatestVersion)
+	}
+
+	version, err := strconv.Atoi(versionPart)
+	if err != nil || version <= 0 {
+		return nil, ErrInvalidScriptRef
+	}
+	return findScriptVersion(s.db, uint(id), version)
+}
+
+func findScriptVersion(db *gorm.DB, id uint, version int) (*models.ScriptVersion, error) {
+	var v models.ScriptVersion
+	if err := db.Where("script_id = ? AND version = ?", id, version).First(&v).Error; err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+func newScriptVersion(id uint, version int, spec *ScriptSpec) *models.ScriptVersion {
+	return &models.ScriptVersion{
+		ScriptID:  id,
+		Version:   version,
+		Type:      spec.Type,
+		Script:    spec.Script,
+		Comment:   spec.Comment,
+		CreatedBy: spec.CreatedBy,
+	}
+}
+
+func validateScriptSpec(spec *ScriptSpec) error {
+	if spec.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if spec.Script == "" {
+		return fmt.Errorf("script is required")
+	}
+	if _, err := ParseScriptType(spec.Type); err != nil {
+		return err
+	}
+	for _, tag := range spec.Tags {
+		if tag == "" {
+			return fmt.Errorf("empty tag")
+		}
+	}
+	return nil
+}
+
+func encodeTags(tags []string) string {
+	if len(tags) == 0 {
+		return "[]"
+	}
+	data, _ := json.Marshal(tags)
+	return string(data)
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/agent-platform/platform/internal/models"
+	"gorm.io/gorm"
+)
+
+func newTestScriptService(t *testing.T) (*ScriptService, *gorm.DB) {
+	db := setupTestDB()
+	assert.NoError(t, db.AutoMigrate(&models.Script{}, &models.ScriptVersion{}))
+	return NewScriptService(db), db
+}
+
+func TestScriptService_Versions(t *testing.T) {
+	s, _ := newTestScriptService(t)
+
+	script, err := s.Create(&ScriptSpec{Name: "cleanup", Type: "shell", Script: "rm -f /tmp/*.log\n", Tags: []string{"ops"}})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, script.LatestVersion)
+
+	// 只修改描述与标签不生成新版本
+	script, err = s.Update(script.ID, &ScriptSpec{Name: "cleanup", Type: "shell", Script: "rm -f /tmp/*.log\n", Description: "clean logs", Tags: []string{"ops", "disk"}})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, script.LatestVersion)
+	assert.Equal(t, `["ops","disk"]`, script.Tags)
+
+	script, err = s.Update(script.ID, &ScriptSpec{Name: "cleanup", Type: "shell", Script: "find /tmp -name '*.log' -delete\n", Comment: "use find"})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, script.LatestVersion)
+
+	versions, err := s.Versions(script.ID)
+	assert.NoError(t, err)
+	assert.Len(t, versions, 2)
+	assert.Equal(t, 2, versions[0].Version)
+	assert.Equal(t, "use find", versions[0].Comment)
+
+	v, err := s.Resolve("1")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, v.Version)
+	v, err = s.Resolve("1@1")
+	assert.NoError(t, err)
+	assert.Equal(t, "rm -f /tmp/*.log\n", v.Script)
+	_, err = s.Resolve("1@9")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	_, err = s.Resolve("cleanup@1")
+	assert.ErrorIs(t, err, ErrInvalidScriptRef)
+
+	diff, err := s.Diff(script.ID, 1, 2)
+	assert.NoError(t, err)
+	assert.Contains(t, diff.Diff, "--- cleanup@1")
+	assert.Contains(t, diff.Diff, "-rm -f /tmp/*.log")
+	assert.Contains(t, diff.Diff, "+find /tmp -name '*.log' -delete")
+}
+
+func TestScriptService_List(t *testing.T) {
+	s, _ := newTestScriptService(t)
+
+	_, err := s.Create(&ScriptSpec{Name: "backup", Type: "shell", Script: "backup.sh", Tags: []string{"db"}})
+	assert.NoError(t, err)
+	_, err = s.Create(&ScriptSpec{Name: "report", Type: "python", Script: "print(1)"})
+	assert.NoError(t, err)
+	_, err = s.Create(&ScriptSpec{Name: "backup", Type: "shell", Script: "other.sh"})
+	assert.Error(t, err)
+
+	all, err := s.List("")
+	assert.NoError(t, err)
+	assert.Len(t, all, 2)
+
+	tagged, err := s.List("db")
+	assert.NoError(t, err)
+	assert.Len(t, tagged, 1)
+	assert.Equal(t, "backup", tagged[0].Name)
+}
+
+func TestScriptService_DeleteReferenced(t *testing.T) {
+	s, db := newTestScriptService(t)
+
+	script, err := s.Create(&ScriptSpec{Name: "deploy", Type: "shell", Script: "v1"})
+	assert.NoError(t, err)
+	_, err = s.Update(script.ID, &ScriptSpec{Name: "deploy", Type: "shell", Script: "v2"})
+	assert.NoError(t, err)
+	_, err = s.Update(script.ID, &ScriptSpec{Name: "deploy", Type: "shell", Script: "v3"})
+	assert.NoError(t, err)
+	db.Create(&models.Task{TaskID: "task-1", AgentID: "agent-1", ScriptID: script.ID, ScriptVersion: 1})
+
+	assert.ErrorIs(t, s.DeleteVersion(script.ID, 1), ErrScriptInUse)
+	assert.ErrorIs(t, s.DeleteVersion(script.ID, 3), ErrLatestScriptVersion)
+	assert.NoError(t, s.DeleteVersion(script.ID, 2))
+	assert.ErrorIs(t, s.DeleteVersion(script.ID, 2), gorm.ErrRecordNotFound)
+	assert.ErrorIs(t, s.Delete(script.ID), ErrScriptInUse)
+
+	db.Where("task_id = ?", "task-1").Delete(&models.Task{})
+	assert.NoError(t, s.Delete(script.ID))
+	var count int64
+	db.Model(&models.ScriptVersion{}).Count(&count)
+	assert.Zero(t, count)
+}
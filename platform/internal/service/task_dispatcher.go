@@ -0,0 +1,815 @@
+package service
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"path"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	pb "github.com/yourusername/agent-platform/proto"
+	"github.com/yourusername/agent-platform/pkg/tasksign"
+	"github.com/yourusername/agent-platform/platform/internal/models"
+	"github.com/yourusername/agent-platform/platform/internal/session"
+	"gorm.io/gorm"
+)
+
+// 任务状态
+const (
+	TaskStatusQueued           = "queued"            // 批量作业中等待轮到下发的子任务
+	TaskStatusAwaitingApproval = "awaiting_approval" // 策略要求审批，等待其他用户批准
+	TaskStatusPending          = "pending"
+	TaskStatusDispatched       = "dispatched"
+	TaskStatusRunning          = "running"
+	TaskStatusCompleted        = "completed"
+	TaskStatusFailed           = "failed"
+	TaskStatusTimeout          = "timeout"
+	TaskStatusCancelled        = "cancelled"
+	TaskStatusRejected         = "rejected" // 被平台或 Agent 的脚本策略拒绝
+)
+
+var (
+	ErrTaskFinished    = errors.New("task already finished")
+	ErrTaskNotAwaiting = errors.New("task is not awaiting approval")
+	ErrInvalidTaskFile = errors.New("invalid task file")
+	ErrTaskNotInFlight = errors.New("task is not in flight on this agent")
+)
+
+// inFlightStatuses Agent 可以上报确认、日志与结果的任务状态。
+// Dispatch 在发送前将任务置为 dispatched，因此不包含 pending
+var inFlightStatuses = []string{TaskStatusDispatched, TaskStatusRunning}
+
+// TaskTypeFile 将文件写入 Agent 临时目录后直接执行的任务类型
+const TaskTypeFile = "file"
+
+const (
+	// DefaultTaskTimeout 未指定超时时间时使用的默认值（秒）
+	DefaultTaskTimeout = 300
+	// taskTimeoutGrace 平台侧判定超时前额外等待的时间，用于容忍网络延迟
+	taskTimeoutGrace = 60 * time.Second
+	// timeoutCheckInterval 超时检查周期
+	timeoutCheckInterval = 30 * time.Second
+	// DefaultTaskSignatureTTL 任务签名的默认有效期，Agent 拒绝执行签名已过期的任务
+	DefaultTaskSignatureTTL = 5 * time.Minute
+	// MaxTaskFileSize file 类型任务的文件大小上限，需小于 gRPC 消息大小上限
+	MaxTaskFileSize = 1 << 20
+)
+
+// TaskFinishedFunc 在任务进入终态后被调用
+type TaskFinishedFunc func(task *models.Task)
+
+// TaskDispatcher 负责将任务下发到目标 Agent 并维护任务状态流转
+type TaskDispatcher struct {
+	db          *gorm.DB
+	sessions    *session.Registry
+	stopCh      chan struct{}
+	mu          sync.RWMutex
+	finishHooks []TaskFinishedFunc
+
+	signingKey   ed25519.PrivateKey // 为 nil 时下发未签名的任务
+	signatureTTL time.Duration
+
+	secretsMu sync.Mutex
+	secrets   map[string][]string  // 未结束任务的 secret 参数值，用于输出脱敏
+	carries   map[string]*logCarry // 有 secret 的任务尚未输出的日志末尾
+	forgotten uint64               // forgetSecrets 的调用次数，用于判断加载期间是否有任务结束
+}
+
+// logCarry 流式日志脱敏时每个任务保留的状态
+type logCarry struct {
+	lastSeq int64
+	pending [2]string // stdout、stderr 末尾可能是 secret 前缀、暂不输出的部分
+}
+
+func NewTaskDispatcher(db *gorm.DB, sessions *session.Registry) *TaskDispatcher {
+	return &TaskDispatcher{
+		db:       db,
+		sessions: sessions,
+		stopCh:   make(chan struct{}),
+		secrets:  make(map[string][]string),
+		carries:  make(map[string]*logCarry),
+	}
+}
+
+// SetSigningKey 设置任务签名私钥，之后下发的任务均携带签名，有效期为 ttl
+func (d *TaskDispatcher) SetSigningKey(key ed25519.PrivateKey, ttl time.Duration) {
+	if ttl <= 0 {
+		ttl = DefaultTaskSignatureTTL
+	}
+	d.signingKey = key
+	d.signatureTTL = ttl
+}
+
+// SignPlugin 使用任务签名私钥对插件安装请求签名，未设置私钥时不签名
+func (d *TaskDispatcher) SignPlugin(req *pb.InstallPluginRequest) {
+	if d.signingKey != nil {
+		tasksign.SignPlugin(d.signingKey, req, time.Now().Add(d.signatureTTL))
+	}
+}
+
+// ParseTaskType 将任务类型字符串转换为 protobuf 枚举
+func ParseTaskType(taskType string) (pb.TaskType, error) {
+	switch taskType {
+	case "shell":
+		return pb.TaskType_TASK_TYPE_SHELL, nil
+	case "bash":
+		return pb.TaskType_TASK_TYPE_BASH, nil
+	case "python":
+		return pb.TaskType_TASK_TYPE_PYTHON, nil
+	case "perl":
+		return pb.TaskType_TASK_TYPE_PERL, nil
+	case "powershell":
+		return pb.TaskType_TASK_TYPE_POWERSHELL, nil
+	case TaskTypeFile:
+		return pb.TaskType_TASK_TYPE_FILE, nil
+	default:
+		return pb.TaskType_TASK_TYPE_UNSPECIFIED, fmt.Errorf("unsupported task type: %s", taskType)
+	}
+}
+
+// ParseScriptType 与 ParseTaskType 相同，但不接受 file 类型，用于只有脚本内容的脚本库、模板与作业
+func ParseScriptType(scriptType string) (pb.TaskType, error) {
+	if scriptType == TaskTypeFile {
+		return pb.TaskType_TASK_TYPE_UNSPECIFIED, fmt.Errorf("task type %s requires a file", scriptType)
+	}
+	return ParseTaskType(scriptType)
+}
+
+// PrepareTaskFile 校验 file 类型任务的文件并填充 SHA-256，其他类型的任务不能携带文件
+func PrepareTaskFile(task *models.Task) error {
+	if task.Type != TaskTypeFile {
+		if len(task.File) > 0 || task.FileName != "" {
+			return fmt.Errorf("%w: only %s tasks carry a file", ErrInvalidTaskFile, TaskTypeFile)
+		}
+		return nil
+	}
+
+	if len(task.File) == 0 {
+		return fmt.Errorf("%w: file is required", ErrInvalidTaskFile)
+	}
+	if len(task.File) > MaxTaskFileSize {
+		return fmt.Errorf("%w: file exceeds %d bytes", ErrInvalidTaskFile, MaxTaskFileSize)
+	}
+	if task.FileName != "" && (task.FileName != path.Base(task.FileName) || strings.ContainsAny(task.FileName, `\:`) || task.FileName == "." || task.FileName == "..") {
+		return fmt.Errorf("%w: file name must not contain a path: %s", ErrInvalidTaskFile, task.FileName)
+	}
+
+	sum := sha256.Sum256(task.File)
+	checksum := hex.EncodeToString(sum[:])
+	if task.FileSHA256 != "" && !strings.EqualFold(task.FileSHA256, checksum) {
+		return fmt.Errorf("%w: sha256 mismatch", ErrInvalidTaskFile)
+	}
+	task.FileSHA256 = checksum
+	return nil
+}
+
+// IsTaskFinished 判断任务是否已处于终态
+func IsTaskFinished(status string) bool {
+	switch status {
+	case TaskStatusCompleted, TaskStatusFailed, TaskStatusTimeout, TaskStatusCancelled, TaskStatusRejected:
+		return true
+	default:
+		return false
+	}
+}
+
+// Submit 保存任务并尝试立即下发，Agent 不在线时任务保持 pending，待其重连后下发
+func (d *TaskDispatcher) Submit(task *models.Task) error {
+	if err := d.create(task, TaskStatusPending); err != nil {
+		return err
+	}
+	return d.dispatchOrQueue(task)
+}
+
+// SubmitForApproval 保存需要审批的任务，批准后才会下发
+func (d *TaskDispatcher) SubmitForApproval(task *models.Task) error {
+	return d.create(task, TaskStatusAwaitingApproval)
+}
+
+// Approve 批准等待审批的任务并尝试下发
+func (d *TaskDispatcher) Approve(task *models.Task, approver string) error {
+	result := d.db.Model(&models.Task{}).
+		Where("task_id = ? AND status = ?", task.TaskID, TaskStatusAwaitingApproval).
+		Updates(map[string]interface{}{
+			"status":      TaskStatusPending,
+			"approved_by": approver,
+		})
+	if result.Error != nil {
+		return fmt.Errorf("failed to approve task: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrTaskNotAwaiting
+	}
+	task.Status = TaskStatusPending
+	task.ApprovedBy = approver
+
+	return d.dispatchOrQueue(task)
+}
+
+// Reject 以 reason 拒绝任务。尚未保存的任务被平台策略拒绝，直接以 rejected 状态保存；
+// 已保存的任务只有在等待审批时可以由审批人驳回
+func (d *TaskDispatcher) Reject(task *models.Task, reason string) error {
+	if task.ID == 0 {
+		task.PolicyRejected = true
+		task.Stderr = reason
+		task.ExitCode = -1
+		now := time.Now()
+		task.CompletedAt = &now
+		if err := d.create(task, TaskStatusRejected); err != nil {
+			return err
+		}
+	} else {
+		result := d.db.Model(&models.Task{}).
+			Where("task_id = ? AND status = ?", task.TaskID, TaskStatusAwaitingApproval).
+			Updates(map[string]interface{}{
+				"status":       TaskStatusRejected,
+				"stderr":       reason,
+				"exit_code":    -1,
+				"completed_at": time.Now(),
+			})
+		if result.Error != nil {
+			return fmt.Errorf("failed to reject task: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return ErrTaskNotAwaiting
+		}
+		task.Status = TaskStatusRejected
+		task.Stderr = reason
+	}
+
+	d.notifyFinished(task.TaskID)
+	return nil
+}
+
+func (d *TaskDispatcher) create(task *models.Task, status string) error {
+	if _, err := ParseTaskType(task.Type); err != nil {
+		return err
+	}
+	if err := PrepareTaskFile(task); err != nil {
+		return err
+	}
+
+	if task.TaskID == "" {
+		task.TaskID = generateID("task")
+	}
+	if task.Timeout <= 0 {
+		task.Timeout = DefaultTaskTimeout
+	}
+	task.Status = status
+
+	if len(task.Secrets) == 0 {
+		if err := d.db.Create(task).Error; err != nil {
+			return fmt.Errorf("failed to create task: %w", err)
+		}
+		return nil
+	}
+
+	// 明文单独保存，任务行中的脚本、环境变量与参数只保留掩码
+	values, _ := json.Marshal(task.Secrets)
+	secret := &models.TaskSecret{
+		TaskID: task.TaskID,
+		Script: task.Script,
+		Env:    task.Env,
+		Values: string(values),
+	}
+	task.Script = MaskSecrets(task.Script, task.Secrets)
+	task.Env = MaskSecrets(task.Env, task.Secrets)
+	task.HasSecrets = true
+
+	err := d.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(task).Error; err != nil {
+			return err
+		}
+		return tx.Create(secret).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create task: %w", err)
+	}
+
+	d.secretsMu.Lock()
+	d.secrets[task.TaskID] = task.Secrets
+	d.secretsMu.Unlock()
+	return nil
+}
+
+// MaskOutput 将任务输出中出现的 secret 参数值替换为掩码
+func (d *TaskDispatcher) MaskOutput(taskID, output string) string {
+	return MaskSecrets(output, d.taskSecrets(taskID))
+}
+
+// MaskLog 对 Agent 流式上报的日志块脱敏，返回 false 表示重复上报的日志块。
+// secret 可能被拆分到相邻的日志块中，因此每个输出流保留末尾可能是 secret 前缀的部分
+// （不超过最长 secret 的长度减一），与下一块拼接后再脱敏，任务结束时由 FlushLog 输出
+func (d *TaskDispatcher) MaskLog(taskLog *pb.TaskLog) (string, bool) {
+	secrets := d.taskSecrets(taskLog.TaskId)
+	if len(secrets) == 0 {
+		return taskLog.Output, true
+	}
+
+	stream := 0
+	if taskLog.IsStderr {
+		stream = 1
+	}
+
+	d.secretsMu.Lock()
+	carry := d.carries[taskLog.TaskId]
+	if carry == nil {
+		// 任务已结束时不再保留末尾
+		if _, ok := d.secrets[taskLog.TaskId]; !ok {
+			d.secretsMu.Unlock()
+			return MaskSecrets(taskLog.Output, secrets), true
+		}
+		carry = &logCarry{}
+		d.carries[taskLog.TaskId] = carry
+	}
+	// 断线重连后重放的日志块已经处理过
+	if taskLog.Seq <= carry.lastSeq {
+		d.secretsMu.Unlock()
+		return "", false
+	}
+	carry.lastSeq = taskLog.Seq
+	text := carry.pending[stream] + taskLog.Output
+	cut := safePrefix(text, secrets)
+	carry.pending[stream] = text[cut:]
+	d.secretsMu.Unlock()
+
+	return MaskSecrets(text[:cut], secrets), true
+}
+
+// FlushLog 返回任务各输出流保留的日志末尾，在任务结果到达、订阅关闭之前写入
+func (d *TaskDispatcher) FlushLog(taskID string) []*pb.TaskLog {
+	secrets := d.taskSecrets(taskID)
+
+	d.secretsMu.Lock()
+	carry := d.carries[taskID]
+	delete(d.carries, taskID)
+	d.secretsMu.Unlock()
+	if carry == nil {
+		return nil
+	}
+
+	var logs []*pb.TaskLog
+	for stream, pending := range carry.pending {
+		if pending == "" {
+			continue
+		}
+		logs = append(logs, &pb.TaskLog{
+			TaskId:   taskID,
+			Output:   MaskSecrets(pending, secrets),
+			IsStderr: stream == 1,
+			Seq:      carry.lastSeq + int64(len(logs)) + 1,
+		})
+	}
+	return logs
+}
+
+// safePrefix 返回可以安全脱敏输出的前缀长度：保留末尾最长 secret 长度减一的字节，
+// 且不切开任何完整出现的 secret，也不切开 UTF-8 字符
+func safePrefix(text string, secrets []string) int {
+	maxLen := 0
+	for _, secret := range secrets {
+		if len(secret) > maxLen {
+			maxLen = len(secret)
+		}
+	}
+	cut := len(text) - (maxLen - 1)
+	if cut <= 0 {
+		return 0
+	}
+	for moved := true; moved; {
+		moved = false
+		for _, secret := range secrets {
+			if secret == "" {
+				continue
+			}
+			for i := 0; i < cut; {
+				j := strings.Index(text[i:], secret)
+				if j < 0 {
+					break
+				}
+				start, end := i+j, i+j+len(secret)
+				if start < cut && cut < end {
+					cut = end
+					moved = true
+				}
+				i = start + 1
+			}
+		}
+	}
+	for cut > 0 && cut < len(text) && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	return cut
+}
+
+// taskSecrets 返回未结束任务的 secret 参数值。平台重启后从数据库加载，
+// 只缓存有 secret 且未结束的任务；查询期间不持有锁，避免阻塞其他任务的日志
+func (d *TaskDispatcher) taskSecrets(taskID string) []string {
+	d.secretsMu.Lock()
+	secrets, ok := d.secrets[taskID]
+	forgotten := d.forgotten
+	d.secretsMu.Unlock()
+	if ok {
+		return secrets
+	}
+
+	var secret models.TaskSecret
+	result := d.db.Where("task_id = ? AND task_id IN (?)", taskID,
+		d.db.Model(&models.Task{}).Select("task_id").Where("task_id = ? AND status IN ?", taskID, inFlightStatuses)).
+		Limit(1).Find(&secret)
+	if result.Error != nil {
+		log.Printf("Failed to load secrets of task %s: %v", taskID, result.Error)
+		return nil
+	}
+	if result.RowsAffected == 0 {
+		return nil
+	}
+	if err := json.Unmarshal([]byte(secret.Values), &secrets); err != nil {
+		log.Printf("Failed to decode secrets of task %s: %v", taskID, err)
+		return nil
+	}
+
+	// 查询期间有任务结束时不缓存，避免缓存已结束的任务
+	d.secretsMu.Lock()
+	if d.forgotten == forgotten {
+		d.secrets[taskID] = secrets
+	}
+	d.secretsMu.Unlock()
+	return secrets
+}
+
+// forgetSecrets 在任务结束后删除 secret 参数的明文
+func (d *TaskDispatcher) forgetSecrets(taskID string) {
+	if err := d.db.Where("task_id = ?", taskID).Delete(&models.TaskSecret{}).Error; err != nil {
+		log.Printf("Failed to delete secrets of task %s: %v", taskID, err)
+	}
+
+	d.secretsMu.Lock()
+	delete(d.secrets, taskID)
+	delete(d.carries, taskID)
+	d.forgotten++
+	d.secretsMu.Unlock()
+}
+
+func (d *TaskDispatcher) dispatchOrQueue(task *models.Task) error {
+	if err := d.Dispatch(task); err != nil {
+		if errors.Is(err, session.ErrAgentNotConnected) {
+			log.Printf("Agent %s offline, task %s queued", task.AgentID, task.TaskID)
+			return nil
+		}
+		log.Printf("Failed to dispatch task %s: %v", task.TaskID, err)
+	}
+	return nil
+}
+
+// Release 将作业中排队的任务转为 pending 并尝试下发，Agent 不在线时等待其重连，超过任务超时时间仍未下发则置为 timeout
+func (d *TaskDispatcher) Release(task *models.Task) error {
+	result := d.db.Model(&models.Task{}).
+		Where("task_id = ? AND status = ?", task.TaskID, TaskStatusQueued).
+		Update("status", TaskStatusPending)
+	if result.Error != nil {
+		return fmt.Errorf("failed to release task: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil
+	}
+	task.Status = TaskStatusPending
+
+	if err := d.Dispatch(task); err != nil && !errors.Is(err, session.ErrAgentNotConnected) {
+		return err
+	}
+	return nil
+}
+
+// Dispatch 将 pending 状态的任务置为 dispatched 后发送给 Agent，发送失败时恢复为 pending。
+// 先以条件更新认领任务，并发下发同一任务时只有一方发送
+func (d *TaskDispatcher) Dispatch(task *models.Task) error {
+	taskType, err := ParseTaskType(task.Type)
+	if err != nil {
+		return err
+	}
+
+	script, env := task.Script, task.Env
+	if task.HasSecrets {
+		var secret models.TaskSecret
+		if err := d.db.Where("task_id = ?", task.TaskID).First(&secret).Error; err != nil {
+			return fmt.Errorf("failed to load secrets of task %s: %w", task.TaskID, err)
+		}
+		script, env = secret.Script, secret.Env
+	}
+
+	req := &pb.TaskRequest{
+		TaskId:     task.TaskID,
+		Type:       taskType,
+		Script:     script,
+		Timeout:    int32(task.Timeout),
+		RunAsUser:  task.RunAsUser,
+		RunAsGroup: task.RunAsGroup,
+		WorkDir:    task.WorkDir,
+		Umask:      task.Umask,
+		File:       task.File,
+		FileName:   task.FileName,
+		FileSha256: task.FileSHA256,
+	}
+	if task.Args != "" {
+		if err := json.Unmarshal([]byte(task.Args), &req.Args); err != nil {
+			return fmt.Errorf("invalid args of task %s: %w", task.TaskID, err)
+		}
+	}
+	if env != "" {
+		if err := json.Unmarshal([]byte(env), &req.Env); err != nil {
+			return fmt.Errorf("invalid env of task %s: %w", task.TaskID, err)
+		}
+	}
+	if task.Limits != (models.ResourceLimits{}) {
+		req.Limits = &pb.ResourceLimits{
+			CpuSeconds:   task.Limits.CPUSeconds,
+			AddressSpace: task.Limits.AddressSpace,
+			OpenFiles:    task.Limits.OpenFiles,
+			Processes:    task.Limits.Processes,
+		}
+	}
+	// 每次下发时签名，离线排队的任务重连下发时获得新的有效期
+	if d.signingKey != nil {
+		tasksign.Sign(d.signingKey, task.AgentID, req, time.Now().Add(d.signatureTTL))
+	}
+
+	// Agent 不在线时不认领，任务保持 pending 等待重连
+	if !d.sessions.IsConnected(task.AgentID) {
+		return fmt.Errorf("%w: %s", session.ErrAgentNotConnected, task.AgentID)
+	}
+	result := d.db.Model(&models.Task{}).
+		Where("task_id = ? AND status = ?", task.TaskID, TaskStatusPending).
+		Update("status", TaskStatusDispatched)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update task status: %w", result.Error)
+	}
+	// 任务已被其他调用下发或已取消
+	if result.RowsAffected != 1 {
+		return nil
+	}
+
+	if err := d.sessions.Send(task.AgentID, &pb.ServerMessage{
+		Message: &pb.ServerMessage_TaskRequest{TaskRequest: req},
+	}); err != nil {
+		if err := d.db.Model(&models.Task{}).
+			Where("task_id = ? AND status = ?", task.TaskID, TaskStatusDispatched).
+			Update("status", TaskStatusPending).Error; err != nil {
+			log.Printf("Failed to requeue task %s: %v", task.TaskID, err)
+		}
+		return err
+	}
+	task.Status = TaskStatusDispatched
+	return nil
+}
+
+// DispatchPending 下发某个 Agent 所有排队中的任务，在 Agent 注册（重连）后调用
+func (d *TaskDispatcher) DispatchPending(agentID string) {
+	var tasks []models.Task
+	if err := d.db.Where("agent_id = ? AND status = ?", agentID, TaskStatusPending).
+		Order("created_at ASC").Find(&tasks).Error; err != nil {
+		log.Printf("Failed to load pending tasks for agent %s: %v", agentID, err)
+		return
+	}
+
+	for i := range tasks {
+		if err := d.Dispatch(&tasks[i]); err != nil {
+			log.Printf("Failed to dispatch task %s: %v", tasks[i].TaskID, err)
+			return
+		}
+	}
+	if len(tasks) > 0 {
+		log.Printf("Dispatched %d pending tasks to agent %s", len(tasks), agentID)
+	}
+}
+
+// MarkRunning 处理 Agent 的开始执行确认，只接受下发给该 Agent 且尚未开始执行的任务
+func (d *TaskDispatcher) MarkRunning(agentID string, ack *pb.TaskAck) error {
+	startedAt := timestampToTime(ack.StartedAt)
+	result := d.db.Model(&models.Task{}).
+		Where("task_id = ? AND agent_id = ? AND status IN ?", ack.TaskId, agentID, []string{TaskStatusPending, TaskStatusDispatched}).
+		Updates(map[string]interface{}{
+			"status":     TaskStatusRunning,
+			"started_at": startedAt,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected != 1 {
+		return fmt.Errorf("%w: %s", ErrTaskNotInFlight, ack.TaskId)
+	}
+	return nil
+}
+
+// CheckInFlight 检查任务是否下发给了该 Agent 且尚未结束，用于校验 Agent 上报的日志
+func (d *TaskDispatcher) CheckInFlight(agentID, taskID string) error {
+	var count int64
+	if err := d.db.Model(&models.Task{}).
+		Where("task_id = ? AND agent_id = ? AND status IN ?", taskID, agentID, inFlightStatuses).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return fmt.Errorf("%w: %s", ErrTaskNotInFlight, taskID)
+	}
+	return nil
+}
+
+// HandleResult 根据 Agent 上报的结果更新任务终态。只接受下发给该 Agent 且尚未结束的任务，
+// 已被取消、拒绝或判定超时的任务不会被迟到的结果覆盖
+func (d *TaskDispatcher) HandleResult(agentID string, result *pb.TaskResult) error {
+	status := TaskStatusCompleted
+	switch {
+	case result.Status == pb.TaskStatus_TASK_STATUS_TIMEOUT:
+		status = TaskStatusTimeout
+	case result.Status == pb.TaskStatus_TASK_STATUS_CANCELLED:
+		status = TaskStatusCancelled
+	case result.Status == pb.TaskStatus_TASK_STATUS_REJECTED:
+		status = TaskStatusRejected
+	case result.Status == pb.TaskStatus_TASK_STATUS_FAILED || result.ExitCode != 0:
+		status = TaskStatusFailed
+	}
+
+	completedAt := timestampToTime(result.CompletedAt)
+	updated := d.db.Model(&models.Task{}).
+		Where("task_id = ? AND agent_id = ? AND status IN ?", result.TaskId, agentID, inFlightStatuses).
+		Updates(map[string]interface{}{
+			"exit_code":       result.ExitCode,
+			"stdout":          d.MaskOutput(result.TaskId, result.Stdout),
+			"stderr":          d.MaskOutput(result.TaskId, result.Stderr),
+			"status":          status,
+			"limit_exceeded":  result.LimitExceeded,
+			"policy_rejected": status == TaskStatusRejected,
+			"completed_at":    completedAt,
+		})
+	if updated.Error != nil {
+		return updated.Error
+	}
+	if updated.RowsAffected != 1 {
+		return fmt.Errorf("%w: %s", ErrTaskNotInFlight, result.TaskId)
+	}
+
+	d.notifyFinished(result.TaskId)
+	return nil
+}
+
+// OnTaskFinished 注册任务进入终态时的回调
+func (d *TaskDispatcher) OnTaskFinished(fn TaskFinishedFunc) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.finishHooks = append(d.finishHooks, fn)
+}
+
+func (d *TaskDispatcher) notifyFinished(taskID string) {
+	d.forgetSecrets(taskID)
+
+	d.mu.RLock()
+	hooks := d.finishHooks
+	d.mu.RUnlock()
+
+	if len(hooks) == 0 {
+		return
+	}
+
+	var task models.Task
+	if err := d.db.Where("task_id = ?", taskID).First(&task).Error; err != nil {
+		log.Printf("Failed to load finished task %s: %v", taskID, err)
+		return
+	}
+	for _, hook := range hooks {
+		hook(&task)
+	}
+}
+
+// Cancel 取消任务：尚未下发（queued/awaiting_approval/pending）的任务直接置为 cancelled，
+// 已下发的任务通知 Agent 终止，最终状态及部分输出由 Agent 的结果上报写入
+func (d *TaskDispatcher) Cancel(task *models.Task) error {
+	if IsTaskFinished(task.Status) {
+		return ErrTaskFinished
+	}
+
+	undispatched := []string{TaskStatusQueued, TaskStatusAwaitingApproval, TaskStatusPending}
+	if task.Status == TaskStatusQueued || task.Status == TaskStatusAwaitingApproval || task.Status == TaskStatusPending {
+		result := d.db.Model(&models.Task{}).
+			Where("task_id = ? AND status IN ?", task.TaskID, undispatched).
+			Updates(map[string]interface{}{
+				"status":       TaskStatusCancelled,
+				"completed_at": time.Now(),
+			})
+		if result.Error != nil {
+			return fmt.Errorf("failed to cancel task: %w", result.Error)
+		}
+		if result.RowsAffected > 0 {
+			task.Status = TaskStatusCancelled
+			d.notifyFinished(task.TaskID)
+			return nil
+		}
+		// 任务刚好被下发，继续通知 Agent
+	}
+
+	return d.sendCancel(task)
+}
+
+// sendCancel 通知 Agent 终止任务
+func (d *TaskDispatcher) sendCancel(task *models.Task) error {
+	return d.sessions.Send(task.AgentID, &pb.ServerMessage{
+		Message: &pb.ServerMessage_CancelTask{
+			CancelTask: &pb.CancelTaskRequest{
+				TaskId: task.TaskID,
+			},
+		},
+	})
+}
+
+// Start 启动超时检查，处理 Agent 失联导致迟迟没有结果的任务
+func (d *TaskDispatcher) Start() {
+	ticker := time.NewTicker(timeoutCheckInterval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				d.checkTimeouts()
+			case <-d.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+func (d *TaskDispatcher) Stop() {
+	close(d.stopCh)
+}
+
+func (d *TaskDispatcher) checkTimeouts() {
+	// 作业中的子任务在 Agent 离线时同样超时，避免占用作业的并发数使作业无法结束
+	var tasks []models.Task
+	if err := d.db.Where("status IN ? OR (status = ? AND job_id <> '')",
+		[]string{TaskStatusDispatched, TaskStatusRunning}, TaskStatusPending).
+		Find(&tasks).Error; err != nil {
+		log.Printf("Failed to load in-flight tasks: %v", err)
+		return
+	}
+
+	now := time.Now()
+	for _, task := range tasks {
+		since := task.UpdatedAt
+		if task.StartedAt != nil {
+			since = *task.StartedAt
+		}
+		deadline := since.Add(time.Duration(task.Timeout)*time.Second + taskTimeoutGrace)
+		if now.Before(deadline) {
+			continue
+		}
+
+		reason := "no result reported by agent before deadline"
+		if task.Status == TaskStatusPending {
+			reason = "agent offline, task not dispatched before deadline"
+		}
+		result := d.db.Model(&models.Task{}).
+			Where("task_id = ? AND status = ?", task.TaskID, task.Status).
+			Updates(map[string]interface{}{
+				"status":       TaskStatusTimeout,
+				"stderr":       reason,
+				"completed_at": now,
+			})
+		if result.Error != nil {
+			log.Printf("Failed to mark task %s as timeout: %v", task.TaskID, result.Error)
+			continue
+		}
+		// 结果恰好在检查期间到达时任务已由 HandleResult 结束
+		if result.RowsAffected != 1 {
+			continue
+		}
+		// Agent 上的脚本可能仍在运行，通知其终止；之后上报的结果会被忽略
+		if task.Status == TaskStatusPending {
+			d.notifyFinished(task.TaskID)
+			continue
+		}
+		if err := d.sendCancel(&task); err != nil && !errors.Is(err, session.ErrAgentNotConnected) {
+			log.Printf("Failed to cancel timed out task %s: %v", task.TaskID, err)
+		}
+		d.notifyFinished(task.TaskID)
+	}
+}
+
+func generateID(prefix string) string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return prefix + "-" + hex.EncodeToString(b)
+}
+
+func timestampToTime(ts *pb.Timestamp) time.Time {
+	if ts == nil {
+		return time.Now()
+	}
+	return time.Unix(ts.Seconds, int64(ts.Nanos))
+}
@@ -0,0 +1,489 @@
+package service
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	pb "github.com/yourusername/agent-platform/proto"
+	"github.com/yourusername/agent-platform/pkg/tasksign"
+	"github.com/yourusername/agent-platform/platform/internal/models"
+	"github.com/yourusername/agent-platform/platform/internal/session"
+)
+
+type mockStream struct {
+	pb.AgentService_ConnectServer
+	mu   sync.Mutex
+	sent []*pb.ServerMessage
+}
+
+func (m *mockStream) Send(msg *pb.ServerMessage) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+func (m *mockStream) messages() []*pb.ServerMessage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]*pb.ServerMessage(nil), m.sent...)
+}
+
+func TestTaskDispatcher_Lifecycle(t *testing.T) {
+	db := setupTestDB()
+	sessions := session.NewRegistry()
+	stream := &mockStream{}
+	sessions.Add("agent-1", session.NewSession(stream))
+	dispatcher := NewTaskDispatcher(db, sessions)
+
+	task := &models.Task{AgentID: "agent-1", Type: "python", Script: "print(1)"}
+	assert.NoError(t, dispatcher.Submit(task))
+	assert.NotEmpty(t, task.TaskID)
+	assert.Equal(t, DefaultTaskTimeout, task.Timeout)
+
+	sent := stream.messages()
+	assert.Len(t, sent, 1)
+	req := sent[0].GetTaskRequest()
+	assert.Equal(t, task.TaskID, req.TaskId)
+	assert.Equal(t, pb.TaskType_TASK_TYPE_PYTHON, req.Type)
+
+	var stored models.Task
+	db.Where("task_id = ?", task.TaskID).First(&stored)
+	assert.Equal(t, TaskStatusDispatched, stored.Status)
+
+	assert.NoError(t, dispatcher.MarkRunning("agent-1", &pb.TaskAck{TaskId: task.TaskID}))
+	db.Where("task_id = ?", task.TaskID).First(&stored)
+	assert.Equal(t, TaskStatusRunning, stored.Status)
+	assert.NotNil(t, stored.StartedAt)
+
+	assert.NoError(t, dispatcher.HandleResult("agent-1", &pb.TaskResult{
+		TaskId:   task.TaskID,
+		ExitCode: -1,
+		Status:   pb.TaskStatus_TASK_STATUS_TIMEOUT,
+	}))
+	db.Where("task_id = ?", task.TaskID).First(&stored)
+	assert.Equal(t, TaskStatusTimeout, stored.Status)
+	assert.NotNil(t, stored.CompletedAt)
+}
+
+func TestTaskDispatcher_DispatchPendingOnReconnect(t *testing.T) {
+	db := setupTestDB()
+	sessions := session.NewRegistry()
+	dispatcher := NewTaskDispatcher(db, sessions)
+
+	// Agent 离线时任务排队
+	task := &models.Task{AgentID: "agent-1", Type: "shell", Script: "echo 1"}
+	assert.NoError(t, dispatcher.Submit(task))
+
+	var stored models.Task
+	db.Where("task_id = ?", task.TaskID).First(&stored)
+	assert.Equal(t, TaskStatusPending, stored.Status)
+
+	stream := &mockStream{}
+	sessions.Add("agent-1", session.NewSession(stream))
+	dispatcher.DispatchPending("agent-1")
+
+	assert.Len(t, stream.messages(), 1)
+	db.Where("task_id = ?", task.TaskID).First(&stored)
+	assert.Equal(t, TaskStatusDispatched, stored.Status)
+}
+
+func TestTaskDispatcher_HandleResultFailed(t *testing.T) {
+	db := setupTestDB()
+	sessions := session.NewRegistry()
+	sessions.Add("agent-1", session.NewSession(&mockStream{}))
+	dispatcher := NewTaskDispatcher(db, sessions)
+
+	task := &models.Task{AgentID: "agent-1", Type: "shell", Script: "exit 3"}
+	assert.NoError(t, dispatcher.Submit(task))
+
+	assert.NoError(t, dispatcher.HandleResult("agent-1", &pb.TaskResult{
+		TaskId:   task.TaskID,
+		ExitCode: 3,
+		Status:   pb.TaskStatus_TASK_STATUS_COMPLETED,
+	}))
+
+	var stored models.Task
+	db.Where("task_id = ?", task.TaskID).First(&stored)
+	assert.Equal(t, TaskStatusFailed, stored.Status)
+	assert.Equal(t, 3, stored.ExitCode)
+}
+
+func TestTaskDispatcher_HandleResultNotInFlight(t *testing.T) {
+	db := setupTestDB()
+	sessions := session.NewRegistry()
+	dispatcher := NewTaskDispatcher(db, sessions)
+	finished := 0
+	dispatcher.OnTaskFinished(func(task *models.Task) { finished++ })
+
+	// 尚未下发的任务不接受上报
+	task := &models.Task{AgentID: "agent-1", Type: "shell", Script: "sleep 60"}
+	assert.NoError(t, dispatcher.Submit(task))
+	assert.ErrorIs(t, dispatcher.CheckInFlight("agent-1", task.TaskID), ErrTaskNotInFlight)
+
+	sessions.Add("agent-1", session.NewSession(&mockStream{}))
+	dispatcher.DispatchPending("agent-1")
+
+	// 其他 Agent 不能上报该任务的确认、日志与结果
+	assert.ErrorIs(t, dispatcher.MarkRunning("agent-2", &pb.TaskAck{TaskId: task.TaskID}), ErrTaskNotInFlight)
+	assert.ErrorIs(t, dispatcher.CheckInFlight("agent-2", task.TaskID), ErrTaskNotInFlight)
+	assert.NoError(t, dispatcher.CheckInFlight("agent-1", task.TaskID))
+	assert.ErrorIs(t, dispatcher.HandleResult("agent-2", &pb.TaskResult{TaskId: task.TaskID, Status: pb.TaskStatus_TASK_STATUS_COMPLETED}), ErrTaskNotInFlight)
+
+	// 已取消的任务不会被迟到的结果覆盖，也不会再次触发结束回调
+	assert.NoError(t, dispatcher.HandleResult("agent-1", &pb.TaskResult{TaskId: task.TaskID, Status: pb.TaskStatus_TASK_STATUS_CANCELLED}))
+	assert.Equal(t, 1, finished)
+	assert.ErrorIs(t, dispatcher.HandleResult("agent-1", &pb.TaskResult{TaskId: task.TaskID, Status: pb.TaskStatus_TASK_STATUS_COMPLETED}), ErrTaskNotInFlight)
+	assert.ErrorIs(t, dispatcher.CheckInFlight("agent-1", task.TaskID), ErrTaskNotInFlight)
+	assert.Equal(t, 1, finished)
+
+	var stored models.Task
+	db.Where("task_id = ?", task.TaskID).First(&stored)
+	assert.Equal(t, TaskStatusCancelled, stored.Status)
+}
+
+func TestTaskDispatcher_DispatchOnce(t *testing.T) {
+	db := setupTestDB()
+	sessions := session.NewRegistry()
+	dispatcher := NewTaskDispatcher(db, sessions)
+
+	task := &models.Task{AgentID: "agent-1", Type: "shell", Script: "uptime"}
+	assert.NoError(t, dispatcher.Submit(task))
+
+	// 发送失败时恢复为 pending
+	closed := session.NewSession(&mockStream{})
+	sessions.Add("agent-1", closed)
+	closed.Close()
+	assert.Error(t, dispatcher.Dispatch(task))
+	var stored models.Task
+	db.Where("task_id = ?", task.TaskID).First(&stored)
+	assert.Equal(t, TaskStatusPending, stored.Status)
+
+	// 新任务下发与重连下发同时进行时只发送一次
+	stream := &mockStream{}
+	sessions.Add("agent-1", session.NewSession(stream))
+	first, second := stored, stored
+	assert.NoError(t, dispatcher.Dispatch(&first))
+	assert.NoError(t, dispatcher.Dispatch(&second))
+	assert.Len(t, stream.messages(), 1)
+	db.Where("task_id = ?", task.TaskID).First(&stored)
+	assert.Equal(t, TaskStatusDispatched, stored.Status)
+}
+
+func TestTaskDispatcher_TimeoutCancelsTask(t *testing.T) {
+	db := setupTestDB()
+	sessions := session.NewRegistry()
+	stream := &mockStream{}
+	sessions.Add("agent-1", session.NewSession(stream))
+	dispatcher := NewTaskDispatcher(db, sessions)
+
+	task := &models.Task{AgentID: "agent-1", Type: "shell", Script: "sleep 600", Timeout: 1}
+	assert.NoError(t, dispatcher.Submit(task))
+	db.Model(&models.Task{}).Where("task_id = ?", task.TaskID).UpdateColumn("updated_at", time.Now().Add(-time.Hour))
+
+	dispatcher.checkTimeouts()
+
+	var stored models.Task
+	db.Where("task_id = ?", task.TaskID).First(&stored)
+	assert.Equal(t, TaskStatusTimeout, stored.Status)
+	// 通知 Agent 终止仍在运行的脚本
+	messages := stream.messages()
+	assert.Len(t, messages, 2)
+	assert.Equal(t, task.TaskID, messages[1].GetCancelTask().GetTaskId())
+}
+
+func TestTaskDispatcher_Cancel(t *testing.T) {
+	db := setupTestDB()
+	sessions := session.NewRegistry()
+	dispatcher := NewTaskDispatcher(db, sessions)
+
+	// 未下发的任务直接取消
+	pending := &models.Task{AgentID: "agent-1", Type: "shell", Script: "sleep 60"}
+	assert.NoError(t, dispatcher.Submit(pending))
+	assert.NoError(t, dispatcher.Cancel(pending))
+	assert.Equal(t, TaskStatusCancelled, pending.Status)
+	assert.ErrorIs(t, dispatcher.Cancel(pending), ErrTaskFinished)
+
+	// 已下发的任务通知 Agent 终止
+	stream := &mockStream{}
+	sessions.Add("agent-1", session.NewSession(stream))
+	running := &models.Task{AgentID: "agent-1", Type: "shell", Script: "sleep 60"}
+	assert.NoError(t, dispatcher.Submit(running))
+	assert.NoError(t, dispatcher.Cancel(running))
+
+	sent := stream.messages()
+	assert.Len(t, sent, 2)
+	assert.Equal(t, running.TaskID, sent[1].GetCancelTask().TaskId)
+
+	assert.NoError(t, dispatcher.HandleResult("agent-1", &pb.TaskResult{
+		TaskId:   running.TaskID,
+		ExitCode: -1,
+		Stdout:   "partial",
+		Status:   pb.TaskStatus_TASK_STATUS_CANCELLED,
+	}))
+
+	var stored models.Task
+	db.Where("task_id = ?", running.TaskID).First(&stored)
+	assert.Equal(t, TaskStatusCancelled, stored.Status)
+	assert.Equal(t, "partial", stored.Stdout)
+}
+
+func TestTaskDispatcher_ApproveAndReject(t *testing.T) {
+	db := setupTestDB()
+	sessions := session.NewRegistry()
+	sessions.Add("agent-1", session.NewSession(&mockStream{}))
+	dispatcher := NewTaskDispatcher(db, sessions)
+
+	var finished []string
+	dispatcher.OnTaskFinished(func(task *models.Task) {
+		finished = append(finished, fmt.Sprintf("%s:%s:%v", task.TaskID, task.Status, task.PolicyRejected))
+	})
+
+	task := &models.Task{AgentID: "agent-1", Type: "shell", Script: "uptime", CreatedBy: "alice"}
+	assert.NoError(t, dispatcher.SubmitForApproval(task))
+	assert.Equal(t, TaskStatusAwaitingApproval, task.Status)
+
+	assert.NoError(t, dispatcher.Approve(task, "bob"))
+	var stored models.Task
+	db.Where("task_id = ?", task.TaskID).First(&stored)
+	assert.Equal(t, TaskStatusDispatched, stored.Status)
+	assert.Equal(t, "bob", stored.ApprovedBy)
+	assert.ErrorIs(t, dispatcher.Approve(task, "bob"), ErrTaskNotAwaiting)
+
+	// 直接拒绝的新任务以 rejected 状态保存
+	denied := &models.Task{AgentID: "agent-1", Type: "shell", Script: "rm -rf /"}
+	assert.NoError(t, dispatcher.Reject(denied, "script matches deny pattern"))
+	var rejected models.Task
+	db.Where("task_id = ?", denied.TaskID).First(&rejected)
+	assert.Equal(t, TaskStatusRejected, rejected.Status)
+	assert.Equal(t, "script matches deny pattern", rejected.Stderr)
+	assert.True(t, rejected.PolicyRejected)
+	assert.Equal(t, []string{denied.TaskID + ":rejected:true"}, finished)
+
+	// 审批人驳回的任务不标记为策略拒绝
+	pending := &models.Task{AgentID: "agent-1", Type: "shell", Script: "reboot"}
+	assert.NoError(t, dispatcher.SubmitForApproval(pending))
+	assert.NoError(t, dispatcher.Reject(pending, "rejected by bob"))
+	assert.Equal(t, pending.TaskID+":rejected:false", finished[1])
+
+	// Agent 拒绝执行的任务标记为策略拒绝
+	assert.NoError(t, dispatcher.HandleResult("agent-1", &pb.TaskResult{TaskId: task.TaskID, Status: pb.TaskStatus_TASK_STATUS_REJECTED}))
+	assert.Equal(t, task.TaskID+":rejected:true", finished[2])
+}
+
+func TestTaskDispatcher_SignsTasks(t *testing.T) {
+	db := setupTestDB()
+	sessions := session.NewRegistry()
+	stream := &mockStream{}
+	sessions.Add("agent-1", session.NewSession(stream))
+	dispatcher := NewTaskDispatcher(db, sessions)
+
+	pub, key, _ := ed25519.GenerateKey(rand.Reader)
+	dispatcher.SetSigningKey(key, time.Minute)
+
+	assert.NoError(t, dispatcher.Submit(&models.Task{AgentID: "agent-1", Type: "shell", Script: "uptime"}))
+	msgs := stream.messages()
+	assert.Len(t, msgs, 1)
+	req := msgs[0].GetTaskRequest()
+	assert.NoError(t, tasksign.Verify(pub, "agent-1", req, time.Now()))
+	assert.ErrorIs(t, tasksign.Verify(pub, "agent-1", req, time.Now().Add(2*time.Minute)), tasksign.ErrExpired)
+	assert.ErrorIs(t, tasksign.Verify(pub, "agent-2", req, time.Now()), tasksign.ErrInvalidSignature)
+}
+
+func TestTaskDispatcher_RunOptionsAndLimits(t *testing.T) {
+	db := setupTestDB()
+	sessions := session.NewRegistry()
+	stream := &mockStream{}
+	sessions.Add("agent-1", session.NewSession(stream))
+	dispatcher := NewTaskDispatcher(db, sessions)
+
+	task := &models.Task{
+		AgentID:   "agent-1",
+		Type:      "shell",
+		Script:    "make build",
+		RunAsUser: "deploy",
+		WorkDir:   "/srv/app",
+		Umask:     "027",
+		Limits:    models.ResourceLimits{CPUSeconds: 60, Processes: 32},
+	}
+	assert.NoError(t, dispatcher.Submit(task))
+
+	req := stream.messages()[0].GetTaskRequest()
+	assert.Equal(t, "deploy", req.RunAsUser)
+	assert.Equal(t, "/srv/app", req.WorkDir)
+	assert.Equal(t, "027", req.Umask)
+	assert.Equal(t, uint64(60), req.Limits.CpuSeconds)
+	assert.Equal(t, uint64(32), req.Limits.Processes)
+
+	assert.NoError(t, dispatcher.HandleResult("agent-1", &pb.TaskResult{
+		TaskId:        task.TaskID,
+		ExitCode:      -1,
+		Status:        pb.TaskStatus_TASK_STATUS_FAILED,
+		LimitExceeded: "cpu_seconds",
+	}))
+	var stored models.Task
+	db.Where("task_id = ?", task.TaskID).First(&stored)
+	assert.Equal(t, TaskStatusFailed, stored.Status)
+	assert.Equal(t, "cpu_seconds", stored.LimitExceeded)
+	assert.Equal(t, uint64(32), stored.Limits.Processes)
+}
+
+func TestTaskDispatcher_Secrets(t *testing.T) {
+	db := setupTestDB()
+	sessions := session.NewRegistry()
+	stream := &mockStream{}
+	sessions.Add("agent-1", session.NewSession(stream))
+	dispatcher := NewTaskDispatcher(db, sessions)
+
+	task := &models.Task{
+		AgentID: "agent-1",
+		Type:    "shell",
+		Script:  "curl -u admin:s3cret https://example.com",
+		Env:     `{"TOKEN":"s3cret"}`,
+		Secrets: []string{"s3cret"},
+	}
+	assert.NoError(t, dispatcher.Submit(task))
+
+	// 下发给 Agent 的是明文
+	req := stream.messages()[0].GetTaskRequest()
+	assert.Equal(t, "curl -u admin:s3cret https://example.com", req.Script)
+	assert.Equal(t, map[string]string{"TOKEN": "s3cret"}, req.Env)
+
+	// 落库的是掩码
+	var stored models.Task
+	db.Where("task_id = ?", task.TaskID).First(&stored)
+	assert.True(t, stored.HasSecrets)
+	assert.Equal(t, "curl -u admin:"+SecretMask+" https://example.com", stored.Script)
+	assert.NotContains(t, stored.Env, "s3cret")
+
+	assert.Equal(t, "token="+SecretMask, dispatcher.MaskOutput(task.TaskID, "token=s3cret"))
+
+	assert.NoError(t, dispatcher.HandleResult("agent-1", &pb.TaskResult{
+		TaskId: task.TaskID,
+		Stdout: "logged in with s3cret",
+		Status: pb.TaskStatus_TASK_STATUS_COMPLETED,
+	}))
+	var finished models.Task
+	db.Where("task_id = ?", task.TaskID).First(&finished)
+	assert.Equal(t, "logged in with "+SecretMask, finished.Stdout)
+
+	// 任务结束后明文被删除
+	var count int64
+	db.Model(&models.TaskSecret{}).Where("task_id = ?", task.TaskID).Count(&count)
+	assert.Zero(t, count)
+
+	// 结束的任务与没有 secret 的任务不缓存
+	assert.Equal(t, "late s3cret", dispatcher.MaskOutput(task.TaskID, "late s3cret"))
+	assert.Equal(t, "output", dispatcher.MaskOutput("unknown-task", "output"))
+	assert.Empty(t, dispatcher.secrets)
+}
+
+func TestTaskDispatcher_MaskLogAcrossChunks(t *testing.T) {
+	db := setupTestDB()
+	sessions := session.NewRegistry()
+	sessions.Add("agent-1", session.NewSession(&mockStream{}))
+	dispatcher := NewTaskDispatcher(db, sessions)
+	task := &models.Task{AgentID: "agent-1", Type: "shell", Script: "echo s3cret", Secrets: []string{"s3cret", "pw"}}
+	assert.NoError(t, dispatcher.Submit(task))
+
+	var stdout string
+	for seq, chunk := range []string{"token=s3", "cret\nnext ", "p", "w done s3cret\n", "tail s3c"} {
+		output, ok := dispatcher.MaskLog(&pb.TaskLog{TaskId: task.TaskID, Seq: int64(seq + 1), Output: chunk})
+		assert.True(t, ok)
+		stdout += output
+	}
+	// 重放的日志块被忽略
+	_, ok := dispatcher.MaskLog(&pb.TaskLog{TaskId: task.TaskID, Seq: 2, Output: "cret\n"})
+	assert.False(t, ok)
+	// 各输出流分别保留末尾
+	stderr, ok := dispatcher.MaskLog(&pb.TaskLog{TaskId: task.TaskID, Seq: 6, Output: "error s3", IsStderr: true})
+	assert.True(t, ok)
+
+	logs := dispatcher.FlushLog(task.TaskID)
+	assert.Len(t, logs, 2)
+	for _, l := range logs {
+		if l.IsStderr {
+			stderr += l.Output
+		} else {
+			stdout += l.Output
+		}
+		assert.Greater(t, l.Seq, int64(6))
+	}
+	assert.Equal(t, "token="+SecretMask+"\nnext "+SecretMask+" done "+SecretMask+"\ntail s3c", stdout)
+	assert.Equal(t, "error s3", stderr)
+	assert.Empty(t, dispatcher.FlushLog(task.TaskID))
+
+	// 没有 secret 的任务不保留末尾
+	plain := &models.Task{AgentID: "agent-1", Type: "shell", Script: "uptime"}
+	assert.NoError(t, dispatcher.Submit(plain))
+	output, ok := dispatcher.MaskLog(&pb.TaskLog{TaskId: plain.TaskID, Seq: 1, Output: "load s3"})
+	assert.True(t, ok)
+	assert.Equal(t, "load s3", output)
+	assert.Empty(t, dispatcher.carries)
+}
+
+func TestTaskDispatcher_SecretsAfterRestart(t *testing.T) {
+	db := setupTestDB()
+	sessions := session.NewRegistry()
+	sessions.Add("agent-1", session.NewSession(&mockStream{}))
+	task := &models.Task{AgentID: "agent-1", Type: "shell", Script: "echo s3cret", Secrets: []string{"s3cret"}}
+	assert.NoError(t, NewTaskDispatcher(db, sessions).Submit(task))
+	plain := &models.Task{AgentID: "agent-1", Type: "shell", Script: "uptime"}
+	assert.NoError(t, NewTaskDispatcher(db, sessions).Submit(plain))
+
+	// 重启后从数据库加载未结束任务的 secret
+	dispatcher := NewTaskDispatcher(db, sessions)
+	assert.Equal(t, SecretMask, dispatcher.MaskOutput(task.TaskID, "s3cret"))
+	assert.Equal(t, "uptime", dispatcher.MaskOutput(plain.TaskID, "uptime"))
+	assert.Len(t, dispatcher.secrets, 1)
+}
+
+func TestTaskDispatcher_FileTask(t *testing.T) {
+	db := setupTestDB()
+	sessions := session.NewRegistry()
+	stream := &mockStream{}
+	sessions.Add("agent-1", session.NewSession(stream))
+	dispatcher := NewTaskDispatcher(db, sessions)
+
+	file := []byte("#!/bin/sh\necho \"$@\"\n")
+	task := &models.Task{
+		AgentID:  "agent-1",
+		Type:     TaskTypeFile,
+		File:     file,
+		FileName: "run.sh",
+		Args:     `["--dry-run","x"]`,
+	}
+	assert.NoError(t, dispatcher.Submit(task))
+
+	req := stream.messages()[0].GetTaskRequest()
+	assert.Equal(t, pb.TaskType_TASK_TYPE_FILE, req.Type)
+	assert.Equal(t, file, req.File)
+	assert.Equal(t, "run.sh", req.FileName)
+	assert.Equal(t, []string{"--dry-run", "x"}, req.Args)
+	sum := sha256.Sum256(file)
+	assert.Equal(t, hex.EncodeToString(sum[:]), req.FileSha256)
+
+	invalid := []*models.Task{
+		{AgentID: "agent-1", Type: TaskTypeFile},
+		{AgentID: "agent-1", Type: TaskTypeFile, File: make([]byte, MaxTaskFileSize+1)},
+		{AgentID: "agent-1", Type: TaskTypeFile, File: file, FileName: "../run.sh"},
+		{AgentID: "agent-1", Type: TaskTypeFile, File: file, FileSHA256: "deadbeef"},
+		{AgentID: "agent-1", Type: "shell", Script: "true", File: file},
+	}
+	for i, task := range invalid {
+		assert.ErrorIs(t, dispatcher.Submit(task), ErrInvalidTaskFile, "task %d", i)
+	}
+
+	_, err := ParseScriptType(TaskTypeFile)
+	assert.Error(t, err)
+	taskType, err := ParseScriptType("perl")
+	assert.NoError(t, err)
+	assert.Equal(t, pb.TaskType_TASK_TYPE_PERL, taskType)
+}
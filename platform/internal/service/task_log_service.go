@@ -0,0 +1,121 @@
+package service
+
+import (
+	"fmt"
+	"sync"
+
+	pb "github.com/yourusername/agent-platform/proto"
+	"github.com/yourusername/agent-platform/platform/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// subscriberBuffer 每个订阅者的缓冲大小，消费过慢时订阅会被关闭，由调用方从数据库补齐
+const subscriberBuffer = 256
+
+// TaskLogService 持久化任务日志，并将新日志实时推送给订阅者
+type TaskLogService struct {
+	db          *gorm.DB
+	mu          sync.Mutex
+	subscribers map[string]map[chan *models.TaskLog]struct{}
+}
+
+func NewTaskLogService(db *gorm.DB) *TaskLogService {
+	return &TaskLogService{
+		db:          db,
+		subscribers: make(map[string]map[chan *models.TaskLog]struct{}),
+	}
+}
+
+// Append 保存 Agent 上报的日志块并通知订阅者，重复的序号会被忽略
+func (s *TaskLogService) Append(taskLog *pb.TaskLog) error {
+	stream := "stdout"
+	if taskLog.IsStderr {
+		stream = "stderr"
+	}
+
+	entry := &models.TaskLog{
+		TaskID:    taskLog.TaskId,
+		Seq:       taskLog.Seq,
+		Stream:    stream,
+		Output:    taskLog.Output,
+		Timestamp: timestampToTime(taskLog.Timestamp),
+	}
+
+	result := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(entry)
+	if result.Error != nil {
+		return fmt.Errorf("failed to save task log: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil
+	}
+
+	s.publish(entry)
+	return nil
+}
+
+// List 按序号返回任务在 afterSeq 之后的日志
+func (s *TaskLogService) List(taskID string, afterSeq int64) ([]models.TaskLog, error) {
+	var logs []models.TaskLog
+	err := s.db.Where("task_id = ? AND seq > ?", taskID, afterSeq).
+		Order("seq ASC").Find(&logs).Error
+	return logs, err
+}
+
+// Subscribe 订阅任务的实时日志，返回的通道在任务结束或消费过慢时关闭
+func (s *TaskLogService) Subscribe(taskID string) (<-chan *models.TaskLog, func()) {
+	ch := make(chan *models.TaskLog, subscriberBuffer)
+
+	s.mu.Lock()
+	if s.subscribers[taskID] == nil {
+		s.subscribers[taskID] = make(map[chan *models.TaskLog]struct{})
+	}
+	s.subscribers[taskID][ch] = struct{}{}
+	s.mu.Unlock()
+
+	unsubscribe := func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.removeLocked(taskID, ch)
+	}
+	return ch, unsubscribe
+}
+
+// Finish 在任务结束后关闭该任务的所有订阅
+func (s *TaskLogService) Finish(taskID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for ch := range s.subscribers[taskID] {
+		s.removeLocked(taskID, ch)
+	}
+}
+
+func (s *TaskLogService) publish(entry *models.TaskLog) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for ch := range s.subscribers[entry.TaskID] {
+		select {
+		case ch <- entry:
+		default:
+			s.removeLocked(entry.TaskID, ch)
+		}
+	}
+}
+
+func (s *TaskLogService) removeLocked(taskID string, ch chan *models.TaskLog) {
+	subs, exists := s.subscribers[taskID]
+	if !exists {
+		return
+	}
+	if _, exists := subs[ch]; !exists {
+		return
+	}
+
+	delete(subs, ch)
+	close(ch)
+	if len(subs) == 0 {
+		delete(s.subscribers, taskID)
+	}
+}
@@ -0,0 +1,37 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	pb "github.com/yourusername/agent-platform/proto"
+	"github.com/yourusername/agent-platform/platform/internal/models"
+)
+
+func TestTaskLogService_AppendAndSubscribe(t *testing.T) {
+	db := setupTestDB()
+	db.AutoMigrate(&models.TaskLog{})
+	service := NewTaskLogService(db)
+
+	ch, unsubscribe := service.Subscribe("task-1")
+	defer unsubscribe()
+
+	assert.NoError(t, service.Append(&pb.TaskLog{TaskId: "task-1", Seq: 1, Output: "a"}))
+	assert.NoError(t, service.Append(&pb.TaskLog{TaskId: "task-1", Seq: 2, Output: "b", IsStderr: true}))
+	// 重复上报的序号被忽略
+	assert.NoError(t, service.Append(&pb.TaskLog{TaskId: "task-1", Seq: 2, Output: "b", IsStderr: true}))
+
+	first := <-ch
+	assert.Equal(t, "a", first.Output)
+	second := <-ch
+	assert.Equal(t, "stderr", second.Stream)
+
+	logs, err := service.List("task-1", 1)
+	assert.NoError(t, err)
+	assert.Len(t, logs, 1)
+	assert.Equal(t, int64(2), logs[0].Seq)
+
+	service.Finish("task-1")
+	_, open := <-ch
+	assert.False(t, open)
+}
@@ -35,6 +35,6 @@ func TestCreateTask(t *testing.T) {
 func setupTestDB() *gorm.DB {
 	// 使用 SQLite 内存数据库进行测试
 	db, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
-	db.AutoMigrate(&models.Task{})
+	db.AutoMigrate(&models.Task{}, &models.TaskSecret{})
 	return db
 }
@@ -0,0 +1,283 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/yourusername/agent-platform/platform/internal/models"
+	"gorm.io/gorm"
+)
+
+// SecretMask 替换 secret 参数值的掩码
+const SecretMask = "******"
+
+var ErrInvalidParams = errors.New("invalid template parameters")
+
+var paramNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// templateFuncs 模板中可用的函数，quote 将参数转义为 shell 单引号字符串
+var templateFuncs = template.FuncMap{
+	"quote": shellQuote,
+}
+
+// TemplateSpec 创建或更新模板的参数
+type TemplateSpec struct {
+	Name        string
+	Description string
+	Type        string
+	Script      string
+	Params      []models.TemplateParam
+	CreatedBy   string
+}
+
+// RenderedTemplate 渲染结果
+type RenderedTemplate struct {
+	Type    string
+	Script  string
+	Params  map[string]interface{} // 校验并转换类型后的参数，secret 参数值已替换为掩码
+	Secrets []string               // secret 参数的明文值
+}
+
+// TemplateService 管理脚本模板，并在平台侧校验参数、渲染脚本
+type TemplateService struct {
+	db *gorm.DB
+}
+
+func NewTemplateService(db *gorm.DB) *TemplateService {
+	return &TemplateService{db: db}
+}
+
+func (s *TemplateService) Create(spec *TemplateSpec) (*models.ScriptTemplate, error) {
+	tpl := &models.ScriptTemplate{CreatedBy: spec.CreatedBy}
+	if err := applyTemplateSpec(tpl, spec); err != nil {
+		return nil, err
+	}
+	if err := s.db.Create(tpl).Error; err != nil {
+		return nil, fmt.Errorf("failed to create template: %w", err)
+	}
+	return tpl, nil
+}
+
+func (s *TemplateService) Update(id uint, spec *TemplateSpec) (*models.ScriptTemplate, error) {
+	var tpl models.ScriptTemplate
+	if err := s.db.First(&tpl, id).Error; err != nil {
+		return nil, err
+	}
+	if err := applyTemplateSpec(&tpl, spec); err != nil {
+		return nil, err
+	}
+	if err := s.db.Save(&tpl).Error; err != nil {
+		return nil, fmt.Errorf("failed to update template: %w", err)
+	}
+	return &tpl, nil
+}
+
+func (s *TemplateService) Delete(id uint) error {
+	result := s.db.Delete(&models.ScriptTemplate{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (s *TemplateService) Get(id uint) (*models.ScriptTemplate, error) {
+	var tpl models.ScriptTemplate
+	if err := s.db.First(&tpl, id).Error; err != nil {
+		return nil, err
+	}
+	return &tpl, nil
+}
+
+func (s *TemplateService) List() ([]models.ScriptTemplate, error) {
+	var templates []models.ScriptTemplate
+	err := s.db.Order("name ASC").Find(&templates).Error
+	return templates, err
+}
+
+// Render 校验参数并渲染模板。未提供的参数使用默认值，缺少必填参数、类型不符或提供未定义的参数时返回 ErrInvalidParams
+func (s *TemplateService) Render(id uint, values map[string]interface{}) (*RenderedTemplate, error) {
+	tpl, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var params []models.TemplateParam
+	if tpl.Params != "" {
+		if err := json.Unmarshal([]byte(tpl.Params), &params); err != nil {
+			return nil, fmt.Errorf("invalid params of template %s: %w", tpl.Name, err)
+		}
+	}
+
+	data, err := validateParams(params, values)
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := template.New(tpl.Name).Funcs(templateFuncs).Option("missingkey=error").Parse(tpl.Script)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template %s: %w", tpl.Name, err)
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("failed to render template %s: %w", tpl.Name, err)
+	}
+
+	rendered := &RenderedTemplate{
+		Type:   tpl.Type,
+		Script: buf.String(),
+		Params: make(map[string]interface{}, len(data)),
+	}
+	for _, p := range params {
+		value, ok := data[p.Name]
+		if !ok {
+			continue
+		}
+		if p.Type == models.ParamTypeSecret {
+			if secret := value.(string); secret != "" {
+				rendered.Secrets = append(rendered.Secrets, secret)
+			}
+			value = SecretMask
+		}
+		rendered.Params[p.Name] = value
+	}
+	return rendered, nil
+}
+
+// validateParams 校验参数值并转换为对应类型
+func validateParams(params []models.TemplateParam, values map[string]interface{}) (map[string]interface{}, error) {
+	defined := make(map[string]bool, len(params))
+	data := make(map[string]interface{}, len(params))
+
+	for _, p := range params {
+		defined[p.Name] = true
+
+		raw, ok := values[p.Name]
+		if !ok || raw == nil {
+			if p.Required && p.Default == "" {
+				return nil, fmt.Errorf("%w: %s is required", ErrInvalidParams, p.Name)
+			}
+			raw = p.Default
+		}
+
+		var str string
+		switch v := raw.(type) {
+		case string:
+			str = v
+		case float64:
+			str = strconv.FormatFloat(v, 'f', -1, 64)
+		case bool:
+			str = strconv.FormatBool(v)
+		default:
+			return nil, fmt.Errorf("%w: %s must be a scalar value", ErrInvalidParams, p.Name)
+		}
+
+		switch p.Type {
+		case models.ParamTypeInt:
+			n, err := strconv.ParseInt(str, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s must be an integer", ErrInvalidParams, p.Name)
+			}
+			data[p.Name] = n
+		case models.ParamTypeEnum:
+			if !containsString(p.Options, str) {
+				return nil, fmt.Errorf("%w: %s must be one of %s", ErrInvalidParams, p.Name, strings.Join(p.Options, ", "))
+			}
+			data[p.Name] = str
+		default:
+			data[p.Name] = str
+		}
+	}
+
+	for name := range values {
+		if !defined[name] {
+			return nil, fmt.Errorf("%w: unknown parameter %s", ErrInvalidParams, name)
+		}
+	}
+	return data, nil
+}
+
+func applyTemplateSpec(tpl *models.ScriptTemplate, spec *TemplateSpec) error {
+	if spec.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if _, err := ParseScriptType(spec.Type); err != nil {
+		return err
+	}
+	if _, err := template.New(spec.Name).Funcs(templateFuncs).Parse(spec.Script); err != nil {
+		return fmt.Errorf("invalid template: %w", err)
+	}
+
+	seen := make(map[string]bool, len(spec.Params))
+	for _, p := range spec.Params {
+		if !paramNamePattern.MatchString(p.Name) {
+			return fmt.Errorf("invalid parameter name: %q", p.Name)
+		}
+		if seen[p.Name] {
+			return fmt.Errorf("duplicate parameter: %s", p.Name)
+		}
+		seen[p.Name] = true
+
+		switch p.Type {
+		case models.ParamTypeString, models.ParamTypeSecret:
+		case models.ParamTypeInt:
+			if _, err := strconv.ParseInt(p.Default, 10, 64); p.Default != "" && err != nil {
+				return fmt.Errorf("default of %s must be an integer", p.Name)
+			}
+		case models.ParamTypeEnum:
+			if len(p.Options) == 0 {
+				return fmt.Errorf("enum parameter %s requires options", p.Name)
+			}
+			if p.Default != "" && !containsString(p.Options, p.Default) {
+				return fmt.Errorf("default of %s must be one of its options", p.Name)
+			}
+		default:
+			return fmt.Errorf("unsupported parameter type for %s: %s", p.Name, p.Type)
+		}
+	}
+
+	params, _ := json.Marshal(spec.Params)
+	tpl.Name = spec.Name
+	tpl.Description = spec.Description
+	tpl.Type = spec.Type
+	tpl.Script = spec.Script
+	tpl.Params = string(params)
+	return nil
+}
+
+// MaskSecrets 将 text 中出现的 secret 值替换为掩码，较长的值优先替换
+func MaskSecrets(text string, secrets []string) string {
+	if len(secrets) == 0 || text == "" {
+		return text
+	}
+	sorted := append([]string(nil), secrets...)
+	sort.Slice(sorted, func(i, j int) bool { return len(sorted[i]) > len(sorted[j]) })
+	for _, secret := range sorted {
+		if secret != "" {
+			text = strings.ReplaceAll(text, secret, SecretMask)
+		}
+	}
+	return text
+}
+
+func shellQuote(v interface{}) string {
+	return "'" + strings.ReplaceAll(fmt.Sprint(v), "'", `'\''`) + "'"
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/agent-platform/platform/internal/models"
+)
+
+func newTestTemplateService(t *testing.T) *TemplateService {
+	db := setupTestDB()
+	assert.NoError(t, db.AutoMigrate(&models.ScriptTemplate{}))
+	return NewTemplateService(db)
+}
+
+func TestTemplateService_Render(t *testing.T) {
+	s := newTestTemplateService(t)
+
+	tpl, err := s.Create(&TemplateSpec{
+		Name:   "deploy",
+		Type:   "shell",
+		Script: "deploy.sh --env {{.env}} --replicas {{.replicas}} --token {{quote .token}} --msg {{quote .msg}}",
+		Params: []models.TemplateParam{
+			{Name: "env", Type: models.ParamTypeEnum, Options: []string{"staging", "prod"}, Required: true},
+			{Name: "replicas", Type: models.ParamTypeInt, Default: "2"},
+			{Name: "token", Type: models.ParamTypeSecret, Required: true},
+			{Name: "msg", Type: models.ParamTypeString},
+		},
+	})
+	assert.NoError(t, err)
+
+	rendered, err := s.Render(tpl.ID, map[string]interface{}{
+		"env":   "prod",
+		"token": "t0ken",
+		"msg":   "it's done",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "shell", rendered.Type)
+	assert.Equal(t, `deploy.sh --env prod --replicas 2 --token 't0ken' --msg 'it'\''s done'`, rendered.Script)
+	assert.Equal(t, []string{"t0ken"}, rendered.Secrets)
+	assert.Equal(t, SecretMask, rendered.Params["token"])
+	assert.Equal(t, int64(2), rendered.Params["replicas"])
+
+	// JSON 数字会解码为 float64
+	rendered, err = s.Render(tpl.ID, map[string]interface{}{"env": "staging", "token": "x", "replicas": float64(5)})
+	assert.NoError(t, err)
+	assert.Contains(t, rendered.Script, "--replicas 5")
+}
+
+func TestTemplateService_InvalidParams(t *testing.T) {
+	s := newTestTemplateService(t)
+
+	tpl, err := s.Create(&TemplateSpec{
+		Name:   "restart",
+		Type:   "shell",
+		Script: "systemctl restart {{.service}} && sleep {{.delay}}",
+		Params: []models.TemplateParam{
+			{Name: "service", Type: models.ParamTypeEnum, Options: []string{"nginx", "redis"}, Required: true},
+			{Name: "delay", Type: models.ParamTypeInt, Default: "0"},
+		},
+	})
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		values map[string]interface{}
+	}{
+		{"missing required", map[string]interface{}{}},
+		{"not an option", map[string]interface{}{"service": "mysql"}},
+		{"not an integer", map[string]interface{}{"service": "nginx", "delay": "soon"}},
+		{"unknown parameter", map[string]interface{}{"service": "nginx", "extra": "x"}},
+		{"not a scalar", map[string]interface{}{"service": []interface{}{"nginx"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.Render(tpl.ID, tt.values)
+			assert.ErrorIs(t, err, ErrInvalidParams)
+		})
+	}
+}
+
+func TestTemplateService_CreateValidation(t *testing.T) {
+	s := newTestTemplateService(t)
+
+	tests := []struct {
+		name string
+		spec TemplateSpec
+	}{
+		{"unsupported type", TemplateSpec{Name: "a", Type: "ruby", Script: "puts 1"}},
+		{"invalid template", TemplateSpec{Name: "a", Type: "shell", Script: "echo {{.x"}},
+		{"invalid param name", TemplateSpec{Name: "a", Type: "shell", Script: "echo", Params: []models.TemplateParam{{Name: "a-b", Type: models.ParamTypeString}}}},
+		{"enum without options", TemplateSpec{Name: "a", Type: "shell", Script: "echo", Params: []models.TemplateParam{{Name: "e", Type: models.ParamTypeEnum}}}},
+		{"invalid int default", TemplateSpec{Name: "a", Type: "shell", Script: "echo", Params: []models.TemplateParam{{Name: "n", Type: models.ParamTypeInt, Default: "x"}}}},
+		{"unsupported param type", TemplateSpec{Name: "a", Type: "shell", Script: "echo", Params: []models.TemplateParam{{Name: "b", Type: "bool"}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.Create(&tt.spec)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestMaskSecrets(t *testing.T) {
+	assert.Equal(t, "user="+SecretMask+" pass="+SecretMask, MaskSecrets("user=admin pass=admin123", []string{"admin", "admin123"}))
+	assert.Equal(t, "unchanged", MaskSecrets("unchanged", nil))
+}
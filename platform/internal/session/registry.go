@@ -0,0 +1,103 @@
+package session
+
+import (
+	"fmt"
+	"sync"
+
+	pb "github.com/yourusername/agent-platform/proto"
+)
+
+// Registry 按 agent_id 维护所有在线 Agent 的会话
+type Registry struct {
+	mu       sync.RWMutex
+	sessions map[string]*Session
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		sessions: make(map[string]*Session),
+	}
+}
+
+// Add 将会话绑定到 agentID，同一 Agent 的旧会话会被关闭
+func (r *Registry) Add(agentID string, s *Session) {
+	s.mu.Lock()
+	s.agentID = agentID
+	s.mu.Unlock()
+
+	r.mu.Lock()
+	old, exists := r.sessions[agentID]
+	r.sessions[agentID] = s
+	r.mu.Unlock()
+
+	if exists && old != s {
+		old.Close()
+	}
+}
+
+// Remove 移除并关闭会话，仅当该会话仍是 Agent 当前会话时才会从注册表中删除
+func (r *Registry) Remove(s *Session) {
+	agentID := s.AgentID()
+
+	r.mu.Lock()
+	if current, exists := r.sessions[agentID]; exists && current == s {
+		delete(r.sessions, agentID)
+	}
+	r.mu.Unlock()
+
+	s.Close()
+}
+
+func (r *Registry) Get(agentID string) (*Session, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	s, exists := r.sessions[agentID]
+	return s, exists
+}
+
+func (r *Registry) IsConnected(agentID string) bool {
+	_, exists := r.Get(agentID)
+	return exists
+}
+
+// Send 向指定 Agent 发送消息
+func (r *Registry) Send(agentID string, msg *pb.ServerMessage) error {
+	s, exists := r.Get(agentID)
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrAgentNotConnected, agentID)
+	}
+
+	if err := s.Send(msg); err != nil {
+		return fmt.Errorf("failed to send to agent %s: %w", agentID, err)
+	}
+	return nil
+}
+
+// Disconnect 主动断开指定 Agent 的连接
+func (r *Registry) Disconnect(agentID string) bool {
+	s, exists := r.Get(agentID)
+	if !exists {
+		return false
+	}
+
+	r.Remove(s)
+	return true
+}
+
+func (r *Registry) AgentIDs() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	ids := make([]string, 0, len(r.sessions))
+	for id := range r.sessions {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func (r *Registry) Count() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.sessions)
+}
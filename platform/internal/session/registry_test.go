@@ -0,0 +1,93 @@
+package session
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	pb "github.com/yourusername/agent-platform/proto"
+)
+
+type mockStream struct {
+	pb.AgentService_ConnectServer
+	mu   sync.Mutex
+	sent []*pb.ServerMessage
+}
+
+func (m *mockStream) Send(msg *pb.ServerMessage) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+func (m *mockStream) count() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.sent)
+}
+
+func TestRegistry_Send(t *testing.T) {
+	registry := NewRegistry()
+	stream := &mockStream{}
+	registry.Add("agent-1", NewSession(stream))
+
+	err := registry.Send("agent-1", &pb.ServerMessage{})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, stream.count())
+
+	err = registry.Send("agent-2", &pb.ServerMessage{})
+	assert.True(t, errors.Is(err, ErrAgentNotConnected))
+}
+
+func TestRegistry_ReplaceSession(t *testing.T) {
+	registry := NewRegistry()
+	oldSession := NewSession(&mockStream{})
+	newSession := NewSession(&mockStream{})
+
+	registry.Add("agent-1", oldSession)
+	registry.Add("agent-1", newSession)
+
+	select {
+	case <-oldSession.Done():
+	default:
+		t.Fatal("expected old session to be closed")
+	}
+
+	// 旧会话断开时不能把新会话移除
+	registry.Remove(oldSession)
+	s, exists := registry.Get("agent-1")
+	assert.True(t, exists)
+	assert.Equal(t, newSession, s)
+	assert.Equal(t, 1, registry.Count())
+}
+
+func TestRegistry_Disconnect(t *testing.T) {
+	registry := NewRegistry()
+	s := NewSession(&mockStream{})
+	registry.Add("agent-1", s)
+
+	assert.True(t, registry.Disconnect("agent-1"))
+	assert.False(t, registry.IsConnected("agent-1"))
+	assert.ErrorIs(t, s.Send(&pb.ServerMessage{}), ErrSessionClosed)
+	assert.False(t, registry.Disconnect("agent-1"))
+}
+
+func TestRegistry_ConcurrentSend(t *testing.T) {
+	registry := NewRegistry()
+	stream := &mockStream{}
+	registry.Add("agent-1", NewSession(stream))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			registry.Send("agent-1", &pb.ServerMessage{})
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 50, stream.count())
+}
@@ -0,0 +1,76 @@
+package session
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	pb "github.com/yourusername/agent-platform/proto"
+)
+
+var (
+	ErrAgentNotConnected = errors.New("agent not connected")
+	ErrSessionClosed     = errors.New("session closed")
+)
+
+// Session 表示一个 Agent 的双向流连接
+type Session struct {
+	mu          sync.Mutex
+	sendMu      sync.Mutex
+	agentID     string
+	stream      pb.AgentService_ConnectServer
+	connectedAt time.Time
+	closed      bool
+	done        chan struct{}
+}
+
+func NewSession(stream pb.AgentService_ConnectServer) *Session {
+	return &Session{
+		stream:      stream,
+		connectedAt: time.Now(),
+		done:        make(chan struct{}),
+	}
+}
+
+func (s *Session) AgentID() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.agentID
+}
+
+func (s *Session) ConnectedAt() time.Time {
+	return s.connectedAt
+}
+
+// Send 向 Agent 发送消息，同一个流上的发送是串行的
+func (s *Session) Send(msg *pb.ServerMessage) error {
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+
+	if s.isClosed() {
+		return ErrSessionClosed
+	}
+	return s.stream.Send(msg)
+}
+
+// Close 关闭会话，Connect 处理函数会在 Done 后返回并断开流
+func (s *Session) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return
+	}
+	s.closed = true
+	close(s.done)
+}
+
+func (s *Session) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+func (s *Session) Done() <-chan struct{} {
+	return s.done
+}
@@ -2,12 +2,13 @@ package main
 
 import (
 	"bufio"
-	"encoding/json"
 	"fmt"
 	"os"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/yourusername/agent-platform/pkg/pluginproto"
 )
 
 type MemoryStats struct {
@@ -53,31 +54,66 @@ func readMemoryStats() (*MemoryStats, error) {
 	return stats, nil
 }
 
+// config 插件配置，由 Agent 按 plugin.yaml 中的 config_schema 校验后发送
+type config struct {
+	Interval int `json:"interval"`
+}
+
 func main() {
-	interval := 60
+	conn := pluginproto.NewConn(os.Stdin, os.Stdout)
+	if _, err := conn.Handshake(pluginproto.Handshake{
+		Name:         "memory",
+		Version:      "1.1.0",
+		Capabilities: []string{"config", "metrics", "health"},
+	}); err != nil {
+		fmt.Fprintf(os.Stderr, "handshake failed: %v\n", err)
+		os.Exit(1)
+	}
 
-	ticker := time.NewTicker(time.Duration(interval) * time.Second)
+	ticker := time.NewTicker(60 * time.Second)
 	defer ticker.Stop()
+	messages := conn.Receive()
+	var lastErr error
 
-	for range ticker.C {
-		stats, err := readMemoryStats()
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "failed to read memory stats: %v\n", err)
-			continue
-		}
+	for {
+		select {
+		case <-ticker.C:
+			stats, err := readMemoryStats()
+			lastErr = err
+			if err != nil {
+				conn.Send(pluginproto.TypeError, pluginproto.Error{Code: "read_failed", Message: err.Error()})
+				continue
+			}
 
-		msg := map[string]interface{}{
-			"type": "metric",
-			"data": map[string]interface{}{
-				"name":       "memory_usage",
-				"value":      stats.UsageRate,
-				"used_kb":    stats.Used,
-				"available_kb": stats.Available,
-				"timestamp":  time.Now().Unix(),
-			},
+			conn.Send(pluginproto.TypeMetric, pluginproto.Metric{
+				Name:      "memory_usage",
+				Value:     &stats.UsageRate,
+				Timestamp: float64(time.Now().Unix()),
+			})
+		case msg, ok := <-messages:
+			if !ok {
+				// stdin 关闭，Agent 已退出
+				return
+			}
+			switch msg.Type {
+			case pluginproto.TypeConfig:
+				var cfg config
+				if err := msg.Decode(&cfg); err != nil || cfg.Interval < 1 {
+					conn.Reply(msg, pluginproto.TypeConfigAck, pluginproto.Ack{Error: "interval must be a positive integer"})
+					continue
+				}
+				ticker.Reset(time.Duration(cfg.Interval) * time.Second)
+				conn.Reply(msg, pluginproto.TypeConfigAck, pluginproto.Ack{})
+			case pluginproto.TypeHealth:
+				health := pluginproto.Health{Status: pluginproto.HealthHealthy}
+				if lastErr != nil {
+					health = pluginproto.Health{Status: pluginproto.HealthUnhealthy, Message: lastErr.Error()}
+				}
+				conn.Reply(msg, pluginproto.TypeHealth, health)
+			case pluginproto.TypeShutdown:
+				conn.Send(pluginproto.TypeShutdownAck, nil)
+				return
+			}
 		}
-
-		data, _ := json.Marshal(msg)
-		fmt.Println(string(data))
 	}
 }
@@ -30,6 +30,12 @@ type AgentRegister struct {
 	Os            string                 `protobuf:"bytes,4,opt,name=os,proto3" json:"os,omitempty"`
 	Arch          string                 `protobuf:"bytes,5,opt,name=arch,proto3" json:"arch,omitempty"`
 	Version       string                 `protobuf:"bytes,6,opt,name=version,proto3" json:"version,omitempty"`
+	KernelVersion string                 `protobuf:"bytes,7,opt,name=kernel_version,json=kernelVersion,proto3" json:"kernel_version,omitempty"`
+	CpuCount      int32                  `protobuf:"varint,8,opt,name=cpu_count,json=cpuCount,proto3" json:"cpu_count,omitempty"`
+	MemoryTotal   uint64                 `protobuf:"varint,9,opt,name=memory_total,json=memoryTotal,proto3" json:"memory_total,omitempty"` // 内存总量（字节）
+	BootTime      *Timestamp             `protobuf:"bytes,10,opt,name=boot_time,json=bootTime,proto3" json:"boot_time,omitempty"`
+	OsVersion     string                 `protobuf:"bytes,11,opt,name=os_version,json=osVersion,proto3" json:"os_version,omitempty"` // 发行版名称，如 Ubuntu 22.04
+	Credential    string                 `protobuf:"bytes,12,opt,name=credential,proto3" json:"credential,omitempty"`                // 登记时平台签发的 Agent 凭据
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -106,6 +112,146 @@ func (x *AgentRegister) GetVersion() string {
 	return ""
 }
 
+func (x *AgentRegister) GetKernelVersion() string {
+	if x != nil {
+		return x.KernelVersion
+	}
+	return ""
+}
+
+func (x *AgentRegister) GetCpuCount() int32 {
+	if x != nil {
+		return x.CpuCount
+	}
+	return 0
+}
+
+func (x *AgentRegister) GetMemoryTotal() uint64 {
+	if x != nil {
+		return x.MemoryTotal
+	}
+	return 0
+}
+
+func (x *AgentRegister) GetBootTime() *Timestamp {
+	if x != nil {
+		return x.BootTime
+	}
+	return nil
+}
+
+func (x *AgentRegister) GetOsVersion() string {
+	if x != nil {
+		return x.OsVersion
+	}
+	return ""
+}
+
+func (x *AgentRegister) GetCredential() string {
+	if x != nil {
+		return x.Credential
+	}
+	return ""
+}
+
+// Agent 登记请求：首次启动时使用一次性引导令牌换取 Agent 凭据
+type EnrollRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	AgentId        string                 `protobuf:"bytes,1,opt,name=agent_id,json=agentId,proto3" json:"agent_id,omitempty"`
+	BootstrapToken string                 `protobuf:"bytes,2,opt,name=bootstrap_token,json=bootstrapToken,proto3" json:"bootstrap_token,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *EnrollRequest) Reset() {
+	*x = EnrollRequest{}
+	mi := &file_proto_agent_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EnrollRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EnrollRequest) ProtoMessage() {}
+
+func (x *EnrollRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EnrollRequest.ProtoReflect.Descriptor instead.
+func (*EnrollRequest) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *EnrollRequest) GetAgentId() string {
+	if x != nil {
+		return x.AgentId
+	}
+	return ""
+}
+
+func (x *EnrollRequest) GetBootstrapToken() string {
+	if x != nil {
+		return x.BootstrapToken
+	}
+	return ""
+}
+
+// Agent 登记响应
+type EnrollResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Credential    string                 `protobuf:"bytes,1,opt,name=credential,proto3" json:"credential,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EnrollResponse) Reset() {
+	*x = EnrollResponse{}
+	mi := &file_proto_agent_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EnrollResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EnrollResponse) ProtoMessage() {}
+
+func (x *EnrollResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EnrollResponse.ProtoReflect.Descriptor instead.
+func (*EnrollResponse) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *EnrollResponse) GetCredential() string {
+	if x != nil {
+		return x.Credential
+	}
+	return ""
+}
+
 // 心跳消息
 type Heartbeat struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -117,7 +263,7 @@ type Heartbeat struct {
 
 func (x *Heartbeat) Reset() {
 	*x = Heartbeat{}
-	mi := &file_proto_agent_proto_msgTypes[1]
+	mi := &file_proto_agent_proto_msgTypes[3]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -129,7 +275,7 @@ func (x *Heartbeat) String() string {
 func (*Heartbeat) ProtoMessage() {}
 
 func (x *Heartbeat) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[1]
+	mi := &file_proto_agent_proto_msgTypes[3]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -142,7 +288,7 @@ func (x *Heartbeat) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Heartbeat.ProtoReflect.Descriptor instead.
 func (*Heartbeat) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{1}
+	return file_proto_agent_proto_rawDescGZIP(), []int{3}
 }
 
 func (x *Heartbeat) GetAgentId() string {
@@ -170,6 +316,8 @@ type ServerMessage struct {
 	//	*ServerMessage_InstallPlugin
 	//	*ServerMessage_UninstallPlugin
 	//	*ServerMessage_ListPlugins
+	//	*ServerMessage_CancelTask
+	//	*ServerMessage_PluginChunk
 	Message       isServerMessage_Message `protobuf_oneof:"message"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
@@ -177,7 +325,7 @@ type ServerMessage struct {
 
 func (x *ServerMessage) Reset() {
 	*x = ServerMessage{}
-	mi := &file_proto_agent_proto_msgTypes[2]
+	mi := &file_proto_agent_proto_msgTypes[4]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -189,7 +337,7 @@ func (x *ServerMessage) String() string {
 func (*ServerMessage) ProtoMessage() {}
 
 func (x *ServerMessage) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[2]
+	mi := &file_proto_agent_proto_msgTypes[4]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -202,7 +350,7 @@ func (x *ServerMessage) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ServerMessage.ProtoReflect.Descriptor instead.
 func (*ServerMessage) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{2}
+	return file_proto_agent_proto_rawDescGZIP(), []int{4}
 }
 
 func (x *ServerMessage) GetMessage() isServerMessage_Message {
@@ -266,6 +414,24 @@ func (x *ServerMessage) GetListPlugins() *ListPluginsRequest {
 	return nil
 }
 
+func (x *ServerMessage) GetCancelTask() *CancelTaskRequest {
+	if x != nil {
+		if x, ok := x.Message.(*ServerMessage_CancelTask); ok {
+			return x.CancelTask
+		}
+	}
+	return nil
+}
+
+func (x *ServerMessage) GetPluginChunk() *PluginPackageChunk {
+	if x != nil {
+		if x, ok := x.Message.(*ServerMessage_PluginChunk); ok {
+			return x.PluginChunk
+		}
+	}
+	return nil
+}
+
 type isServerMessage_Message interface {
 	isServerMessage_Message()
 }
@@ -294,6 +460,14 @@ type ServerMessage_ListPlugins struct {
 	ListPlugins *ListPluginsRequest `protobuf:"bytes,6,opt,name=list_plugins,json=listPlugins,proto3,oneof"` // 列出插件
 }
 
+type ServerMessage_CancelTask struct {
+	CancelTask *CancelTaskRequest `protobuf:"bytes,7,opt,name=cancel_task,json=cancelTask,proto3,oneof"` // 取消任务
+}
+
+type ServerMessage_PluginChunk struct {
+	PluginChunk *PluginPackageChunk `protobuf:"bytes,8,opt,name=plugin_chunk,json=pluginChunk,proto3,oneof"` // 插件安装包分块
+}
+
 func (*ServerMessage_RegisterResponse) isServerMessage_Message() {}
 
 func (*ServerMessage_HeartbeatAck) isServerMessage_Message() {}
@@ -306,6 +480,10 @@ func (*ServerMessage_UninstallPlugin) isServerMessage_Message() {}
 
 func (*ServerMessage_ListPlugins) isServerMessage_Message() {}
 
+func (*ServerMessage_CancelTask) isServerMessage_Message() {}
+
+func (*ServerMessage_PluginChunk) isServerMessage_Message() {}
+
 // 从 Agent 到管理平台的消息
 type AgentMessage struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
@@ -318,6 +496,9 @@ type AgentMessage struct {
 	//	*AgentMessage_InstallPluginResponse
 	//	*AgentMessage_UninstallPluginResponse
 	//	*AgentMessage_ListPluginsResponse
+	//	*AgentMessage_TaskAck
+	//	*AgentMessage_MetricBatch
+	//	*AgentMessage_PluginStatus
 	Message       isAgentMessage_Message `protobuf_oneof:"message"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
@@ -325,7 +506,7 @@ type AgentMessage struct {
 
 func (x *AgentMessage) Reset() {
 	*x = AgentMessage{}
-	mi := &file_proto_agent_proto_msgTypes[3]
+	mi := &file_proto_agent_proto_msgTypes[5]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -337,7 +518,7 @@ func (x *AgentMessage) String() string {
 func (*AgentMessage) ProtoMessage() {}
 
 func (x *AgentMessage) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[3]
+	mi := &file_proto_agent_proto_msgTypes[5]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -350,7 +531,7 @@ func (x *AgentMessage) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use AgentMessage.ProtoReflect.Descriptor instead.
 func (*AgentMessage) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{3}
+	return file_proto_agent_proto_rawDescGZIP(), []int{5}
 }
 
 func (x *AgentMessage) GetMessage() isAgentMessage_Message {
@@ -423,6 +604,33 @@ func (x *AgentMessage) GetListPluginsResponse() *ListPluginsResponse {
 	return nil
 }
 
+func (x *AgentMessage) GetTaskAck() *TaskAck {
+	if x != nil {
+		if x, ok := x.Message.(*AgentMessage_TaskAck); ok {
+			return x.TaskAck
+		}
+	}
+	return nil
+}
+
+func (x *AgentMessage) GetMetricBatch() *MetricBatch {
+	if x != nil {
+		if x, ok := x.Message.(*AgentMessage_MetricBatch); ok {
+			return x.MetricBatch
+		}
+	}
+	return nil
+}
+
+func (x *AgentMessage) GetPluginStatus() *PluginStatus {
+	if x != nil {
+		if x, ok := x.Message.(*AgentMessage_PluginStatus); ok {
+			return x.PluginStatus
+		}
+	}
+	return nil
+}
+
 type isAgentMessage_Message interface {
 	isAgentMessage_Message()
 }
@@ -455,6 +663,18 @@ type AgentMessage_ListPluginsResponse struct {
 	ListPluginsResponse *ListPluginsResponse `protobuf:"bytes,7,opt,name=list_plugins_response,json=listPluginsResponse,proto3,oneof"` // 列出插件响应
 }
 
+type AgentMessage_TaskAck struct {
+	TaskAck *TaskAck `protobuf:"bytes,8,opt,name=task_ack,json=taskAck,proto3,oneof"` // 任务开始执行确认
+}
+
+type AgentMessage_MetricBatch struct {
+	MetricBatch *MetricBatch `protobuf:"bytes,9,opt,name=metric_batch,json=metricBatch,proto3,oneof"` // 插件指标批量上报
+}
+
+type AgentMessage_PluginStatus struct {
+	PluginStatus *PluginStatus `protobuf:"bytes,10,opt,name=plugin_status,json=pluginStatus,proto3,oneof"` // 插件状态变化
+}
+
 func (*AgentMessage_Register) isAgentMessage_Message() {}
 
 func (*AgentMessage_Heartbeat) isAgentMessage_Message() {}
@@ -469,29 +689,55 @@ func (*AgentMessage_UninstallPluginResponse) isAgentMessage_Message() {}
 
 func (*AgentMessage_ListPluginsResponse) isAgentMessage_Message() {}
 
+func (*AgentMessage_TaskAck) isAgentMessage_Message() {}
+
+func (*AgentMessage_MetricBatch) isAgentMessage_Message() {}
+
+func (*AgentMessage_PluginStatus) isAgentMessage_Message() {}
+
 var File_proto_agent_proto protoreflect.FileDescriptor
 
 const file_proto_agent_proto_rawDesc = "" +
 	"\n" +
-	"\x11proto/agent.proto\x12\x05proto\x1a\x12proto/common.proto\x1a\x10proto/task.proto\x1a\x12proto/plugin.proto\"\x94\x01\n" +
+	"\x11proto/agent.proto\x12\x05proto\x1a\x12proto/common.proto\x1a\x10proto/task.proto\x1a\x12proto/plugin.proto\x1a\x12proto/metric.proto\"\xe9\x02\n" +
 	"\rAgentRegister\x12\x19\n" +
 	"\bagent_id\x18\x01 \x01(\tR\aagentId\x12\x1a\n" +
 	"\bhostname\x18\x02 \x01(\tR\bhostname\x12\x0e\n" +
 	"\x02ip\x18\x03 \x01(\tR\x02ip\x12\x0e\n" +
 	"\x02os\x18\x04 \x01(\tR\x02os\x12\x12\n" +
 	"\x04arch\x18\x05 \x01(\tR\x04arch\x12\x18\n" +
-	"\aversion\x18\x06 \x01(\tR\aversion\"V\n" +
+	"\aversion\x18\x06 \x01(\tR\aversion\x12%\n" +
+	"\x0ekernel_version\x18\a \x01(\tR\rkernelVersion\x12\x1b\n" +
+	"\tcpu_count\x18\b \x01(\x05R\bcpuCount\x12!\n" +
+	"\fmemory_total\x18\t \x01(\x04R\vmemoryTotal\x12-\n" +
+	"\tboot_time\x18\n" +
+	" \x01(\v2\x10.proto.TimestampR\bbootTime\x12\x1d\n" +
+	"\n" +
+	"os_version\x18\v \x01(\tR\tosVersion\x12\x1e\n" +
+	"\n" +
+	"credential\x18\f \x01(\tR\n" +
+	"credential\"S\n" +
+	"\rEnrollRequest\x12\x19\n" +
+	"\bagent_id\x18\x01 \x01(\tR\aagentId\x12'\n" +
+	"\x0fbootstrap_token\x18\x02 \x01(\tR\x0ebootstrapToken\"0\n" +
+	"\x0eEnrollResponse\x12\x1e\n" +
+	"\n" +
+	"credential\x18\x01 \x01(\tR\n" +
+	"credential\"V\n" +
 	"\tHeartbeat\x12\x19\n" +
 	"\bagent_id\x18\x01 \x01(\tR\aagentId\x12.\n" +
-	"\ttimestamp\x18\x02 \x01(\v2\x10.proto.TimestampR\ttimestamp\"\x9d\x03\n" +
+	"\ttimestamp\x18\x02 \x01(\v2\x10.proto.TimestampR\ttimestamp\"\x9a\x04\n" +
 	"\rServerMessage\x12>\n" +
 	"\x11register_response\x18\x01 \x01(\v2\x0f.proto.ResponseH\x00R\x10registerResponse\x126\n" +
 	"\rheartbeat_ack\x18\x02 \x01(\v2\x0f.proto.ResponseH\x00R\fheartbeatAck\x127\n" +
 	"\ftask_request\x18\x03 \x01(\v2\x12.proto.TaskRequestH\x00R\vtaskRequest\x12D\n" +
 	"\x0einstall_plugin\x18\x04 \x01(\v2\x1b.proto.InstallPluginRequestH\x00R\rinstallPlugin\x12J\n" +
 	"\x10uninstall_plugin\x18\x05 \x01(\v2\x1d.proto.UninstallPluginRequestH\x00R\x0funinstallPlugin\x12>\n" +
-	"\flist_plugins\x18\x06 \x01(\v2\x19.proto.ListPluginsRequestH\x00R\vlistPluginsB\t\n" +
-	"\amessage\"\xea\x03\n" +
+	"\flist_plugins\x18\x06 \x01(\v2\x19.proto.ListPluginsRequestH\x00R\vlistPlugins\x12;\n" +
+	"\vcancel_task\x18\a \x01(\v2\x18.proto.CancelTaskRequestH\x00R\n" +
+	"cancelTask\x12>\n" +
+	"\fplugin_chunk\x18\b \x01(\v2\x19.proto.PluginPackageChunkH\x00R\vpluginChunkB\t\n" +
+	"\amessage\"\x8c\x05\n" +
 	"\fAgentMessage\x122\n" +
 	"\bregister\x18\x01 \x01(\v2\x14.proto.AgentRegisterH\x00R\bregister\x120\n" +
 	"\theartbeat\x18\x02 \x01(\v2\x10.proto.HeartbeatH\x00R\theartbeat\x124\n" +
@@ -500,10 +746,15 @@ const file_proto_agent_proto_rawDesc = "" +
 	"\btask_log\x18\x04 \x01(\v2\x0e.proto.TaskLogH\x00R\ataskLog\x12V\n" +
 	"\x17install_plugin_response\x18\x05 \x01(\v2\x1c.proto.InstallPluginResponseH\x00R\x15installPluginResponse\x12\\\n" +
 	"\x19uninstall_plugin_response\x18\x06 \x01(\v2\x1e.proto.UninstallPluginResponseH\x00R\x17uninstallPluginResponse\x12P\n" +
-	"\x15list_plugins_response\x18\a \x01(\v2\x1a.proto.ListPluginsResponseH\x00R\x13listPluginsResponseB\t\n" +
-	"\amessage2H\n" +
+	"\x15list_plugins_response\x18\a \x01(\v2\x1a.proto.ListPluginsResponseH\x00R\x13listPluginsResponse\x12+\n" +
+	"\btask_ack\x18\b \x01(\v2\x0e.proto.TaskAckH\x00R\ataskAck\x127\n" +
+	"\fmetric_batch\x18\t \x01(\v2\x12.proto.MetricBatchH\x00R\vmetricBatch\x12:\n" +
+	"\rplugin_status\x18\n" +
+	" \x01(\v2\x13.proto.PluginStatusH\x00R\fpluginStatusB\t\n" +
+	"\amessage2\x7f\n" +
 	"\fAgentService\x128\n" +
-	"\aConnect\x12\x13.proto.AgentMessage\x1a\x14.proto.ServerMessage(\x010\x01B.Z,github.com/yourusername/agent-platform/protob\x06proto3"
+	"\aConnect\x12\x13.proto.AgentMessage\x1a\x14.proto.ServerMessage(\x010\x01\x125\n" +
+	"\x06Enroll\x12\x14.proto.EnrollRequest\x1a\x15.proto.EnrollResponseB.Z,github.com/yourusername/agent-platform/protob\x06proto3"
 
 var (
 	file_proto_agent_proto_rawDescOnce sync.Once
@@ -517,46 +768,61 @@ func file_proto_agent_proto_rawDescGZIP() []byte {
 	return file_proto_agent_proto_rawDescData
 }
 
-var file_proto_agent_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_proto_agent_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
 var file_proto_agent_proto_goTypes = []any{
 	(*AgentRegister)(nil),           // 0: proto.AgentRegister
-	(*Heartbeat)(nil),               // 1: proto.Heartbeat
-	(*ServerMessage)(nil),           // 2: proto.ServerMessage
-	(*AgentMessage)(nil),            // 3: proto.AgentMessage
-	(*Timestamp)(nil),               // 4: proto.Timestamp
-	(*Response)(nil),                // 5: proto.Response
-	(*TaskRequest)(nil),             // 6: proto.TaskRequest
-	(*InstallPluginRequest)(nil),    // 7: proto.InstallPluginRequest
-	(*UninstallPluginRequest)(nil),  // 8: proto.UninstallPluginRequest
-	(*ListPluginsRequest)(nil),      // 9: proto.ListPluginsRequest
-	(*TaskResult)(nil),              // 10: proto.TaskResult
-	(*TaskLog)(nil),                 // 11: proto.TaskLog
-	(*InstallPluginResponse)(nil),   // 12: proto.InstallPluginResponse
-	(*UninstallPluginResponse)(nil), // 13: proto.UninstallPluginResponse
-	(*ListPluginsResponse)(nil),     // 14: proto.ListPluginsResponse
+	(*EnrollRequest)(nil),           // 1: proto.EnrollRequest
+	(*EnrollResponse)(nil),          // 2: proto.EnrollResponse
+	(*Heartbeat)(nil),               // 3: proto.Heartbeat
+	(*ServerMessage)(nil),           // 4: proto.ServerMessage
+	(*AgentMessage)(nil),            // 5: proto.AgentMessage
+	(*Timestamp)(nil),               // 6: proto.Timestamp
+	(*Response)(nil),                // 7: proto.Response
+	(*TaskRequest)(nil),             // 8: proto.TaskRequest
+	(*InstallPluginRequest)(nil),    // 9: proto.InstallPluginRequest
+	(*UninstallPluginRequest)(nil),  // 10: proto.UninstallPluginRequest
+	(*ListPluginsRequest)(nil),      // 11: proto.ListPluginsRequest
+	(*CancelTaskRequest)(nil),       // 12: proto.CancelTaskRequest
+	(*PluginPackageChunk)(nil),      // 13: proto.PluginPackageChunk
+	(*TaskResult)(nil),              // 14: proto.TaskResult
+	(*TaskLog)(nil),                 // 15: proto.TaskLog
+	(*InstallPluginResponse)(nil),   // 16: proto.InstallPluginResponse
+	(*UninstallPluginResponse)(nil), // 17: proto.UninstallPluginResponse
+	(*ListPluginsResponse)(nil),     // 18: proto.ListPluginsResponse
+	(*TaskAck)(nil),                 // 19: proto.TaskAck
+	(*MetricBatch)(nil),             // 20: proto.MetricBatch
+	(*PluginStatus)(nil),            // 21: proto.PluginStatus
 }
 var file_proto_agent_proto_depIdxs = []int32{
-	4,  // 0: proto.Heartbeat.timestamp:type_name -> proto.Timestamp
-	5,  // 1: proto.ServerMessage.register_response:type_name -> proto.Response
-	5,  // 2: proto.ServerMessage.heartbeat_ack:type_name -> proto.Response
-	6,  // 3: proto.ServerMessage.task_request:type_name -> proto.TaskRequest
-	7,  // 4: proto.ServerMessage.install_plugin:type_name -> proto.InstallPluginRequest
-	8,  // 5: proto.ServerMessage.uninstall_plugin:type_name -> proto.UninstallPluginRequest
-	9,  // 6: proto.ServerMessage.list_plugins:type_name -> proto.ListPluginsRequest
-	0,  // 7: proto.AgentMessage.register:type_name -> proto.AgentRegister
-	1,  // 8: proto.AgentMessage.heartbeat:type_name -> proto.Heartbeat
-	10, // 9: proto.AgentMessage.task_result:type_name -> proto.TaskResult
-	11, // 10: proto.AgentMessage.task_log:type_name -> proto.TaskLog
-	12, // 11: proto.AgentMessage.install_plugin_response:type_name -> proto.InstallPluginResponse
-	13, // 12: proto.AgentMessage.uninstall_plugin_response:type_name -> proto.UninstallPluginResponse
-	14, // 13: proto.AgentMessage.list_plugins_response:type_name -> proto.ListPluginsResponse
-	3,  // 14: proto.AgentService.Connect:input_type -> proto.AgentMessage
-	2,  // 15: proto.AgentService.Connect:output_type -> proto.ServerMessage
-	15, // [15:16] is the sub-list for method output_type
-	14, // [14:15] is the sub-list for method input_type
-	14, // [14:14] is the sub-list for extension type_name
-	14, // [14:14] is the sub-list for extension extendee
-	0,  // [0:14] is the sub-list for field type_name
+	6,  // 0: proto.AgentRegister.boot_time:type_name -> proto.Timestamp
+	6,  // 1: proto.Heartbeat.timestamp:type_name -> proto.Timestamp
+	7,  // 2: proto.ServerMessage.register_response:type_name -> proto.Response
+	7,  // 3: proto.ServerMessage.heartbeat_ack:type_name -> proto.Response
+	8,  // 4: proto.ServerMessage.task_request:type_name -> proto.TaskRequest
+	9,  // 5: proto.ServerMessage.install_plugin:type_name -> proto.InstallPluginRequest
+	10, // 6: proto.ServerMessage.uninstall_plugin:type_name -> proto.UninstallPluginRequest
+	11, // 7: proto.ServerMessage.list_plugins:type_name -> proto.ListPluginsRequest
+	12, // 8: proto.ServerMessage.cancel_task:type_name -> proto.CancelTaskRequest
+	13, // 9: proto.ServerMessage.plugin_chunk:type_name -> proto.PluginPackageChunk
+	0,  // 10: proto.AgentMessage.register:type_name -> proto.AgentRegister
+	3,  // 11: proto.AgentMessage.heartbeat:type_name -> proto.Heartbeat
+	14, // 12: proto.AgentMessage.task_result:type_name -> proto.TaskResult
+	15, // 13: proto.AgentMessage.task_log:type_name -> proto.TaskLog
+	16, // 14: proto.AgentMessage.install_plugin_response:type_name -> proto.InstallPluginResponse
+	17, // 15: proto.AgentMessage.uninstall_plugin_response:type_name -> proto.UninstallPluginResponse
+	18, // 16: proto.AgentMessage.list_plugins_response:type_name -> proto.ListPluginsResponse
+	19, // 17: proto.AgentMessage.task_ack:type_name -> proto.TaskAck
+	20, // 18: proto.AgentMessage.metric_batch:type_name -> proto.MetricBatch
+	21, // 19: proto.AgentMessage.plugin_status:type_name -> proto.PluginStatus
+	5,  // 20: proto.AgentService.Connect:input_type -> proto.AgentMessage
+	1,  // 21: proto.AgentService.Enroll:input_type -> proto.EnrollRequest
+	4,  // 22: proto.AgentService.Connect:output_type -> proto.ServerMessage
+	2,  // 23: proto.AgentService.Enroll:output_type -> proto.EnrollResponse
+	22, // [22:24] is the sub-list for method output_type
+	20, // [20:22] is the sub-list for method input_type
+	20, // [20:20] is the sub-list for extension type_name
+	20, // [20:20] is the sub-list for extension extendee
+	0,  // [0:20] is the sub-list for field type_name
 }
 
 func init() { file_proto_agent_proto_init() }
@@ -567,15 +833,18 @@ func file_proto_agent_proto_init() {
 	file_proto_common_proto_init()
 	file_proto_task_proto_init()
 	file_proto_plugin_proto_init()
-	file_proto_agent_proto_msgTypes[2].OneofWrappers = []any{
+	file_proto_metric_proto_init()
+	file_proto_agent_proto_msgTypes[4].OneofWrappers = []any{
 		(*ServerMessage_RegisterResponse)(nil),
 		(*ServerMessage_HeartbeatAck)(nil),
 		(*ServerMessage_TaskRequest)(nil),
 		(*ServerMessage_InstallPlugin)(nil),
 		(*ServerMessage_UninstallPlugin)(nil),
 		(*ServerMessage_ListPlugins)(nil),
+		(*ServerMessage_CancelTask)(nil),
+		(*ServerMessage_PluginChunk)(nil),
 	}
-	file_proto_agent_proto_msgTypes[3].OneofWrappers = []any{
+	file_proto_agent_proto_msgTypes[5].OneofWrappers = []any{
 		(*AgentMessage_Register)(nil),
 		(*AgentMessage_Heartbeat)(nil),
 		(*AgentMessage_TaskResult)(nil),
@@ -583,6 +852,9 @@ func file_proto_agent_proto_init() {
 		(*AgentMessage_InstallPluginResponse)(nil),
 		(*AgentMessage_UninstallPluginResponse)(nil),
 		(*AgentMessage_ListPluginsResponse)(nil),
+		(*AgentMessage_TaskAck)(nil),
+		(*AgentMessage_MetricBatch)(nil),
+		(*AgentMessage_PluginStatus)(nil),
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
@@ -590,7 +862,7 @@ func file_proto_agent_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_agent_proto_rawDesc), len(file_proto_agent_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   4,
+			NumMessages:   6,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -28,6 +28,10 @@ const (
 	TaskType_TASK_TYPE_UNSPECIFIED TaskType = 0
 	TaskType_TASK_TYPE_SHELL       TaskType = 1
 	TaskType_TASK_TYPE_PYTHON      TaskType = 2
+	TaskType_TASK_TYPE_BASH        TaskType = 3
+	TaskType_TASK_TYPE_POWERSHELL  TaskType = 4 // PowerShell Core（pwsh），Agent 未安装时任务失败
+	TaskType_TASK_TYPE_PERL        TaskType = 5
+	TaskType_TASK_TYPE_FILE        TaskType = 6 // 将 file 写入临时目录后直接执行
 )
 
 // Enum value maps for TaskType.
@@ -36,11 +40,19 @@ var (
 		0: "TASK_TYPE_UNSPECIFIED",
 		1: "TASK_TYPE_SHELL",
 		2: "TASK_TYPE_PYTHON",
+		3: "TASK_TYPE_BASH",
+		4: "TASK_TYPE_POWERSHELL",
+		5: "TASK_TYPE_PERL",
+		6: "TASK_TYPE_FILE",
 	}
 	TaskType_value = map[string]int32{
 		"TASK_TYPE_UNSPECIFIED": 0,
 		"TASK_TYPE_SHELL":       1,
 		"TASK_TYPE_PYTHON":      2,
+		"TASK_TYPE_BASH":        3,
+		"TASK_TYPE_POWERSHELL":  4,
+		"TASK_TYPE_PERL":        5,
+		"TASK_TYPE_FILE":        6,
 	}
 )
 
@@ -71,6 +83,65 @@ func (TaskType) EnumDescriptor() ([]byte, []int) {
 	return file_proto_task_proto_rawDescGZIP(), []int{0}
 }
 
+// 任务结束状态
+type TaskStatus int32
+
+const (
+	TaskStatus_TASK_STATUS_UNSPECIFIED TaskStatus = 0
+	TaskStatus_TASK_STATUS_COMPLETED   TaskStatus = 1 // 脚本执行结束（以 exit_code 区分成功与失败）
+	TaskStatus_TASK_STATUS_FAILED      TaskStatus = 2 // 脚本未能执行
+	TaskStatus_TASK_STATUS_TIMEOUT     TaskStatus = 3 // 执行超时被终止
+	TaskStatus_TASK_STATUS_CANCELLED   TaskStatus = 4 // 被平台取消
+	TaskStatus_TASK_STATUS_REJECTED    TaskStatus = 5 // 被 Agent 拒绝（本地策略或签名校验未通过）
+)
+
+// Enum value maps for TaskStatus.
+var (
+	TaskStatus_name = map[int32]string{
+		0: "TASK_STATUS_UNSPECIFIED",
+		1: "TASK_STATUS_COMPLETED",
+		2: "TASK_STATUS_FAILED",
+		3: "TASK_STATUS_TIMEOUT",
+		4: "TASK_STATUS_CANCELLED",
+		5: "TASK_STATUS_REJECTED",
+	}
+	TaskStatus_value = map[string]int32{
+		"TASK_STATUS_UNSPECIFIED": 0,
+		"TASK_STATUS_COMPLETED":   1,
+		"TASK_STATUS_FAILED":      2,
+		"TASK_STATUS_TIMEOUT":     3,
+		"TASK_STATUS_CANCELLED":   4,
+		"TASK_STATUS_REJECTED":    5,
+	}
+)
+
+func (x TaskStatus) Enum() *TaskStatus {
+	p := new(TaskStatus)
+	*p = x
+	return p
+}
+
+func (x TaskStatus) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (TaskStatus) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_task_proto_enumTypes[1].Descriptor()
+}
+
+func (TaskStatus) Type() protoreflect.EnumType {
+	return &file_proto_task_proto_enumTypes[1]
+}
+
+func (x TaskStatus) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use TaskStatus.Descriptor instead.
+func (TaskStatus) EnumDescriptor() ([]byte, []int) {
+	return file_proto_task_proto_rawDescGZIP(), []int{1}
+}
+
 // 任务请求
 type TaskRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -79,6 +150,17 @@ type TaskRequest struct {
 	Script        string                 `protobuf:"bytes,3,opt,name=script,proto3" json:"script,omitempty"`
 	Timeout       int32                  `protobuf:"varint,4,opt,name=timeout,proto3" json:"timeout,omitempty"`                                                                  // 秒
 	Env           map[string]string      `protobuf:"bytes,5,rep,name=env,proto3" json:"env,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // 环境变量
+	ExpiresAt     int64                  `protobuf:"varint,6,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`                                             // 签名过期时间（Unix 秒）
+	Signature     []byte                 `protobuf:"bytes,7,opt,name=signature,proto3" json:"signature,omitempty"`                                                               // 平台对任务内容与过期时间的 Ed25519 签名
+	RunAsUser     string                 `protobuf:"bytes,8,opt,name=run_as_user,json=runAsUser,proto3" json:"run_as_user,omitempty"`                                            // 以该用户身份执行，为空时使用 Agent 的用户
+	RunAsGroup    string                 `protobuf:"bytes,9,opt,name=run_as_group,json=runAsGroup,proto3" json:"run_as_group,omitempty"`                                         // 以该用户组身份执行，为空时使用用户的主组
+	WorkDir       string                 `protobuf:"bytes,10,opt,name=work_dir,json=workDir,proto3" json:"work_dir,omitempty"`                                                   // 工作目录
+	Umask         string                 `protobuf:"bytes,11,opt,name=umask,proto3" json:"umask,omitempty"`                                                                      // 八进制，如 "022"，为空时继承 Agent
+	Limits        *ResourceLimits        `protobuf:"bytes,12,opt,name=limits,proto3" json:"limits,omitempty"`
+	Args          []string               `protobuf:"bytes,13,rep,name=args,proto3" json:"args,omitempty"`                               // 脚本或可执行文件的参数
+	File          []byte                 `protobuf:"bytes,14,opt,name=file,proto3" json:"file,omitempty"`                               // TASK_TYPE_FILE 的文件内容
+	FileName      string                 `protobuf:"bytes,15,opt,name=file_name,json=fileName,proto3" json:"file_name,omitempty"`       // 写入临时目录时的文件名，不含路径
+	FileSha256    string                 `protobuf:"bytes,16,opt,name=file_sha256,json=fileSha256,proto3" json:"file_sha256,omitempty"` // 文件内容的 SHA-256（十六进制），Agent 执行前校验
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -148,6 +230,197 @@ func (x *TaskRequest) GetEnv() map[string]string {
 	return nil
 }
 
+func (x *TaskRequest) GetExpiresAt() int64 {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return 0
+}
+
+func (x *TaskRequest) GetSignature() []byte {
+	if x != nil {
+		return x.Signature
+	}
+	return nil
+}
+
+func (x *TaskRequest) GetRunAsUser() string {
+	if x != nil {
+		return x.RunAsUser
+	}
+	return ""
+}
+
+func (x *TaskRequest) GetRunAsGroup() string {
+	if x != nil {
+		return x.RunAsGroup
+	}
+	return ""
+}
+
+func (x *TaskRequest) GetWorkDir() string {
+	if x != nil {
+		return x.WorkDir
+	}
+	return ""
+}
+
+func (x *TaskRequest) GetUmask() string {
+	if x != nil {
+		return x.Umask
+	}
+	return ""
+}
+
+func (x *TaskRequest) GetLimits() *ResourceLimits {
+	if x != nil {
+		return x.Limits
+	}
+	return nil
+}
+
+func (x *TaskRequest) GetArgs() []string {
+	if x != nil {
+		return x.Args
+	}
+	return nil
+}
+
+func (x *TaskRequest) GetFile() []byte {
+	if x != nil {
+		return x.File
+	}
+	return nil
+}
+
+func (x *TaskRequest) GetFileName() string {
+	if x != nil {
+		return x.FileName
+	}
+	return ""
+}
+
+func (x *TaskRequest) GetFileSha256() string {
+	if x != nil {
+		return x.FileSha256
+	}
+	return ""
+}
+
+// 资源限制，0 表示不限制
+type ResourceLimits struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	CpuSeconds    uint64                 `protobuf:"varint,1,opt,name=cpu_seconds,json=cpuSeconds,proto3" json:"cpu_seconds,omitempty"`       // CPU 时间（秒）
+	AddressSpace  uint64                 `protobuf:"varint,2,opt,name=address_space,json=addressSpace,proto3" json:"address_space,omitempty"` // 虚拟内存（字节）
+	OpenFiles     uint64                 `protobuf:"varint,3,opt,name=open_files,json=openFiles,proto3" json:"open_files,omitempty"`          // 打开文件数
+	Processes     uint64                 `protobuf:"varint,4,opt,name=processes,proto3" json:"processes,omitempty"`                           // 进程数
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ResourceLimits) Reset() {
+	*x = ResourceLimits{}
+	mi := &file_proto_task_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ResourceLimits) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResourceLimits) ProtoMessage() {}
+
+func (x *ResourceLimits) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_task_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResourceLimits.ProtoReflect.Descriptor instead.
+func (*ResourceLimits) Descriptor() ([]byte, []int) {
+	return file_proto_task_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ResourceLimits) GetCpuSeconds() uint64 {
+	if x != nil {
+		return x.CpuSeconds
+	}
+	return 0
+}
+
+func (x *ResourceLimits) GetAddressSpace() uint64 {
+	if x != nil {
+		return x.AddressSpace
+	}
+	return 0
+}
+
+func (x *ResourceLimits) GetOpenFiles() uint64 {
+	if x != nil {
+		return x.OpenFiles
+	}
+	return 0
+}
+
+func (x *ResourceLimits) GetProcesses() uint64 {
+	if x != nil {
+		return x.Processes
+	}
+	return 0
+}
+
+// 取消任务请求
+type CancelTaskRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TaskId        string                 `protobuf:"bytes,1,opt,name=task_id,json=taskId,proto3" json:"task_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CancelTaskRequest) Reset() {
+	*x = CancelTaskRequest{}
+	mi := &file_proto_task_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CancelTaskRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancelTaskRequest) ProtoMessage() {}
+
+func (x *CancelTaskRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_task_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CancelTaskRequest.ProtoReflect.Descriptor instead.
+func (*CancelTaskRequest) Descriptor() ([]byte, []int) {
+	return file_proto_task_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *CancelTaskRequest) GetTaskId() string {
+	if x != nil {
+		return x.TaskId
+	}
+	return ""
+}
+
 // 任务执行结果
 type TaskResult struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -156,13 +429,16 @@ type TaskResult struct {
 	Stdout        string                 `protobuf:"bytes,3,opt,name=stdout,proto3" json:"stdout,omitempty"`
 	Stderr        string                 `protobuf:"bytes,4,opt,name=stderr,proto3" json:"stderr,omitempty"`
 	CompletedAt   *Timestamp             `protobuf:"bytes,5,opt,name=completed_at,json=completedAt,proto3" json:"completed_at,omitempty"`
+	Status        TaskStatus             `protobuf:"varint,6,opt,name=status,proto3,enum=proto.TaskStatus" json:"status,omitempty"`
+	Error         string                 `protobuf:"bytes,7,opt,name=error,proto3" json:"error,omitempty"`
+	LimitExceeded string                 `protobuf:"bytes,8,opt,name=limit_exceeded,json=limitExceeded,proto3" json:"limit_exceeded,omitempty"` // 因资源限制被终止时为触发的限制，如 cpu_seconds
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *TaskResult) Reset() {
 	*x = TaskResult{}
-	mi := &file_proto_task_proto_msgTypes[1]
+	mi := &file_proto_task_proto_msgTypes[3]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -174,7 +450,7 @@ func (x *TaskResult) String() string {
 func (*TaskResult) ProtoMessage() {}
 
 func (x *TaskResult) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_task_proto_msgTypes[1]
+	mi := &file_proto_task_proto_msgTypes[3]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -187,7 +463,7 @@ func (x *TaskResult) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use TaskResult.ProtoReflect.Descriptor instead.
 func (*TaskResult) Descriptor() ([]byte, []int) {
-	return file_proto_task_proto_rawDescGZIP(), []int{1}
+	return file_proto_task_proto_rawDescGZIP(), []int{3}
 }
 
 func (x *TaskResult) GetTaskId() string {
@@ -225,6 +501,80 @@ func (x *TaskResult) GetCompletedAt() *Timestamp {
 	return nil
 }
 
+func (x *TaskResult) GetStatus() TaskStatus {
+	if x != nil {
+		return x.Status
+	}
+	return TaskStatus_TASK_STATUS_UNSPECIFIED
+}
+
+func (x *TaskResult) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *TaskResult) GetLimitExceeded() string {
+	if x != nil {
+		return x.LimitExceeded
+	}
+	return ""
+}
+
+// 任务开始执行确认
+type TaskAck struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TaskId        string                 `protobuf:"bytes,1,opt,name=task_id,json=taskId,proto3" json:"task_id,omitempty"`
+	StartedAt     *Timestamp             `protobuf:"bytes,2,opt,name=started_at,json=startedAt,proto3" json:"started_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TaskAck) Reset() {
+	*x = TaskAck{}
+	mi := &file_proto_task_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TaskAck) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TaskAck) ProtoMessage() {}
+
+func (x *TaskAck) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_task_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TaskAck.ProtoReflect.Descriptor instead.
+func (*TaskAck) Descriptor() ([]byte, []int) {
+	return file_proto_task_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *TaskAck) GetTaskId() string {
+	if x != nil {
+		return x.TaskId
+	}
+	return ""
+}
+
+func (x *TaskAck) GetStartedAt() *Timestamp {
+	if x != nil {
+		return x.StartedAt
+	}
+	return nil
+}
+
 // 任务执行日志（流式）
 type TaskLog struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -232,13 +582,14 @@ type TaskLog struct {
 	Output        string                 `protobuf:"bytes,2,opt,name=output,proto3" json:"output,omitempty"`
 	IsStderr      bool                   `protobuf:"varint,3,opt,name=is_stderr,json=isStderr,proto3" json:"is_stderr,omitempty"`
 	Timestamp     *Timestamp             `protobuf:"bytes,4,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Seq           int64                  `protobuf:"varint,5,opt,name=seq,proto3" json:"seq,omitempty"` // 同一任务内递增的日志序号
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *TaskLog) Reset() {
 	*x = TaskLog{}
-	mi := &file_proto_task_proto_msgTypes[2]
+	mi := &file_proto_task_proto_msgTypes[5]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -250,7 +601,7 @@ func (x *TaskLog) String() string {
 func (*TaskLog) ProtoMessage() {}
 
 func (x *TaskLog) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_task_proto_msgTypes[2]
+	mi := &file_proto_task_proto_msgTypes[5]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -263,7 +614,7 @@ func (x *TaskLog) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use TaskLog.ProtoReflect.Descriptor instead.
 func (*TaskLog) Descriptor() ([]byte, []int) {
-	return file_proto_task_proto_rawDescGZIP(), []int{2}
+	return file_proto_task_proto_rawDescGZIP(), []int{5}
 }
 
 func (x *TaskLog) GetTaskId() string {
@@ -294,36 +645,87 @@ func (x *TaskLog) GetTimestamp() *Timestamp {
 	return nil
 }
 
+func (x *TaskLog) GetSeq() int64 {
+	if x != nil {
+		return x.Seq
+	}
+	return 0
+}
+
 var File_proto_task_proto protoreflect.FileDescriptor
 
 const file_proto_task_proto_rawDesc = "" +
 	"\n" +
-	"\x10proto/task.proto\x12\x05proto\x1a\x12proto/common.proto\"\xe4\x01\n" +
+	"\x10proto/task.proto\x12\x05proto\x1a\x12proto/common.proto\"\xa9\x04\n" +
 	"\vTaskRequest\x12\x17\n" +
 	"\atask_id\x18\x01 \x01(\tR\x06taskId\x12#\n" +
 	"\x04type\x18\x02 \x01(\x0e2\x0f.proto.TaskTypeR\x04type\x12\x16\n" +
 	"\x06script\x18\x03 \x01(\tR\x06script\x12\x18\n" +
 	"\atimeout\x18\x04 \x01(\x05R\atimeout\x12-\n" +
-	"\x03env\x18\x05 \x03(\v2\x1b.proto.TaskRequest.EnvEntryR\x03env\x1a6\n" +
+	"\x03env\x18\x05 \x03(\v2\x1b.proto.TaskRequest.EnvEntryR\x03env\x12\x1d\n" +
+	"\n" +
+	"expires_at\x18\x06 \x01(\x03R\texpiresAt\x12\x1c\n" +
+	"\tsignature\x18\a \x01(\fR\tsignature\x12\x1e\n" +
+	"\vrun_as_user\x18\b \x01(\tR\trunAsUser\x12 \n" +
+	"\frun_as_group\x18\t \x01(\tR\n" +
+	"runAsGroup\x12\x19\n" +
+	"\bwork_dir\x18\n" +
+	" \x01(\tR\aworkDir\x12\x14\n" +
+	"\x05umask\x18\v \x01(\tR\x05umask\x12-\n" +
+	"\x06limits\x18\f \x01(\v2\x15.proto.ResourceLimitsR\x06limits\x12\x12\n" +
+	"\x04args\x18\r \x03(\tR\x04args\x12\x12\n" +
+	"\x04file\x18\x0e \x01(\fR\x04file\x12\x1b\n" +
+	"\tfile_name\x18\x0f \x01(\tR\bfileName\x12\x1f\n" +
+	"\vfile_sha256\x18\x10 \x01(\tR\n" +
+	"fileSha256\x1a6\n" +
 	"\bEnvEntry\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
-	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xa7\x01\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\x93\x01\n" +
+	"\x0eResourceLimits\x12\x1f\n" +
+	"\vcpu_seconds\x18\x01 \x01(\x04R\n" +
+	"cpuSeconds\x12#\n" +
+	"\raddress_space\x18\x02 \x01(\x04R\faddressSpace\x12\x1d\n" +
+	"\n" +
+	"open_files\x18\x03 \x01(\x04R\topenFiles\x12\x1c\n" +
+	"\tprocesses\x18\x04 \x01(\x04R\tprocesses\",\n" +
+	"\x11CancelTaskRequest\x12\x17\n" +
+	"\atask_id\x18\x01 \x01(\tR\x06taskId\"\x8f\x02\n" +
 	"\n" +
 	"TaskResult\x12\x17\n" +
 	"\atask_id\x18\x01 \x01(\tR\x06taskId\x12\x1b\n" +
 	"\texit_code\x18\x02 \x01(\x05R\bexitCode\x12\x16\n" +
 	"\x06stdout\x18\x03 \x01(\tR\x06stdout\x12\x16\n" +
 	"\x06stderr\x18\x04 \x01(\tR\x06stderr\x123\n" +
-	"\fcompleted_at\x18\x05 \x01(\v2\x10.proto.TimestampR\vcompletedAt\"\x87\x01\n" +
+	"\fcompleted_at\x18\x05 \x01(\v2\x10.proto.TimestampR\vcompletedAt\x12)\n" +
+	"\x06status\x18\x06 \x01(\x0e2\x11.proto.TaskStatusR\x06status\x12\x14\n" +
+	"\x05error\x18\a \x01(\tR\x05error\x12%\n" +
+	"\x0elimit_exceeded\x18\b \x01(\tR\rlimitExceeded\"S\n" +
+	"\aTaskAck\x12\x17\n" +
+	"\atask_id\x18\x01 \x01(\tR\x06taskId\x12/\n" +
+	"\n" +
+	"started_at\x18\x02 \x01(\v2\x10.proto.TimestampR\tstartedAt\"\x99\x01\n" +
 	"\aTaskLog\x12\x17\n" +
 	"\atask_id\x18\x01 \x01(\tR\x06taskId\x12\x16\n" +
 	"\x06output\x18\x02 \x01(\tR\x06output\x12\x1b\n" +
 	"\tis_stderr\x18\x03 \x01(\bR\bisStderr\x12.\n" +
-	"\ttimestamp\x18\x04 \x01(\v2\x10.proto.TimestampR\ttimestamp*P\n" +
+	"\ttimestamp\x18\x04 \x01(\v2\x10.proto.TimestampR\ttimestamp\x12\x10\n" +
+	"\x03seq\x18\x05 \x01(\x03R\x03seq*\xa6\x01\n" +
 	"\bTaskType\x12\x19\n" +
 	"\x15TASK_TYPE_UNSPECIFIED\x10\x00\x12\x13\n" +
 	"\x0fTASK_TYPE_SHELL\x10\x01\x12\x14\n" +
-	"\x10TASK_TYPE_PYTHON\x10\x02B.Z,github.com/yourusername/agent-platform/protob\x06proto3"
+	"\x10TASK_TYPE_PYTHON\x10\x02\x12\x12\n" +
+	"\x0eTASK_TYPE_BASH\x10\x03\x12\x18\n" +
+	"\x14TASK_TYPE_POWERSHELL\x10\x04\x12\x12\n" +
+	"\x0eTASK_TYPE_PERL\x10\x05\x12\x12\n" +
+	"\x0eTASK_TYPE_FILE\x10\x06*\xaa\x01\n" +
+	"\n" +
+	"TaskStatus\x12\x1b\n" +
+	"\x17TASK_STATUS_UNSPECIFIED\x10\x00\x12\x19\n" +
+	"\x15TASK_STATUS_COMPLETED\x10\x01\x12\x16\n" +
+	"\x12TASK_STATUS_FAILED\x10\x02\x12\x17\n" +
+	"\x13TASK_STATUS_TIMEOUT\x10\x03\x12\x19\n" +
+	"\x15TASK_STATUS_CANCELLED\x10\x04\x12\x18\n" +
+	"\x14TASK_STATUS_REJECTED\x10\x05B.Z,github.com/yourusername/agent-platform/protob\x06proto3"
 
 var (
 	file_proto_task_proto_rawDescOnce sync.Once
@@ -337,26 +739,33 @@ func file_proto_task_proto_rawDescGZIP() []byte {
 	return file_proto_task_proto_rawDescData
 }
 
-var file_proto_task_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
-var file_proto_task_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_proto_task_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
+var file_proto_task_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
 var file_proto_task_proto_goTypes = []any{
-	(TaskType)(0),       // 0: proto.TaskType
-	(*TaskRequest)(nil), // 1: proto.TaskRequest
-	(*TaskResult)(nil),  // 2: proto.TaskResult
-	(*TaskLog)(nil),     // 3: proto.TaskLog
-	nil,                 // 4: proto.TaskRequest.EnvEntry
-	(*Timestamp)(nil),   // 5: proto.Timestamp
+	(TaskType)(0),             // 0: proto.TaskType
+	(TaskStatus)(0),           // 1: proto.TaskStatus
+	(*TaskRequest)(nil),       // 2: proto.TaskRequest
+	(*ResourceLimits)(nil),    // 3: proto.ResourceLimits
+	(*CancelTaskRequest)(nil), // 4: proto.CancelTaskRequest
+	(*TaskResult)(nil),        // 5: proto.TaskResult
+	(*TaskAck)(nil),           // 6: proto.TaskAck
+	(*TaskLog)(nil),           // 7: proto.TaskLog
+	nil,                       // 8: proto.TaskRequest.EnvEntry
+	(*Timestamp)(nil),         // 9: proto.Timestamp
 }
 var file_proto_task_proto_depIdxs = []int32{
 	0, // 0: proto.TaskRequest.type:type_name -> proto.TaskType
-	4, // 1: proto.TaskRequest.env:type_name -> proto.TaskRequest.EnvEntry
-	5, // 2: proto.TaskResult.completed_at:type_name -> proto.Timestamp
-	5, // 3: proto.TaskLog.timestamp:type_name -> proto.Timestamp
-	4, // [4:4] is the sub-list for method output_type
-	4, // [4:4] is the sub-list for method input_type
-	4, // [4:4] is the sub-list for extension type_name
-	4, // [4:4] is the sub-list for extension extendee
-	0, // [0:4] is the sub-list for field type_name
+	8, // 1: proto.TaskRequest.env:type_name -> proto.TaskRequest.EnvEntry
+	3, // 2: proto.TaskRequest.limits:type_name -> proto.ResourceLimits
+	9, // 3: proto.TaskResult.completed_at:type_name -> proto.Timestamp
+	1, // 4: proto.TaskResult.status:type_name -> proto.TaskStatus
+	9, // 5: proto.TaskAck.started_at:type_name -> proto.Timestamp
+	9, // 6: proto.TaskLog.timestamp:type_name -> proto.Timestamp
+	7, // [7:7] is the sub-list for method output_type
+	7, // [7:7] is the sub-list for method input_type
+	7, // [7:7] is the sub-list for extension type_name
+	7, // [7:7] is the sub-list for extension extendee
+	0, // [0:7] is the sub-list for field type_name
 }
 
 func init() { file_proto_task_proto_init() }
@@ -370,8 +779,8 @@ func file_proto_task_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_task_proto_rawDesc), len(file_proto_task_proto_rawDesc)),
-			NumEnums:      1,
-			NumMessages:   4,
+			NumEnums:      2,
+			NumMessages:   7,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
@@ -2,12 +2,15 @@ import React from 'react'
 import { BrowserRouter, Routes, Route, Navigate } from 'react-router-dom'
 import Layout from './components/Layout'
 import AgentList from './pages/AgentList'
+import Login from './pages/Login'
+import { getToken } from './services/api'
 
 const App: React.FC = () => {
   return (
     <BrowserRouter>
       <Routes>
-        <Route path="/" element={<Layout />}>
+        <Route path="/login" element={<Login />} />
+        <Route path="/" element={getToken() ? <Layout /> : <Navigate to="/login" replace />}>
           <Route index element={<Navigate to="/agents" replace />} />
           <Route path="agents" element={<AgentList />} />
         </Route>
@@ -0,0 +1,46 @@
+import React, { useState } from 'react'
+import { Card, Form, Input, Button, message } from 'antd'
+import { useNavigate } from 'react-router-dom'
+import { authApi, setToken } from '../services/api'
+
+const Login: React.FC = () => {
+  const navigate = useNavigate()
+  const [loading, setLoading] = useState(false)
+
+  const handleLogin = async (values: { username: string; password: string }) => {
+    setLoading(true)
+    try {
+      const res = await authApi.login(values.username, values.password)
+      if (res.data.code !== 0) {
+        message.error('用户名或密码错误')
+        return
+      }
+      setToken(res.data.data.token)
+      navigate('/agents', { replace: true })
+    } catch (error) {
+      message.error('登录失败')
+    } finally {
+      setLoading(false)
+    }
+  }
+
+  return (
+    <div style={{ display: 'flex', justifyContent: 'center', alignItems: 'center', minHeight: '100vh', background: '#f0f2f5' }}>
+      <Card title="Agent 管理平台" style={{ width: 360 }}>
+        <Form onFinish={handleLogin} layout="vertical">
+          <Form.Item name="username" label="用户名" rules={[{ required: true }]}>
+            <Input />
+          </Form.Item>
+          <Form.Item name="password" label="密码" rules={[{ required: true }]}>
+            <Input.Password />
+          </Form.Item>
+          <Button type="primary" htmlType="submit" loading={loading} block>
+            登录
+          </Button>
+        </Form>
+      </Card>
+    </div>
+  )
+}
+
+export default Login
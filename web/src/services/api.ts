@@ -1,25 +1,178 @@
 import axios from 'axios'
-import type { Agent, Task, Metric } from '../types'
+import type { Agent, AgentEvent, AuditPage, AuditVerifyResult, EnrollmentToken, PluginPackage, Principal, ResourceLimits, Script, ScriptDiff, ScriptTemplate, ScriptVersion, Task, TaskLog, TemplateParam, Job, JobSummary, Metric } from '../types'
 
 const api = axios.create({
   baseURL: '/api/v1',
   timeout: 10000,
 })
 
+const TOKEN_KEY = 'token'
+
+export const getToken = () => localStorage.getItem(TOKEN_KEY)
+export const setToken = (token: string | null) =>
+  token ? localStorage.setItem(TOKEN_KEY, token) : localStorage.removeItem(TOKEN_KEY)
+
+// 携带登录令牌，令牌失效时跳转登录页
+api.interceptors.request.use((config) => {
+  const token = getToken()
+  if (token) {
+    config.headers.Authorization = `Bearer ${token}`
+  }
+  return config
+})
+
+api.interceptors.response.use((response) => {
+  if (response.data?.code === 401 && !response.config.url?.startsWith('/auth/login')) {
+    setToken(null)
+    window.location.href = '/login'
+  }
+  return response
+})
+
+export const authApi = {
+  login: (username: string, password: string) =>
+    api.post<{ code: number; message: string; data: { token: string } }>('/auth/login', { username, password }),
+  logout: () => api.post('/auth/logout'),
+  me: () => api.get<{ data: Principal }>('/auth/me'),
+}
+
 export const agentApi = {
   list: () => api.get<{ data: Agent[] }>('/agents'),
   get: (id: number) => api.get<{ data: Agent }>(`/agents/${id}`),
   delete: (id: number) => api.delete(`/agents/${id}`),
+  events: (id: number, type?: string) =>
+    api.get<{ data: AgentEvent[] }>(`/agents/${id}/events`, { params: { type } }),
+  revoke: (id: number) => api.post(`/agents/${id}/revoke`),
+}
+
+export const enrollmentApi = {
+  // 明文令牌只在创建时返回一次
+  createToken: (data: { ttl_seconds?: number; description?: string }) =>
+    api.post<{ data: { token: string; info: EnrollmentToken } }>('/enrollment-tokens', data),
+  listTokens: () => api.get<{ data: EnrollmentToken[] }>('/enrollment-tokens'),
+  deleteToken: (id: number) => api.delete(`/enrollment-tokens/${id}`),
 }
 
 export const taskApi = {
-  create: (data: { agent_id: string; type: string; script: string; timeout?: number }) =>
-    api.post<{ data: Task }>('/tasks', data),
+  create: (data: {
+    agent_id: string
+    type?: string
+    script?: string
+    // 引用脚本库，格式为 script_id@version，省略版本时使用最新版本
+    script_ref?: string
+    // 指定模板时由模板渲染脚本
+    template_id?: number
+    params?: Record<string, string | number>
+    env?: Record<string, string>
+    args?: string[]
+    // type 为 file 时使用，file 为 base64 编码的文件内容
+    file?: string
+    file_name?: string
+    file_sha256?: string
+    timeout?: number
+    run_as_user?: string
+    run_as_group?: string
+    work_dir?: string
+    umask?: string
+    limits?: Partial<ResourceLimits>
+  }) => api.post<{ data: Task }>('/tasks', data),
   list: (agentId?: string) => api.get<{ data: Task[] }>('/tasks', { params: { agent_id: agentId } }),
   get: (id: number) => api.get<{ data: Task }>(`/tasks/${id}`),
+  // 策略要求审批的任务处于 awaiting_approval，需由创建者以外的用户批准
+  approve: (id: number) => api.post<{ data: Task }>(`/tasks/${id}/approve`),
+  reject: (id: number, reason?: string) => api.post<{ data: Task }>(`/tasks/${id}/reject`, { reason }),
+  logs: (id: number, afterSeq?: number) =>
+    api.get<{ data: TaskLog[] }>(`/tasks/${id}/logs`, { params: { after_seq: afterSeq } }),
+  // 实时日志（Server-Sent Events），监听 log 与 end 事件
+  // EventSource 无法设置请求头，通过查询参数携带令牌
+  streamLogs: (id: number) =>
+    new EventSource(`/api/v1/tasks/${id}/logs/stream?access_token=${encodeURIComponent(getToken() ?? '')}`),
+}
+
+export interface ScriptInput {
+  name: string
+  description?: string
+  type: string
+  script: string
+  tags?: string[]
+  comment?: string
+}
+
+export const scriptApi = {
+  list: (tag?: string) => api.get<{ data: Script[] }>('/scripts', { params: { tag } }),
+  get: (id: number) => api.get<{ data: Script }>(`/scripts/${id}`),
+  create: (data: ScriptInput) => api.post<{ data: Script }>('/scripts', data),
+  // 内容或解释器变化时生成新版本
+  update: (id: number, data: ScriptInput) => api.put<{ data: Script }>(`/scripts/${id}`, data),
+  delete: (id: number) => api.delete(`/scripts/${id}`),
+  versions: (id: number) => api.get<{ data: ScriptVersion[] }>(`/scripts/${id}/versions`),
+  version: (id: number, version: number) => api.get<{ data: ScriptVersion }>(`/scripts/${id}/versions/${version}`),
+  deleteVersion: (id: number, version: number) => api.delete(`/scripts/${id}/versions/${version}`),
+  diff: (id: number, from?: number, to?: number) =>
+    api.get<{ data: ScriptDiff }>(`/scripts/${id}/diff`, { params: { from, to } }),
+}
+
+export const templateApi = {
+  list: () => api.get<{ data: ScriptTemplate[] }>('/templates'),
+  get: (id: number) => api.get<{ data: ScriptTemplate }>(`/templates/${id}`),
+  create: (data: { name: string; description?: string; type: string; script: string; params?: TemplateParam[] }) =>
+    api.post<{ data: ScriptTemplate }>('/templates', data),
+  update: (id: number, data: { name: string; description?: string; type: string; script: string; params?: TemplateParam[] }) =>
+    api.put<{ data: ScriptTemplate }>(`/templates/${id}`, data),
+  delete: (id: number) => api.delete(`/templates/${id}`),
+}
+
+export const jobApi = {
+  create: (data: {
+    name?: string
+    agent_ids?: string[]
+    selector?: string
+    type: string
+    script: string
+    timeout?: number
+    concurrency?: number
+    batch_size?: number
+    stop_on_failure_percent?: number
+  }) => api.post<{ data: Job }>('/jobs', data),
+  list: (status?: string) => api.get<{ data: Job[] }>('/jobs', { params: { status } }),
+  get: (id: number) => api.get<{ data: JobSummary }>(`/jobs/${id}`),
+  cancel: (id: number) => api.post(`/jobs/${id}/cancel`),
+}
+
+export const pluginApi = {
+  // 插件仓库，安装包为包含 plugin.yaml 的 tar.gz
+  listPackages: (name?: string) => api.get<{ data: PluginPackage[] }>('/plugin-packages', { params: { name } }),
+  getPackage: (id: number) => api.get<{ data: PluginPackage }>(`/plugin-packages/${id}`),
+  uploadPackage: (file: File) => {
+    const form = new FormData()
+    form.append('file', file)
+    return api.post<{ data: PluginPackage }>('/plugin-packages', form, { timeout: 0 })
+  },
+  deletePackage: (id: number) => api.delete(`/plugin-packages/${id}`),
+  // 未指定版本时安装仓库中最后上传的版本
+  install: (data: { agent_id: string; plugin_name: string; version?: string; config?: Record<string, string> }) =>
+    api.post('/plugins/install', data),
+  uninstall: (agent_id: string, plugin_name: string) => api.post('/plugins/uninstall', { agent_id, plugin_name }),
+  list: (agent_id: string) => api.get('/plugins', { params: { agent_id } }),
 }
 
 export const metricApi = {
   query: (params: { agent_id?: string; name?: string; start_time?: string; end_time?: string }) =>
     api.get<{ data: Metric[] }>('/metrics', { params }),
 }
+
+export interface AuditQuery {
+  user_id?: string
+  action?: string
+  start_time?: string
+  end_time?: string
+  page?: number
+  page_size?: number
+}
+
+export const auditApi = {
+  list: (params: AuditQuery) => api.get<{ data: AuditPage }>('/audit', { params }),
+  export: (params: AuditQuery, format: 'csv' | 'jsonl') =>
+    api.get<Blob>('/audit/export', { params: { ...params, format }, responseType: 'blob' }),
+  verify: () => api.get<{ data: AuditVerifyResult }>('/audit/verify'),
+}
@@ -4,14 +4,48 @@ export interface Agent {
   hostname: string
   ip: string
   os: string
+  os_version: string
   arch: string
   version: string
+  kernel_version: string
+  cpu_count: number
+  memory_total: number
+  boot_time: string | null
+  labels: string
+  group: string
   status: string
   last_heartbeat: string
+  enrolled_at: string | null
+  revoked_at: string | null
   created_at: string
   updated_at: string
 }
 
+export interface AgentEvent {
+  id: number
+  agent_id: string
+  type: 'status_changed' | 'inventory_changed' | 'enrolled' | 'revoked' | 'plugin_status'
+  details: string
+  created_at: string
+}
+
+export interface EnrollmentToken {
+  id: number
+  description: string
+  expires_at: string
+  used_at: string | null
+  used_by: string
+  created_at: string
+}
+
+// 资源限制，0 表示不限制
+export interface ResourceLimits {
+  cpu_seconds: number
+  address_space: number
+  open_files: number
+  processes: number
+}
+
 export interface Task {
   id: number
   agent_id: string
@@ -19,10 +53,126 @@ export interface Task {
   script: string
   status: string
   result: string
+  run_as_user?: string
+  run_as_group?: string
+  work_dir?: string
+  umask?: string
+  limits?: ResourceLimits
+  limit_exceeded?: string
+  args?: string // JSON 编码的参数列表
+  file_name?: string
+  file_sha256?: string
+  env?: string // JSON 编码，secret 参数值以掩码代替
+  template_id?: number
+  script_id?: number
+  script_version?: number
+  params?: string // JSON 编码，secret 参数值以掩码代替
+  has_secrets?: boolean
+  created_by: string
+  approved_by?: string
+  created_at: string
+  updated_at: string
+}
+
+export interface Script {
+  id: number
+  name: string
+  description: string
+  type: string
+  tags: string // JSON 编码的标签列表
+  latest_version: number
+  created_by: string
   created_at: string
   updated_at: string
 }
 
+export interface ScriptVersion {
+  id: number
+  script_id: number
+  version: number
+  type: string
+  script: string
+  comment: string
+  created_by: string
+  created_at: string
+}
+
+export interface ScriptDiff {
+  from: number
+  to: number
+  from_type: string
+  to_type: string
+  diff: string
+}
+
+export interface TemplateParam {
+  name: string
+  type: 'string' | 'int' | 'enum' | 'secret'
+  required: boolean
+  default?: string
+  options?: string[]
+  description?: string
+}
+
+export interface ScriptTemplate {
+  id: number
+  name: string
+  description: string
+  type: string
+  script: string
+  params: string // JSON 编码的 TemplateParam[]
+  created_by: string
+  created_at: string
+  updated_at: string
+}
+
+export interface PluginPackage {
+  id: number
+  name: string
+  version: string
+  description: string
+  sha256: string
+  size: number
+  manifest: string // JSON 编码的 plugin.yaml
+  uploaded_by: string
+  created_at: string
+}
+
+export interface TaskLog {
+  id: number
+  task_id: string
+  seq: number
+  stream: 'stdout' | 'stderr'
+  output: string
+  timestamp: string
+}
+
+export interface Job {
+  id: number
+  job_id: string
+  name: string
+  type: string
+  script: string
+  timeout: number
+  selector: string
+  concurrency: number
+  batch_size: number
+  stop_on_failure_percent: number
+  status: string
+  created_at: string
+  updated_at: string
+  completed_at: string | null
+}
+
+export interface JobSummary {
+  job: Job
+  total: number
+  counts: Record<string, number>
+  success_rate: number
+  exit_codes: Record<string, number>
+  agents: { agent_id: string; task_id: string; batch: number; status: string; exit_code: number }[]
+}
+
 export interface Metric {
   id: number
   agent_id: string
@@ -31,3 +181,41 @@ export interface Metric {
   labels: string
   timestamp: string
 }
+
+export interface Principal {
+  user_id: number
+  username: string
+  role: 'viewer' | 'operator' | 'admin'
+  groups: string[] | null
+}
+
+export interface AuditLog {
+  id: number
+  user_id: string
+  action: string
+  resource: string
+  details: string
+  ip: string
+  user_agent: string
+  status: string
+  prev_hash: string
+  hash: string
+  created_at: string
+}
+
+export interface AuditVerifyResult {
+  valid: boolean
+  checked: number
+  unchained: number
+  checkpoints: number
+  broken_id?: number
+  reason?: string
+  public_key?: string
+}
+
+export interface AuditPage {
+  items: AuditLog[]
+  total: number
+  page: number
+  page_size: number
+}